@@ -0,0 +1,77 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	config "ecommerce/pkg/config"
+	db "ecommerce/pkg/db"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/repository"
+	"ecommerce/pkg/usecase"
+)
+
+const usage = `usage: admin <command>
+
+commands:
+  create-super-admin <email> <name>   create the first super admin, the password
+                                      is read from ADMIN_PASSWORD or asked for`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	switch os.Args[1] {
+	case "create-super-admin":
+		if len(os.Args) != 4 {
+			fmt.Println(usage)
+			os.Exit(2)
+		}
+		createSuperAdmin(os.Args[2], os.Args[3])
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
+
+func createSuperAdmin(email, name string) {
+	password := os.Getenv("ADMIN_PASSWORD")
+	if password == "" {
+		fmt.Print("password: ")
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		if err != nil {
+			log.Fatal("cannot read password: ", err)
+		}
+		password = strings.TrimSpace(line)
+	}
+	if len(password) < 8 || len(password) > 15 {
+		log.Fatal("password must be 8 to 15 characters")
+	}
+
+	cfg, configErr := config.LoadConfig()
+	if configErr != nil {
+		log.Fatal("cannot load config: ", configErr)
+	}
+
+	gormDB, err := db.OpenDatabase(cfg)
+	if err != nil {
+		log.Fatal("cannot connect to database: ", err)
+	}
+
+	admins := usecase.NewAdminUseCase(repository.NewAdminRepository(gormDB), nil)
+	err = admins.CreateSuperAdmin(context.Background(), domain.Admin{
+		AdminName: name,
+		Email:     email,
+		Password:  password,
+	})
+	if err != nil {
+		log.Fatal("cannot create super admin: ", err)
+	}
+	fmt.Printf("created super admin %s\n", email)
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"log"
+	"time"
 
 	config "ecommerce/pkg/config"
 	di "ecommerce/pkg/di"
@@ -13,10 +15,10 @@ func main() {
 		log.Fatal("cannot load config: ", configErr)
 	}
 
-	server, diErr := di.InitializeAPI(config)
+	app, diErr := di.InitializeAPI(config)
 	if diErr != nil {
 		log.Fatal("cannot start server: ", diErr)
-	} else {
-		server.Start()
 	}
+	app.Sweeper.Start(context.Background(), time.Minute)
+	app.Server.Start()
 }
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	config "ecommerce/pkg/config"
+	db "ecommerce/pkg/db"
+)
+
+const usage = `usage: migrate <command>
+
+commands:
+  up           apply all pending migrations
+  down [n]     revert the last n migrations (default 1)
+  status       list migrations and whether they are applied`
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+
+	cfg, configErr := config.LoadConfig()
+	if configErr != nil {
+		log.Fatal("cannot load config: ", configErr)
+	}
+
+	gormDB, err := db.OpenDatabase(cfg)
+	if err != nil {
+		log.Fatal("cannot connect to database: ", err)
+	}
+
+	switch os.Args[1] {
+	case "up":
+		applied, err := db.MigrateUp(gormDB)
+		for _, migration := range applied {
+			fmt.Printf("up   %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(applied) == 0 {
+			fmt.Println("database is up to date")
+		}
+	case "down":
+		steps := 1
+		if len(os.Args) > 2 {
+			steps, err = strconv.Atoi(os.Args[2])
+			if err != nil {
+				log.Fatal("invalid number of steps: ", err)
+			}
+		}
+		reverted, err := db.MigrateDown(gormDB, steps)
+		for _, migration := range reverted {
+			fmt.Printf("down %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			log.Fatal(err)
+		}
+	case "status":
+		status, err := db.GetMigrationStatus(gormDB)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, migration := range status {
+			if migration.Applied {
+				fmt.Printf("[x] %04d_%s  %s\n", migration.Version, migration.Name, migration.AppliedAt.Format("2006-01-02 15:04:05"))
+			} else {
+				fmt.Printf("[ ] %04d_%s\n", migration.Version, migration.Name)
+			}
+		}
+	default:
+		fmt.Println(usage)
+		os.Exit(2)
+	}
+}
@@ -2,10 +2,12 @@ package handler
 
 import (
 	"ecommerce/pkg/api/utilhandler"
+	"ecommerce/pkg/commonhelp/export"
 	"ecommerce/pkg/domain"
 	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
@@ -26,7 +28,7 @@ func NewAdminHandler(Adusecase services.AdminUsecase) *AdminHandler {
 
 // @Summary SaveAdmin
 // @ID SaveAdmin
-// @Description Save admin with details.
+// @Description Super admin can create an admin with one of the roles super_admin, catalog_manager, order_manager or support.
 // @Tags Admin
 // @Accept json
 // @Produce json
@@ -87,7 +89,7 @@ func (cr *AdminHandler) LoginAdmin(c *gin.Context) {
 		})
 		return
 	}
-	ss, err := cr.AdminUsecase.LoginAdmin(c.Request.Context(), admin)
+	pair, err := cr.AdminUsecase.LoginAdmin(c.Request.Context(), admin, utilhandler.GetDevice(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -97,12 +99,11 @@ func (cr *AdminHandler) LoginAdmin(c *gin.Context) {
 		})
 		return
 	}
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("AdminAuth", ss, 3600*24*1, "", "", false, true)
+	setTokenCookies(c, pair, adminAccessCookie, adminRefreshCookie)
 	c.JSON(http.StatusOK, response.Response{
 		StatusCode: 200,
 		Message:    "logged in successfuly",
-		Data:       nil,
+		Data:       pair,
 		Errors:     nil,
 	})
 
@@ -117,7 +118,18 @@ func (cr *AdminHandler) LoginAdmin(c *gin.Context) {
 // @Failure 400 "failed"
 // @Router /admin/logout [post]
 func (cr *AdminHandler) AdminLogout(c *gin.Context) {
-	c.SetCookie("AdminAuth", "", -1, "", "", false, true)
+	err := cr.AdminUsecase.LogoutAdmin(c.Request.Context(), refreshTokenFrom(c, adminRefreshCookie))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to logout",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	clearTokenCookies(c, adminAccessCookie, adminRefreshCookie)
 	c.JSON(http.StatusOK, response.Response{
 		StatusCode: 200,
 		Message:    "logout successfully",
@@ -126,6 +138,47 @@ func (cr *AdminHandler) AdminLogout(c *gin.Context) {
 	})
 }
 
+// AdminLogoutEverywhere
+// @Summary Admin logout from every device
+// @ID AdminLogoutEverywhere
+// @Description Sign the admin out of every session, the current one included
+// @Tags Admin
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/logout/all [post]
+func (cr *AdminHandler) AdminLogoutEverywhere(c *gin.Context) {
+	adminId, err := utilhandler.GetAdminIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	err = cr.AdminUsecase.LogoutAdminEverywhere(c.Request.Context(), uint(adminId))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to logout",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	clearTokenCookies(c, adminAccessCookie, adminRefreshCookie)
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "logged out from every device",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
 type PaginationRequest struct {
 	Page    uint `json:"page"`
 	PerPage uint `json:"perpage"`
@@ -181,7 +234,7 @@ func (cr *AdminHandler) FindAllUser(c *gin.Context) {
 // BlockUser
 // @Summary Admin can block a user
 // @ID block-user
-// @Description Admin can block a  user
+// @Description Admin can block a user, for duration_hours or until unblocked when it is 0. The user is signed out everywhere
 // @Tags Admin
 // @Accept json
 // @Produce json
@@ -256,7 +309,17 @@ func (cr *AdminHandler) UnblockUser(c *gin.Context) {
 		})
 		return
 	}
-	err = cr.AdminUsecase.UnblockUser(id)
+	adminId, err := utilhandler.GetAdminIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	err = cr.AdminUsecase.UnblockUser(id, adminId)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -275,6 +338,282 @@ func (cr *AdminHandler) UnblockUser(c *gin.Context) {
 	})
 }
 
+// BlockHistory
+// @Summary Blocked user history
+// @ID block-history
+// @Description Admin can list every block with who placed and lifted it, newest first. user_id narrows it to one user
+// @Tags Admin
+// @Produce json
+// @Param user_id query int false "Only this user's blocks"
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response{data=[]response.BlockRecord}
+// @Failure 400 {object} response.Response
+// @Router /admin/blockhistory [get]
+func (cr *AdminHandler) BlockHistory(c *gin.Context) {
+	userId := 0
+	if value := c.Query("user_id"); value != "" {
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				StatusCode: 400,
+				Message:    "invalid user id",
+				Data:       nil,
+				Errors:     err.Error(),
+			})
+			return
+		}
+		userId = id
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	history, err := cr.AdminUsecase.BlockHistory(c.Request.Context(), userId, requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to get block history",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "block history",
+		Data:       history,
+		Errors:     nil,
+	})
+}
+
+// DeleteUser
+// @Summary Admin can delete a user
+// @ID delete-user
+// @Description Admin can delete a user's account, it is archived so their orders and reviews stay and it can be restored
+// @Tags Admin
+// @Produce json
+// @Param user_id path string true "ID of the user to be deleted"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/deleteuser/{user_id} [delete]
+func (cr *AdminHandler) DeleteUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	if err := cr.AdminUsecase.DeleteUser(c.Request.Context(), id); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant delete user",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "user deleted",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// RestoreUser
+// @Summary Admin can restore a deleted user
+// @ID restore-user
+// @Description Admin can restore an archived user account so they can log in again
+// @Tags Admin
+// @Produce json
+// @Param user_id path string true "ID of the user to be restored"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/restoreuser/{user_id} [patch]
+func (cr *AdminHandler) RestoreUser(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("user_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	user, err := cr.AdminUsecase.RestoreUser(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant restore user",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "user restored",
+		Data:       user,
+		Errors:     nil,
+	})
+}
+
+// ListAdmins
+// @Summary List admins
+// @ID list-admins
+// @Description Super admin can list the admins with their roles
+// @Tags Admin
+// @Produce json
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response{data=[]response.Admin}
+// @Failure 400 {object} response.Response
+// @Router /admin/admins [get]
+func (cr *AdminHandler) ListAdmins(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	admins, err := cr.AdminUsecase.ListAdmins(c.Request.Context(), requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "admins not found",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "admins",
+		Data:       admins,
+		Errors:     nil,
+	})
+}
+
+// ChangeAdminRole
+// @Summary Change an admin's role
+// @ID change-admin-role
+// @Description Super admin can give an admin another role, it applies to the admin's next request. The only super admin can't be demoted
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param admin_id path int true "Admin ID"
+// @Param input body requests.AdminRole true "New role"
+// @Success 200 {object} response.Response{data=response.Admin}
+// @Failure 400 {object} response.Response
+// @Router /admin/admins/{admin_id}/role [patch]
+func (cr *AdminHandler) ChangeAdminRole(c *gin.Context) {
+	adminId, err := strconv.Atoi(c.Param("admin_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid admin id",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var body requests.AdminRole
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	admin, err := cr.AdminUsecase.ChangeAdminRole(c.Request.Context(), uint(adminId), body.Role)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to change role",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "role changed",
+		Data:       admin,
+		Errors:     nil,
+	})
+}
+
+// ArchivedUsers
+// @Summary List deleted users
+// @ID archived-users
+// @Description Admin can list the archived user accounts, last deleted first
+// @Tags Admin
+// @Produce json
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/archivedusers [get]
+func (cr *AdminHandler) ArchivedUsers(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	users, err := cr.AdminUsecase.ArchivedUsers(c.Request.Context(), requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "users not found",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "archived users",
+		Data:       users,
+		Errors:     nil,
+	})
+}
+
 // FindUserByID
 // @Summary Admin can fetch a specific user details using user id
 // @ID find-user-by-id
@@ -305,3 +644,119 @@ func (cr *AdminHandler) FindUserByID(c *gin.Context) {
 	})
 
 }
+
+// AdminDashboard
+// @Summary Admin sales dashboard
+// @ID admin-dashboard
+// @Description Admin can see order, payment and user totals for a date range
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param start_date query string false "Start date (YYYY-MM-DD)"
+// @Param end_date query string false "End date (YYYY-MM-DD)"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/dashboard [get]
+func (cr *AdminHandler) Dashboard(c *gin.Context) {
+	dateRange, err := utilhandler.GetDateRangeFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid date range",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	dashboard, err := cr.AdminUsecase.Dashboard(c.Request.Context(), dateRange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't load dashboard",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "admin dashboard",
+		Data:       dashboard,
+		Errors:     nil,
+	})
+}
+
+// SalesReport
+// @Summary Admin sales report
+// @ID admin-sales-report
+// @Description Admin can download the sales report for a period as json, csv, xlsx or pdf
+// @Tags Admin
+// @Produce json
+// @Param period query string true "daily, weekly, monthly, yearly or custom"
+// @Param start_date query string false "Start date for custom period (YYYY-MM-DD)"
+// @Param end_date query string false "End date for custom period (YYYY-MM-DD)"
+// @Param format query string false "json (default), csv, xlsx or pdf"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/salesreport [get]
+func (cr *AdminHandler) SalesReport(c *gin.Context) {
+	dateRange, err := utilhandler.GetDateRangeFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid date range",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	period := c.DefaultQuery("period", "monthly")
+	report, err := cr.AdminUsecase.SalesReport(c.Request.Context(), period, dateRange)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't create sales report",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	fileName := fmt.Sprintf("sales_report_%s_%s", period, time.Now().Format("20060102"))
+	switch format := c.DefaultQuery("format", "json"); format {
+	case "json":
+		c.JSON(http.StatusOK, response.Response{
+			StatusCode: 200,
+			Message:    "sales report",
+			Data:       report,
+			Errors:     nil,
+		})
+		return
+	case "csv":
+		c.Header("Content-Type", "text/csv")
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".csv")
+		err = export.SalesReportCSV(c.Writer, report)
+	case "xlsx":
+		c.Header("Content-Type", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet")
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".xlsx")
+		err = export.SalesReportXLSX(c.Writer, report)
+	case "pdf":
+		c.Header("Content-Type", "application/pdf")
+		c.Header("Content-Disposition", "attachment; filename="+fileName+".pdf")
+		err = export.SalesReportPDF(c.Writer, "Sales Report ("+period+")", report)
+	default:
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "unsupported format",
+			Data:       nil,
+			Errors:     "format must be json, csv, xlsx or pdf",
+		})
+		return
+	}
+	if err != nil {
+		c.Error(err)
+	}
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/token"
+	services "ecommerce/pkg/usecase/interface"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// cookies the tokens are kept in, the access token cookie is the one the
+// auth middleware reads
+const (
+	userAccessCookie   = "UserAuth"
+	userRefreshCookie  = "UserRefresh"
+	adminAccessCookie  = "AdminAuth"
+	adminRefreshCookie = "AdminRefresh"
+)
+
+type AuthHandler struct {
+	tokenUseCase services.TokenUseCase
+}
+
+func NewAuthHandler(tokenUseCase services.TokenUseCase) *AuthHandler {
+	return &AuthHandler{
+		tokenUseCase: tokenUseCase,
+	}
+}
+
+// RefreshUserToken
+// @Summary Refresh user tokens
+// @ID RefreshUserToken
+// @Description Trade a user refresh token for a new access and refresh token. The token is read from the UserRefresh cookie or the body, and can be used once
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param   input   body     requests.RefreshToken{}   false  "Refresh token when not sent as a cookie"
+// @Success 200 {object} response.Response{data=response.TokenPair}
+// @Failure 401 {object} response.Response
+// @Router /token/refresh [post]
+func (cr *AuthHandler) RefreshUserToken(c *gin.Context) {
+	cr.refresh(c, token.AudienceUser, userAccessCookie, userRefreshCookie)
+}
+
+// RefreshAdminToken
+// @Summary Refresh admin tokens
+// @ID RefreshAdminToken
+// @Description Trade an admin refresh token for a new access and refresh token. The token is read from the AdminRefresh cookie or the body, and can be used once
+// @Tags Admin
+// @Accept json
+// @Produce json
+// @Param   input   body     requests.RefreshToken{}   false  "Refresh token when not sent as a cookie"
+// @Success 200 {object} response.Response{data=response.TokenPair}
+// @Failure 401 {object} response.Response
+// @Router /admin/token/refresh [post]
+func (cr *AuthHandler) RefreshAdminToken(c *gin.Context) {
+	cr.refresh(c, token.AudienceAdmin, adminAccessCookie, adminRefreshCookie)
+}
+
+func (cr *AuthHandler) refresh(c *gin.Context, audience, accessCookie, refreshCookie string) {
+	refreshToken := refreshTokenFrom(c, refreshCookie)
+	if refreshToken == "" {
+		c.JSON(http.StatusUnauthorized, response.Response{
+			StatusCode: 401,
+			Message:    "failed to refresh token",
+			Data:       nil,
+			Errors:     "refresh token is missing",
+		})
+		return
+	}
+
+	pair, err := cr.tokenUseCase.Refresh(c.Request.Context(), refreshToken, audience)
+	if err != nil {
+		clearTokenCookies(c, accessCookie, refreshCookie)
+		c.JSON(http.StatusUnauthorized, response.Response{
+			StatusCode: 401,
+			Message:    "failed to refresh token",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	setTokenCookies(c, pair, accessCookie, refreshCookie)
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "token refreshed",
+		Data:       pair,
+		Errors:     nil,
+	})
+}
+
+// refreshTokenFrom reads the refresh token from its cookie, or from the json
+// body for clients that don't keep cookies
+func refreshTokenFrom(c *gin.Context, cookie string) string {
+	if value, err := c.Cookie(cookie); err == nil && value != "" {
+		return value
+	}
+	var body requests.RefreshToken
+	if err := c.ShouldBindJSON(&body); err != nil {
+		return ""
+	}
+	return body.RefreshToken
+}
+
+func setTokenCookies(c *gin.Context, pair response.TokenPair, accessCookie, refreshCookie string) {
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(accessCookie, pair.AccessToken, pair.ExpiresIn, "", "", false, true)
+	c.SetCookie(refreshCookie, pair.RefreshToken, pair.RefreshExpiresIn, "", "", false, true)
+}
+
+func clearTokenCookies(c *gin.Context, accessCookie, refreshCookie string) {
+	c.SetCookie(accessCookie, "", -1, "", "", false, true)
+	c.SetCookie(refreshCookie, "", -1, "", "", false, true)
+}
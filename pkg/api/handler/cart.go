@@ -193,7 +193,7 @@ func (c *CartHandler) Addcount(ctx *gin.Context) {
 
 // viewCart godoc
 // @summary api for get all cart item of user
-// @description user can see all productItem that stored in cart
+// @description user can see all productItem that stored in cart with the subtotal, discount, tax, shipping and total
 // @security ApiKeyAuth
 // @id Cart
 // @tags  Cart
@@ -233,7 +233,7 @@ func (c *CartHandler) ViewCartItems(ctx *gin.Context) {
 		return
 	}
 
-	cartitems, err := c.CartUsecase.FindCartlistByCartID(ctx, cart.Id)
+	cartView, err := c.CartUsecase.ViewCart(ctx, cart.Id)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -243,7 +243,7 @@ func (c *CartHandler) ViewCartItems(ctx *gin.Context) {
 		})
 		return
 	}
-	if cartitems == nil {
+	if len(cartView.Items) == 0 {
 		ctx.JSON(http.StatusOK, response.Response{
 			StatusCode: 200,
 			Message:    "sorry no products in your cart",
@@ -255,7 +255,7 @@ func (c *CartHandler) ViewCartItems(ctx *gin.Context) {
 	ctx.JSON(http.StatusOK, response.Response{
 		StatusCode: 200,
 		Message:    "your carts here",
-		Data:       cartitems,
+		Data:       cartView,
 		Errors:     nil,
 	})
 }
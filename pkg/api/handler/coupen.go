@@ -133,7 +133,7 @@ func (cr *CouponHandler) UpdateCoupon(ctx *gin.Context) {
 // DeleteCoupon godoc
 // @Summary Admin can delete a coupon
 // @ID delete-coupon
-// @Description Admin can delete a coupon
+// @Description Admin can delete a coupon, it is archived so orders that used it keep it and it can be restored
 // @Tags Coupon
 // @Accept json
 // @Produce json
@@ -172,6 +172,74 @@ func (cr *CouponHandler) DeleteCoupon(ctx *gin.Context) {
 	})
 }
 
+// RestoreCoupon godoc
+// @Summary Admin can restore a deleted coupon
+// @ID restore-coupon
+// @Description Admin can make an archived coupon usable again
+// @Tags Coupon
+// @Produce json
+// @Param CouponID path string true "CouponID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/coupon/Restore/{CouponID} [patch]
+func (cr *CouponHandler) RestoreCoupon(ctx *gin.Context) {
+	couponID, err := strconv.Atoi(ctx.Param("CouponID"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Invalid coupon ID",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	coupon, err := cr.CouponUsecase.RestoreCoupon(ctx, couponID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Failed to restore coupon",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "Successfully restored coupon",
+		Data:       coupon,
+		Errors:     nil,
+	})
+}
+
+// ArchivedCoupons godoc
+// @Summary Get deleted coupons
+// @ID archived-coupons
+// @Description Admin can list the archived coupons, last deleted first
+// @Tags Coupon
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/coupon/archived [get]
+func (cr *CouponHandler) ArchivedCoupons(ctx *gin.Context) {
+	coupons, err := cr.CouponUsecase.ArchivedCoupons(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Failed to fetch coupons",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "List of archived coupons",
+		Data:       coupons,
+		Errors:     nil,
+	})
+}
+
 // ViewCoupon godoc
 // @Summary Admins can see Coupons with coupon_id
 // @ID find-Coupon-by-id
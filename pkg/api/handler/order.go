@@ -4,7 +4,9 @@ import (
 	"ecommerce/pkg/api/utilhandler"
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
 	services "ecommerce/pkg/usecase/interface"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
@@ -30,6 +32,7 @@ func NewOrderHandler(orderUsecase services.Orderusecase) *OrderHandler {
 // @Accept json
 // @Produce json
 // @Param payment_id path string true "payment_id"
+// @Param use_wallet query bool false "pay as much as possible from the wallet"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
 // @Router /order/orderAll/{payment_id} [post]
@@ -56,7 +59,17 @@ func (cr *OrderHandler) CashonDElivery(ctx *gin.Context) {
 		})
 		return
 	}
-	order, err := cr.orderusecase.PlaceOrder(ctx, UserID, PaymentMethodId)
+	useWallet, err := strconv.ParseBool(ctx.DefaultQuery("use_wallet", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid use_wallet",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	order, err := cr.orderusecase.PlaceOrder(ctx, UserID, PaymentMethodId, useWallet)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -97,7 +110,18 @@ func (c *OrderHandler) RazorpayCheckout(ctx *gin.Context) {
 	// 	return
 	// }
 
-	razorPayOrder, err := c.orderusecase.Razorpay(ctx, UserID, 2)
+	useWallet, err := strconv.ParseBool(ctx.DefaultQuery("use_wallet", "false"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid use_wallet",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	razorPayOrder, err := c.orderusecase.Razorpay(ctx, UserID, 2, useWallet)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -144,13 +168,24 @@ func (cr *OrderHandler) RazorpayVerify(ctx *gin.Context) {
 		return
 	}
 
+	if paymentid != 2 {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     "payment_id is not razorpay",
+		})
+		return
+	}
+
 	body := requests.RazorPayRequest{
 		RazorPayPaymentId:  razorPayPaymentId,
 		RazorPayOrderId:    razorPayOrderId,
 		Razorpay_signature: razorpay_signature,
+		UserID:             userId,
 	}
 
-	err = cr.orderusecase.VerifyRazorPay(ctx, body)
+	order, err := cr.orderusecase.VerifyRazorPay(ctx, body)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -160,16 +195,6 @@ func (cr *OrderHandler) RazorpayVerify(ctx *gin.Context) {
 		})
 		return
 	}
-	order, err := cr.orderusecase.PlaceOrder(ctx, userId, paymentid)
-	if err != nil {
-		ctx.JSON(http.StatusBadRequest, response.Response{
-			StatusCode: 400,
-			Message:    "cant place order",
-			Data:       nil,
-			Errors:     err.Error(),
-		})
-		return
-	}
 	ctx.JSON(http.StatusOK, response.Response{
 		StatusCode: 200,
 		Message:    "orderplaced",
@@ -186,6 +211,7 @@ func (cr *OrderHandler) RazorpayVerify(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param orderId path int true "ID of the order to be cancelled"
+// @Param refund_to query string false "wallet (default) or source to refund a razorpay payment back to the card"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
 // @Router /order/cancel/{orderId} [patch]
@@ -211,7 +237,8 @@ func (cr *OrderHandler) CancelOrder(ctx *gin.Context) {
 		})
 		return
 	}
-	err = cr.orderusecase.CancelOrder(ctx, orderId, UserID)
+	refundTo := ctx.DefaultQuery("refund_to", domain.RefundToWallet)
+	err = cr.orderusecase.CancelOrder(ctx, orderId, UserID, refundTo)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -327,6 +354,8 @@ func (cr *OrderHandler) ListAllOrders(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param orderId path int true "ID of the order to be cancelled"
+// @Param reason query string false "why the order is returned"
+// @Param refund_to query string false "wallet (default) or source to refund a razorpay payment back to the card"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
 // @Router /order/return/{orderId} [patch]
@@ -353,7 +382,8 @@ func (cr *OrderHandler) ReturnOrder(ctx *gin.Context) {
 		return
 	}
 
-	returnAmount, err := cr.orderusecase.ReturnOrder(UserID, orderId)
+	refundTo := ctx.DefaultQuery("refund_to", domain.RefundToWallet)
+	returnAmount, err := cr.orderusecase.ReturnOrder(UserID, orderId, ctx.Query("reason"), refundTo)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -365,7 +395,208 @@ func (cr *OrderHandler) ReturnOrder(ctx *gin.Context) {
 	}
 	ctx.JSON(http.StatusOK, response.Response{
 		StatusCode: 200,
-		Message:    "order returnd ",
+		Message:    "return requested, the refund will be made once it is approved",
+		Data:       returnAmount,
+		Errors:     nil,
+	})
+}
+
+// ListOrderItems
+// @Summary Items of an order
+// @ID view-order-items
+// @Description every item of the order with what was cancelled or returned of it
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /order/view/{order_id}/items [get]
+func (cr *OrderHandler) ListOrderItems(ctx *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	orderId, err := strconv.Atoi(ctx.Param("order_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	lines, err := cr.orderusecase.ListOrderLines(ctx, UserID, orderId)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find order items",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "order items",
+		Data:       lines,
+		Errors:     nil,
+	})
+}
+
+// CancelOrderItem
+// @Summary Cancel one item of an order
+// @ID cancel-order-item
+// @Description cancel some or all of one item, the order total and coupon discount are reduced by its share
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param orderId path int true "order id"
+// @Param itemId path int true "order item id"
+// @Param qty query int false "how many to cancel, all that is left when empty"
+// @Param refund_to query string false "wallet (default) or source to refund a razorpay payment back to the card"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /order/cancel/{orderId}/items/{itemId} [patch]
+func (cr *OrderHandler) CancelOrderItem(ctx *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	orderId, err := strconv.Atoi(ctx.Param("orderId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	itemId, err := strconv.Atoi(ctx.Param("itemId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	qty, err := strconv.Atoi(ctx.DefaultQuery("qty", "0"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid qty",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	refundTo := ctx.DefaultQuery("refund_to", domain.RefundToWallet)
+	err = cr.orderusecase.CancelOrderLine(ctx, UserID, orderId, itemId, qty, refundTo)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't cancel item",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "item canceld",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// ReturnOrderItem
+// @Summary Return one item of a delivered order
+// @ID return-order-item
+// @Description ask for a return of some or all of one item, the refund is made once an admin approves it
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param orderId path int true "order id"
+// @Param itemId path int true "order item id"
+// @Param qty query int false "how many to return, all that is left when empty"
+// @Param reason query string false "why the item is returned"
+// @Param refund_to query string false "wallet (default) or source to refund a razorpay payment back to the card"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /order/return/{orderId}/items/{itemId} [patch]
+func (cr *OrderHandler) ReturnOrderItem(ctx *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	orderId, err := strconv.Atoi(ctx.Param("orderId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	itemId, err := strconv.Atoi(ctx.Param("itemId"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	qty, err := strconv.Atoi(ctx.DefaultQuery("qty", "0"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid qty",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	refundTo := ctx.DefaultQuery("refund_to", domain.RefundToWallet)
+	returnAmount, err := cr.orderusecase.ReturnOrderLine(ctx, UserID, orderId, itemId, qty, ctx.Query("reason"), refundTo)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't return item",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "return requested, the refund will be made once it is approved",
 		Data:       returnAmount,
 		Errors:     nil,
 	})
@@ -460,7 +691,7 @@ func (cr *OrderHandler) AllOrders(ctx *gin.Context) {
 
 // @Summary Updateorderstatus
 // @ID Order_status
-// @Description update the order statuses by every orderid.
+// @Description move an order to its next status, only the transitions of the order state machine are allowed.
 // @Tags Order
 // @Accept json
 // @Produce json
@@ -480,7 +711,17 @@ func (cr *OrderHandler) UpdateOrderStatus(ctx *gin.Context) {
 		})
 		return
 	}
-	err = cr.orderusecase.UpdateOrderStatus(ctx, Update)
+	adminId, err := utilhandler.GetAdminIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	err = cr.orderusecase.UpdateOrderStatus(ctx, Update, adminId)
 	if err != nil {
 		ctx.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -499,3 +740,454 @@ func (cr *OrderHandler) UpdateOrderStatus(ctx *gin.Context) {
 	fmt.Println("done")
 
 }
+
+// GetWallet
+// @Summary Get the wallet of the logged in user
+// @ID get-wallet
+// @Description user can see their wallet balance
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /wallet [get]
+func (cr *OrderHandler) GetWallet(ctx *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	wallet, err := cr.orderusecase.GetUserWallet(ctx, uint(UserID))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find wallet",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "wallet",
+		Data:       wallet,
+		Errors:     nil,
+	})
+}
+
+// GetWalletTransactions
+// @Summary Wallet transaction history of the logged in user
+// @ID get-wallet-transactions
+// @Description user can see their wallet credits and debits, newest first
+// @Tags Wallet
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /wallet/transactions [get]
+func (cr *OrderHandler) GetWalletTransactions(ctx *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	page, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(ctx.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	transactions, err := cr.orderusecase.GetUserWalletTransactions(ctx, uint(UserID), requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't list wallet transactions",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "wallet transactions",
+		Data:       transactions,
+		Errors:     nil,
+	})
+}
+
+// RazorpayWebhook
+// @Summary Razorpay webhook receiver
+// @ID razorpay-webhook
+// @Description Server to server payment events from razorpay, signed with the webhook secret
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param X-Razorpay-Signature header string true "HMAC SHA256 signature of the body"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Failure 401 {object} response.Response
+// @Router /webhooks/razorpay [post]
+func (cr *OrderHandler) RazorpayWebhook(ctx *gin.Context) {
+	payload, err := ctx.GetRawData()
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't read webhook body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	signature := ctx.GetHeader("X-Razorpay-Signature")
+	eventId := ctx.GetHeader("X-Razorpay-Event-Id")
+
+	err = cr.orderusecase.RazorpayWebhook(ctx, eventId, signature, payload)
+	if errors.Is(err, services.ErrInvalidSignature) {
+		ctx.JSON(http.StatusUnauthorized, response.Response{
+			StatusCode: 401,
+			Message:    "invalid signature",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't process webhook",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "webhook processed",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// ListRazorpayCheckouts
+// @Summary List razorpay checkouts for admin
+// @ID list-razorpay-checkouts
+// @Description admin can see razorpay checkouts, filter by status to find failed payments and payments refunded because no order could be placed
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param status query string false "pending, paid, failed, refunding or refunded"
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/order/checkouts [get]
+func (cr *OrderHandler) ListRazorpayCheckouts(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(ctx.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	checkouts, err := cr.orderusecase.ListRazorpayCheckouts(ctx, ctx.Query("status"), requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't list checkouts",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "checkouts",
+		Data:       checkouts,
+		Errors:     nil,
+	})
+}
+
+// ListRefunds
+// @Summary List refunds for admin
+// @ID list-refunds
+// @Description admin can see refunds, filter by status to find pending returns
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param status query string false "pending, approved, processed, denied or failed"
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/order/refunds [get]
+func (cr *OrderHandler) ListRefunds(ctx *gin.Context) {
+	page, err := strconv.Atoi(ctx.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(ctx.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	refunds, err := cr.orderusecase.ListRefunds(ctx, ctx.Query("status"), requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't list refunds",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "refunds",
+		Data:       refunds,
+		Errors:     nil,
+	})
+}
+
+// ApproveRefund
+// @Summary Approve a return refund
+// @ID approve-refund
+// @Description admin approves a pending return, a smaller amount makes it a partial refund. A failed gateway refund is retried
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param refund_id path int true "refund id"
+// @Param inputs body requests.RefundDecision false "amount to refund, empty for the full amount"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/order/refunds/{refund_id}/approve [patch]
+func (cr *OrderHandler) ApproveRefund(ctx *gin.Context) {
+	refundId, err := strconv.Atoi(ctx.Param("refund_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var decision requests.RefundDecision
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&decision); err != nil {
+			ctx.JSON(http.StatusBadRequest, response.Response{
+				StatusCode: 400,
+				Message:    "failed to read request body",
+				Data:       nil,
+				Errors:     err.Error(),
+			})
+			return
+		}
+	}
+
+	adminId, err := utilhandler.GetAdminIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	refund, err := cr.orderusecase.ApproveRefund(ctx, uint(refundId), decision.Amount, adminId)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't approve refund",
+			Data:       refund,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "refund approved",
+		Data:       refund,
+		Errors:     nil,
+	})
+}
+
+// DenyRefund
+// @Summary Deny a return refund
+// @ID deny-refund
+// @Description admin denies a pending return, the order goes back to delivered
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param refund_id path int true "refund id"
+// @Param inputs body requests.RefundDecision false "reason for denying"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/order/refunds/{refund_id}/deny [patch]
+func (cr *OrderHandler) DenyRefund(ctx *gin.Context) {
+	refundId, err := strconv.Atoi(ctx.Param("refund_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var decision requests.RefundDecision
+	if ctx.Request.ContentLength > 0 {
+		if err := ctx.ShouldBindJSON(&decision); err != nil {
+			ctx.JSON(http.StatusBadRequest, response.Response{
+				StatusCode: 400,
+				Message:    "failed to read request body",
+				Data:       nil,
+				Errors:     err.Error(),
+			})
+			return
+		}
+	}
+
+	adminId, err := utilhandler.GetAdminIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	if err := cr.orderusecase.DenyRefund(ctx, uint(refundId), decision.Reason, adminId); err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't deny refund",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "refund denied",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// OrderTimeline
+// @Summary Status history of an order
+// @ID order-timeline
+// @Description every status the order went through, who changed it and when
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /order/view/{order_id}/timeline [get]
+func (cr *OrderHandler) OrderTimeline(ctx *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(ctx)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	orderId, err := strconv.Atoi(ctx.Param("order_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	timeline, err := cr.orderusecase.OrderTimeline(ctx, orderId, UserID)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find order timeline",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "order timeline",
+		Data:       timeline,
+		Errors:     nil,
+	})
+}
+
+// AdminOrderTimeline
+// @Summary Status history of an order for admin
+// @ID admin-order-timeline
+// @Description every status the order went through, who changed it and when
+// @Tags Order
+// @Accept json
+// @Produce json
+// @Param order_id path int true "Order ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/order/{order_id}/timeline [get]
+func (cr *OrderHandler) AdminOrderTimeline(ctx *gin.Context) {
+	orderId, err := strconv.Atoi(ctx.Param("order_id"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "bind faild",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	timeline, err := cr.orderusecase.AdminOrderTimeline(ctx, orderId)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find order timeline",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	ctx.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "order timeline",
+		Data:       timeline,
+		Errors:     nil,
+	})
+}
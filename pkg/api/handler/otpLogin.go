@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"ecommerce/pkg/api/utilhandler"
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
 	"ecommerce/pkg/config"
@@ -18,7 +19,6 @@ type OtpHandler struct {
 }
 
 func NewOtpHandler(cfg config.Config, otpUseCase services.OtpUseCase, userUseCase services.UserUseCase) *OtpHandler {
-	fmt.Printf("DEBUG: Initializing OtpHandler with cfg=%+v, otpUseCase=%+v, userUseCase=%+v\n", cfg, otpUseCase, userUseCase)
 	return &OtpHandler{
 		otpUseCase:  otpUseCase,
 		userUseCase: userUseCase,
@@ -105,9 +105,10 @@ func (cr *OtpHandler) ValidateOtp(c *gin.Context) {
 			Data:       nil,
 			Errors:     err.Error(),
 		})
+		return
 	}
 
-	ss, err := cr.userUseCase.OtpLogin(otpDetails.Phone)
+	pair, err := cr.userUseCase.OtpLogin(c.Request.Context(), otpDetails.Phone, utilhandler.GetDevice(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -118,12 +119,11 @@ func (cr *OtpHandler) ValidateOtp(c *gin.Context) {
 		return
 	}
 
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("UserAuth", ss, 3600*24*30, "", "", false, true)
+	setTokenCookies(c, pair, userAccessCookie, userRefreshCookie)
 	c.JSON(http.StatusOK, response.Response{
 		StatusCode: 200,
 		Message:    "login successful",
-		Data:       nil,
+		Data:       pair,
 		Errors:     nil,
 	})
 }
@@ -0,0 +1,194 @@
+package handler
+
+import (
+	"ecommerce/pkg/api/utilhandler"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	services "ecommerce/pkg/usecase/interface"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PasswordHandler struct {
+	passwordUseCase services.PasswordUseCase
+}
+
+func NewPasswordHandler(passwordUseCase services.PasswordUseCase) *PasswordHandler {
+	return &PasswordHandler{
+		passwordUseCase: passwordUseCase,
+	}
+}
+
+// ForgotPassword
+// @Summary Forgot password
+// @ID ForgotPassword
+// @Description Ask for a password reset. With channel email a single use reset token is emailed, with sms an OTP is sent to the phone to verify at /password/forgot/verify. The answer is the same whether or not an account matches
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body requests.ForgotPassword true "Where to send the reset"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /password/forgot [post]
+func (cr *PasswordHandler) ForgotPassword(c *gin.Context) {
+	var body requests.ForgotPassword
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	if err := cr.passwordUseCase.ForgotPassword(c.Request.Context(), body); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to start password reset",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "if an account matches, reset instructions were sent to it",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// VerifyResetOtp
+// @Summary Verify password reset OTP
+// @ID VerifyResetOtp
+// @Description Verify the OTP of an sms password reset, the answer carries a single use reset token for /password/reset
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body requests.Otpverifier true "Phone and OTP"
+// @Success 200 {object} response.Response{data=response.ResetToken}
+// @Failure 400 {object} response.Response
+// @Router /password/forgot/verify [post]
+func (cr *PasswordHandler) VerifyResetOtp(c *gin.Context) {
+	var body requests.Otpverifier
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	resetToken, err := cr.passwordUseCase.VerifyResetOtp(c.Request.Context(), body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to verify otp",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "otp verified",
+		Data:       response.ResetToken{ResetToken: resetToken},
+		Errors:     nil,
+	})
+}
+
+// ResetPassword
+// @Summary Reset password
+// @ID ResetPassword
+// @Description Set a new password with a reset token, the token works once. The user is signed out everywhere
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body requests.ResetPassword true "Reset token and new password"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /password/reset [post]
+func (cr *PasswordHandler) ResetPassword(c *gin.Context) {
+	var body requests.ResetPassword
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	if err := cr.passwordUseCase.ResetPassword(c.Request.Context(), body); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to reset password",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	clearTokenCookies(c, userAccessCookie, userRefreshCookie)
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "password reset, sign in with the new password",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// ChangePassword
+// @Summary Change password
+// @ID ChangePassword
+// @Description Change the signed in user's password, the old one is required. Every other session is signed out
+// @Tags Users
+// @Accept json
+// @Produce json
+// @Param input body requests.ChangePassword true "Old and new password"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /password/change [post]
+func (cr *PasswordHandler) ChangePassword(c *gin.Context) {
+	var body requests.ChangePassword
+	if err := c.ShouldBindJSON(&body); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	UserID, err := utilhandler.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to get user ID",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	err = cr.passwordUseCase.ChangePassword(c.Request.Context(), uint(UserID), utilhandler.GetSessionIdFromContext(c), body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to change password",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "password changed, other devices were signed out",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
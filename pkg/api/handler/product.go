@@ -1,11 +1,13 @@
 package handler
 
 import (
+	"ecommerce/pkg/api/utilhandler"
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
 	services "ecommerce/pkg/usecase/interface"
 	"net/http"
 	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 )
@@ -23,7 +25,7 @@ func NewproductHandler(ProductUsecase services.ProductUsecase) *ProductHandler {
 // AddNEWCategory
 // @Summary Create new product category
 // @ID create-category
-// @Description Admin can create new category from admin panel
+// @Description Admin can create new category from admin panel, a parent_id nests it under another category
 // @Tags Product Category
 // @Accept json
 // @Produce json
@@ -66,7 +68,7 @@ func (cr *ProductHandler) Addcategory(c *gin.Context) {
 // UpdateCategory
 // @Summary Admin can update category details
 // @ID update-category
-// @Description Admin can update category details
+// @Description Admin can update category details, a parent_id moves it under another category and 0 moves it to the top level
 // @Tags Product Category
 // @Accept json
 // @Produce json
@@ -121,11 +123,12 @@ func (cr *ProductHandler) UpdateCategory(c *gin.Context) {
 // DeleteCategory
 // @Summary Admin can delete a category
 // @ID delete-category
-// @Description Admin can delete a category
+// @Description Admin can delete a category, it is archived and its subcategories move up to its parent. A category that still has products can only be deleted by moving them to the category in reassign_to
 // @Tags Product Category
 // @Accept json
 // @Produce json
 // @Param category_id path string true "category_id"
+// @Param reassign_to query int false "category the products move to"
 // @Success 200 {object} response.Response
 // @Failure 400 {object} response.Response
 // @Router /admin/category/delete/{category_id} [delete]
@@ -139,8 +142,21 @@ func (cr *ProductHandler) DeleteCategory(c *gin.Context) {
 			Data:       nil,
 			Errors:     err.Error(),
 		})
+		return
+	}
+	var reassignTo int
+	if param := c.Query("reassign_to"); param != "" {
+		if reassignTo, err = strconv.Atoi(param); err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				StatusCode: 400,
+				Message:    "invalid reassign_to",
+				Data:       nil,
+				Errors:     err.Error(),
+			})
+			return
+		}
 	}
-	err = cr.ProductUsecase.DeleteCategory(c.Request.Context(), id)
+	err = cr.ProductUsecase.DeleteCategory(c.Request.Context(), id, reassignTo)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -160,6 +176,74 @@ func (cr *ProductHandler) DeleteCategory(c *gin.Context) {
 
 }
 
+// RestoreCategory
+// @Summary Admin can restore a deleted category
+// @ID restore-category
+// @Description Admin can bring an archived category back under its old parent, or at the top level when the parent is archived too
+// @Tags Product Category
+// @Produce json
+// @Param category_id path string true "category_id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/category/restore/{category_id} [patch]
+func (cr *ProductHandler) RestoreCategory(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("category_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't bind data",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	category, err := cr.ProductUsecase.RestoreCategory(c.Request.Context(), id)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't restore category",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "Category restored",
+		Data:       category,
+		Errors:     nil,
+	})
+}
+
+// ArchivedCategories
+// @Summary List deleted categories
+// @ID archived-categories
+// @Description Admin can list the archived categories, last deleted first
+// @Tags Product Category
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/category/archived [get]
+func (cr *ProductHandler) ArchivedCategories(c *gin.Context) {
+	categories, err := cr.ProductUsecase.ArchivedCategories(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find category",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "archived categories",
+		Data:       categories,
+		Errors:     nil,
+	})
+}
+
 // ListAllCategories
 // @Summary View all available categories
 // @ID view-all-categories
@@ -191,10 +275,39 @@ func (cr *ProductHandler) ListCategories(c *gin.Context) {
 	})
 }
 
+// CategoryTree
+// @Summary View the category tree
+// @ID category-tree
+// @Description Admin, users and unregistered users can see the top level categories with their subcategories nested under them
+// @Tags Product Category
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /category/tree [get]
+func (cr *ProductHandler) CategoryTree(c *gin.Context) {
+	categories, err := cr.ProductUsecase.CategoryTree(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find category",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "category tree",
+		Data:       categories,
+		Errors:     nil,
+	})
+}
+
 // FindCategoryByID
 // @Summary Fetch details of a specific category using category id
 // @ID find-category-by-id
-// @Description Users and admins can fetch details of a specific category using id
+// @Description Users and admins can fetch details of a specific category using id, with its subcategories nested under it
 // @Tags Product Category
 // @Accept json
 // @Produce json
@@ -259,7 +372,17 @@ func (cr *ProductHandler) SaveProduct(c *gin.Context) {
 		})
 		return
 	}
-	newProduct, err := cr.ProductUsecase.SaveProduct(c.Request.Context(), product)
+	adminId, err := utilhandler.GetAdminIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	newProduct, err := cr.ProductUsecase.SaveProduct(c.Request.Context(), product, adminId)
 
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
@@ -316,7 +439,17 @@ func (cr *ProductHandler) UpdateProduct(c *gin.Context) {
 		return
 	}
 
-	updatedCategory, err := cr.ProductUsecase.UpdateProduct(c.Request.Context(), id, product)
+	adminId, err := utilhandler.GetAdminIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	updatedCategory, err := cr.ProductUsecase.UpdateProduct(c.Request.Context(), id, product, adminId)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -338,7 +471,7 @@ func (cr *ProductHandler) UpdateProduct(c *gin.Context) {
 // DeleteProduct
 // @Summary Admin can delete a product
 // @ID delete-product
-// @Description Admin can delete a product
+// @Description Admin can delete a product, it is archived so past orders keep it and it can be restored
 // @Tags Product
 // @Accept json
 // @Produce json
@@ -379,47 +512,134 @@ func (cr *ProductHandler) DeleteProduct(c *gin.Context) {
 
 }
 
-// ViewAllProducts
-// @Summary Admins and users can see all available products
-// @ID user-view-all-products
-// @Description  users can ses all available products
+// RestoreProduct
+// @Summary Admin can restore a deleted product
+// @ID restore-product
+// @Description Admin can put an archived product back on sale, its category has to be live
 // @Tags Product
-// @Accept json
 // @Produce json
-// @Param page query int false "Page number for pagination"
-// @Param perPage query int false "Number of items to retrieve per page"
+// @Param product_id path string true "product_id"
 // @Success 200 {object} response.Response
-// @Failure 500 {object} response.Response
-// @Router /user/product/ViewAllProducts [get]
-
-func (cr *ProductHandler) ViewAllProducts(c *gin.Context) {
-
-	//fetch query parameters
-	page, err := strconv.Atoi(c.Query("page"))
+// @Failure 400 {object} response.Response
+// @Router /admin/product/restore/{product_id} [patch]
+func (cr *ProductHandler) RestoreProduct(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("product_id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
-			Message:    "invalied pagenumber",
+			Message:    "Can't bind data",
 			Data:       nil,
 			Errors:     err.Error(),
 		})
+		return
 	}
-	perPage, err := strconv.Atoi(c.Query("perPage"))
+
+	product, err := cr.ProductUsecase.RestoreProduct(c.Request.Context(), id)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
-			Message:    "invalied perPage",
+			Message:    "Can't restore product",
 			Data:       nil,
 			Errors:     err.Error(),
 		})
+		return
 	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "product restored",
+		Data:       product,
+		Errors:     nil,
+	})
+}
 
-	listProducts := requests.Pagination{
+// ArchivedProducts
+// @Summary List deleted products
+// @ID archived-products
+// @Description Admin can list the archived products, last deleted first
+// @Tags Product
+// @Produce json
+// @Param page query int false "page number"
+// @Param perPage query int false "products per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/archived [get]
+func (cr *ProductHandler) ArchivedProducts(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	products, err := cr.ProductUsecase.ArchivedProducts(c.Request.Context(), requests.Pagination{
 		Page:    uint(page),
 		PerPage: uint(perPage),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't get archived products",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
 	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "archived products",
+		Data:       products,
+		Errors:     nil,
+	})
+}
 
-	products, err := cr.ProductUsecase.ViewAllProducts(c.Request.Context(), listProducts)
+// ViewAllProducts
+// @Summary Admins and users can see all available products
+// @ID user-view-all-products
+// @Description users can list products narrowed by categories, brands, price, stock and text, in the order they pick. Pages go by page and perPage or by the next_cursor of the previous page
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param category_id query []int false "category ids" collectionFormat(multi)
+// @Param brand query []string false "brands" collectionFormat(multi)
+// @Param min_price query int false "lowest price"
+// @Param max_price query int false "highest price"
+// @Param in_stock query bool false "only products in stock"
+// @Param q query string false "search text"
+// @Param sort query string false "newest, price_asc, price_desc, popularity, rating or relevance"
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /user/product/ViewAllProducts [get]
+func (cr *ProductHandler) ViewAllProducts(c *gin.Context) {
+	var filter requests.ProductFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid filters",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	cr.listProducts(c, filter)
+}
+
+// listProducts writes the listing for filter, shared by the routes that list products
+func (cr *ProductHandler) listProducts(c *gin.Context, filter requests.ProductFilter) {
+	if filter.Sort == "relevance" && strings.TrimSpace(filter.Query) == "" {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid filters",
+			Data:       nil,
+			Errors:     "sorting by relevance needs search text in q",
+		})
+		return
+	}
+	products, err := cr.ProductUsecase.ViewAllProducts(c.Request.Context(), filter)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -427,6 +647,7 @@ func (cr *ProductHandler) ViewAllProducts(c *gin.Context) {
 			Data:       nil,
 			Errors:     err.Error(),
 		})
+		return
 	}
 
 	c.JSON(http.StatusOK, response.Response{
@@ -478,54 +699,505 @@ func (cr *ProductHandler) VeiwProduct(c *gin.Context) {
 	})
 }
 
+// SearchProducts
+// @Summary Search products
+// @ID search-products
+// @Description users can search products by name, brand and description, best matches first. Results can be narrowed by category, brand and price and come with facet counts, suggestions are given when nothing matched
+// @Tags Product
+// @Produce json
+// @Param q query string true "search text"
+// @Param category_id query int false "category id"
+// @Param brand query string false "brand"
+// @Param min_price query int false "lowest price"
+// @Param max_price query int false "highest price"
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /product/products/search [get]
 func (cr *ProductHandler) SearchProducts(c *gin.Context) {
-	query := c.Query("q") // Example: /products/search?q=k
-	var products []requests.Product
-
-	if query == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "query parameter 'q' is required"})
+	var search requests.ProductSearch
+	if err := c.ShouldBindQuery(&search); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid search",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
 		return
 	}
 
-	// Case-insensitive prefix search
-	products, err := cr.ProductUsecase.SearchProducts(c.Request.Context(), query)
+	result, err := cr.ProductUsecase.SearchProducts(c.Request.Context(), search)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search products"})
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to search products",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
 		return
 	}
-	c.JSON(http.StatusOK, gin.H{
-		"status":  200,
-		"message": "search results",
-		"data":    products,
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "search results",
+		Data:       result,
+		Errors:     nil,
 	})
 }
 
+// FilterProductsByPrice
+// @Summary Filter products by price
+// @ID filter-products-by-price
+// @Description the product listing narrowed to prices from min to max, it takes every other listing filter too
+// @Tags Product
+// @Produce json
+// @Param min query int false "lowest price"
+// @Param max query int false "highest price"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /product/filter [get]
 func (cr *ProductHandler) FilterProductsByPrice(c *gin.Context) {
-	var min, max *float64
-
-	if minStr := c.DefaultQuery("min", "0"); minStr != "" {
-		if val, err := strconv.ParseFloat(minStr, 64); err == nil {
-			min = &val
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid min value"})
+	var filter requests.ProductFilter
+	if err := c.ShouldBindQuery(&filter); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "invalid filters",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	if min := c.Query("min"); min != "" {
+		price, err := strconv.Atoi(min)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				StatusCode: 400,
+				Message:    "invalid min value",
+				Data:       nil,
+				Errors:     err.Error(),
+			})
 			return
 		}
+		filter.MinPrice = &price
 	}
-
-	if maxStr := c.DefaultQuery("max", "999999"); maxStr != "" {
-		if val, err := strconv.ParseFloat(maxStr, 64); err == nil {
-			max = &val
-		} else {
-			c.JSON(http.StatusBadRequest, gin.H{"error": "invalid max value"})
+	if max := c.Query("max"); max != "" {
+		price, err := strconv.Atoi(max)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				StatusCode: 400,
+				Message:    "invalid max value",
+				Data:       nil,
+				Errors:     err.Error(),
+			})
 			return
 		}
+		filter.MaxPrice = &price
+	}
+	cr.listProducts(c, filter)
+}
+
+// StockHistory
+// @Summary Stock history of a product
+// @ID stock-history
+// @Description admin can see every change to a product's stock, newest first
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param product_id path int true "product id"
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/stock/{product_id} [get]
+func (cr *ProductHandler) StockHistory(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("perPage"))
+	if err != nil {
+		perPage = 10
 	}
 
-	products, err := cr.ProductUsecase.GetProductsByPriceRange(min, max)
+	movements, err := cr.ProductUsecase.StockHistory(c.Request.Context(), productId, requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to fetch products"})
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't get stock history",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
 		return
 	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "stock history",
+		Data:       movements,
+		Errors:     nil,
+	})
+}
 
-	c.JSON(http.StatusOK, gin.H{"products": products})
+// AdjustStock
+// @Summary Adjust the stock of a product
+// @ID adjust-stock
+// @Description admin can restock a product or correct its stock, change is added to the stock
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param product_id path int true "product id"
+// @Param adjustment body requests.StockAdjustment true "stock change, reason adjustment or restock"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/stock/{product_id} [post]
+func (cr *ProductHandler) AdjustStock(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var adjustment requests.StockAdjustment
+	if err := c.ShouldBindJSON(&adjustment); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	adminId, err := utilhandler.GetAdminIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	movement, err := cr.ProductUsecase.AdjustStock(c.Request.Context(), productId, adminId, adjustment)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't adjust stock",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "stock adjusted",
+		Data:       movement,
+		Errors:     nil,
+	})
+}
+
+// SetReorderThreshold
+// @Summary Set the reorder threshold of a product
+// @ID set-reorder-threshold
+// @Description the product shows up in the low stock report once its stock is at or below the threshold, zero turns it off
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param product_id path int true "product id"
+// @Param threshold body requests.ReorderThreshold true "reorder threshold"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/stock/{product_id}/threshold [patch]
+func (cr *ProductHandler) SetReorderThreshold(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var threshold requests.ReorderThreshold
+	if err := c.ShouldBindJSON(&threshold); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	if err = cr.ProductUsecase.SetReorderThreshold(c.Request.Context(), productId, threshold.Threshold); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't set reorder threshold",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "reorder threshold updated",
+		Data:       threshold,
+		Errors:     nil,
+	})
+}
+
+// LowStockReport
+// @Summary Products that need reordering
+// @ID low-stock-report
+// @Description admin can see products at or below their reorder threshold, the emptiest first
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/lowstock [get]
+func (cr *ProductHandler) LowStockReport(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	products, err := cr.ProductUsecase.LowStockReport(c.Request.Context(), requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't get low stock report",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "low stock products",
+		Data:       products,
+		Errors:     nil,
+	})
+}
+
+// ListVariants
+// @Summary List the variants of a product
+// @ID list-variants
+// @Description admin can see every variant of a product with its sku, attributes, price and stock
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param product_id path int true "product id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/variants/{product_id} [get]
+func (cr *ProductHandler) ListVariants(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	variants, err := cr.ProductUsecase.ListVariants(c.Request.Context(), productId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't list variants",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "product variants",
+		Data:       variants,
+		Errors:     nil,
+	})
+}
+
+// AddVariant
+// @Summary Add a variant to a product
+// @ID add-variant
+// @Description admin can add a variant with its own sku, attributes, price and opening stock. An empty sku is made up
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param product_id path int true "product id"
+// @Param variant body requests.Variant true "variant details"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/variants/{product_id} [post]
+func (cr *ProductHandler) AddVariant(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var variant requests.Variant
+	if err := c.ShouldBindJSON(&variant); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	adminId, err := utilhandler.GetAdminIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	newVariant, err := cr.ProductUsecase.AddVariant(c.Request.Context(), productId, adminId, variant)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't add variant",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "variant added",
+		Data:       newVariant,
+		Errors:     nil,
+	})
+}
+
+// UpdateVariant
+// @Summary Edit a product variant
+// @ID update-variant
+// @Description admin can change a variant's sku, attributes and price, fields left out are kept. Stock is changed with a stock adjustment
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param variant_id path int true "variant id"
+// @Param variant body requests.Variant true "variant details"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/variant/{variant_id} [patch]
+func (cr *ProductHandler) UpdateVariant(c *gin.Context) {
+	variantId, err := strconv.Atoi(c.Param("variant_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find variantid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var variant requests.Variant
+	if err := c.ShouldBindJSON(&variant); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	updated, err := cr.ProductUsecase.UpdateVariant(c.Request.Context(), variantId, variant)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't update variant",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "variant updated",
+		Data:       updated,
+		Errors:     nil,
+	})
+}
+
+// DeleteVariant
+// @Summary Delete a product variant
+// @ID delete-variant
+// @Description admin can delete a variant that was never ordered, a product keeps at least one variant
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param variant_id path int true "variant id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/variant/{variant_id} [delete]
+func (cr *ProductHandler) DeleteVariant(c *gin.Context) {
+	variantId, err := strconv.Atoi(c.Param("variant_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find variantid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	err = cr.ProductUsecase.DeleteVariant(c.Request.Context(), variantId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't delete variant",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "variant deleted",
+		Data:       nil,
+		Errors:     nil,
+	})
 }
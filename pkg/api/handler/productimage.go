@@ -0,0 +1,317 @@
+package handler
+
+import (
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/storage"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// maxImageSize is the largest image file an admin can upload
+const maxImageSize = 5 << 20
+
+// maxImageRequestSize caps a whole upload request, the largest images it can
+// carry and some room for the multipart headers
+const maxImageRequestSize = requests.MaxImageUploads*maxImageSize + 1<<20
+
+// UploadProductImages
+// @Summary Upload product images
+// @ID upload-product-images
+// @Description admin can upload up to 10 jpeg, png, gif or webp images of at most 5MB in the images field. The first image of a product becomes its primary image
+// @Tags Product
+// @Accept multipart/form-data
+// @Produce json
+// @Param product_id path int true "product id"
+// @Param images formData file true "product images"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/images/{product_id} [post]
+func (cr *ProductHandler) UploadProductImages(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImageRequestSize)
+	form, err := c.MultipartForm()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read multipart form",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	files := form.File["images"]
+	uploads := make([]requests.ImageUpload, 0, len(files))
+	for _, file := range files {
+		body, err := readImage(file)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				StatusCode: 400,
+				Message:    "can't read " + file.Filename,
+				Data:       nil,
+				Errors:     err.Error(),
+			})
+			return
+		}
+		uploads = append(uploads, requests.ImageUpload{Filename: file.Filename, Body: body})
+	}
+
+	images, err := cr.ProductUsecase.UploadProductImages(c.Request.Context(), productId, uploads)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't upload images",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "images uploaded",
+		Data:       images,
+		Errors:     nil,
+	})
+}
+
+// ListProductImages
+// @Summary List product images
+// @ID list-product-images
+// @Description admin can list a product's images in the order they are shown
+// @Tags Product
+// @Produce json
+// @Param product_id path int true "product id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/images/{product_id} [get]
+func (cr *ProductHandler) ListProductImages(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	images, err := cr.ProductUsecase.ListProductImages(c.Request.Context(), productId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't list images",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "product images",
+		Data:       images,
+		Errors:     nil,
+	})
+}
+
+// ReorderProductImages
+// @Summary Reorder product images
+// @ID reorder-product-images
+// @Description admin can set the order a product's images are shown in by listing all of their ids
+// @Tags Product
+// @Accept json
+// @Produce json
+// @Param product_id path int true "product id"
+// @Param order body requests.ImageOrder true "image ids in order"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/images/{product_id}/order [patch]
+func (cr *ProductHandler) ReorderProductImages(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var order requests.ImageOrder
+	if err := c.ShouldBindJSON(&order); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	images, err := cr.ProductUsecase.ReorderProductImages(c.Request.Context(), productId, order)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't reorder images",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "images reordered",
+		Data:       images,
+		Errors:     nil,
+	})
+}
+
+// SetPrimaryImage
+// @Summary Set the primary product image
+// @ID set-primary-image
+// @Description admin can pick the image shown for the product in listings, carts and wishlists
+// @Tags Product
+// @Produce json
+// @Param image_id path int true "image id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/image/{image_id}/primary [patch]
+func (cr *ProductHandler) SetPrimaryImage(c *gin.Context) {
+	imageId, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find imageid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	image, err := cr.ProductUsecase.SetPrimaryImage(c.Request.Context(), imageId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't set primary image",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "primary image set",
+		Data:       image,
+		Errors:     nil,
+	})
+}
+
+// DeleteProductImage
+// @Summary Delete a product image
+// @ID delete-product-image
+// @Description admin can delete a product image, the next image takes over when the primary one is deleted
+// @Tags Product
+// @Produce json
+// @Param image_id path int true "image id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/product/image/{image_id} [delete]
+func (cr *ProductHandler) DeleteProductImage(c *gin.Context) {
+	imageId, err := strconv.Atoi(c.Param("image_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find imageid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	if err := cr.ProductUsecase.DeleteProductImage(c.Request.Context(), imageId); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't delete image",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "image deleted",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// ServeImage
+// @Summary Get a product image
+// @ID serve-image
+// @Description serves an uploaded product image or thumbnail by the key in its url
+// @Tags Product
+// @Produce image/jpeg,image/png,image/gif,image/webp
+// @Param key path string true "image key"
+// @Success 200 {file} file
+// @Failure 404 {object} response.Response
+// @Router /images/{key} [get]
+func (cr *ProductHandler) ServeImage(c *gin.Context) {
+	key := c.Param("key")
+	if len(key) > 0 && key[0] == '/' {
+		key = key[1:]
+	}
+
+	file, err := cr.ProductUsecase.OpenImage(c.Request.Context(), key)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, storage.ErrNotFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, response.Response{
+			StatusCode: status,
+			Message:    "can't get image",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	contentType := mime.TypeByExtension(path.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	// keys are never reused, a new upload always gets a new name
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.DataFromReader(http.StatusOK, -1, contentType, file, nil)
+}
+
+func readImage(header *multipart.FileHeader) ([]byte, error) {
+	if header.Size > maxImageSize {
+		return nil, fmt.Errorf("image is larger than %dMB", maxImageSize>>20)
+	}
+	file, err := header.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(io.LimitReader(file, maxImageSize))
+}
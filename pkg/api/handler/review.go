@@ -0,0 +1,480 @@
+package handler
+
+import (
+	"ecommerce/pkg/api/utilhandler"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	services "ecommerce/pkg/usecase/interface"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReviewHandler struct {
+	ReviewUsecase services.ReviewUseCase
+}
+
+func NewReviewHandler(ReviewUsecase services.ReviewUseCase) *ReviewHandler {
+	return &ReviewHandler{
+		ReviewUsecase: ReviewUsecase,
+	}
+}
+
+// AddReview
+// @Summary Review a product
+// @ID add-review
+// @Description users who got the product delivered can rate it from 1 to 5 stars with a written review, once per product
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Param product_id path int true "product id"
+// @Param review body requests.Review true "rating and review"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /review/product/{product_id} [post]
+func (cr *ReviewHandler) AddReview(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var review requests.Review
+	if err := c.ShouldBindJSON(&review); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	userId, err := utilhandler.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	newReview, err := cr.ReviewUsecase.AddReview(c.Request.Context(), uint(userId), uint(productId), review)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't add review",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "review added",
+		Data:       newReview,
+		Errors:     nil,
+	})
+}
+
+// UpdateReview
+// @Summary Edit your review
+// @ID update-review
+// @Description users can change the rating and text of their own review
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Param review_id path int true "review id"
+// @Param review body requests.Review true "rating and review"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /review/{review_id} [patch]
+func (cr *ReviewHandler) UpdateReview(c *gin.Context) {
+	reviewId, err := strconv.Atoi(c.Param("review_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find reviewid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var review requests.Review
+	if err := c.ShouldBindJSON(&review); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to read request body",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	userId, err := utilhandler.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	updated, err := cr.ReviewUsecase.UpdateReview(c.Request.Context(), uint(userId), uint(reviewId), review)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't update review",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "review updated",
+		Data:       updated,
+		Errors:     nil,
+	})
+}
+
+// DeleteReview
+// @Summary Delete your review
+// @ID delete-review
+// @Description users can delete their own review
+// @Tags Review
+// @Produce json
+// @Param review_id path int true "review id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /review/{review_id} [delete]
+func (cr *ReviewHandler) DeleteReview(c *gin.Context) {
+	reviewId, err := strconv.Atoi(c.Param("review_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find reviewid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	userId, err := utilhandler.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	if err := cr.ReviewUsecase.DeleteReview(c.Request.Context(), uint(userId), uint(reviewId)); err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't delete review",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "review deleted",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// ProductReviews
+// @Summary Reviews of a product
+// @ID product-reviews
+// @Description users can read a product's reviews, most helpful first by default
+// @Tags Review
+// @Produce json
+// @Param product_id path int true "product id"
+// @Param sort query string false "helpful, newest, highest or lowest"
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /review/product/{product_id} [get]
+func (cr *ReviewHandler) ProductReviews(c *gin.Context) {
+	productId, err := strconv.Atoi(c.Param("product_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find productid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	reviews, err := cr.ReviewUsecase.ProductReviews(c.Request.Context(), uint(productId), c.Query("sort"), requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't list reviews",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "product reviews",
+		Data:       reviews,
+		Errors:     nil,
+	})
+}
+
+// MarkHelpful
+// @Summary Vote a review helpful
+// @ID mark-review-helpful
+// @Description users can vote once for a review they found helpful, except their own
+// @Tags Review
+// @Produce json
+// @Param review_id path int true "review id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /review/{review_id}/helpful [post]
+func (cr *ReviewHandler) MarkHelpful(c *gin.Context) {
+	cr.helpfulVote(c, true)
+}
+
+// UnmarkHelpful
+// @Summary Take back a helpful vote
+// @ID unmark-review-helpful
+// @Description users can take back their helpful vote for a review
+// @Tags Review
+// @Produce json
+// @Param review_id path int true "review id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /review/{review_id}/helpful [delete]
+func (cr *ReviewHandler) UnmarkHelpful(c *gin.Context) {
+	cr.helpfulVote(c, false)
+}
+
+func (cr *ReviewHandler) helpfulVote(c *gin.Context, helpful bool) {
+	reviewId, err := strconv.Atoi(c.Param("review_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find reviewid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	userId, err := utilhandler.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "cant find userid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	message := "vote counted"
+	if helpful {
+		err = cr.ReviewUsecase.MarkHelpful(c.Request.Context(), uint(userId), uint(reviewId))
+	} else {
+		message = "vote removed"
+		err = cr.ReviewUsecase.UnmarkHelpful(c.Request.Context(), uint(userId), uint(reviewId))
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't change vote",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    message,
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// ListReviews
+// @Summary List reviews for moderation
+// @ID list-reviews
+// @Description admin can see reviews newest first, filter by status to find hidden ones
+// @Tags Review
+// @Produce json
+// @Param status query string false "published or hidden"
+// @Param page query int false "Page number for pagination"
+// @Param perPage query int false "Number of items to retrieve per page"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/review [get]
+func (cr *ReviewHandler) ListReviews(c *gin.Context) {
+	page, err := strconv.Atoi(c.Query("page"))
+	if err != nil {
+		page = 1
+	}
+	perPage, err := strconv.Atoi(c.Query("perPage"))
+	if err != nil {
+		perPage = 10
+	}
+
+	reviews, err := cr.ReviewUsecase.ListReviews(c.Request.Context(), c.Query("status"), requests.Pagination{
+		Page:    uint(page),
+		PerPage: uint(perPage),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't list reviews",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "reviews",
+		Data:       reviews,
+		Errors:     nil,
+	})
+}
+
+// HideReview
+// @Summary Hide a review
+// @ID hide-review
+// @Description admin can hide an abusive review, it no longer shows or counts in the product's rating
+// @Tags Review
+// @Accept json
+// @Produce json
+// @Param review_id path int true "review id"
+// @Param inputs body requests.ReviewModeration false "reason for hiding"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/review/{review_id}/hide [patch]
+func (cr *ReviewHandler) HideReview(c *gin.Context) {
+	reviewId, err := strconv.Atoi(c.Param("review_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find reviewid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	var moderation requests.ReviewModeration
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&moderation); err != nil {
+			c.JSON(http.StatusBadRequest, response.Response{
+				StatusCode: 400,
+				Message:    "failed to read request body",
+				Data:       nil,
+				Errors:     err.Error(),
+			})
+			return
+		}
+	}
+	adminId, err := utilhandler.GetAdminIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	review, err := cr.ReviewUsecase.HideReview(c.Request.Context(), uint(reviewId), moderation.Reason, uint(adminId))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't hide review",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "review hidden",
+		Data:       review,
+		Errors:     nil,
+	})
+}
+
+// PublishReview
+// @Summary Show a hidden review again
+// @ID publish-review
+// @Description admin can publish a hidden review again
+// @Tags Review
+// @Produce json
+// @Param review_id path int true "review id"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /admin/review/{review_id}/publish [patch]
+func (cr *ReviewHandler) PublishReview(c *gin.Context) {
+	reviewId, err := strconv.Atoi(c.Param("review_id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't find reviewid",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	adminId, err := utilhandler.GetAdminIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "Can't find AdminId",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	review, err := cr.ReviewUsecase.PublishReview(c.Request.Context(), uint(reviewId), uint(adminId))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "can't publish review",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "review published",
+		Data:       review,
+		Errors:     nil,
+	})
+}
@@ -93,7 +93,7 @@ func (cr *UserHandler) UserLogin(c *gin.Context) {
 		return
 	}
 
-	ss, err := cr.userUseCase.UserLogin(c.Request.Context(), user)
+	pair, err := cr.userUseCase.UserLogin(c.Request.Context(), user, utilhandler.GetDevice(c))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, response.Response{
 			StatusCode: 400,
@@ -104,12 +104,11 @@ func (cr *UserHandler) UserLogin(c *gin.Context) {
 		return
 	}
 
-	c.SetSameSite(http.SameSiteLaxMode)
-	c.SetCookie("UserAuth", ss, 3600*24*1, "", "", false, true)
+	setTokenCookies(c, pair, userAccessCookie, userRefreshCookie)
 	c.JSON(http.StatusOK, response.Response{
 		StatusCode: 200,
 		Message:    "logined successfuly",
-		Data:       nil,
+		Data:       pair,
 		Errors:     nil,
 	})
 }
@@ -140,7 +139,18 @@ func (cr *UserHandler) Home(c *gin.Context) {
 // @Failure 400 "failed"
 // @Router /logout [post]
 func (cr *UserHandler) UserLogout(c *gin.Context) {
-	c.SetCookie("UserAuth", "", -1, "", "", false, true)
+	err := cr.userUseCase.UserLogout(c.Request.Context(), refreshTokenFrom(c, userRefreshCookie))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to logout",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	clearTokenCookies(c, userAccessCookie, userRefreshCookie)
 	c.JSON(http.StatusOK, response.Response{
 		StatusCode: 200,
 		Message:    "logout successfully",
@@ -149,6 +159,131 @@ func (cr *UserHandler) UserLogout(c *gin.Context) {
 	})
 }
 
+// LogoutEverywhere
+// @Summary User logout from every device
+// @ID UserLogoutEverywhere
+// @Description Sign the user out of every session, the current one included
+// @Tags Users
+// @Produce json
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /logout/all [post]
+func (cr *UserHandler) LogoutEverywhere(c *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to get user ID",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	err = cr.userUseCase.LogoutEverywhere(c.Request.Context(), uint(UserID))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to logout",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	clearTokenCookies(c, userAccessCookie, userRefreshCookie)
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "logged out from every device",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
+// ListSessions
+// @Summary List signed in devices
+// @ID ListSessions
+// @Description List the user's active sessions with the device and address they were signed in from, current marks this one
+// @Tags Users
+// @Produce json
+// @Success 200 {object} response.Response{data=[]response.Session}
+// @Failure 400 {object} response.Response
+// @Router /sessions [get]
+func (cr *UserHandler) ListSessions(c *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to get user ID",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	sessions, err := cr.userUseCase.Sessions(c.Request.Context(), uint(UserID), utilhandler.GetSessionIdFromContext(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to list sessions",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "active sessions",
+		Data:       sessions,
+		Errors:     nil,
+	})
+}
+
+// LogoutSession
+// @Summary Sign out a device
+// @ID LogoutSession
+// @Description End one of the user's sessions, its tokens stop working at once
+// @Tags Users
+// @Produce json
+// @Param session_id path string true "Session ID"
+// @Success 200 {object} response.Response
+// @Failure 400 {object} response.Response
+// @Router /sessions/{session_id} [delete]
+func (cr *UserHandler) LogoutSession(c *gin.Context) {
+	UserID, err := utilhandler.GetUserIdFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to get user ID",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	sessionId := c.Param("session_id")
+	err = cr.userUseCase.LogoutSession(c.Request.Context(), uint(UserID), sessionId)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, response.Response{
+			StatusCode: 400,
+			Message:    "failed to sign out the session",
+			Data:       nil,
+			Errors:     err.Error(),
+		})
+		return
+	}
+
+	if sessionId == utilhandler.GetSessionIdFromContext(c) {
+		clearTokenCookies(c, userAccessCookie, userRefreshCookie)
+	}
+	c.JSON(http.StatusOK, response.Response{
+		StatusCode: 200,
+		Message:    "session signed out",
+		Data:       nil,
+		Errors:     nil,
+	})
+}
+
 // @Summary AddAdrress_for_user
 // @ID Add_Adress
 // @Description Create a new user with the specified details.
@@ -1,24 +1,45 @@
 package middleware
 
 import (
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/token"
+	services "ecommerce/pkg/usecase/interface"
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func UserAuth(c *gin.Context) {
-	// s := c.Request.Header.Get("Authorization")
-	tokenString, err := c.Cookie("UserAuth")
-	if err != nil {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
+func UserAuth(tokens services.TokenUseCase, users services.UserUseCase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := accessToken(c, "UserAuth")
+		if tokenString == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
 
-	userId, err := ValidateToken(tokenString)
-	if err != nil {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+		claims, err := tokens.Authenticate(c.Request.Context(), tokenString, token.AudienceUser)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		// a block applies at once, even to tokens issued before it
+		if err := users.CheckBlocked(c.Request.Context(), claims.SubjectID()); err != nil {
+			if !errors.Is(err, domain.ErrUserBlocked) {
+				c.AbortWithStatus(http.StatusInternalServerError)
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusForbidden, response.Response{
+				StatusCode: 403,
+				Message:    "user is blocked",
+				Data:       nil,
+				Errors:     err.Error(),
+			})
+			return
+		}
+		c.Set("userId", claims.SubjectID())
+		c.Set("sessionId", claims.Session)
+		c.Next()
 	}
-	c.Set("userId", userId)
-	c.Next()
 }
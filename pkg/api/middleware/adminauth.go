@@ -1,24 +1,47 @@
 package middleware
 
 import (
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/token"
+	services "ecommerce/pkg/usecase/interface"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 )
 
-func AdminAuth(c *gin.Context) {
-	tokenString, err := c.Cookie("AdminAuth")
-	if err != nil {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
-	}
+func AdminAuth(tokens services.TokenUseCase, admins services.AdminUsecase) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tokenString := accessToken(c, "AdminAuth")
+		if tokenString == "" {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
 
-	adminId, err := ValidateToken(tokenString)
-	if err != nil {
-		c.AbortWithStatus(http.StatusUnauthorized)
-		return
+		claims, err := tokens.Authenticate(c.Request.Context(), tokenString, token.AudienceAdmin)
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		role, err := admins.AdminRole(c.Request.Context(), claims.SubjectID())
+		if err != nil {
+			c.AbortWithStatus(http.StatusUnauthorized)
+			return
+		}
+		c.Set("adminId", claims.SubjectID())
+		c.Set("adminRole", role)
+		c.Set("sessionId", claims.Session)
+		c.Next()
 	}
-	c.Set("adminId", adminId)
-	c.Next()
+}
 
+// AdminPermission lets the request through only when the signed in admin's
+// role has the permission, it runs after AdminAuth
+func AdminPermission(permission string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !domain.AdminCan(c.GetString("adminRole"), permission) {
+			c.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+		c.Next()
+	}
 }
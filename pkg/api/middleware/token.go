@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// accessToken reads the access token from its cookie, or from an
+// Authorization: Bearer header for clients that don't keep cookies
+func accessToken(c *gin.Context, cookie string) string {
+	if value, err := c.Cookie(cookie); err == nil && value != "" {
+		return value
+	}
+	scheme, value, ok := strings.Cut(c.GetHeader("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return ""
+	}
+	return strings.TrimSpace(value)
+}
@@ -3,6 +3,8 @@ package http
 import (
 	"ecommerce/pkg/api/handler"
 	"ecommerce/pkg/api/middleware"
+	"ecommerce/pkg/domain"
+	services "ecommerce/pkg/usecase/interface"
 	"log"
 	"net/http"
 
@@ -25,6 +27,12 @@ func NewServerHTTP(
 	CartHandler *handler.CartHandler,
 	CouponHandler *handler.CouponHandler,
 	OrderHandler *handler.OrderHandler,
+	ReviewHandler *handler.ReviewHandler,
+	AuthHandler *handler.AuthHandler,
+	PasswordHandler *handler.PasswordHandler,
+	tokens services.TokenUseCase,
+	userUseCase services.UserUseCase,
+	adminUseCase services.AdminUsecase,
 ) *ServerHTTP {
 	engine := gin.Default()
 	// engine.Use(gin.Logger())
@@ -40,6 +48,15 @@ func NewServerHTTP(
 		log.Fatal("handler dependencies cannot be nil")
 	}
 
+	// Product images, the path matches the default STORAGE_BASE_URL
+	engine.GET("/images/*key", ProductHandler.ServeImage)
+
+	// ==================== Webhooks ====================
+	webhooks := engine.Group("/webhooks")
+	{
+		webhooks.POST("/razorpay", OrderHandler.RazorpayWebhook)
+	}
+
 	// ==================== User Routes ====================
 	user := engine.Group("/")
 	{
@@ -50,10 +67,14 @@ func NewServerHTTP(
 		user.POST("login", userHandler.UserLogin)
 		user.POST("otp/send", otpHandler.SendOtp)
 		user.POST("otp/verify", otpHandler.ValidateOtp)
+		user.POST("token/refresh", AuthHandler.RefreshUserToken)
+		user.POST("password/forgot", PasswordHandler.ForgotPassword)
+		user.POST("password/forgot/verify", PasswordHandler.VerifyResetOtp)
+		user.POST("password/reset", PasswordHandler.ResetPassword)
 		user.GET("home", userHandler.Home)
 	}
 
-	user.Use(middleware.UserAuth)
+	user.Use(middleware.UserAuth(tokens, userUseCase))
 	{
 		user.POST("SaveAddress", userHandler.AddAdress)
 		user.PATCH("UpdateAddress", userHandler.UpdateAdress)
@@ -62,20 +83,36 @@ func NewServerHTTP(
 		user.DELETE("/Removewishlist/:id", userHandler.RemoveFromWishList)
 		user.GET("wishlist", userHandler.GetWishList)
 		user.POST("logout", userHandler.UserLogout)
+		user.POST("logout/all", userHandler.LogoutEverywhere)
+		user.GET("sessions", userHandler.ListSessions)
+		user.DELETE("sessions/:session_id", userHandler.LogoutSession)
+		user.POST("password/change", PasswordHandler.ChangePassword)
 
 		category := user.Group("/category")
 		{
 			category.GET("showall/", ProductHandler.ListCategories)
+			category.GET("tree", ProductHandler.CategoryTree)
 			category.GET("disply/:id", ProductHandler.DisplayCategory)
 		}
 
 		product := user.Group("/product")
 		{
 			product.GET("ViewAllProducts", ProductHandler.ViewAllProducts)
+			product.GET("ViewProduct/:id", ProductHandler.VeiwProduct)
 			product.GET("/products/search", ProductHandler.SearchProducts)
 			product.GET("/filter", ProductHandler.FilterProductsByPrice)
 		}
 
+		review := user.Group("/review")
+		{
+			review.GET("/product/:product_id", ReviewHandler.ProductReviews)
+			review.POST("/product/:product_id", ReviewHandler.AddReview)
+			review.PATCH("/:review_id", ReviewHandler.UpdateReview)
+			review.DELETE("/:review_id", ReviewHandler.DeleteReview)
+			review.POST("/:review_id/helpful", ReviewHandler.MarkHelpful)
+			review.DELETE("/:review_id/helpful", ReviewHandler.UnmarkHelpful)
+		}
+
 		cart := user.Group("/cart")
 		{
 			cart.POST("/AddToCart", CartHandler.AddCartItem)
@@ -96,64 +133,133 @@ func NewServerHTTP(
 			order.GET("/razor", OrderHandler.RazorpayCheckout)
 			order.POST("/razor/success", OrderHandler.RazorpayVerify)
 			order.PATCH("/cancel/:orderId", OrderHandler.CancelOrder)
+			order.PATCH("/cancel/:orderId/items/:itemId", OrderHandler.CancelOrderItem)
 			order.GET("/view/:order_id", OrderHandler.ListOrder)
+			order.GET("/view/:order_id/items", OrderHandler.ListOrderItems)
+			order.GET("/view/:order_id/timeline", OrderHandler.OrderTimeline)
 			order.GET("/listall", OrderHandler.ListAllOrders)
 			order.PATCH("/return/:orderId", OrderHandler.ReturnOrder)
+			order.PATCH("/return/:orderId/items/:itemId", OrderHandler.ReturnOrderItem)
+		}
+
+		wallet := user.Group("/wallet")
+		{
+			wallet.GET("", OrderHandler.GetWallet)
+			wallet.GET("/transactions", OrderHandler.GetWalletTransactions)
 		}
 	}
 
 	// ==================== Admin Routes ====================
 	admin := engine.Group("/admin")
 	{
-		admin.POST("/signup", adminHandler.SaveAdmin)
 		admin.POST("/login", adminHandler.LoginAdmin)
 		admin.POST("/logout", adminHandler.AdminLogout)
+		admin.POST("/token/refresh", AuthHandler.RefreshAdminToken)
 
 		// Protected admin routes
-		admin.Use(middleware.AdminAuth)
+		// Protected admin routes, each group needs its permission in the admin's role
+		admin.Use(middleware.AdminAuth(tokens, adminUseCase))
 		{
-			admin.GET("/findall", adminHandler.FindAllUser)
-			admin.GET("/finduser/:user_id", adminHandler.FindUserByID)
-			admin.POST("/finduser", adminHandler.FindUserByID)
-			admin.PATCH("/block", adminHandler.BlockUser)
-			admin.PATCH("/unblock/:user_id", adminHandler.UnblockUser)
+			admin.POST("/logout/all", adminHandler.AdminLogoutEverywhere)
+		}
+
+		// Admins
+		admins := admin.Group("", middleware.AdminPermission(domain.PermissionAdmins))
+		{
+			admins.POST("/signup", adminHandler.SaveAdmin)
+			admins.GET("/admins", adminHandler.ListAdmins)
+			admins.PATCH("/admins/:admin_id/role", adminHandler.ChangeAdminRole)
+		}
+
+		// Users
+		users := admin.Group("", middleware.AdminPermission(domain.PermissionUsers))
+		{
+			users.GET("/findall", adminHandler.FindAllUser)
+			users.GET("/finduser/:user_id", adminHandler.FindUserByID)
+			users.POST("/finduser", adminHandler.FindUserByID)
+			users.PATCH("/block", adminHandler.BlockUser)
+			users.PATCH("/unblock/:user_id", adminHandler.UnblockUser)
+			users.GET("/blockhistory", adminHandler.BlockHistory)
+			users.DELETE("/deleteuser/:user_id", adminHandler.DeleteUser)
+			users.PATCH("/restoreuser/:user_id", adminHandler.RestoreUser)
+			users.GET("/archivedusers", adminHandler.ArchivedUsers)
+		}
+
+		// Reports
+		reports := admin.Group("", middleware.AdminPermission(domain.PermissionReports))
+		{
+			reports.GET("/dashboard", adminHandler.Dashboard)
+			reports.GET("/salesreport", adminHandler.SalesReport)
 		}
 
 		// Category
-		category := admin.Group("/category")
+		category := admin.Group("/category", middleware.AdminPermission(domain.PermissionCatalog))
 		{
 			category.POST("add", ProductHandler.Addcategory)
 			category.PATCH("update/:id", ProductHandler.UpdateCategory)
 			category.DELETE("delete/:category_id", ProductHandler.DeleteCategory)
+			category.PATCH("restore/:category_id", ProductHandler.RestoreCategory)
+			category.GET("archived", ProductHandler.ArchivedCategories)
 			category.GET("showall/", ProductHandler.ListCategories)
+			category.GET("tree", ProductHandler.CategoryTree)
 			category.GET("disply/:id", ProductHandler.DisplayCategory)
 		}
 
 		// Product
-		product := admin.Group("/product")
+		product := admin.Group("/product", middleware.AdminPermission(domain.PermissionCatalog))
 		{
 			product.POST("save", ProductHandler.SaveProduct)
 			product.PATCH("updateproduct/:id", ProductHandler.UpdateProduct)
 			product.DELETE("delete/:product_id", ProductHandler.DeleteProduct)
+			product.PATCH("restore/:product_id", ProductHandler.RestoreProduct)
+			product.GET("archived", ProductHandler.ArchivedProducts)
 			product.GET("ViewAllProducts", ProductHandler.ViewAllProducts)
 			product.GET("ViewProduct/:id", ProductHandler.VeiwProduct)
+			product.GET("stock/:product_id", ProductHandler.StockHistory)
+			product.POST("stock/:product_id", ProductHandler.AdjustStock)
+			product.PATCH("stock/:product_id/threshold", ProductHandler.SetReorderThreshold)
+			product.GET("lowstock", ProductHandler.LowStockReport)
+			product.GET("variants/:product_id", ProductHandler.ListVariants)
+			product.POST("variants/:product_id", ProductHandler.AddVariant)
+			product.PATCH("variant/:variant_id", ProductHandler.UpdateVariant)
+			product.DELETE("variant/:variant_id", ProductHandler.DeleteVariant)
+			product.GET("images/:product_id", ProductHandler.ListProductImages)
+			product.POST("images/:product_id", ProductHandler.UploadProductImages)
+			product.PATCH("images/:product_id/order", ProductHandler.ReorderProductImages)
+			product.PATCH("image/:image_id/primary", ProductHandler.SetPrimaryImage)
+			product.DELETE("image/:image_id", ProductHandler.DeleteProductImage)
 			// product.GET("/products/search", ProductHandler.SearchProducts)
 		}
 
 		// Order
-		order := admin.Group("/order")
+		order := admin.Group("/order", middleware.AdminPermission(domain.PermissionOrders))
 		{
 			order.GET("/Status", OrderHandler.Statuses)
 			order.GET("/Allorders", OrderHandler.AllOrders)
 			order.PATCH("/UpdateStatus", OrderHandler.UpdateOrderStatus)
+			order.GET("/:order_id/timeline", OrderHandler.AdminOrderTimeline)
+			order.GET("/refunds", OrderHandler.ListRefunds)
+			order.GET("/checkouts", OrderHandler.ListRazorpayCheckouts)
+			order.PATCH("/refunds/:refund_id/approve", OrderHandler.ApproveRefund)
+			order.PATCH("/refunds/:refund_id/deny", OrderHandler.DenyRefund)
+		}
+
+		// Review
+		review := admin.Group("/review", middleware.AdminPermission(domain.PermissionReviews))
+		{
+			review.GET("", ReviewHandler.ListReviews)
+			review.PATCH("/:review_id/hide", ReviewHandler.HideReview)
+			review.PATCH("/:review_id/publish", ReviewHandler.PublishReview)
 		}
 
 		// Coupon
-		coupon := admin.Group("/coupon")
+		coupon := admin.Group("/coupon", middleware.AdminPermission(domain.PermissionCoupons))
 		{
 			coupon.POST("/AddCoupons", CouponHandler.AddCoupon)
 			coupon.PATCH("/Update/:CouponID", CouponHandler.UpdateCoupon)
 			coupon.DELETE("/Delete/:CouponID", CouponHandler.DeleteCoupon)
+			coupon.PATCH("/Restore/:CouponID", CouponHandler.RestoreCoupon)
+			coupon.GET("/archived", CouponHandler.ArchivedCoupons)
 			coupon.GET("/Viewcoupon/:id", CouponHandler.ViewCoupon)
 			coupon.GET("/couponlist", CouponHandler.Coupons)
 		}
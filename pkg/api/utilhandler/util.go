@@ -1,12 +1,16 @@
 package utilhandler
 
 import (
+	"ecommerce/pkg/commonhelp/requests.go"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
 
+const dateLayout = "2006-01-02"
+
 func GetAdminIdFromContext(c *gin.Context) (int, error) {
 	id := c.Value("adminId")
 	adminId, err := strconv.Atoi(fmt.Sprintf("%v", id))
@@ -18,3 +22,41 @@ func GetUserIdFromContext(c *gin.Context) (int, error) {
 	userId, err := strconv.Atoi(fmt.Sprintf("%v", id))
 	return userId, err
 }
+
+// GetSessionIdFromContext is the session of the access token the request
+// was signed in with
+func GetSessionIdFromContext(c *gin.Context) string {
+	return c.GetString("sessionId")
+}
+
+// GetDevice is where the request comes from, kept on the session a sign in starts
+func GetDevice(c *gin.Context) requests.Device {
+	return requests.Device{
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+	}
+}
+
+// GetDateRangeFromQuery reads start_date and end_date (YYYY-MM-DD) from the query.
+// Both are optional, end_date is inclusive of the whole day
+func GetDateRangeFromQuery(c *gin.Context) (requests.DateRange, error) {
+	var dateRange requests.DateRange
+
+	if start := c.Query("start_date"); start != "" {
+		startDate, err := time.ParseInLocation(dateLayout, start, time.Local)
+		if err != nil {
+			return dateRange, fmt.Errorf("invalid start_date, expected YYYY-MM-DD")
+		}
+		dateRange.StartDate = startDate
+	}
+
+	if end := c.Query("end_date"); end != "" {
+		endDate, err := time.ParseInLocation(dateLayout, end, time.Local)
+		if err != nil {
+			return dateRange, fmt.Errorf("invalid end_date, expected YYYY-MM-DD")
+		}
+		dateRange.EndDate = endDate.Add(24*time.Hour - time.Nanosecond)
+	}
+
+	return dateRange, nil
+}
@@ -0,0 +1,56 @@
+package utilhandler
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestGetDateRangeFromQuery(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	tests := []struct {
+		name    string
+		query   string
+		start   time.Time
+		end     time.Time
+		wantErr bool
+	}{
+		{name: "no dates"},
+		{
+			name:  "end date covers the whole day",
+			query: "?start_date=2024-01-01&end_date=2024-01-31",
+			start: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local),
+			end:   time.Date(2024, 1, 31, 23, 59, 59, 999999999, time.Local),
+		},
+		{
+			name:  "only a start date",
+			query: "?start_date=2024-02-29",
+			start: time.Date(2024, 2, 29, 0, 0, 0, 0, time.Local),
+		},
+		{name: "bad start date", query: "?start_date=01-02-2024", wantErr: true},
+		{name: "bad end date", query: "?end_date=2024-13-01", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("GET", "/admin/dashboard"+tt.query, nil)
+
+			dateRange, err := GetDateRangeFromQuery(c)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error for %q", tt.query)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !dateRange.StartDate.Equal(tt.start) || !dateRange.EndDate.Equal(tt.end) {
+				t.Fatalf("got %v - %v, want %v - %v", dateRange.StartDate, dateRange.EndDate, tt.start, tt.end)
+			}
+		})
+	}
+}
@@ -0,0 +1,122 @@
+package export
+
+import (
+	"ecommerce/pkg/commonhelp/response"
+	"encoding/csv"
+	"io"
+	"strconv"
+
+	"github.com/go-pdf/fpdf"
+	"github.com/xuri/excelize/v2"
+)
+
+const dateTimeLayout = "2006-01-02 15:04"
+
+var salesReportHeader = []string{"Order ID", "Customer", "Mobile", "Payment Method", "Order Date", "House Number", "Pincode", "Order Total"}
+
+func salesReportRow(row response.SalesReport) []string {
+	return []string{
+		row.Id,
+		row.Name,
+		row.Mobile,
+		row.Payment_method,
+		row.OrderDate.Format(dateTimeLayout),
+		row.HouseNumber,
+		row.Pincode,
+		formatAmount(row.Order_Total),
+	}
+}
+
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+func salesReportTotal(report []response.SalesReport) float64 {
+	total := 0.0
+	for _, row := range report {
+		total += row.Order_Total
+	}
+	return total
+}
+
+func SalesReportCSV(w io.Writer, report []response.SalesReport) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(salesReportHeader); err != nil {
+		return err
+	}
+	for _, row := range report {
+		if err := writer.Write(salesReportRow(row)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func SalesReportXLSX(w io.Writer, report []response.SalesReport) error {
+	file := excelize.NewFile()
+	defer file.Close()
+
+	sheet := "Sales Report"
+	if err := file.SetSheetName("Sheet1", sheet); err != nil {
+		return err
+	}
+
+	for i, title := range salesReportHeader {
+		cell, _ := excelize.CoordinatesToCellName(i+1, 1)
+		file.SetCellValue(sheet, cell, title)
+	}
+	for r, row := range report {
+		values := salesReportRow(row)
+		for i, value := range values {
+			cell, _ := excelize.CoordinatesToCellName(i+1, r+2)
+			if i == len(values)-1 {
+				file.SetCellValue(sheet, cell, row.Order_Total)
+				continue
+			}
+			file.SetCellValue(sheet, cell, value)
+		}
+	}
+
+	totalRow := len(report) + 2
+	labelCell, _ := excelize.CoordinatesToCellName(len(salesReportHeader)-1, totalRow)
+	totalCell, _ := excelize.CoordinatesToCellName(len(salesReportHeader), totalRow)
+	file.SetCellValue(sheet, labelCell, "Total")
+	file.SetCellValue(sheet, totalCell, salesReportTotal(report))
+
+	return file.Write(w)
+}
+
+func SalesReportPDF(w io.Writer, title string, report []response.SalesReport) error {
+	pdf := fpdf.New("L", "mm", "A4", "")
+	pdf.AddPage()
+
+	pdf.SetFont("Helvetica", "B", 14)
+	pdf.CellFormat(0, 10, title, "", 1, "C", false, 0, "")
+
+	widths := []float64{22, 45, 30, 35, 35, 40, 25, 30}
+
+	pdf.SetFont("Helvetica", "B", 9)
+	for i, heading := range salesReportHeader {
+		pdf.CellFormat(widths[i], 8, heading, "1", 0, "C", false, 0, "")
+	}
+	pdf.Ln(-1)
+
+	pdf.SetFont("Helvetica", "", 9)
+	for _, row := range report {
+		for i, value := range salesReportRow(row) {
+			pdf.CellFormat(widths[i], 7, value, "1", 0, "L", false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	pdf.SetFont("Helvetica", "B", 9)
+	labelWidth := 0.0
+	for _, width := range widths[:len(widths)-1] {
+		labelWidth += width
+	}
+	pdf.CellFormat(labelWidth, 8, "Total", "1", 0, "R", false, 0, "")
+	pdf.CellFormat(widths[len(widths)-1], 8, formatAmount(salesReportTotal(report)), "1", 1, "L", false, 0, "")
+
+	return pdf.Output(w)
+}
@@ -0,0 +1,72 @@
+package export
+
+import (
+	"bytes"
+	"ecommerce/pkg/commonhelp/response"
+	"encoding/csv"
+	"testing"
+	"time"
+
+	"github.com/xuri/excelize/v2"
+)
+
+var testReport = []response.SalesReport{
+	{Id: "1", Name: "asha", Mobile: "9000000001", Payment_method: "COD", OrderDate: time.Date(2024, 1, 2, 10, 30, 0, 0, time.UTC), HouseNumber: "12", Pincode: "560001", Order_Total: 499.50},
+	{Id: "2", Name: "ravi", Mobile: "9000000002", Payment_method: "razorpay", OrderDate: time.Date(2024, 1, 3, 18, 5, 0, 0, time.UTC), HouseNumber: "7B", Pincode: "682001", Order_Total: 1200.25},
+}
+
+func TestSalesReportCSVKeepsPaise(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SalesReportCSV(&buf, testReport); err != nil {
+		t.Fatal(err)
+	}
+
+	records, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != len(testReport)+1 {
+		t.Fatalf("got %d records, want a header and %d rows", len(records), len(testReport))
+	}
+	want := []string{"1", "asha", "9000000001", "COD", "2024-01-02 10:30", "12", "560001", "499.50"}
+	for i, value := range want {
+		if records[1][i] != value {
+			t.Fatalf("column %q = %q, want %q", salesReportHeader[i], records[1][i], value)
+		}
+	}
+	if got := records[2][len(salesReportHeader)-1]; got != "1200.25" {
+		t.Fatalf("second order total = %q, want 1200.25", got)
+	}
+}
+
+func TestSalesReportXLSXTotal(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SalesReportXLSX(&buf, testReport); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := excelize.OpenReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	rows, err := file.GetRows("Sales Report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	last := rows[len(rows)-1]
+	if last[len(last)-2] != "Total" || last[len(last)-1] != "1699.75" {
+		t.Fatalf("total row = %v, want Total 1699.75", last)
+	}
+}
+
+func TestSalesReportPDF(t *testing.T) {
+	var buf bytes.Buffer
+	if err := SalesReportPDF(&buf, "Sales Report", testReport); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("%PDF-")) {
+		t.Fatal("output isn't a pdf")
+	}
+}
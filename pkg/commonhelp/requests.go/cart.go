@@ -1,19 +1,24 @@
 package requests
 
+// Cartreq picks a product for the cart, VariantId can be left out when the
+// product has a single variant
 type Cartreq struct {
 	ProductId int `json:"product_id"`
+	VariantId int `json:"variant_id"`
 	UserID    int `json:"-"`
 }
 
 type Addcount struct {
 	UserID    int  `json:"-"`
 	ProductId int  `json:"product_id" binding:"required"`
+	VariantId int  `json:"variant_id"`
 	Count     uint `json:"count" binding:"omitempty,gte=1"`
 }
 
 type CartItems struct {
 	ProductId    int
+	VariantId    int
 	Qty          int
-	Price        int
+	Price        float64
 	Qty_In_Stock int
 }
@@ -1,12 +1,58 @@
 package requests
 
+import "time"
+
 type RazorPayRequest struct {
 	RazorPayPaymentId  string
 	RazorPayOrderId    string
 	Razorpay_signature string
+	UserID             int
+	Amount             float64
 }
 
 type Update struct {
-	OrderId  int `json:"order_id" binding:"required"`
-	StatusId int `json:"status_id" binding:"required"`
+	OrderId  int    `json:"order_id" binding:"required"`
+	StatusId int    `json:"status_id" binding:"required"`
+	Note     string `json:"note"`
+}
+
+// RefundDecision is what an admin sends when approving or denying a return
+type RefundDecision struct {
+	Amount float64 `json:"amount" binding:"omitempty,gte=0"`
+	Reason string  `json:"reason"`
+}
+
+type DateRange struct {
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+// RazorpayWebhook is the part of a razorpay webhook body that is reconciled,
+// amounts are in paise
+type RazorpayWebhook struct {
+	Event   string `json:"event"`
+	Payload struct {
+		Payment struct {
+			Entity RazorpayPayment `json:"entity"`
+		} `json:"payment"`
+		Refund struct {
+			Entity RazorpayRefund `json:"entity"`
+		} `json:"refund"`
+	} `json:"payload"`
+}
+
+type RazorpayPayment struct {
+	ID      string `json:"id"`
+	OrderID string `json:"order_id"`
+	Amount  int64  `json:"amount"`
+	Status  string `json:"status"`
+	// set on payment.failed
+	ErrorDescription string `json:"error_description"`
+}
+
+type RazorpayRefund struct {
+	ID        string `json:"id"`
+	PaymentID string `json:"payment_id"`
+	Amount    int64  `json:"amount"`
+	Status    string `json:"status"`
 }
@@ -2,6 +2,9 @@ package requests
 
 type Category struct {
 	Name string `json:"name" validate:"required"`
+	// ParentID nests the category under another one. On update it is left
+	// as it was when missing and 0 moves the category to the top level
+	ParentID *uint `json:"parent_id"`
 }
 
 type Product struct {
@@ -11,4 +14,84 @@ type Product struct {
 	Prize        int
 	Qty_in_stock int
 	Category_Id  string `json:"categoryid" validate:"required"`
+	// Sku names the product's first variant, it is made up when left out
+	Sku string `json:"sku" gorm:"-"`
+}
+
+// StockAdjustment is an admin's manual stock change to a variant, Change is
+// added to the stock and may be negative for an adjustment
+type StockAdjustment struct {
+	// VariantID can be left out when the product has a single variant
+	VariantID uint   `json:"variant_id"`
+	Change    int    `json:"change" binding:"required"`
+	Reason    string `json:"reason" binding:"omitempty,oneof=adjustment restock"`
+	Note      string `json:"note"`
+}
+
+type ReorderThreshold struct {
+	Threshold int `json:"threshold" binding:"gte=0"`
+}
+
+// Variant is a product variant as an admin creates or edits it. Fields left
+// out of an edit keep their value, stock is only set on create and changed
+// through stock adjustments after
+type Variant struct {
+	Sku        string            `json:"sku"`
+	Attributes map[string]string `json:"attributes"`
+	Price      *int              `json:"price" binding:"omitempty,gte=0"`
+	QtyInStock int               `json:"qty_in_stock" binding:"gte=0"`
+}
+
+// MaxImageUploads is how many images one upload request can carry
+const MaxImageUploads = 10
+
+// ImageUpload is one file from a multipart image upload
+type ImageUpload struct {
+	Filename string
+	Body     []byte
+}
+
+// ImageOrder lists every image of a product in the order they should be shown
+type ImageOrder struct {
+	ImageIDs []uint `json:"image_ids" binding:"required,min=1"`
+}
+
+// ProductSearch is a full text search over product names, brands and
+// descriptions. The other fields narrow the results down, prices are inclusive
+type ProductSearch struct {
+	Query      string `form:"q" binding:"required"`
+	CategoryID uint   `form:"category_id"`
+	Brand      string `form:"brand"`
+	MinPrice   *int   `form:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice   *int   `form:"max_price" binding:"omitempty,gte=0"`
+	Page       uint   `form:"page"`
+	PerPage    uint   `form:"perPage"`
+}
+
+// ProductFilter narrows and orders the product listing. Repeat category_id and
+// brand to pick several, a category takes in its subcategories and prices are
+// inclusive. Pages are numbered by page and perPage, or followed with the
+// next_cursor of the previous page in cursor
+type ProductFilter struct {
+	CategoryIDs []uint   `form:"category_id"`
+	Brands      []string `form:"brand"`
+	MinPrice    *int     `form:"min_price" binding:"omitempty,gte=0"`
+	MaxPrice    *int     `form:"max_price" binding:"omitempty,gte=0"`
+	InStock     bool     `form:"in_stock"`
+	Query       string   `form:"q"`
+	Sort        string   `form:"sort" binding:"omitempty,oneof=newest price_asc price_desc popularity rating relevance"`
+	Page        uint     `form:"page"`
+	PerPage     uint     `form:"perPage" binding:"lte=100"`
+	Cursor      string   `form:"cursor"`
+}
+
+// Review is a rating from 1 to 5 stars with an optional written review
+type Review struct {
+	Rating int    `json:"rating" binding:"required,min=1,max=5"`
+	Review string `json:"review" binding:"max=2000"`
+}
+
+// ReviewModeration is why an admin hid a review
+type ReviewModeration struct {
+	Reason string `json:"reason"`
 }
@@ -20,14 +20,48 @@ type Otpverifier struct {
 	Phone string `json:"Phone,omitempty" validate:"required"`
 }
 
+// Device is where a sign in came from, kept on its session
+type Device struct {
+	UserAgent string
+	IPAddress string
+}
+
+// ForgotPassword asks for a reset token by email, or for an OTP by sms that
+// is traded for one
+type ForgotPassword struct {
+	Channel string `json:"channel" binding:"required,oneof=email sms"`
+	Email   string `json:"email" binding:"omitempty,email"`
+	Phone   string `json:"phone"`
+}
+
+type ResetPassword struct {
+	Token       string `json:"token" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+type ChangePassword struct {
+	OldPassword string `json:"old_password" binding:"required"`
+	NewPassword string `json:"new_password" binding:"required,min=8"`
+}
+
+type RefreshToken struct {
+	RefreshToken string `json:"refresh_token"`
+}
+
 type Pagination struct {
 	Page    uint `json:"page"`
 	PerPage uint `json:"page_per"`
 }
 
+type AdminRole struct {
+	Role string `json:"role" binding:"required"`
+}
+
+// BlockUser blocks the user for DurationHours, or until unblocked when it is 0
 type BlockUser struct {
-	UserID int    `json:"user_id"`
-	Reason string `json:"reason"`
+	UserID        int    `json:"user_id"`
+	Reason        string `json:"reason"`
+	DurationHours uint   `json:"duration_hours"`
 }
 
 type AddressReq struct {
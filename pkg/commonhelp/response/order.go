@@ -10,6 +10,10 @@ type RazorPayResponse struct {
 	OrderId     interface{}
 	AmountToPay float64
 	Total       float64
+	UseWallet   bool
+	WalletUsed  float64
+	// the cart's stock is held for this razorpay order until then
+	ReservedUntil time.Time
 }
 
 type OrderResponse struct {
@@ -32,12 +36,24 @@ type OrderResponse struct {
 	DeliveryUpdatedAt time.Time `json:"expected_delivery_time"`
 }
 
+// OrderTimeline is one status change of an order
+type OrderTimeline struct {
+	FromStatusID uint      `json:"from_status_id,omitempty"`
+	FromStatus   string    `json:"from_status,omitempty"`
+	ToStatusID   uint      `json:"to_status_id"`
+	ToStatus     string    `json:"to_status"`
+	ChangedBy    string    `json:"changed_by"`
+	ChangedByID  uint      `json:"changed_by_id,omitempty"`
+	Note         string    `json:"note,omitempty"`
+	ChangedAt    time.Time `json:"changed_at"`
+}
+
 type SalesReport struct {
 	Id             string
 	Name           string
 	Payment_method string
 	OrderDate      time.Time
-	Order_Total    int
+	Order_Total    float64
 	Mobile         string
 	HouseNumber    string
 	Pincode        string
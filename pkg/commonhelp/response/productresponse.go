@@ -1,8 +1,16 @@
 package response
 
+import (
+	"ecommerce/pkg/domain"
+	"time"
+)
+
 type Category struct {
-	ID           uint   `json:"id" gorm:"unique;not null"`
-	CategoryName string `json:"name"`
+	ID           uint       `json:"id" gorm:"unique;not null"`
+	CategoryName string     `json:"name"`
+	ParentID     uint       `json:"parent_id,omitempty"`
+	Children     []Category `json:"children,omitempty" gorm:"-"`
+	DeletedAt    *time.Time `json:"deleted_at,omitempty"`
 }
 
 type Product struct {
@@ -14,12 +22,124 @@ type Product struct {
 	Brand        string
 	Category_Id  uint
 	CategoryName string
+	// Breadcrumbs are the categories from the top level down to the product's own
+	Breadcrumbs []Category `json:",omitempty" gorm:"-"`
+	// Rating is the average of the published reviews, ReviewCount how many there are
+	Rating      float64
+	ReviewCount int
+	Variants    []domain.ProductVariant `json:",omitempty" gorm:"-"`
+	// Thumbnail is the url of the primary image's thumbnail
+	Thumbnail    string         `json:",omitempty" gorm:"-"`
+	ThumbnailKey string         `json:"-"`
+	Images       []ProductImage `json:",omitempty" gorm:"-"`
+	DeletedAt    *time.Time     `json:",omitempty"`
+}
+
+// ProductImage is a product image with the urls it is served from
+type ProductImage struct {
+	ID           uint   `json:"image_id"`
+	URL          string `json:"url"`
+	ThumbnailURL string `json:"thumbnail_url"`
+	ContentType  string `json:"content_type"`
+	Width        int    `json:"width"`
+	Height       int    `json:"height"`
+	Position     int    `json:"position"`
+	IsPrimary    bool   `json:"is_primary"`
+}
+
+// ProductList is a page of the product listing, Total counts every product
+// the filters match. NextCursor is empty on the last page
+type ProductList struct {
+	Products   []Product `json:"products"`
+	Total      int       `json:"total"`
+	Page       uint      `json:"page,omitempty"`
+	PerPage    uint      `json:"per_page"`
+	NextCursor string    `json:"next_cursor,omitempty"`
+}
+
+// ProductSearch is a page of search results by relevance. Facet counts cover
+// every product matching the text, whatever filters were picked, and
+// Suggestions are only filled when nothing matched
+type ProductSearch struct {
+	Products    []Product    `json:"products"`
+	Total       int          `json:"total"`
+	Page        uint         `json:"page"`
+	PerPage     uint         `json:"per_page"`
+	Facets      SearchFacets `json:"facets"`
+	Suggestions []string     `json:"suggestions,omitempty"`
+}
+
+type SearchFacets struct {
+	Categories []CategoryFacet `json:"categories"`
+	Brands     []BrandFacet    `json:"brands"`
+	Prices     []PriceFacet    `json:"prices"`
+}
+
+type CategoryFacet struct {
+	CategoryID   uint   `json:"category_id"`
+	CategoryName string `json:"category_name"`
+	Count        int    `json:"count"`
+}
+
+type BrandFacet struct {
+	Brand string `json:"brand"`
+	Count int    `json:"count"`
+}
+
+// PriceFacet counts the products priced from Min to Max, Max is nil for the top bucket
+type PriceFacet struct {
+	Min   int  `json:"min"`
+	Max   *int `json:"max"`
+	Count int  `json:"count"`
+}
+
+// Review is a review as listed under a product, Status and HiddenReason are
+// only filled for admins
+type Review struct {
+	ReviewID     uint      `json:"review_id" gorm:"column:id"`
+	ProductID    uint      `json:"product_id"`
+	UserName     string    `json:"user_name"`
+	Rating       int       `json:"rating"`
+	Review       string    `json:"review" gorm:"column:body"`
+	HelpfulCount int       `json:"helpful_count"`
+	Status       string    `json:"status,omitempty"`
+	HiddenReason string    `json:"hidden_reason,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// LowStockProduct is a product at or below its reorder threshold, ReservedQty
+// is already held by razorpay checkouts and not counted in QtyInStock
+type LowStockProduct struct {
+	ProductID        uint   `json:"product_id"`
+	ProductName      string `json:"product_name"`
+	Brand            string `json:"brand"`
+	CategoryName     string `json:"category_name"`
+	QtyInStock       int    `json:"qty_in_stock"`
+	ReorderThreshold int    `json:"reorder_threshold"`
+	ReservedQty      int    `json:"reserved_qty"`
 }
 
 type Cartres struct {
-	Product_Id   uint   `json:"product_item_id"`
-	ProductName  string `json:"product_name"`
-	Prize        uint   `json:"prize"`
-	Qty_in_stock uint   `json:"qty_in_stock"`
-	Qty          uint   `json:"qty"`
+	Product_Id   uint                     `json:"product_item_id"`
+	VariantID    uint                     `json:"variant_id"`
+	ProductName  string                   `json:"product_name"`
+	SKU          string                   `json:"sku" gorm:"column:sku"`
+	Attributes   domain.VariantAttributes `json:"attributes"`
+	Prize        uint                     `json:"prize"`
+	Qty_in_stock uint                     `json:"qty_in_stock"`
+	Qty          uint                     `json:"qty"`
+	Line_total   float64                  `json:"line_total"`
+	Image        string                   `json:"image" gorm:"-"`
+	ThumbnailKey string                   `json:"-"`
+}
+
+// CartView is the cart with its price breakdown, Total = Subtotal - Discount + Tax + Shipping
+type CartView struct {
+	Items    []Cartres `json:"items"`
+	Subtotal float64   `json:"subtotal"`
+	Discount float64   `json:"discount"`
+	Tax      float64   `json:"tax"`
+	Shipping float64   `json:"shipping"`
+	Total    float64   `json:"total"`
 }
@@ -3,16 +3,66 @@ package response
 import "time"
 
 type UserValue struct {
-	ID        uint      `json:"id" gorm:"unique;not null"`
-	Name      string    `json:"name"`
-	Email     string    `json:"email"`
-	Password  string    `json:"-"`
-	CreatedAt time.Time `json:"created_time"`
+	ID        uint       `json:"id" gorm:"unique;not null"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Password  string     `json:"-"`
+	CreatedAt time.Time  `json:"created_time"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+}
+type Admin struct {
+	ID        uint   `json:"id"`
+	AdminName string `json:"admin_name"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+}
+
+// BlockRecord is one block in a user's history, Active is false once it was
+// lifted or ran out
+type BlockRecord struct {
+	ID           uint       `json:"id"`
+	UserID       uint       `json:"user_id"`
+	UserName     string     `json:"user_name"`
+	Email        string     `json:"email"`
+	Reason       string     `json:"reason"`
+	BlockedAt    *time.Time `json:"blocked_at"`
+	BlockedBy    uint       `json:"blocked_by,omitempty"`
+	BlockedUntil *time.Time `json:"blocked_until,omitempty"`
+	UnblockedAt  *time.Time `json:"unblocked_at,omitempty"`
+	UnblockedBy  uint       `json:"unblocked_by,omitempty"`
+	Active       bool       `json:"active"`
 }
 type Wishlist struct {
-	ProductID   uint   `json:"product_item_id"`
-	ProductName string `json:"product_name"`
-	Price       uint   `json:"price"`
-	Image       string `json:"image"`
-	QtyInStock  uint   `json:"qty_in_stock"`
+	ProductID    uint   `json:"product_item_id"`
+	ProductName  string `json:"product_name"`
+	Price        uint   `json:"price"`
+	Image        string `json:"image" gorm:"-"`
+	QtyInStock   uint   `json:"qty_in_stock"`
+	ThumbnailKey string `json:"-"`
+}
+
+// TokenPair is what a sign in or refresh hands out, lifetimes are in seconds.
+// The refresh token gets a new pair before the access token expires
+type TokenPair struct {
+	AccessToken      string `json:"access_token"`
+	RefreshToken     string `json:"refresh_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int    `json:"expires_in"`
+	RefreshExpiresIn int    `json:"refresh_expires_in"`
+}
+
+// Session is a signed in device, Current marks the one making the request
+type Session struct {
+	ID         string    `json:"id"`
+	UserAgent  string    `json:"user_agent"`
+	IPAddress  string    `json:"ip_address"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current" gorm:"-"`
+}
+
+// ResetToken is handed out after the sms OTP of a password reset is verified
+type ResetToken struct {
+	ResetToken string `json:"reset_token"`
 }
@@ -10,33 +10,76 @@ import (
 )
 
 type Config struct {
-	DBHost           string `mapstructure:"DB_HOST" validate:"required"`
-	DBName           string `mapstructure:"DB_NAME" validate:"required"`
-	DBUser           string `mapstructure:"DB_USER" validate:"required"`
-	DBPort           string `mapstructure:"DB_PORT" validate:"required"`
-	DBPassword       string `mapstructure:"DB_PASSWORD" validate:"required"`
-	AUTHTOCKEN       string `mapstructure:"TWILIO_AUTHTOCKEN" validate:"required"`
-	ACCOUNTSID       string `mapstructure:"TWILIO_ACCOUNT_SID" validate:"required"`
-	SERVICES_ID      string `mapstructure:"TWILIO_SERVICES_ID" validate:"required"`
-	RAZOR_PAY_KEY    string `mapstructure:"RAZOR_PAY_KEY"`
-	RAZOR_PAY_SECRET string `mapstructure:"RAZOR_PAY_SECRET"`
+	APP_ENV                  string  `mapstructure:"APP_ENV"`
+	DBHost                   string  `mapstructure:"DB_HOST" validate:"required"`
+	DBName                   string  `mapstructure:"DB_NAME" validate:"required"`
+	DBUser                   string  `mapstructure:"DB_USER" validate:"required"`
+	DBPort                   string  `mapstructure:"DB_PORT" validate:"required"`
+	DBPassword               string  `mapstructure:"DB_PASSWORD" validate:"required"`
+	AUTHTOCKEN               string  `mapstructure:"TWILIO_AUTHTOCKEN" validate:"required"`
+	ACCOUNTSID               string  `mapstructure:"TWILIO_ACCOUNT_SID" validate:"required"`
+	SERVICES_ID              string  `mapstructure:"TWILIO_SERVICES_ID" validate:"required"`
+	RAZOR_PAY_KEY            string  `mapstructure:"RAZOR_PAY_KEY"`
+	RAZOR_PAY_SECRET         string  `mapstructure:"RAZOR_PAY_SECRET"`
+	RAZOR_PAY_WEBHOOK_SECRET string  `mapstructure:"RAZOR_PAY_WEBHOOK_SECRET"`
+	PAYMENT_GATEWAY          string  `mapstructure:"PAYMENT_GATEWAY"`
+	TAX_PERCENT              float64 `mapstructure:"TAX_PERCENT"`
+	SHIPPING_CHARGE          float64 `mapstructure:"SHIPPING_CHARGE"`
+	FREE_SHIPPING_ABOVE      float64 `mapstructure:"FREE_SHIPPING_ABOVE"`
+	RESERVATION_MINUTES      int     `mapstructure:"RESERVATION_MINUTES"`
+	STORAGE_BACKEND          string  `mapstructure:"STORAGE_BACKEND"`
+	STORAGE_DIR              string  `mapstructure:"STORAGE_DIR"`
+	STORAGE_BASE_URL         string  `mapstructure:"STORAGE_BASE_URL"`
+	JWT_KEYS                 string  `mapstructure:"JWT_KEYS" validate:"required"`
+	JWT_SIGNING_KID          string  `mapstructure:"JWT_SIGNING_KID"`
+	ACCESS_TOKEN_MINUTES     int     `mapstructure:"ACCESS_TOKEN_MINUTES"`
+	REFRESH_TOKEN_DAYS       int     `mapstructure:"REFRESH_TOKEN_DAYS"`
+	PASSWORD_RESET_MINUTES   int     `mapstructure:"PASSWORD_RESET_MINUTES"`
+	MAIL_BACKEND             string  `mapstructure:"MAIL_BACKEND"`
+	MAIL_FROM                string  `mapstructure:"MAIL_FROM"`
+	SMTP_HOST                string  `mapstructure:"SMTP_HOST"`
+	SMTP_PORT                string  `mapstructure:"SMTP_PORT"`
+	SMTP_USERNAME            string  `mapstructure:"SMTP_USERNAME"`
+	SMTP_PASSWORD            string  `mapstructure:"SMTP_PASSWORD"`
 }
 
 var envs = []string{
+	"APP_ENV", // development, test or production
 	"DB_HOST", "DB_NAME", "DB_USER", "DB_PORT", "DB_PASSWORD",
 	"TWILIO_AUTHTOCKEN", "TWILIO_ACCOUNT_SID", "TWILIO_SERVICES_ID", //twilio
-	"RAZOR_PAY_KEY", "RAZOR_PAY_SECRET", //razor
+	"RAZOR_PAY_KEY", "RAZOR_PAY_SECRET", "RAZOR_PAY_WEBHOOK_SECRET", //razor
+	"PAYMENT_GATEWAY",                                       // razorpay or fake
+	"TAX_PERCENT", "SHIPPING_CHARGE", "FREE_SHIPPING_ABOVE", // cart pricing
+	"RESERVATION_MINUTES",                                // how long a razorpay checkout holds stock
+	"STORAGE_BACKEND", "STORAGE_DIR", "STORAGE_BASE_URL", // product images
+	"JWT_KEYS", "JWT_SIGNING_KID", // kid:secret pairs, the signing kid defaults to the first
+	"ACCESS_TOKEN_MINUTES", "REFRESH_TOKEN_DAYS",
+	"PASSWORD_RESET_MINUTES",    // how long a reset token lasts
+	"MAIL_BACKEND", "MAIL_FROM", // smtp, or log in development and test
+	"SMTP_HOST", "SMTP_PORT", "SMTP_USERNAME", "SMTP_PASSWORD",
 }
 
 func LoadConfig() (Config, error) {
 	var config Config
 
 	// Set default values
+	viper.SetDefault("APP_ENV", "production")
 	viper.SetDefault("DB_HOST", "localhost")
 	viper.SetDefault("DB_PORT", "5432")
 	viper.SetDefault("DB_USER", "postgres")
 	viper.SetDefault("DB_NAME", "ecommerce")
 	viper.SetDefault("DB_PASSWORD", "1234")
+	viper.SetDefault("TAX_PERCENT", 0)
+	viper.SetDefault("SHIPPING_CHARGE", 0)
+	viper.SetDefault("FREE_SHIPPING_ABOVE", 0)
+	viper.SetDefault("RESERVATION_MINUTES", 15)
+	viper.SetDefault("STORAGE_BACKEND", "local")
+	viper.SetDefault("STORAGE_DIR", "uploads")
+	viper.SetDefault("STORAGE_BASE_URL", "/images")
+	viper.SetDefault("ACCESS_TOKEN_MINUTES", 15)
+	viper.SetDefault("REFRESH_TOKEN_DAYS", 30)
+	viper.SetDefault("PASSWORD_RESET_MINUTES", 15)
+	viper.SetDefault("SMTP_PORT", "587")
 
 	// Try to load from .env file
 	viper.SetConfigFile(".env")
@@ -84,6 +127,12 @@ func LoadConfig() (Config, error) {
 	return config, nil
 }
 
+// IsDevelopment is true for local and test runs, the in-memory stand-ins for
+// outside services are only allowed then
+func (c Config) IsDevelopment() bool {
+	return c.APP_ENV == "development" || c.APP_ENV == "test"
+}
+
 func GetConfig() Config {
 	config, err := LoadConfig()
 	if err != nil {
@@ -2,25 +2,34 @@ package db
 
 import (
 	"fmt"
+	"log"
 
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 
 	config "ecommerce/pkg/config"
-	domain "ecommerce/pkg/domain"
 )
 
-func ConnectDatabase(cfg config.Config) (*gorm.DB, error) {
+// OpenDatabase only opens the connection, it does not touch the schema
+func OpenDatabase(cfg config.Config) (*gorm.DB, error) {
 	psqlInfo := fmt.Sprintf("host=%s user=%s dbname=%s port=%s password=%s", cfg.DBHost, cfg.DBUser, cfg.DBName, cfg.DBPort, cfg.DBPassword)
-	db, dbErr := gorm.Open(postgres.Open(psqlInfo), &gorm.Config{
+	return gorm.Open(postgres.Open(psqlInfo), &gorm.Config{
 		SkipDefaultTransaction: true,
 	})
+}
 
+func ConnectDatabase(cfg config.Config) (*gorm.DB, error) {
+	db, dbErr := OpenDatabase(cfg)
 	if dbErr != nil {
 		return db, dbErr
 	}
-	db.AutoMigrate(
-		&domain.Admin{},
-	)
+
+	applied, err := MigrateUp(db)
+	if err != nil {
+		return db, fmt.Errorf("failed to migrate database: %v", err)
+	}
+	for _, migration := range applied {
+		log.Printf("applied migration %04d_%s", migration.Version, migration.Name)
+	}
 	return db, nil
 }
@@ -0,0 +1,277 @@
+package db
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// Migration is one numbered schema change loaded from the migrations folder.
+// Files are named <version>_<name>.up.sql and <version>_<name>.down.sql
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type schemaMigration struct {
+	Version   int
+	Name      string
+	AppliedAt time.Time
+}
+
+const createMigrationTable = `CREATE TABLE IF NOT EXISTS schema_migrations (
+	version    BIGINT PRIMARY KEY,
+	name       TEXT NOT NULL,
+	applied_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+)`
+
+// migrationLockKey is the pg_advisory_lock key taken while migrations run, so
+// API instances starting together apply them one at a time
+const migrationLockKey = 7320191
+
+// LoadMigrations reads the embedded sql files and returns them sorted by version
+func LoadMigrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		fileName := entry.Name()
+
+		var direction string
+		switch {
+		case strings.HasSuffix(fileName, ".up.sql"):
+			direction = "up"
+		case strings.HasSuffix(fileName, ".down.sql"):
+			direction = "down"
+		default:
+			continue
+		}
+
+		base := strings.TrimSuffix(fileName, "."+direction+".sql")
+		parts := strings.SplitN(base, "_", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file name %s", fileName)
+		}
+		version, err := strconv.Atoi(parts[0])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version in %s: %v", fileName, err)
+		}
+
+		body, err := migrationFiles.ReadFile(path.Join("migrations", fileName))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: parts[1]}
+			byVersion[version] = migration
+		} else if migration.Name != parts[1] {
+			return nil, fmt.Errorf("migration %d has two names: %s and %s", version, migration.Name, parts[1])
+		}
+
+		if direction == "up" {
+			migration.Up = string(body)
+		} else {
+			migration.Down = string(body)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" {
+			return nil, fmt.Errorf("migration %d_%s has no up file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+func appliedMigrations(db *gorm.DB) (map[int]schemaMigration, error) {
+	if err := db.Exec(createMigrationTable).Error; err != nil {
+		return nil, fmt.Errorf("failed to create schema_migrations: %v", err)
+	}
+
+	var rows []schemaMigration
+	if err := db.Raw(`SELECT version, name, applied_at FROM schema_migrations ORDER BY version`).Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+// withMigrationLock runs fn while holding the migration advisory lock. The lock
+// is held by one pooled connection that is kept aside until fn returns, a
+// second caller waits for it and then finds the migrations already applied
+func withMigrationLock(db *gorm.DB, fn func() error) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	ctx := context.Background()
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, `SELECT pg_advisory_lock($1)`, migrationLockKey); err != nil {
+		return fmt.Errorf("failed to take the migration lock: %v", err)
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, migrationLockKey)
+
+	return fn()
+}
+
+// MigrateUp applies every migration that is not yet recorded in schema_migrations.
+// It returns the migrations that were applied by this call
+func MigrateUp(db *gorm.DB) ([]Migration, error) {
+	var done []Migration
+	err := withMigrationLock(db, func() error {
+		var err error
+		done, err = migrateUp(db)
+		return err
+	})
+	return done, err
+}
+
+func migrateUp(db *gorm.DB) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for _, migration := range migrations {
+		if _, ok := applied[migration.Version]; ok {
+			continue
+		}
+
+		tx := db.Begin()
+		if err := tx.Exec(migration.Up).Error; err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("migration %d_%s failed: %v", migration.Version, migration.Name, err)
+		}
+		insert := `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, NOW())`
+		if err := tx.Exec(insert, migration.Version, migration.Name).Error; err != nil {
+			tx.Rollback()
+			return done, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			tx.Rollback()
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the last `steps` applied migrations, newest first
+func MigrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	if steps < 1 {
+		return nil, fmt.Errorf("steps must be at least 1")
+	}
+	var done []Migration
+	err := withMigrationLock(db, func() error {
+		var err error
+		done, err = migrateDown(db, steps)
+		return err
+	})
+	return done, err
+}
+
+func migrateDown(db *gorm.DB, steps int) ([]Migration, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	var done []Migration
+	for i := len(migrations) - 1; i >= 0 && len(done) < steps; i-- {
+		migration := migrations[i]
+		if _, ok := applied[migration.Version]; !ok {
+			continue
+		}
+		if migration.Down == "" {
+			return done, fmt.Errorf("migration %d_%s has no down file", migration.Version, migration.Name)
+		}
+
+		tx := db.Begin()
+		if err := tx.Exec(migration.Down).Error; err != nil {
+			tx.Rollback()
+			return done, fmt.Errorf("reverting %d_%s failed: %v", migration.Version, migration.Name, err)
+		}
+		if err := tx.Exec(`DELETE FROM schema_migrations WHERE version = $1`, migration.Version).Error; err != nil {
+			tx.Rollback()
+			return done, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			tx.Rollback()
+			return done, err
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+// GetMigrationStatus lists every known migration and whether it has been applied
+func GetMigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, migration := range migrations {
+		row, ok := applied[migration.Version]
+		status = append(status, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   ok,
+			AppliedAt: row.AppliedAt,
+		})
+	}
+	return status, nil
+}
@@ -0,0 +1,16 @@
+DROP TABLE IF EXISTS payment_details;
+DROP TABLE IF EXISTS order_lines;
+DROP TABLE IF EXISTS orders;
+DROP TABLE IF EXISTS order_statuses;
+DROP TABLE IF EXISTS payment_statuses;
+DROP TABLE IF EXISTS payment_methods;
+DROP TABLE IF EXISTS coupons;
+DROP TABLE IF EXISTS cart_items;
+DROP TABLE IF EXISTS carts;
+DROP TABLE IF EXISTS wish_lists;
+DROP TABLE IF EXISTS products;
+DROP TABLE IF EXISTS categories;
+DROP TABLE IF EXISTS addresses;
+DROP TABLE IF EXISTS user_statuses;
+DROP TABLE IF EXISTS users;
+DROP TABLE IF EXISTS admins;
@@ -0,0 +1,130 @@
+CREATE TABLE IF NOT EXISTS admins (
+    id         BIGSERIAL PRIMARY KEY,
+    admin_name TEXT NOT NULL,
+    email      TEXT NOT NULL,
+    password   TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS users (
+    id         BIGSERIAL PRIMARY KEY,
+    name       TEXT NOT NULL,
+    email      TEXT NOT NULL UNIQUE,
+    mobile     TEXT NOT NULL UNIQUE,
+    password   TEXT NOT NULL,
+    is_blocked BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_statuses (
+    id                  BIGSERIAL PRIMARY KEY,
+    users_id            BIGINT NOT NULL REFERENCES users (id),
+    blocked_at          TIMESTAMPTZ,
+    blocked_by          BIGINT,
+    reason_for_blocking TEXT
+);
+
+CREATE TABLE IF NOT EXISTS addresses (
+    id           BIGSERIAL PRIMARY KEY,
+    user_id      BIGINT NOT NULL REFERENCES users (id),
+    house_number TEXT,
+    street       TEXT,
+    city         TEXT,
+    district     TEXT,
+    pincode      TEXT,
+    landmark     TEXT
+);
+
+CREATE TABLE IF NOT EXISTS categories (
+    id            BIGSERIAL PRIMARY KEY,
+    category_name TEXT NOT NULL UNIQUE,
+    created_at    TIMESTAMPTZ,
+    updated_at    TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS products (
+    id           BIGSERIAL PRIMARY KEY,
+    product_name TEXT NOT NULL UNIQUE,
+    description  TEXT,
+    brand        TEXT,
+    prize        BIGINT NOT NULL DEFAULT 0,
+    qty_in_stock BIGINT NOT NULL DEFAULT 0,
+    category_id  BIGINT REFERENCES categories (id),
+    created_at   TIMESTAMPTZ,
+    updated_at   TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS wish_lists (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    product_id BIGINT NOT NULL REFERENCES products (id)
+);
+
+CREATE TABLE IF NOT EXISTS carts (
+    id          BIGSERIAL PRIMARY KEY,
+    user_id     BIGINT NOT NULL REFERENCES users (id),
+    is_applied  BOOLEAN NOT NULL DEFAULT FALSE,
+    discount    NUMERIC NOT NULL DEFAULT 0,
+    total_price NUMERIC NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS cart_items (
+    id         BIGSERIAL PRIMARY KEY,
+    cart_id    BIGINT NOT NULL REFERENCES carts (id),
+    product_id BIGINT NOT NULL REFERENCES products (id),
+    qty        BIGINT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS coupons (
+    id                     BIGSERIAL PRIMARY KEY,
+    code                   TEXT,
+    discount_percent       NUMERIC,
+    usage_limits           BIGINT,
+    maximum_discount_price NUMERIC,
+    minimum_purchase_price NUMERIC,
+    expiry_date            TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS payment_methods (
+    id             BIGSERIAL PRIMARY KEY,
+    payment_method TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS payment_statuses (
+    id             BIGSERIAL PRIMARY KEY,
+    payment_status TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS order_statuses (
+    id           BIGSERIAL PRIMARY KEY,
+    order_status TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS orders (
+    id                  BIGSERIAL PRIMARY KEY,
+    user_id             BIGINT NOT NULL REFERENCES users (id),
+    order_date          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    payment_method_id   BIGINT REFERENCES payment_methods (id),
+    shipping_address_id BIGINT REFERENCES addresses (id),
+    discount            NUMERIC NOT NULL DEFAULT 0,
+    order_total         NUMERIC NOT NULL DEFAULT 0,
+    coupon_code         TEXT,
+    order_status_id     BIGINT REFERENCES order_statuses (id),
+    delivery_updated_at TIMESTAMPTZ
+);
+
+CREATE TABLE IF NOT EXISTS order_lines (
+    id         BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products (id),
+    order_id   BIGINT NOT NULL REFERENCES orders (id),
+    qty        BIGINT NOT NULL,
+    price      NUMERIC NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS payment_details (
+    id                BIGSERIAL PRIMARY KEY,
+    orders_id         BIGINT NOT NULL REFERENCES orders (id),
+    order_total       NUMERIC NOT NULL,
+    payment_method_id BIGINT REFERENCES payment_methods (id),
+    payment_status_id BIGINT REFERENCES payment_statuses (id),
+    updated_at        TIMESTAMPTZ
+);
@@ -0,0 +1,3 @@
+DELETE FROM payment_statuses WHERE id IN (1, 2, 3, 4);
+DELETE FROM payment_methods WHERE id IN (1, 2);
+DELETE FROM order_statuses WHERE id IN (1, 2, 3, 4, 5, 6);
@@ -0,0 +1,25 @@
+-- ids are referenced directly by the repositories, keep them stable
+INSERT INTO order_statuses (id, order_status) VALUES
+    (1, 'pending'),
+    (2, 'shipped'),
+    (3, 'delivered'),
+    (4, 'return requested'),
+    (5, 'cancelled'),
+    (6, 'returned')
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO payment_methods (id, payment_method) VALUES
+    (1, 'cash on delivery'),
+    (2, 'razorpay')
+ON CONFLICT (id) DO NOTHING;
+
+INSERT INTO payment_statuses (id, payment_status) VALUES
+    (1, 'pending'),
+    (2, 'paid'),
+    (3, 'failed'),
+    (4, 'refunded')
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('order_statuses', 'id'), (SELECT MAX(id) FROM order_statuses));
+SELECT setval(pg_get_serial_sequence('payment_methods', 'id'), (SELECT MAX(id) FROM payment_methods));
+SELECT setval(pg_get_serial_sequence('payment_statuses', 'id'), (SELECT MAX(id) FROM payment_statuses));
@@ -0,0 +1,4 @@
+DELETE FROM payment_methods WHERE id = 3;
+ALTER TABLE orders DROP COLUMN IF EXISTS wallet_amount;
+DROP TABLE IF EXISTS transactions;
+DROP TABLE IF EXISTS wallets;
@@ -0,0 +1,24 @@
+CREATE TABLE IF NOT EXISTS wallets (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL UNIQUE REFERENCES users (id),
+    balance    NUMERIC NOT NULL DEFAULT 0 CHECK (balance >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS transactions (
+    id               BIGSERIAL PRIMARY KEY,
+    wallet_id        BIGINT NOT NULL REFERENCES wallets (id),
+    order_id         BIGINT REFERENCES orders (id),
+    amount           NUMERIC NOT NULL,
+    transaction_type TEXT NOT NULL,
+    description      TEXT,
+    created_at       TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_wallet_id ON transactions (wallet_id, created_at DESC);
+
+-- part of the order total paid from the wallet
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS wallet_amount NUMERIC NOT NULL DEFAULT 0;
+
+INSERT INTO payment_methods (id, payment_method) VALUES (3, 'wallet') ON CONFLICT (id) DO NOTHING;
+SELECT setval(pg_get_serial_sequence('payment_methods', 'id'), (SELECT MAX(id) FROM payment_methods));
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS razorpay_checkouts;
+
+DROP INDEX IF EXISTS idx_payment_details_razorpay_payment_id;
+ALTER TABLE payment_details DROP COLUMN IF EXISTS razorpay_payment_id;
+ALTER TABLE payment_details DROP COLUMN IF EXISTS razorpay_order_id;
@@ -0,0 +1,26 @@
+ALTER TABLE payment_details ADD COLUMN IF NOT EXISTS razorpay_order_id TEXT;
+ALTER TABLE payment_details ADD COLUMN IF NOT EXISTS razorpay_payment_id TEXT;
+
+-- one razorpay payment can only ever pay for one order
+CREATE UNIQUE INDEX IF NOT EXISTS idx_payment_details_razorpay_payment_id
+    ON payment_details (razorpay_payment_id) WHERE razorpay_payment_id IS NOT NULL;
+
+-- one row per razorpay order, the wallet split is decided when the checkout
+-- starts and read back when the payment comes in. A payment that no order
+-- could be placed for is refunded and the refund is kept here
+CREATE TABLE IF NOT EXISTS razorpay_checkouts (
+    razorpay_order_id   TEXT PRIMARY KEY,
+    user_id             BIGINT NOT NULL REFERENCES users (id),
+    amount              NUMERIC NOT NULL CHECK (amount > 0),
+    wallet_amount       NUMERIC NOT NULL DEFAULT 0 CHECK (wallet_amount >= 0),
+    status              TEXT NOT NULL,
+    razorpay_payment_id TEXT,
+    refund_to           TEXT,
+    gateway_refund_id   TEXT,
+    note                TEXT,
+    created_at          TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_razorpay_checkouts_user_id ON razorpay_checkouts (user_id);
+CREATE INDEX IF NOT EXISTS idx_razorpay_checkouts_status ON razorpay_checkouts (status);
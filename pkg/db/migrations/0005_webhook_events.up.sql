@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS webhook_events (
+    event_id     TEXT PRIMARY KEY,
+    event        TEXT NOT NULL,
+    payload      JSONB NOT NULL,
+    processed_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS refunds (
+    id                 BIGSERIAL PRIMARY KEY,
+    order_id           BIGINT NOT NULL REFERENCES orders (id),
+    user_id            BIGINT NOT NULL REFERENCES users (id),
+    amount             NUMERIC NOT NULL CHECK (amount >= 0),
+    reason             TEXT,
+    refund_to          TEXT NOT NULL,
+    gateway_payment_id TEXT,
+    gateway_refund_id  TEXT,
+    status             TEXT NOT NULL,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refunds_order_id ON refunds (order_id);
+CREATE INDEX IF NOT EXISTS idx_refunds_status ON refunds (status);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refunds_gateway_refund_id
+    ON refunds (gateway_refund_id) WHERE gateway_refund_id IS NOT NULL;
@@ -0,0 +1,14 @@
+UPDATE payment_details SET payment_status_id = 4 WHERE payment_status_id = 5;
+DELETE FROM payment_statuses WHERE id = 5;
+
+ALTER TABLE refunds
+    DROP COLUMN IF EXISTS qty,
+    DROP COLUMN IF EXISTS order_line_id;
+
+ALTER TABLE order_lines DROP CONSTRAINT IF EXISTS order_lines_removed_qty_check;
+
+ALTER TABLE order_lines
+    DROP COLUMN IF EXISTS updated_at,
+    DROP COLUMN IF EXISTS returned_qty,
+    DROP COLUMN IF EXISTS cancelled_qty,
+    DROP COLUMN IF EXISTS status;
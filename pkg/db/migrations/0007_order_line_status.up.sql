@@ -0,0 +1,25 @@
+-- lines are no longer deleted on cancel, they keep what happened to them
+ALTER TABLE order_lines
+    ADD COLUMN IF NOT EXISTS status        TEXT NOT NULL DEFAULT 'placed',
+    ADD COLUMN IF NOT EXISTS cancelled_qty BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS returned_qty  BIGINT NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS updated_at    TIMESTAMPTZ;
+
+ALTER TABLE order_lines
+    ADD CONSTRAINT order_lines_removed_qty_check CHECK (cancelled_qty >= 0 AND returned_qty >= 0 AND cancelled_qty + returned_qty <= qty);
+
+UPDATE order_lines SET status = 'returned', returned_qty = qty
+WHERE order_id IN (SELECT id FROM orders WHERE order_status_id = 6);
+
+UPDATE order_lines SET status = 'return requested'
+WHERE order_id IN (SELECT id FROM orders WHERE order_status_id = 4);
+
+ALTER TABLE refunds
+    ADD COLUMN IF NOT EXISTS order_line_id BIGINT REFERENCES order_lines (id),
+    ADD COLUMN IF NOT EXISTS qty           BIGINT NOT NULL DEFAULT 0;
+
+INSERT INTO payment_statuses (id, payment_status) VALUES
+    (5, 'partially refunded')
+ON CONFLICT (id) DO NOTHING;
+
+SELECT setval(pg_get_serial_sequence('payment_statuses', 'id'), (SELECT MAX(id) FROM payment_statuses));
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS order_status_history (
+    id             BIGSERIAL PRIMARY KEY,
+    order_id       BIGINT NOT NULL REFERENCES orders (id),
+    from_status_id BIGINT REFERENCES order_statuses (id),
+    to_status_id   BIGINT NOT NULL REFERENCES order_statuses (id),
+    changed_by     TEXT NOT NULL,
+    changed_by_id  BIGINT,
+    note           TEXT,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_order_id ON order_status_history (order_id, created_at);
+
+-- orders placed before the history was kept start their timeline at the status they have now
+INSERT INTO order_status_history (order_id, from_status_id, to_status_id, changed_by, note, created_at)
+SELECT id, NULL, order_status_id, 'system', 'status before history was recorded', order_date
+FROM orders
+WHERE order_status_id IS NOT NULL;
@@ -0,0 +1,9 @@
+ALTER TABLE orders
+    DROP COLUMN IF EXISTS shipping,
+    DROP COLUMN IF EXISTS tax;
+
+ALTER TABLE carts
+    DROP COLUMN IF EXISTS coupon_id,
+    DROP COLUMN IF EXISTS shipping,
+    DROP COLUMN IF EXISTS tax,
+    DROP COLUMN IF EXISTS subtotal;
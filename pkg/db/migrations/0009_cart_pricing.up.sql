@@ -0,0 +1,12 @@
+ALTER TABLE carts
+    ADD COLUMN IF NOT EXISTS subtotal  NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS tax       NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS shipping  NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS coupon_id BIGINT REFERENCES coupons (id);
+
+ALTER TABLE orders
+    ADD COLUMN IF NOT EXISTS tax      NUMERIC NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS shipping NUMERIC NOT NULL DEFAULT 0;
+
+-- the old totals were never kept up to date, they are worked out again on the next cart change
+UPDATE carts SET total_price = 0, discount = 0, is_applied = FALSE;
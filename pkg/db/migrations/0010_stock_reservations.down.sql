@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS stock_reservations;
+
+ALTER TABLE products
+    DROP CONSTRAINT IF EXISTS products_qty_in_stock_check;
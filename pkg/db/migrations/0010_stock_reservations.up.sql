@@ -0,0 +1,21 @@
+-- stock was decremented without a check before, clamp anything that went below zero
+UPDATE products SET qty_in_stock = 0 WHERE qty_in_stock < 0;
+
+ALTER TABLE products
+    ADD CONSTRAINT products_qty_in_stock_check CHECK (qty_in_stock >= 0);
+
+CREATE TABLE IF NOT EXISTS stock_reservations (
+    id                BIGSERIAL PRIMARY KEY,
+    user_id           BIGINT NOT NULL REFERENCES users (id),
+    product_id        BIGINT NOT NULL REFERENCES products (id),
+    qty               INTEGER NOT NULL CHECK (qty > 0),
+    razorpay_order_id TEXT NOT NULL,
+    status            TEXT NOT NULL,
+    expires_at        TIMESTAMPTZ NOT NULL,
+    created_at        TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_razorpay_order_id ON stock_reservations (razorpay_order_id);
+CREATE INDEX IF NOT EXISTS idx_stock_reservations_held_expires_at
+    ON stock_reservations (expires_at) WHERE status = 'held';
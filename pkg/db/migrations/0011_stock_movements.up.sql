@@ -0,0 +1,22 @@
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS reorder_threshold INTEGER NOT NULL DEFAULT 0 CHECK (reorder_threshold >= 0);
+
+CREATE TABLE IF NOT EXISTS stock_movements (
+    id         BIGSERIAL PRIMARY KEY,
+    product_id BIGINT NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    qty_change INTEGER NOT NULL CHECK (qty_change <> 0),
+    qty_after  INTEGER NOT NULL CHECK (qty_after >= 0),
+    reason     TEXT NOT NULL,
+    order_id   BIGINT REFERENCES orders (id),
+    admin_id   BIGINT REFERENCES admins (id),
+    note       TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_stock_movements_product_id ON stock_movements (product_id, created_at DESC);
+
+-- the ledger starts from the stock products have now
+INSERT INTO stock_movements (product_id, qty_change, qty_after, reason, note, created_at)
+SELECT id, qty_in_stock, qty_in_stock, 'restock', 'opening balance', NOW()
+FROM products
+WHERE qty_in_stock > 0;
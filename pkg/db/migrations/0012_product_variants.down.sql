@@ -0,0 +1,6 @@
+ALTER TABLE stock_movements DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE stock_reservations DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE order_lines DROP COLUMN IF EXISTS variant_id;
+ALTER TABLE cart_items DROP COLUMN IF EXISTS variant_id;
+
+DROP TABLE IF EXISTS product_variants;
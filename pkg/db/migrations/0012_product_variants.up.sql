@@ -0,0 +1,31 @@
+CREATE TABLE IF NOT EXISTS product_variants (
+    id           BIGSERIAL PRIMARY KEY,
+    product_id   BIGINT NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    sku          TEXT NOT NULL UNIQUE,
+    attributes   JSONB NOT NULL DEFAULT '{}',
+    price        BIGINT NOT NULL CHECK (price >= 0),
+    qty_in_stock BIGINT NOT NULL DEFAULT 0 CHECK (qty_in_stock >= 0),
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_variants_product_id ON product_variants (product_id);
+
+-- every existing product becomes a single variant carrying its price and stock
+INSERT INTO product_variants (product_id, sku, price, qty_in_stock)
+SELECT id, 'P' || id, prize, qty_in_stock FROM products;
+
+ALTER TABLE cart_items ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES product_variants (id);
+UPDATE cart_items ci SET variant_id = v.id FROM product_variants v WHERE v.product_id = ci.product_id;
+ALTER TABLE cart_items ALTER COLUMN variant_id SET NOT NULL;
+
+ALTER TABLE order_lines ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES product_variants (id);
+UPDATE order_lines ol SET variant_id = v.id FROM product_variants v WHERE v.product_id = ol.product_id;
+ALTER TABLE order_lines ALTER COLUMN variant_id SET NOT NULL;
+
+ALTER TABLE stock_reservations ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES product_variants (id) ON DELETE CASCADE;
+UPDATE stock_reservations r SET variant_id = v.id FROM product_variants v WHERE v.product_id = r.product_id;
+ALTER TABLE stock_reservations ALTER COLUMN variant_id SET NOT NULL;
+
+ALTER TABLE stock_movements ADD COLUMN IF NOT EXISTS variant_id BIGINT REFERENCES product_variants (id) ON DELETE SET NULL;
+UPDATE stock_movements m SET variant_id = v.id FROM product_variants v WHERE v.product_id = m.product_id;
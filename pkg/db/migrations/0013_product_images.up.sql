@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS product_images (
+    id            BIGSERIAL PRIMARY KEY,
+    product_id    BIGINT NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    storage_key   TEXT NOT NULL UNIQUE,
+    thumbnail_key TEXT NOT NULL,
+    content_type  TEXT NOT NULL,
+    width         INT NOT NULL,
+    height        INT NOT NULL,
+    position      INT NOT NULL DEFAULT 0,
+    is_primary    BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_images_product_id ON product_images (product_id, position);
+
+-- a product has at most one primary image
+CREATE UNIQUE INDEX IF NOT EXISTS idx_product_images_primary ON product_images (product_id) WHERE is_primary;
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_products_brand_trgm;
+DROP INDEX IF EXISTS idx_products_name_trgm;
+DROP INDEX IF EXISTS idx_products_search_vector;
+ALTER TABLE products DROP COLUMN IF EXISTS search_vector;
+-- pg_trgm is left installed, other objects may have come to use it
@@ -0,0 +1,10 @@
+DROP INDEX IF EXISTS idx_order_lines_product_id;
+DROP INDEX IF EXISTS idx_products_category_id;
+DROP INDEX IF EXISTS idx_products_prize;
+DROP INDEX IF EXISTS idx_products_created_at;
+
+ALTER TABLE products ALTER COLUMN created_at DROP NOT NULL, ALTER COLUMN created_at DROP DEFAULT;
+
+ALTER TABLE products
+    DROP COLUMN IF EXISTS rating_count,
+    DROP COLUMN IF EXISTS rating_avg;
@@ -0,0 +1,13 @@
+-- rating summary of a product's reviews, kept on the product so listings can sort by it
+ALTER TABLE products
+    ADD COLUMN IF NOT EXISTS rating_avg   NUMERIC(3, 2) NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS rating_count BIGINT NOT NULL DEFAULT 0;
+
+-- listings page through products by created_at, it can't be missing
+UPDATE products SET created_at = NOW() WHERE created_at IS NULL;
+ALTER TABLE products ALTER COLUMN created_at SET DEFAULT NOW(), ALTER COLUMN created_at SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_products_created_at ON products (created_at, id);
+CREATE INDEX IF NOT EXISTS idx_products_prize ON products (prize, id);
+CREATE INDEX IF NOT EXISTS idx_products_category_id ON products (category_id);
+CREATE INDEX IF NOT EXISTS idx_order_lines_product_id ON order_lines (product_id);
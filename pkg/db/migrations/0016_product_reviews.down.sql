@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS review_votes;
+DROP TABLE IF EXISTS product_reviews;
+
+UPDATE products SET rating_avg = 0, rating_count = 0;
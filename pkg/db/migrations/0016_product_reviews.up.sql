@@ -0,0 +1,25 @@
+CREATE TABLE IF NOT EXISTS product_reviews (
+    id            BIGSERIAL PRIMARY KEY,
+    product_id    BIGINT NOT NULL REFERENCES products (id) ON DELETE CASCADE,
+    user_id       BIGINT NOT NULL REFERENCES users (id),
+    rating        SMALLINT NOT NULL CHECK (rating BETWEEN 1 AND 5),
+    body          TEXT NOT NULL DEFAULT '',
+    status        TEXT NOT NULL DEFAULT 'published',
+    hidden_reason TEXT,
+    moderated_by  BIGINT,
+    helpful_count BIGINT NOT NULL DEFAULT 0 CHECK (helpful_count >= 0),
+    created_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    UNIQUE (product_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_product_reviews_product_id ON product_reviews (product_id, status, helpful_count DESC);
+CREATE INDEX IF NOT EXISTS idx_product_reviews_status ON product_reviews (status, created_at);
+
+-- one helpful vote per user and review
+CREATE TABLE IF NOT EXISTS review_votes (
+    review_id  BIGINT NOT NULL REFERENCES product_reviews (id) ON DELETE CASCADE,
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (review_id, user_id)
+);
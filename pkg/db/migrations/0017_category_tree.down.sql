@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_categories_sibling_name;
+ALTER TABLE categories ADD CONSTRAINT categories_category_name_key UNIQUE (category_name);
+
+DROP INDEX IF EXISTS idx_categories_parent_id;
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_parent_check;
+ALTER TABLE categories DROP COLUMN IF EXISTS parent_id;
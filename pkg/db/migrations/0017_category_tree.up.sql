@@ -0,0 +1,9 @@
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS parent_id BIGINT REFERENCES categories (id);
+ALTER TABLE categories ADD CONSTRAINT categories_parent_check CHECK (parent_id <> id);
+
+CREATE INDEX IF NOT EXISTS idx_categories_parent_id ON categories (parent_id);
+
+-- names only have to be unique among siblings now, Watches > Accessories and
+-- Phones > Accessories can both exist
+ALTER TABLE categories DROP CONSTRAINT IF EXISTS categories_category_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_sibling_name ON categories (COALESCE(parent_id, 0), LOWER(category_name));
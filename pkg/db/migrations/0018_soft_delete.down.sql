@@ -0,0 +1,15 @@
+DROP INDEX IF EXISTS idx_products_live_name;
+ALTER TABLE products ADD CONSTRAINT products_product_name_key UNIQUE (product_name);
+
+DROP INDEX IF EXISTS idx_categories_sibling_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_sibling_name ON categories (COALESCE(parent_id, 0), LOWER(category_name));
+
+DROP INDEX IF EXISTS idx_users_deleted_at;
+DROP INDEX IF EXISTS idx_coupons_deleted_at;
+DROP INDEX IF EXISTS idx_categories_deleted_at;
+DROP INDEX IF EXISTS idx_products_deleted_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE coupons DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE categories DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE products DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,19 @@
+-- deleted rows stay so past orders, wishlists and reviews can still join them
+ALTER TABLE products ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE categories ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE coupons ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS idx_products_deleted_at ON products (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_categories_deleted_at ON categories (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_coupons_deleted_at ON coupons (deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- a deleted category's name can be used again
+DROP INDEX IF EXISTS idx_categories_sibling_name;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_categories_sibling_name ON categories (COALESCE(parent_id, 0), LOWER(category_name))
+    WHERE deleted_at IS NULL;
+
+-- and so can a deleted product's
+ALTER TABLE products DROP CONSTRAINT IF EXISTS products_product_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_products_live_name ON products (product_name) WHERE deleted_at IS NULL;
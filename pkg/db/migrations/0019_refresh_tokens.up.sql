@@ -0,0 +1,16 @@
+-- refresh tokens are kept as sha256 hashes. Every refresh swaps the token for
+-- a new one in the same family, a used token coming back revokes the family
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         BIGSERIAL PRIMARY KEY,
+    token_hash TEXT NOT NULL UNIQUE,
+    family_id  TEXT NOT NULL,
+    subject_id BIGINT NOT NULL,
+    audience   TEXT NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    revoked_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_subject ON refresh_tokens (audience, subject_id);
@@ -0,0 +1,8 @@
+ALTER TABLE refresh_tokens DROP CONSTRAINT IF EXISTS fk_refresh_tokens_session;
+ALTER TABLE refresh_tokens ADD COLUMN IF NOT EXISTS revoked_at TIMESTAMPTZ;
+
+UPDATE refresh_tokens r SET revoked_at = s.revoked_at
+FROM sessions s
+WHERE s.id = r.family_id AND s.revoked_at IS NOT NULL;
+
+DROP TABLE IF EXISTS sessions;
@@ -0,0 +1,29 @@
+-- a session is one sign in on one device, its id is the refresh token family
+-- and the sid claim of its access tokens. Only the access token with the
+-- session's current jti is accepted, revoking the session signs it out
+CREATE TABLE IF NOT EXISTS sessions (
+    id           TEXT PRIMARY KEY,
+    subject_id   BIGINT NOT NULL,
+    audience     TEXT NOT NULL,
+    access_jti   TEXT NOT NULL DEFAULT '',
+    user_agent   TEXT NOT NULL DEFAULT '',
+    ip_address   TEXT NOT NULL DEFAULT '',
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at   TIMESTAMPTZ NOT NULL,
+    revoked_at   TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sessions_subject ON sessions (audience, subject_id) WHERE revoked_at IS NULL;
+
+-- existing sign ins keep their refresh token, their access tokens have no
+-- session to match and are refreshed on the next request
+INSERT INTO sessions (id, subject_id, audience, created_at, last_seen_at, expires_at, revoked_at)
+SELECT family_id, MIN(subject_id), MIN(audience), MIN(created_at), MAX(created_at), MAX(expires_at), MAX(revoked_at)
+FROM refresh_tokens
+GROUP BY family_id
+ON CONFLICT (id) DO NOTHING;
+
+ALTER TABLE refresh_tokens DROP COLUMN IF EXISTS revoked_at;
+ALTER TABLE refresh_tokens ADD CONSTRAINT fk_refresh_tokens_session
+    FOREIGN KEY (family_id) REFERENCES sessions (id) ON DELETE CASCADE;
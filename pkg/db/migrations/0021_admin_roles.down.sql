@@ -0,0 +1,2 @@
+ALTER TABLE admins DROP CONSTRAINT IF EXISTS chk_admins_role;
+ALTER TABLE admins DROP COLUMN IF EXISTS role;
@@ -0,0 +1,5 @@
+-- admins that signed up before roles existed get the least access, the
+-- first super admin is made with the bootstrap command
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS role TEXT NOT NULL DEFAULT 'support';
+ALTER TABLE admins ADD CONSTRAINT chk_admins_role
+    CHECK (role IN ('super_admin', 'catalog_manager', 'order_manager', 'support'));
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_user_statuses_blocked_at;
+DROP INDEX IF EXISTS idx_user_statuses_active;
+ALTER TABLE user_statuses DROP COLUMN IF EXISTS unblocked_by;
+ALTER TABLE user_statuses DROP COLUMN IF EXISTS unblocked_at;
+ALTER TABLE user_statuses DROP COLUMN IF EXISTS blocked_until;
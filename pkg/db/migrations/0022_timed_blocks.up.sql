@@ -0,0 +1,20 @@
+-- user_statuses keeps every block. A block is active until it is lifted by
+-- an admin or blocked_until passes, NULL blocked_until blocks until lifted
+ALTER TABLE user_statuses ADD COLUMN IF NOT EXISTS blocked_until TIMESTAMPTZ;
+ALTER TABLE user_statuses ADD COLUMN IF NOT EXISTS unblocked_at TIMESTAMPTZ;
+ALTER TABLE user_statuses ADD COLUMN IF NOT EXISTS unblocked_by BIGINT REFERENCES admins (id);
+
+-- unblocking used to blank the rows of users that are no longer blocked
+UPDATE user_statuses s SET unblocked_at = COALESCE(s.blocked_at, NOW())
+FROM users u
+WHERE u.id = s.users_id AND NOT u.is_blocked;
+
+INSERT INTO user_statuses (users_id, blocked_at, reason_for_blocking)
+SELECT u.id, NOW(), ''
+FROM users u
+WHERE u.is_blocked AND NOT EXISTS (
+    SELECT 1 FROM user_statuses s WHERE s.users_id = u.id AND s.unblocked_at IS NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_statuses_active ON user_statuses (users_id) WHERE unblocked_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_user_statuses_blocked_at ON user_statuses (blocked_at DESC);
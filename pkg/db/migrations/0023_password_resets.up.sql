@@ -0,0 +1,13 @@
+-- reset tokens are kept as sha256 hashes, a token works once and only until
+-- it expires. Asking for a new one drops the user's unused ones
+CREATE TABLE IF NOT EXISTS password_resets (
+    id         BIGSERIAL PRIMARY KEY,
+    user_id    BIGINT NOT NULL REFERENCES users (id),
+    token_hash TEXT NOT NULL UNIQUE,
+    channel    TEXT NOT NULL CHECK (channel IN ('email', 'sms')),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_password_resets_user_id ON password_resets (user_id);
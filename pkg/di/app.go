@@ -0,0 +1,13 @@
+package di
+
+import (
+	http "ecommerce/pkg/api"
+	"ecommerce/pkg/usecase"
+)
+
+// App is what cmd/api runs, the HTTP server and the background sweeps. The
+// sweeps are started by main, not while the app is built
+type App struct {
+	Server  *http.ServerHTTP
+	Sweeper *usecase.Sweeper
+}
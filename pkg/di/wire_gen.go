@@ -11,37 +11,76 @@ import (
 	"ecommerce/pkg/api/handler"
 	"ecommerce/pkg/config"
 	"ecommerce/pkg/db"
+	"ecommerce/pkg/mail"
+	"ecommerce/pkg/payment"
+	"ecommerce/pkg/pricing"
 	"ecommerce/pkg/repository"
+	"ecommerce/pkg/storage"
+	"ecommerce/pkg/token"
 	"ecommerce/pkg/usecase"
+	"time"
 )
 
 // Injectors from wire.go:
 
-func InitializeAPI(cfg config.Config) (*http.ServerHTTP, error) {
+func InitializeAPI(cfg config.Config) (*App, error) {
 	gormDB, err := db.ConnectDatabase(cfg)
 	if err != nil {
 		return nil, err
 	}
+	imageStorage, err := storage.NewStorage(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tokenManager, err := token.NewManager(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tokenRepo := repository.NewTokenRepository(gormDB)
+	tokenUseCase := usecase.NewTokenUseCase(tokenRepo, tokenManager)
+	authHandler := handler.NewAuthHandler(tokenUseCase)
 	userRepository := repository.NewUserRepository(gormDB)
-	userUseCase := usecase.NewUserUseCase(userRepository)
+	userUseCase := usecase.NewUserUseCase(userRepository, imageStorage, tokenUseCase)
 	userHandler := handler.NewUserHandler(userUseCase)
 	otpUseCase := usecase.NewOtpUseCase(cfg)
 	otpHandler := handler.NewOtpHandler(cfg, otpUseCase, userUseCase)
+	mailer, err := mail.NewMailer(cfg)
+	if err != nil {
+		return nil, err
+	}
+	passwordUseCase := usecase.NewPasswordUseCase(userRepository, otpUseCase, mailer, tokenUseCase, time.Duration(cfg.PASSWORD_RESET_MINUTES)*time.Minute)
+	passwordHandler := handler.NewPasswordHandler(passwordUseCase)
 	adminRepository := repository.NewAdminRepository(gormDB)
-	adminUsecase := usecase.NewAdminUseCase(adminRepository)
+	adminUsecase := usecase.NewAdminUseCase(adminRepository, tokenUseCase)
 	adminHandler := handler.NewAdminHandler(adminUsecase)
 	productRepo := repository.NewproductRepository(gormDB)
-	productUsecase := usecase.NewProductUsecase(productRepo)
+	inventoryRepo := repository.NewInventoryRepository(gormDB, time.Duration(cfg.RESERVATION_MINUTES)*time.Minute)
+	productUsecase := usecase.NewProductUsecase(productRepo, inventoryRepo, imageStorage)
 	productHandler := handler.NewproductHandler(productUsecase)
-	cartRepo := repository.NewecartRepository(gormDB)
-	cartUsecase := usecase.NewCartUsecase(cartRepo)
+	pricer := pricing.NewPricer(cfg)
+	cartRepo := repository.NewecartRepository(gormDB, pricer)
+	cartUsecase := usecase.NewCartUsecase(cartRepo, imageStorage)
 	cartHandler := handler.NewCartHandler(cartUsecase)
-	couponRepo := repository.NewCouponrepo(gormDB)
+	couponRepo := repository.NewCouponrepo(gormDB, pricer)
 	couponUseCase := usecase.NewCouponUseCase(couponRepo)
 	couponHandler := handler.NewCouponHandler(couponUseCase)
-	orderRepo := repository.NewOrderRepository(gormDB)
-	orderusecase := usecase.NewOrderUseCase(orderRepo, cartRepo)
+	orderRepo := repository.NewOrderRepository(gormDB, pricer)
+	walletRepo := repository.NewWalletRepository(gormDB)
+	refundRepo := repository.NewRefundRepository(gormDB)
+	paymentGateway, err := payment.NewPaymentGateway(cfg)
+	if err != nil {
+		return nil, err
+	}
+	orderusecase := usecase.NewOrderUseCase(orderRepo, cartRepo, walletRepo, refundRepo, inventoryRepo, paymentGateway)
 	orderHandler := handler.NewOrderHandler(orderusecase)
-	serverHTTP := http.NewServerHTTP(userHandler, otpHandler, adminHandler, productHandler, cartHandler, couponHandler, orderHandler)
-	return serverHTTP, nil
+	reviewRepo := repository.NewReviewRepository(gormDB)
+	reviewUseCase := usecase.NewReviewUseCase(reviewRepo)
+	reviewHandler := handler.NewReviewHandler(reviewUseCase)
+	serverHTTP := http.NewServerHTTP(userHandler, otpHandler, adminHandler, productHandler, cartHandler, couponHandler, orderHandler, reviewHandler, authHandler, passwordHandler, tokenUseCase, userUseCase, adminUsecase)
+	sweeper := usecase.NewSweeper(inventoryRepo, adminRepository)
+	app := &App{
+		Server:  serverHTTP,
+		Sweeper: sweeper,
+	}
+	return app, nil
 }
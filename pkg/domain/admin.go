@@ -5,4 +5,49 @@ type Admin struct {
 	AdminName string `json:"admin_name" gorm:"not null" binding:"omitempty,min=4,max=12"`
 	Email     string `json:"email" gorm:"not null" binding:"omitempty,email"`
 	Password  string `json:"password" gorm:"not null" binding:"required,min=8,max=15"`
+	Role      string `json:"role" gorm:"not null"`
+}
+
+// admin roles, a super admin can do everything including managing admins
+const (
+	AdminRoleSuperAdmin     = "super_admin"
+	AdminRoleCatalogManager = "catalog_manager"
+	AdminRoleOrderManager   = "order_manager"
+	AdminRoleSupport        = "support"
+)
+
+// what an admin route needs the admin's role to allow
+const (
+	PermissionAdmins  = "admins"
+	PermissionUsers   = "users"
+	PermissionCatalog = "catalog"
+	PermissionCoupons = "coupons"
+	PermissionOrders  = "orders"
+	PermissionReviews = "reviews"
+	PermissionReports = "reports"
+)
+
+// adminRolePermissions lists what each role other than super admin may do
+var adminRolePermissions = map[string][]string{
+	AdminRoleCatalogManager: {PermissionCatalog, PermissionCoupons, PermissionReviews},
+	AdminRoleOrderManager:   {PermissionOrders, PermissionReports},
+	AdminRoleSupport:        {PermissionUsers, PermissionOrders, PermissionReviews},
+}
+
+func IsAdminRole(role string) bool {
+	_, ok := adminRolePermissions[role]
+	return ok || role == AdminRoleSuperAdmin
+}
+
+// AdminCan tells whether an admin with the role has the permission
+func AdminCan(role, permission string) bool {
+	if role == AdminRoleSuperAdmin {
+		return true
+	}
+	for _, allowed := range adminRolePermissions[role] {
+		if allowed == permission {
+			return true
+		}
+	}
+	return false
 }
@@ -5,7 +5,11 @@ type Cart struct {
 	User_id     uint
 	Users       Users `gorm:"foreignKey:User_id"`
 	Is_applied  bool
+	CouponID    uint    `json:"coupon_id,omitempty"`
+	Subtotal    float64 `json:"subtotal" gorm:"not null"`
 	Discount    float64 `json:"discount" gorm:"not null"`
+	Tax         float64 `json:"tax" gorm:"not null"`
+	Shipping    float64 `json:"shipping" gorm:"not null"`
 	Total_price float64 `json:"total_price" gorm:"not null"`
 }
 
@@ -15,5 +19,6 @@ type CartItem struct {
 	Cart      Cart
 	ProductId uint    `json:"product_id" gorm:"not null"`
 	Product   Product `json:"-"`
+	VariantID uint    `json:"variant_id" gorm:"not null"`
 	Qty       uint    `json:"qty" gorm:"not null"`
 }
@@ -10,4 +10,5 @@ type Coupon struct {
 	MaximumDiscountPrice float64
 	MinimumPurchasePrice float64
 	ExpiryDate           time.Time
+	DeletedAt            *time.Time `json:",omitempty"`
 }
@@ -0,0 +1,49 @@
+package domain
+
+import "time"
+
+// StockReservation holds stock for a razorpay checkout until the payment comes
+// back or the reservation expires. The held qty is already taken off the product
+type StockReservation struct {
+	ID              uint      `json:"id" gorm:"primaryKey"`
+	UserID          uint      `json:"user_id" gorm:"not null"`
+	ProductID       uint      `json:"product_id" gorm:"not null"`
+	VariantID       uint      `json:"variant_id" gorm:"not null"`
+	Qty             int       `json:"qty" gorm:"not null"`
+	RazorpayOrderID string    `json:"razorpay_order_id" gorm:"not null"`
+	Status          string    `json:"status" gorm:"not null"`
+	ExpiresAt       time.Time `json:"expires_at"`
+	CreatedAt       time.Time `json:"created_at"`
+	UpdatedAt       time.Time `json:"updated_at"`
+}
+
+const (
+	ReservationHeld     = "held"
+	ReservationConsumed = "consumed"
+	ReservationReleased = "released"
+)
+
+// StockMovement is one change to a variant's stock, QtyAfter is the variant's
+// stock right after it. AdminID is set when an admin made the change
+type StockMovement struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	ProductID uint      `json:"product_id" gorm:"not null"`
+	VariantID uint      `json:"variant_id,omitempty"`
+	QtyChange int       `json:"qty_change" gorm:"not null"`
+	QtyAfter  int       `json:"qty_after" gorm:"not null"`
+	Reason    string    `json:"reason" gorm:"not null"`
+	OrderID   uint      `json:"order_id,omitempty"`
+	AdminID   uint      `json:"admin_id,omitempty"`
+	Note      string    `json:"note,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+const (
+	StockSale       = "sale"
+	StockCancel     = "cancel"
+	StockReturn     = "return"
+	StockAdjustment = "adjustment"
+	StockRestock    = "restock"
+	StockReserve    = "reserve"
+	StockRelease    = "release"
+)
@@ -22,23 +22,45 @@ type Orders struct {
 	ShippingAddressID uint          `json:"shipping_address_id"`
 	Address           Address       `gorm:"foreignKey:ShippingAddressID" json:"-"`
 	Discount          float64       `json:"discount"`
+	Tax               float64       `json:"tax"`
+	Shipping          float64       `json:"shipping"`
 	OrderTotal        float64       `json:"order_total"`
 	CouponCode        string        `json:"coupon_code"`
+	WalletAmount      float64       `json:"wallet_amount"`
 	OrderStatusID     uint          `json:"order_status_id"`
 	OrderStatus       OrderStatus   `gorm:"foreignKey:OrderStatusID" json:"-"`
 	DeliveryUpdatedAt time.Time     `json:"delivery_time"`
 }
 
 type OrderLine struct {
-	ID        uint    `gorm:"primaryKey"`
-	ProductID uint    `json:"product_id"`
-	Product   Product ` json:"-"`
-	OrderID   uint    `json:"order_Id"`
-	Order     Orders
-	Qty       int     `json:"qty"`
-	Price     float64 `json:"price"`
+	ID           uint    `gorm:"primaryKey"`
+	ProductID    uint    `json:"product_id"`
+	Product      Product ` json:"-"`
+	VariantID    uint    `json:"variant_id"`
+	OrderID      uint    `json:"order_Id"`
+	Order        Orders
+	Qty          int       `json:"qty"`
+	Price        float64   `json:"price"`
+	Status       string    `json:"status"`
+	CancelledQty int       `json:"cancelled_qty"`
+	ReturnedQty  int       `json:"returned_qty"`
+	UpdatedAt    time.Time `json:"updated_at"`
 }
 
+// ActiveQty is what is still ordered after cancellations and returns
+func (l OrderLine) ActiveQty() int {
+	return l.Qty - l.CancelledQty - l.ReturnedQty
+}
+
+const (
+	OrderLinePlaced             = "placed"
+	OrderLinePartiallyCancelled = "partially cancelled"
+	OrderLineCancelled          = "cancelled"
+	OrderLineReturnRequested    = "return requested"
+	OrderLinePartiallyReturned  = "partially returned"
+	OrderLineReturned           = "returned"
+)
+
 type OrderStatus struct {
 	ID          uint `gorm:"primaryKey"`
 	OrderStatus string
@@ -50,13 +72,43 @@ type DeliveryStatus struct {
 }
 
 type PaymentDetails struct {
-	ID              uint          `gorm:"primaryKey" json:"id,omitempty"`
-	OrdersID        uint          `json:"order_id,omitempty"`
-	Orders          Orders        `gorm:"foreignKey:OrdersID" json:"-"`
-	OrderTotal      float64       `json:"order_total"`
-	PaymentMethodID uint          `json:"payment_method_id"`
-	PaymentMethod   PaymentMethod `gorm:"foreignKey:PaymentMethodID"`
-	PaymentStatusID uint          `json:"payment_status_id,omitempty"`
-	PaymentStatus   PaymentStatus `gorm:"foreignKey:PaymentStatusID" json:"-"`
-	UpdatedAt       time.Time
+	ID                uint          `gorm:"primaryKey" json:"id,omitempty"`
+	OrdersID          uint          `json:"order_id,omitempty"`
+	Orders            Orders        `gorm:"foreignKey:OrdersID" json:"-"`
+	OrderTotal        float64       `json:"order_total"`
+	PaymentMethodID   uint          `json:"payment_method_id"`
+	PaymentMethod     PaymentMethod `gorm:"foreignKey:PaymentMethodID"`
+	PaymentStatusID   uint          `json:"payment_status_id,omitempty"`
+	PaymentStatus     PaymentStatus `gorm:"foreignKey:PaymentStatusID" json:"-"`
+	RazorpayOrderID   string        `json:"razorpay_order_id,omitempty"`
+	RazorpayPaymentID string        `json:"razorpay_payment_id,omitempty"`
+	UpdatedAt         time.Time
+}
+
+// RazorpayCheckout is a razorpay order the user was sent to pay. Amount is what
+// razorpay collects and WalletAmount what the wallet pays on top of it
+type RazorpayCheckout struct {
+	RazorpayOrderID   string    `json:"razorpay_order_id" gorm:"primaryKey"`
+	UserID            uint      `json:"user_id"`
+	Amount            float64   `json:"amount"`
+	WalletAmount      float64   `json:"wallet_amount"`
+	Status            string    `json:"status"`
+	RazorpayPaymentID string    `json:"razorpay_payment_id,omitempty"`
+	RefundTo          string    `json:"refund_to,omitempty"`
+	GatewayRefundID   string    `json:"gateway_refund_id,omitempty"`
+	Note              string    `json:"note,omitempty"`
+	CreatedAt         time.Time `json:"created_at"`
+	UpdatedAt         time.Time `json:"updated_at"`
 }
+
+const (
+	CheckoutPending = "pending"
+	CheckoutPaid    = "paid"
+	// CheckoutFailed is a checkout whose last payment attempt failed, razorpay
+	// lets the user try again on the same order so it can still be paid
+	CheckoutFailed = "failed"
+	// CheckoutRefunding is set while the payment of a checkout that could not
+	// become an order is being given back, so only one caller refunds it
+	CheckoutRefunding = "refunding"
+	CheckoutRefunded  = "refunded"
+)
@@ -0,0 +1,74 @@
+package domain
+
+import "time"
+
+// order_statuses ids, they are seeded by the migrations and never change
+const (
+	OrderStatusPending         uint = 1
+	OrderStatusShipped         uint = 2
+	OrderStatusDelivered       uint = 3
+	OrderStatusReturnRequested uint = 4
+	OrderStatusCancelled       uint = 5
+	OrderStatusReturned        uint = 6
+)
+
+var orderStatusNames = map[uint]string{
+	OrderStatusPending:         "pending",
+	OrderStatusShipped:         "shipped",
+	OrderStatusDelivered:       "delivered",
+	OrderStatusReturnRequested: "return requested",
+	OrderStatusCancelled:       "cancelled",
+	OrderStatusReturned:        "returned",
+}
+
+// orderTransitions lists where an order can go from each status, cancelled
+// and returned orders are final. A delivered order goes straight to returned
+// when its last item is returned on its own
+var orderTransitions = map[uint][]uint{
+	OrderStatusPending:         {OrderStatusShipped, OrderStatusCancelled},
+	OrderStatusShipped:         {OrderStatusDelivered, OrderStatusCancelled},
+	OrderStatusDelivered:       {OrderStatusReturnRequested, OrderStatusReturned},
+	OrderStatusReturnRequested: {OrderStatusReturned, OrderStatusDelivered},
+}
+
+func OrderStatusName(statusId uint) string {
+	if name, ok := orderStatusNames[statusId]; ok {
+		return name
+	}
+	return "unknown"
+}
+
+// CanChangeOrderStatus tells whether the state machine allows an order to move from one status to another
+func CanChangeOrderStatus(from, to uint) bool {
+	for _, next := range orderTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// who changed an order's status
+const (
+	ChangedByUser   = "user"
+	ChangedByAdmin  = "admin"
+	ChangedBySystem = "system"
+)
+
+// Actor is the user or admin that made a change
+type Actor struct {
+	Type string
+	ID   uint
+}
+
+type OrderStatusHistory struct {
+	ID           uint      `json:"id" gorm:"primaryKey"`
+	OrderID      uint      `json:"order_id" gorm:"not null"`
+	Order        Orders    `gorm:"foreignKey:OrderID" json:"-"`
+	FromStatusID uint      `json:"from_status_id,omitempty"`
+	ToStatusID   uint      `json:"to_status_id" gorm:"not null"`
+	ChangedBy    string    `json:"changed_by" gorm:"not null"`
+	ChangedByID  uint      `json:"changed_by_id,omitempty"`
+	Note         string    `json:"note"`
+	CreatedAt    time.Time `json:"created_at"`
+}
@@ -0,0 +1,39 @@
+package domain
+
+import "testing"
+
+func TestCanChangeOrderStatus(t *testing.T) {
+	tests := []struct {
+		from, to uint
+		want     bool
+	}{
+		{OrderStatusPending, OrderStatusShipped, true},
+		{OrderStatusPending, OrderStatusCancelled, true},
+		{OrderStatusPending, OrderStatusDelivered, false},
+		{OrderStatusShipped, OrderStatusDelivered, true},
+		{OrderStatusShipped, OrderStatusPending, false},
+		{OrderStatusDelivered, OrderStatusReturnRequested, true},
+		{OrderStatusDelivered, OrderStatusReturned, true},
+		{OrderStatusDelivered, OrderStatusCancelled, false},
+		{OrderStatusReturnRequested, OrderStatusReturned, true},
+		{OrderStatusReturnRequested, OrderStatusDelivered, true},
+		{OrderStatusCancelled, OrderStatusPending, false},
+		{OrderStatusReturned, OrderStatusDelivered, false},
+		{OrderStatusPending, OrderStatusPending, false},
+		{OrderStatusPending, 9, false},
+	}
+	for _, tt := range tests {
+		if got := CanChangeOrderStatus(tt.from, tt.to); got != tt.want {
+			t.Errorf("%s to %s: got %v, want %v", OrderStatusName(tt.from), OrderStatusName(tt.to), got, tt.want)
+		}
+	}
+}
+
+func TestOrderStatusName(t *testing.T) {
+	if name := OrderStatusName(OrderStatusReturnRequested); name != "return requested" {
+		t.Fatalf("got %q", name)
+	}
+	if name := OrderStatusName(42); name != "unknown" {
+		t.Fatalf("expected an unknown status, got %q", name)
+	}
+}
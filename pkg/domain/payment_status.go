@@ -0,0 +1,11 @@
+package domain
+
+// payment_statuses ids, they are seeded by the migrations and never change
+const (
+	PaymentStatusPending  uint = 1
+	PaymentStatusPaid     uint = 2
+	PaymentStatusFailed   uint = 3
+	PaymentStatusRefunded uint = 4
+	// set when only some of the items or some of the amount were refunded
+	PaymentStatusPartiallyRefunded uint = 5
+)
@@ -1,12 +1,19 @@
 package domain
 
-import "time"
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"time"
+)
 
 type Category struct {
 	Id           uint   `gorm:"primaryKey;unique;not null"`
-	CategoryName string `gorm:"unique;not null"`
+	CategoryName string `gorm:"not null"`
+	ParentID     *uint
 	Created_at   time.Time
 	Updated_at   time.Time
+	Deleted_at   *time.Time
 }
 
 type Product struct {
@@ -20,4 +27,64 @@ type Product struct {
 	Category     Category `gorm:"foreignKey:Category_id"`
 	Created_at   time.Time
 	Updated_at   time.Time
+	Deleted_at   *time.Time
+}
+
+// ProductVariant is one sellable version of a product, like a strap color and
+// size. Price and stock live on the variant, the product keeps the lowest
+// price and the total stock of its variants for listings
+type ProductVariant struct {
+	ID         uint              `json:"variant_id" gorm:"primaryKey"`
+	ProductID  uint              `json:"product_id" gorm:"not null"`
+	Product    Product           `gorm:"foreignKey:ProductID" json:"-"`
+	SKU        string            `json:"sku" gorm:"column:sku;unique;not null"`
+	Attributes VariantAttributes `json:"attributes" gorm:"type:jsonb"`
+	Price      int               `json:"price" gorm:"not null"`
+	QtyInStock int               `json:"qty_in_stock" gorm:"not null"`
+	CreatedAt  time.Time         `json:"created_at"`
+	UpdatedAt  time.Time         `json:"updated_at"`
+}
+
+// ProductImage is an uploaded picture of a product and its thumbnail, both kept
+// in storage under their keys. Images are shown in position order and a
+// product has at most one primary image, used in listings, carts and wishlists
+type ProductImage struct {
+	ID           uint      `json:"image_id" gorm:"primaryKey"`
+	ProductID    uint      `json:"product_id" gorm:"not null"`
+	Product      Product   `gorm:"foreignKey:ProductID" json:"-"`
+	StorageKey   string    `json:"-" gorm:"unique;not null"`
+	ThumbnailKey string    `json:"-" gorm:"not null"`
+	ContentType  string    `json:"content_type" gorm:"not null"`
+	Width        int       `json:"width" gorm:"not null"`
+	Height       int       `json:"height" gorm:"not null"`
+	Position     int       `json:"position" gorm:"not null"`
+	IsPrimary    bool      `json:"is_primary" gorm:"not null"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// VariantAttributes are what tells variants apart, like color=black size=42mm
+type VariantAttributes map[string]string
+
+func (a VariantAttributes) Value() (driver.Value, error) {
+	if a == nil {
+		return "{}", nil
+	}
+	body, err := json.Marshal(a)
+	return string(body), err
+}
+
+func (a *VariantAttributes) Scan(value interface{}) error {
+	var body []byte
+	switch v := value.(type) {
+	case nil:
+		*a = VariantAttributes{}
+		return nil
+	case []byte:
+		body = v
+	case string:
+		body = []byte(v)
+	default:
+		return fmt.Errorf("can't scan %T into variant attributes", value)
+	}
+	return json.Unmarshal(body, a)
 }
@@ -0,0 +1,34 @@
+package domain
+
+import "testing"
+
+func TestVariantAttributesRoundTrip(t *testing.T) {
+	value, err := VariantAttributes{"color": "black", "size": "42mm"}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var scanned VariantAttributes
+	if err = scanned.Scan([]byte(value.(string))); err != nil {
+		t.Fatal(err)
+	}
+	if len(scanned) != 2 || scanned["color"] != "black" || scanned["size"] != "42mm" {
+		t.Fatalf("attributes changed on the way through the database: %v", scanned)
+	}
+}
+
+func TestVariantAttributesScanNull(t *testing.T) {
+	var attributes VariantAttributes
+	if err := attributes.Scan(nil); err != nil {
+		t.Fatal(err)
+	}
+	if attributes == nil || len(attributes) != 0 {
+		t.Fatalf("expected empty attributes, got %v", attributes)
+	}
+	if value, _ := VariantAttributes(nil).Value(); value != "{}" {
+		t.Fatalf("expected nil attributes to be saved as {}, got %v", value)
+	}
+	if err := attributes.Scan(42); err == nil {
+		t.Fatal("expected a number to be refused")
+	}
+}
@@ -0,0 +1,35 @@
+package domain
+
+import "time"
+
+type Refund struct {
+	ID               uint      `json:"refund_id" gorm:"primaryKey"`
+	OrderID          uint      `json:"order_id" gorm:"not null"`
+	Order            Orders    `gorm:"foreignKey:OrderID" json:"-"`
+	UserID           uint      `json:"user_id" gorm:"not null"`
+	OrderLineID      uint      `json:"order_line_id,omitempty"`
+	Qty              int       `json:"qty,omitempty"`
+	Amount           float64   `json:"amount" gorm:"not null"`
+	Reason           string    `json:"reason"`
+	RefundTo         string    `json:"refund_to" gorm:"not null"`
+	GatewayPaymentID string    `json:"gateway_payment_id,omitempty"`
+	GatewayRefundID  string    `json:"gateway_refund_id,omitempty"`
+	Status           string    `json:"status" gorm:"not null"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// where the money goes back to
+const (
+	RefundToWallet = "wallet"
+	RefundToSource = "source"
+)
+
+// pending refunds wait for an admin, approved ones wait for the payment gateway
+const (
+	RefundPending   = "pending"
+	RefundApproved  = "approved"
+	RefundProcessed = "processed"
+	RefundDenied    = "denied"
+	RefundFailed    = "failed"
+)
@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// Review is a verified buyer's rating of a product. Hidden reviews are taken
+// out of listings and of the product's rating by an admin
+type Review struct {
+	ID           uint      `json:"review_id" gorm:"primaryKey"`
+	ProductID    uint      `json:"product_id" gorm:"not null"`
+	Product      Product   `gorm:"foreignKey:ProductID" json:"-"`
+	UserID       uint      `json:"user_id" gorm:"not null"`
+	Users        Users     `gorm:"foreignKey:UserID" json:"-"`
+	Rating       int       `json:"rating" gorm:"not null"`
+	Body         string    `json:"review"`
+	Status       string    `json:"status" gorm:"not null"`
+	HiddenReason string    `json:"hidden_reason,omitempty"`
+	ModeratedBy  uint      `json:"moderated_by,omitempty"`
+	HelpfulCount int       `json:"helpful_count"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+const (
+	ReviewPublished = "published"
+	ReviewHidden    = "hidden"
+)
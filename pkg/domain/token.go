@@ -0,0 +1,33 @@
+package domain
+
+import "time"
+
+// Session is one sign in of a user or admin on one device. Its id is the
+// family of its refresh tokens and the sid claim of its access tokens, only
+// the access token with AccessJTI is accepted
+type Session struct {
+	ID         string `gorm:"primaryKey"`
+	SubjectID  uint   `gorm:"not null"`
+	Audience   string `gorm:"not null"`
+	AccessJTI  string `gorm:"column:access_jti"`
+	UserAgent  string
+	IPAddress  string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+// RefreshToken is a server side record of a refresh token, only its hash is
+// kept. Tokens of one session share its id as family, each refresh uses up
+// the token and adds the next one to the family
+type RefreshToken struct {
+	ID        uint   `gorm:"primaryKey"`
+	TokenHash string `gorm:"unique;not null"`
+	FamilyID  string `gorm:"not null"`
+	SubjectID uint   `gorm:"not null"`
+	Audience  string `gorm:"not null"`
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
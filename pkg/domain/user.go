@@ -1,6 +1,9 @@
 package domain
 
-import "time"
+import (
+	"errors"
+	"time"
+)
 
 type Users struct {
 	ID        uint   `gorm:"primaryKey;unique;not null"`
@@ -10,8 +13,11 @@ type Users struct {
 	Password  string `json:"password" binding:"required,min=8" gorm:"not null"`
 	IsBlocked bool   `gorm:"default:false"`
 	CreatedAt time.Time
+	DeletedAt *time.Time
 }
 
+// UserStatus is one block of a user. It is active until UnblockedAt is set
+// by an admin or BlockedUntil passes, a nil BlockedUntil lasts until lifted
 type UserStatus struct {
 	ID                uint `gorm:"primaryKey"`
 	UsersID           uint
@@ -19,7 +25,32 @@ type UserStatus struct {
 	BlockedAt         time.Time
 	BlockedBy         uint
 	ReasonForBlocking string
+	BlockedUntil      *time.Time
+	UnblockedAt       *time.Time
+	UnblockedBy       uint
 }
+
+// PasswordReset is a single use token to set a new password without the old
+// one, only its hash is kept
+type PasswordReset struct {
+	ID        uint `gorm:"primaryKey"`
+	UserID    uint `gorm:"not null"`
+	TokenHash string
+	Channel   string
+	ExpiresAt time.Time
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+// how a password reset token reaches the user
+const (
+	ResetByEmail = "email"
+	ResetBySMS   = "sms"
+)
+
+// ErrUserBlocked is returned when a blocked user signs in or makes a request
+var ErrUserBlocked = errors.New("user is blocked")
+
 type Address struct {
 	ID          uint   `json:"id"`
 	UserID      uint   `json:"user_id"`
@@ -0,0 +1,27 @@
+package domain
+
+import "time"
+
+type Wallet struct {
+	ID        uint      `json:"wallet_id" gorm:"primaryKey"`
+	UserID    uint      `json:"user_id" gorm:"unique;not null"`
+	Users     Users     `gorm:"foreignKey:UserID" json:"-"`
+	Balance   float64   `json:"balance" gorm:"not null"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type Transaction struct {
+	ID              uint      `json:"transaction_id" gorm:"primaryKey"`
+	WalletID        uint      `json:"wallet_id" gorm:"not null"`
+	Wallet          Wallet    `gorm:"foreignKey:WalletID" json:"-"`
+	OrderID         uint      `json:"order_id,omitempty"`
+	Amount          float64   `json:"amount" gorm:"not null"`
+	TransactionType string    `json:"transaction_type" gorm:"not null"`
+	Description     string    `json:"description"`
+	CreatedAt       time.Time `json:"created_at"`
+}
+
+const (
+	TransactionCredit = "credit"
+	TransactionDebit  = "debit"
+)
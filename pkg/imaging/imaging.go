@@ -0,0 +1,74 @@
+package imaging
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	_ "image/gif"
+	"image/jpeg"
+	_ "image/png"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// ContentTypes are the uploads we accept, by the format name image.Decode reports
+var ContentTypes = map[string]string{
+	"jpeg": "image/jpeg",
+	"png":  "image/png",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+// Extensions are the file extensions the formats are stored with
+var Extensions = map[string]string{
+	"jpeg": ".jpg",
+	"png":  ".png",
+	"gif":  ".gif",
+	"webp": ".webp",
+}
+
+// MaxPixels keeps a small file that claims a huge size from being decoded
+const MaxPixels = 40_000_000
+
+// Decode reads an uploaded image and tells which of the accepted formats it is
+func Decode(body []byte) (image.Image, string, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", errors.New("file is not a jpeg, png, gif or webp image")
+	}
+	if config.Width*config.Height > MaxPixels {
+		return nil, "", errors.New("image is too large")
+	}
+	img, format, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return nil, "", errors.New("file is not a jpeg, png, gif or webp image")
+	}
+	return img, format, nil
+}
+
+// Thumbnail scales img down to fit inside size x size keeping its aspect ratio
+// and encodes it as a jpeg. Smaller images keep their size, transparent parts
+// turn white
+func Thumbnail(img image.Image, size int) ([]byte, error) {
+	bounds := img.Bounds()
+	width, height := bounds.Dx(), bounds.Dy()
+	if width > size || height > size {
+		if width >= height {
+			width, height = size, max(1, height*size/bounds.Dx())
+		} else {
+			width, height = max(1, width*size/bounds.Dy()), size
+		}
+	}
+
+	thumb := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.Draw(thumb, thumb.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(thumb, thumb.Bounds(), img, bounds, draw.Over, nil)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,17 @@
+package mail
+
+import "log"
+
+// LogMailer writes emails to the server log instead of sending them, for
+// development without a mail server. Anyone reading the log can use the
+// links and codes in them, so NewMailer only picks it in development and test
+type LogMailer struct{}
+
+func NewLogMailer() *LogMailer {
+	return &LogMailer{}
+}
+
+func (m *LogMailer) Send(to, subject, body string) error {
+	log.Printf("[mail] to %s: %s\n%s", to, subject, body)
+	return nil
+}
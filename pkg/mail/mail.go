@@ -0,0 +1,30 @@
+package mail
+
+import (
+	"ecommerce/pkg/config"
+	"fmt"
+)
+
+// Mailer sends plain text emails
+type Mailer interface {
+	Send(to, subject, body string) error
+}
+
+// NewMailer picks the backend from MAIL_BACKEND, it has no default so reset
+// emails never end up in a log by accident. The log backend only runs in
+// development and test
+func NewMailer(cfg config.Config) (Mailer, error) {
+	switch cfg.MAIL_BACKEND {
+	case "":
+		return nil, fmt.Errorf("MAIL_BACKEND is required, set it to smtp, or to log in development")
+	case "log":
+		if !cfg.IsDevelopment() {
+			return nil, fmt.Errorf("the log mail backend only runs with APP_ENV development or test")
+		}
+		return NewLogMailer(), nil
+	case "smtp":
+		return NewSMTPMailer(cfg)
+	default:
+		return nil, fmt.Errorf("unknown mail backend %q", cfg.MAIL_BACKEND)
+	}
+}
@@ -0,0 +1,33 @@
+package mail
+
+import (
+	"ecommerce/pkg/config"
+	"testing"
+)
+
+func TestNewMailer(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     config.Config
+		wantErr bool
+	}{
+		{name: "no backend", cfg: config.Config{APP_ENV: "development"}, wantErr: true},
+		{name: "log in development", cfg: config.Config{MAIL_BACKEND: "log", APP_ENV: "development"}},
+		{name: "log in test", cfg: config.Config{MAIL_BACKEND: "log", APP_ENV: "test"}},
+		{name: "log in production", cfg: config.Config{MAIL_BACKEND: "log", APP_ENV: "production"}, wantErr: true},
+		{name: "smtp", cfg: config.Config{MAIL_BACKEND: "smtp", SMTP_HOST: "smtp.example.com", SMTP_PORT: "587", MAIL_FROM: "shop@example.com"}},
+		{name: "smtp without a host", cfg: config.Config{MAIL_BACKEND: "smtp", MAIL_FROM: "shop@example.com"}, wantErr: true},
+		{name: "unknown backend", cfg: config.Config{MAIL_BACKEND: "pigeon", APP_ENV: "development"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mailer, err := NewMailer(tt.cfg)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected an error %v, got %v", tt.wantErr, err)
+			}
+			if !tt.wantErr && mailer == nil {
+				t.Fatal("expected a mailer")
+			}
+		})
+	}
+}
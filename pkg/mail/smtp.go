@@ -0,0 +1,48 @@
+package mail
+
+import (
+	"ecommerce/pkg/config"
+	"fmt"
+	"net"
+	"net/smtp"
+	"strings"
+)
+
+// SMTPMailer sends through an SMTP server, with PLAIN auth when a username is set
+type SMTPMailer struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTPMailer(cfg config.Config) (*SMTPMailer, error) {
+	if cfg.SMTP_HOST == "" || cfg.MAIL_FROM == "" {
+		return nil, fmt.Errorf("SMTP_HOST and MAIL_FROM are required for the smtp mail backend")
+	}
+	mailer := &SMTPMailer{
+		addr: net.JoinHostPort(cfg.SMTP_HOST, cfg.SMTP_PORT),
+		host: cfg.SMTP_HOST,
+		from: cfg.MAIL_FROM,
+	}
+	if cfg.SMTP_USERNAME != "" {
+		mailer.auth = smtp.PlainAuth("", cfg.SMTP_USERNAME, cfg.SMTP_PASSWORD, cfg.SMTP_HOST)
+	}
+	return mailer, nil
+}
+
+func (m *SMTPMailer) Send(to, subject, body string) error {
+	if strings.ContainsAny(to+subject, "\r\n") {
+		return fmt.Errorf("invalid mail header")
+	}
+	message := "From: " + m.from + "\r\n" +
+		"To: " + to + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body
+	if err := smtp.SendMail(m.addr, m.auth, m.from, []string{to}, []byte(message)); err != nil {
+		return fmt.Errorf("failed to send mail: %w", err)
+	}
+	return nil
+}
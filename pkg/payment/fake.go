@@ -0,0 +1,130 @@
+package payment
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"sync"
+)
+
+// FakeGateway keeps orders, payments and refunds in memory so the checkout
+// can run without the network. Pay stands in for the customer paying on the
+// checkout page. Every fake signs with its own random secret, only Pay and
+// SignWebhook can make signatures it accepts
+type FakeGateway struct {
+	mu       sync.Mutex
+	secret   string
+	counter  int
+	orders   map[string]Order
+	payments map[string]Payment
+	refunds  map[string]Refund
+}
+
+func NewFakeGateway() (*FakeGateway, error) {
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, err
+	}
+	return &FakeGateway{
+		secret:   hex.EncodeToString(secret),
+		orders:   map[string]Order{},
+		payments: map[string]Payment{},
+		refunds:  map[string]Refund{},
+	}, nil
+}
+
+func (f *FakeGateway) nextId(prefix string) string {
+	f.counter++
+	return fmt.Sprintf("%s_fake%06d", prefix, f.counter)
+}
+
+func (f *FakeGateway) Key() string {
+	return "rzp_test_fake"
+}
+
+func (f *FakeGateway) CreateOrder(amount float64, receipt string, notes map[string]string) (Order, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if amount <= 0 {
+		return Order{}, fmt.Errorf("amount must be positive")
+	}
+	order := Order{ID: f.nextId("order"), Amount: amount}
+	f.orders[order.ID] = order
+	return order, nil
+}
+
+// Pay captures the full amount of a created order and returns the payment id
+// with the signature the checkout page would post back
+func (f *FakeGateway) Pay(orderId string) (paymentId, signature string, err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	order, ok := f.orders[orderId]
+	if !ok {
+		return "", "", fmt.Errorf("no such order %s", orderId)
+	}
+	payment := Payment{ID: f.nextId("pay"), OrderID: order.ID, Amount: order.Amount, Status: "captured"}
+	f.payments[payment.ID] = payment
+	return payment.ID, sign(f.secret, []byte(order.ID+"|"+payment.ID)), nil
+}
+
+func (f *FakeGateway) FetchPayment(paymentId string) (Payment, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payment, ok := f.payments[paymentId]
+	if !ok {
+		return Payment{}, fmt.Errorf("no such payment %s", paymentId)
+	}
+	return payment, nil
+}
+
+func (f *FakeGateway) Refund(paymentId string, amount float64, notes map[string]string) (Refund, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	payment, ok := f.payments[paymentId]
+	if !ok {
+		return Refund{}, fmt.Errorf("no such payment %s", paymentId)
+	}
+	refunded := 0.0
+	for _, refund := range f.refunds {
+		if refund.PaymentID == paymentId {
+			refunded += refund.Amount
+		}
+	}
+	if amount <= 0 || toPaise(refunded+amount) > toPaise(payment.Amount) {
+		return Refund{}, fmt.Errorf("refund amount exceeds the captured amount")
+	}
+	refund := Refund{ID: f.nextId("rfnd"), PaymentID: paymentId, Amount: amount, Status: "processed"}
+	f.refunds[refund.ID] = refund
+	return refund, nil
+}
+
+func (f *FakeGateway) VerifySignature(orderId, paymentId, signature string) bool {
+	return validSignature(f.secret, []byte(orderId+"|"+paymentId), signature)
+}
+
+func (f *FakeGateway) VerifyWebhookSignature(payload []byte, signature string) bool {
+	return validSignature(f.secret, payload, signature)
+}
+
+// SignWebhook returns the signature header for a webhook body sent to the API
+func (f *FakeGateway) SignWebhook(payload []byte) string {
+	return sign(f.secret, payload)
+}
+
+// RefundedAmount is how much of a payment was refunded so far
+func (f *FakeGateway) RefundedAmount(paymentId string) float64 {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	refunded := 0.0
+	for _, refund := range f.refunds {
+		if refund.PaymentID == paymentId {
+			refunded += refund.Amount
+		}
+	}
+	return refunded
+}
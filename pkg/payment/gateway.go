@@ -0,0 +1,59 @@
+package payment
+
+import (
+	"ecommerce/pkg/config"
+	"fmt"
+	"math"
+)
+
+// PaymentGateway is everything the order flow needs from an online payment
+// provider. Amounts are in rupees, implementations convert them as needed
+type PaymentGateway interface {
+	// Key is the public key the checkout page needs to open the payment form
+	Key() string
+	CreateOrder(amount float64, receipt string, notes map[string]string) (Order, error)
+	FetchPayment(paymentId string) (Payment, error)
+	Refund(paymentId string, amount float64, notes map[string]string) (Refund, error)
+	VerifySignature(orderId, paymentId, signature string) bool
+	VerifyWebhookSignature(payload []byte, signature string) bool
+}
+
+type Order struct {
+	ID     string
+	Amount float64
+}
+
+type Payment struct {
+	ID      string
+	OrderID string
+	Amount  float64
+	Status  string
+}
+
+type Refund struct {
+	ID        string
+	PaymentID string
+	Amount    float64
+	Status    string
+}
+
+// NewPaymentGateway picks the gateway from PAYMENT_GATEWAY, razorpay unless it
+// is set to fake. The fake takes no money, so it is refused outside development
+// and test
+func NewPaymentGateway(cfg config.Config) (PaymentGateway, error) {
+	switch cfg.PAYMENT_GATEWAY {
+	case "", "razorpay":
+		return NewRazorpayGateway(cfg), nil
+	case "fake":
+		if !cfg.IsDevelopment() {
+			return nil, fmt.Errorf("the fake payment gateway only runs with APP_ENV development or test")
+		}
+		return NewFakeGateway()
+	default:
+		return nil, fmt.Errorf("unknown payment gateway %q", cfg.PAYMENT_GATEWAY)
+	}
+}
+
+func toPaise(amount float64) int {
+	return int(math.Round(amount * 100))
+}
@@ -0,0 +1,92 @@
+package payment
+
+import (
+	"ecommerce/pkg/config"
+	"fmt"
+
+	"github.com/razorpay/razorpay-go"
+)
+
+type razorpayGateway struct {
+	key           string
+	secret        string
+	webhookSecret string
+	client        *razorpay.Client
+}
+
+func NewRazorpayGateway(cfg config.Config) PaymentGateway {
+	return &razorpayGateway{
+		key:           cfg.RAZOR_PAY_KEY,
+		secret:        cfg.RAZOR_PAY_SECRET,
+		webhookSecret: cfg.RAZOR_PAY_WEBHOOK_SECRET,
+		client:        razorpay.NewClient(cfg.RAZOR_PAY_KEY, cfg.RAZOR_PAY_SECRET),
+	}
+}
+
+func (r *razorpayGateway) Key() string {
+	return r.key
+}
+
+func (r *razorpayGateway) CreateOrder(amount float64, receipt string, notes map[string]string) (Order, error) {
+	data := map[string]interface{}{
+		"amount":   toPaise(amount),
+		"currency": "INR",
+		"receipt":  receipt,
+		"notes":    notes,
+	}
+	order, err := r.client.Order.Create(data, nil)
+	if err != nil {
+		return Order{}, fmt.Errorf("faild to create razorpay order, %s", err.Error())
+	}
+	return Order{
+		ID:     stringField(order, "id"),
+		Amount: amount,
+	}, nil
+}
+
+func (r *razorpayGateway) FetchPayment(paymentId string) (Payment, error) {
+	payment, err := r.client.Payment.Fetch(paymentId, nil, nil)
+	if err != nil {
+		return Payment{}, err
+	}
+	return Payment{
+		ID:      stringField(payment, "id"),
+		OrderID: stringField(payment, "order_id"),
+		Amount:  paiseField(payment, "amount"),
+		Status:  stringField(payment, "status"),
+	}, nil
+}
+
+func (r *razorpayGateway) Refund(paymentId string, amount float64, notes map[string]string) (Refund, error) {
+	data := map[string]interface{}{
+		"notes": notes,
+	}
+	refund, err := r.client.Payment.Refund(paymentId, toPaise(amount), data, nil)
+	if err != nil {
+		return Refund{}, err
+	}
+	return Refund{
+		ID:        stringField(refund, "id"),
+		PaymentID: paymentId,
+		Amount:    paiseField(refund, "amount"),
+		Status:    stringField(refund, "status"),
+	}, nil
+}
+
+func (r *razorpayGateway) VerifySignature(orderId, paymentId, signature string) bool {
+	return validSignature(r.secret, []byte(orderId+"|"+paymentId), signature)
+}
+
+func (r *razorpayGateway) VerifyWebhookSignature(payload []byte, signature string) bool {
+	return validSignature(r.webhookSecret, payload, signature)
+}
+
+func stringField(data map[string]interface{}, key string) string {
+	value, _ := data[key].(string)
+	return value
+}
+
+func paiseField(data map[string]interface{}, key string) float64 {
+	value, _ := data[key].(float64)
+	return value / 100
+}
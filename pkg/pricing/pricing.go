@@ -0,0 +1,90 @@
+package pricing
+
+import (
+	"ecommerce/pkg/config"
+	"ecommerce/pkg/domain"
+	"errors"
+	"math"
+	"time"
+)
+
+// Item is one cart line priced at the product's current price
+type Item struct {
+	ProductID uint
+	Qty       int
+	Price     float64
+}
+
+// Breakdown is how a cart total is made up, Total = Subtotal - Discount + Tax + Shipping
+type Breakdown struct {
+	Subtotal float64 `json:"subtotal"`
+	Discount float64 `json:"discount"`
+	Tax      float64 `json:"tax"`
+	Shipping float64 `json:"shipping"`
+	Total    float64 `json:"total"`
+	// CouponID is the coupon that gave the discount, zero when none applies
+	CouponID uint `json:"-"`
+}
+
+// Pricer derives cart totals. Tax is charged on the discounted subtotal and
+// shipping is free once the discounted subtotal reaches FreeShippingAbove
+type Pricer struct {
+	TaxPercent        float64
+	ShippingCharge    float64
+	FreeShippingAbove float64
+}
+
+func NewPricer(cfg config.Config) *Pricer {
+	return &Pricer{
+		TaxPercent:        cfg.TAX_PERCENT,
+		ShippingCharge:    cfg.SHIPPING_CHARGE,
+		FreeShippingAbove: cfg.FREE_SHIPPING_ABOVE,
+	}
+}
+
+// Price works out the breakdown for items, coupon may be nil. A coupon that
+// does not apply to these items is left out of the breakdown
+func (p *Pricer) Price(items []Item, coupon *domain.Coupon, now time.Time) Breakdown {
+	var breakdown Breakdown
+	for _, item := range items {
+		breakdown.Subtotal += float64(item.Qty) * item.Price
+	}
+	breakdown.Subtotal = round(breakdown.Subtotal)
+	if breakdown.Subtotal == 0 {
+		return breakdown
+	}
+
+	if coupon != nil {
+		if discount, err := CouponDiscount(*coupon, breakdown.Subtotal, now); err == nil {
+			breakdown.Discount = discount
+			breakdown.CouponID = coupon.Id
+		}
+	}
+
+	taxable := breakdown.Subtotal - breakdown.Discount
+	breakdown.Tax = round(taxable * p.TaxPercent / 100)
+	if p.FreeShippingAbove <= 0 || taxable < p.FreeShippingAbove {
+		breakdown.Shipping = round(p.ShippingCharge)
+	}
+	breakdown.Total = round(taxable + breakdown.Tax + breakdown.Shipping)
+	return breakdown
+}
+
+// CouponDiscount is what coupon takes off subtotal, or why it can't be used
+func CouponDiscount(coupon domain.Coupon, subtotal float64, now time.Time) (float64, error) {
+	if coupon.ExpiryDate.Before(now) {
+		return 0, errors.New("coupon expired")
+	}
+	if subtotal <= coupon.MinimumPurchasePrice {
+		return 0, errors.New("minimum cart prize required")
+	}
+	discount := subtotal * coupon.DiscountPercent / 100
+	if discount > coupon.MaximumDiscountPrice {
+		discount = coupon.MaximumDiscountPrice
+	}
+	return round(discount), nil
+}
+
+func round(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
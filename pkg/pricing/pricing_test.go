@@ -0,0 +1,114 @@
+package pricing
+
+import (
+	"ecommerce/pkg/domain"
+	"testing"
+	"time"
+)
+
+func TestPrice(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	coupon := &domain.Coupon{Id: 3, DiscountPercent: 10, MaximumDiscountPrice: 150, MinimumPurchasePrice: 500, ExpiryDate: now.AddDate(0, 0, 1)}
+	expired := &domain.Coupon{Id: 4, DiscountPercent: 10, MaximumDiscountPrice: 150, ExpiryDate: now.Add(-time.Minute)}
+	pricer := &Pricer{TaxPercent: 18, ShippingCharge: 40, FreeShippingAbove: 1000}
+
+	tests := []struct {
+		name   string
+		items  []Item
+		coupon *domain.Coupon
+		want   Breakdown
+	}{
+		{
+			name:  "empty cart costs nothing",
+			items: nil,
+			want:  Breakdown{},
+		},
+		{
+			name:  "no coupon",
+			items: []Item{{ProductID: 1, Qty: 2, Price: 300}},
+			want:  Breakdown{Subtotal: 600, Tax: 108, Shipping: 40, Total: 748},
+		},
+		{
+			name:   "tax is on the discounted subtotal",
+			items:  []Item{{ProductID: 1, Qty: 2, Price: 300}},
+			coupon: coupon,
+			want:   Breakdown{Subtotal: 600, Discount: 60, Tax: 97.2, Shipping: 40, Total: 677.2, CouponID: 3},
+		},
+		{
+			name:   "discount is capped at the coupon maximum",
+			items:  []Item{{ProductID: 1, Qty: 1, Price: 2000}},
+			coupon: coupon,
+			want:   Breakdown{Subtotal: 2000, Discount: 150, Tax: 333, Total: 2183, CouponID: 3},
+		},
+		{
+			name:  "shipping is free from the threshold",
+			items: []Item{{ProductID: 1, Qty: 4, Price: 250}},
+			want:  Breakdown{Subtotal: 1000, Tax: 180, Total: 1180},
+		},
+		{
+			name:   "a discount can take the cart under the free shipping threshold",
+			items:  []Item{{ProductID: 1, Qty: 1, Price: 700}, {ProductID: 2, Qty: 1, Price: 350}},
+			coupon: coupon,
+			want:   Breakdown{Subtotal: 1050, Discount: 105, Tax: 170.1, Shipping: 40, Total: 1155.1, CouponID: 3},
+		},
+		{
+			name:   "expired coupon is left out",
+			items:  []Item{{ProductID: 1, Qty: 2, Price: 300}},
+			coupon: expired,
+			want:   Breakdown{Subtotal: 600, Tax: 108, Shipping: 40, Total: 748},
+		},
+		{
+			name:   "coupon below its minimum purchase is left out",
+			items:  []Item{{ProductID: 1, Qty: 1, Price: 400}},
+			coupon: coupon,
+			want:   Breakdown{Subtotal: 400, Tax: 72, Shipping: 40, Total: 512},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := pricer.Price(tt.items, tt.coupon, now); got != tt.want {
+				t.Fatalf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCouponDiscount(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	coupon := domain.Coupon{DiscountPercent: 10, MaximumDiscountPrice: 150, MinimumPurchasePrice: 500, ExpiryDate: now.AddDate(0, 0, 1)}
+
+	tests := []struct {
+		name     string
+		expiry   time.Time
+		subtotal float64
+		want     float64
+		wantErr  bool
+	}{
+		{name: "percent of the subtotal", subtotal: 999.99, want: 100},
+		{name: "capped at the maximum", subtotal: 5000, want: 150},
+		{name: "the minimum itself is not enough", subtotal: 500, wantErr: true},
+		{name: "below the minimum", subtotal: 120, wantErr: true},
+		{name: "expired", expiry: now.Add(-time.Second), subtotal: 800, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := coupon
+			if !tt.expiry.IsZero() {
+				c.ExpiryDate = tt.expiry
+			}
+			got, err := CouponDiscount(c, tt.subtotal, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected the coupon to be refused, got a discount of %.2f", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Fatalf("got %.2f, want %.2f", got, tt.want)
+			}
+		})
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"ecommerce/pkg/commonhelp/response"
 	"ecommerce/pkg/domain"
 	interfaces "ecommerce/pkg/repository/interface"
+	"ecommerce/pkg/token"
 	"errors"
 	"fmt"
 
@@ -29,14 +30,71 @@ func (c *AdminDB) FindAdmin(ctx context.Context, admin domain.Admin) (domain.Adm
 	return admin, nil
 }
 func (c *AdminDB) SaveAdmin(ctx context.Context, admin domain.Admin) error {
-	Query := `	INSERT INTO admins(admin_name, email, password)
- VALUES($1, $2, $3) RETURNING *;`
+	Query := `	INSERT INTO admins(admin_name, email, password, role)
+ VALUES($1, $2, $3, $4) RETURNING *;`
 
-	if c.DB.Exec(Query, admin.AdminName, admin.Email, admin.Password).Error != nil {
+	if c.DB.Exec(Query, admin.AdminName, admin.Email, admin.Password, admin.Role).Error != nil {
 		return errors.New("failed to create admin")
 	}
 	return nil
 }
+
+func (c *AdminDB) FindAdminByID(ctx context.Context, adminId uint) (domain.Admin, error) {
+	var admin domain.Admin
+	if err := c.DB.WithContext(ctx).Raw(`SELECT * FROM admins WHERE id = $1`, adminId).Scan(&admin).Error; err != nil {
+		return admin, errors.New("failed to find admin")
+	}
+	return admin, nil
+}
+
+func (c *AdminDB) HasSuperAdmin(ctx context.Context) (bool, error) {
+	var exists bool
+	err := c.DB.WithContext(ctx).Raw(`SELECT EXISTS(SELECT 1 FROM admins WHERE role = $1)`, domain.AdminRoleSuperAdmin).Scan(&exists).Error
+	return exists, err
+}
+
+func (c *AdminDB) FindAdmins(ctx context.Context, pagination requests.Pagination) ([]response.Admin, error) {
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	var admins []response.Admin
+	query := `SELECT id, admin_name, email, role FROM admins ORDER BY id LIMIT $1 OFFSET $2`
+	if err := c.DB.WithContext(ctx).Raw(query, limit, offset).Scan(&admins).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch admins: %w", err)
+	}
+	return admins, nil
+}
+
+// UpdateAdminRole gives the admin a new role. The super admins are locked
+// first so two of them can't demote each other at once and leave none
+func (c *AdminDB) UpdateAdminRole(ctx context.Context, adminId uint, role string) (response.Admin, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	var superAdmins []uint
+	if err := tx.Raw(`SELECT id FROM admins WHERE role = $1 FOR UPDATE`, domain.AdminRoleSuperAdmin).Scan(&superAdmins).Error; err != nil {
+		tx.Rollback()
+		return response.Admin{}, err
+	}
+	if role != domain.AdminRoleSuperAdmin && len(superAdmins) == 1 && superAdmins[0] == adminId {
+		tx.Rollback()
+		return response.Admin{}, errors.New("can't change the role of the only super admin")
+	}
+
+	var admin response.Admin
+	query := `UPDATE admins SET role = $1 WHERE id = $2 RETURNING id, admin_name, email, role`
+	if err := tx.Raw(query, role, adminId).Scan(&admin).Error; err != nil {
+		tx.Rollback()
+		return response.Admin{}, err
+	}
+	if admin.ID == 0 {
+		tx.Rollback()
+		return response.Admin{}, errors.New("no admin found")
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return response.Admin{}, err
+	}
+	return admin, nil
+}
 func (c *AdminDB) FindAllUser(ctx context.Context, pagination requests.Pagination) ([]response.UserValue, error) {
 	// Validate pagination parameters
 	if pagination.Page < 1 || pagination.PerPage < 1 {
@@ -48,7 +106,7 @@ func (c *AdminDB) FindAllUser(ctx context.Context, pagination requests.Paginatio
 	offset := (pagination.Page - 1) * limit
 
 	// Define the query with parameterized placeholders
-	query := `SELECT * FROM users LIMIT $1 OFFSET $2`
+	query := `SELECT * FROM users WHERE deleted_at IS NULL ORDER BY id LIMIT $1 OFFSET $2`
 
 	// Execute the query with context
 	var users []response.UserValue
@@ -59,27 +117,47 @@ func (c *AdminDB) FindAllUser(ctx context.Context, pagination requests.Paginatio
 
 	return users, nil
 }
+
+// activeBlock matches the user_statuses rows of blocks still in force
+const activeBlock = `unblocked_at IS NULL AND (blocked_until IS NULL OR blocked_until > NOW())`
+
 func (c *AdminDB) BlockUser(body requests.BlockUser, AdminId int) error {
 	// Start a transaction
 	tx := c.DB.Begin()
-	//Check if the user is there
-	var isExists bool
-	if err := tx.Raw("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", body.UserID).Scan(&isExists).Error; err != nil {
+	//Check if the user is there, locking it so two admins can't block it at once
+	var userIds []uint
+	if err := tx.Raw("SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE", body.UserID).Scan(&userIds).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
-	if !isExists {
+	if len(userIds) == 0 {
 		tx.Rollback()
 		return fmt.Errorf("no such user")
 	}
+	var isBlocked bool
+	if err := tx.Raw("SELECT EXISTS(SELECT 1 FROM user_statuses WHERE users_id = $1 AND "+activeBlock+")", body.UserID).Scan(&isBlocked).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if isBlocked {
+		tx.Rollback()
+		return fmt.Errorf("user is already blocked")
+	}
 
 	// Execute the first SQL command (UPDATE)
 	if err := tx.Exec("UPDATE users SET is_blocked = true WHERE id = ?", body.UserID).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
-	// Execute the second SQL command (INSERT)
-	if err := tx.Exec("INSERT INTO user_statuses  (users_id, reason_for_blocking, blocked_at, blocked_by) VALUES (?, ?, NOW(), ?)", body.UserID, body.Reason, AdminId).Error; err != nil {
+	// Execute the second SQL command (INSERT), a block without a duration lasts until lifted
+	query := `INSERT INTO user_statuses (users_id, reason_for_blocking, blocked_at, blocked_by, blocked_until)
+	VALUES ($1, $2, NOW(), $3, CASE WHEN $4 > 0 THEN NOW() + $4 * INTERVAL '1 hour' END)`
+	if err := tx.Exec(query, body.UserID, body.Reason, AdminId, body.DurationHours).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	// Sign the user out of every device
+	if err := revokeSessions(tx, uint(body.UserID), token.AudienceUser, ""); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -92,15 +170,18 @@ func (c *AdminDB) BlockUser(body requests.BlockUser, AdminId int) error {
 	return nil
 
 }
-func (c *AdminDB) UnblockUser(id int) error {
+
+// UnblockUser lifts the user's block, it stays in the block history
+func (c *AdminDB) UnblockUser(id int, adminId int) error {
 	tx := c.DB.Begin()
 
-	var isExists bool
-	if err := tx.Raw("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND is_blocked=true)", id).Scan(&isExists).Error; err != nil {
+	var lifted []uint
+	query := "UPDATE user_statuses SET unblocked_at = NOW(), unblocked_by = $1 WHERE users_id = $2 AND " + activeBlock + " RETURNING id"
+	if err := tx.Raw(query, adminId, id).Scan(&lifted).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
-	if !isExists {
+	if len(lifted) == 0 {
 		tx.Rollback()
 		return fmt.Errorf("no such user to unblock")
 	}
@@ -108,11 +189,6 @@ func (c *AdminDB) UnblockUser(id int) error {
 		tx.Rollback()
 		return err
 	}
-	query := "UPDATE user_statuses SET reason_for_blocking=$1,blocked_at=NULL,blocked_by=$2 WHERE users_id=$3"
-	if err := tx.Exec(query, "", 0, id).Error; err != nil {
-		tx.Rollback()
-		return err
-	}
 	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		return err
@@ -123,7 +199,7 @@ func (c *AdminDB) UnblockUser(id int) error {
 func (c *AdminDB) FindUserbyId(ctx context.Context, userID int) (domain.Users, error) {
 	var user domain.Users
 
-	FindbyID := `SELECT *FROM users WHERE id= $1;`
+	FindbyID := `SELECT *FROM users WHERE id= $1 AND deleted_at IS NULL;`
 
 	err := c.DB.Raw(FindbyID, userID).Scan(&user).Error
 
@@ -132,3 +208,177 @@ func (c *AdminDB) FindUserbyId(ctx context.Context, userID int) (domain.Users, e
 	}
 	return user, err
 }
+
+// UnblockExpired closes the timed blocks that ran out and clears the users'
+// blocked flag, it returns how many users got unblocked
+func (c *AdminDB) UnblockExpired(ctx context.Context) (int64, error) {
+	query := `WITH expired AS (
+		UPDATE user_statuses SET unblocked_at = blocked_until
+		WHERE unblocked_at IS NULL AND blocked_until <= NOW()
+		RETURNING users_id
+	)
+	UPDATE users SET is_blocked = false
+	WHERE id IN (SELECT users_id FROM expired)
+	AND NOT EXISTS (SELECT 1 FROM user_statuses s WHERE s.users_id = users.id AND ` + activeBlock + `)`
+	result := c.DB.WithContext(ctx).Exec(query)
+	return result.RowsAffected, result.Error
+}
+
+// FindBlockHistory lists blocks newest first, of one user when userId is set
+func (c *AdminDB) FindBlockHistory(ctx context.Context, userId int, pagination requests.Pagination) ([]response.BlockRecord, error) {
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	var history []response.BlockRecord
+	query := `SELECT s.id, s.users_id AS user_id, u.name AS user_name, u.email,
+		COALESCE(s.reason_for_blocking, '') AS reason, s.blocked_at, COALESCE(s.blocked_by, 0) AS blocked_by,
+		s.blocked_until, s.unblocked_at, COALESCE(s.unblocked_by, 0) AS unblocked_by,
+		(` + activeBlock + `) AS active
+	FROM user_statuses s
+	JOIN users u ON u.id = s.users_id
+	WHERE ($1 = 0 OR s.users_id = $1)
+	ORDER BY s.blocked_at DESC NULLS LAST, s.id DESC
+	LIMIT $2 OFFSET $3`
+	if err := c.DB.WithContext(ctx).Raw(query, userId, limit, offset).Scan(&history).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch block history: %w", err)
+	}
+	return history, nil
+}
+
+// DeleteUser archives the user's account and signs them out of every device,
+// their orders and reviews stay
+func (c *AdminDB) DeleteUser(ctx context.Context, userID int) error {
+	tx := c.DB.WithContext(ctx).Begin()
+	var archived []uint
+	query := `UPDATE users SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING id`
+	if err := tx.Raw(query, userID).Scan(&archived).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(archived) == 0 {
+		tx.Rollback()
+		return fmt.Errorf("no user found")
+	}
+	if err := revokeSessions(tx, uint(userID), token.AudienceUser, ""); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (c *AdminDB) RestoreUser(ctx context.Context, userID int) (response.UserValue, error) {
+	var user response.UserValue
+	query := `UPDATE users SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING *`
+	if err := c.DB.WithContext(ctx).Raw(query, userID).Scan(&user).Error; err != nil {
+		return user, err
+	}
+	if user.ID == 0 {
+		return user, fmt.Errorf("no archived user with this id")
+	}
+	return user, nil
+}
+
+// FindDeletedUsers lists the archived users, last archived first
+func (c *AdminDB) FindDeletedUsers(ctx context.Context, pagination requests.Pagination) ([]response.UserValue, error) {
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	var users []response.UserValue
+	query := `SELECT * FROM users WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id LIMIT $1 OFFSET $2`
+	if err := c.DB.WithContext(ctx).Raw(query, limit, offset).Scan(&users).Error; err != nil {
+		return nil, fmt.Errorf("failed to fetch archived users: %w", err)
+	}
+	return users, nil
+}
+
+func (c *AdminDB) Dashboard(ctx context.Context, dateRange requests.DateRange) (response.AdminDashboard, error) {
+	var dashboard response.AdminDashboard
+
+	orderQuery := `SELECT
+		COUNT(*) AS total_orders,
+		COUNT(*) FILTER (WHERE order_status_id = $3) AS completed_orders,
+		COUNT(*) FILTER (WHERE order_status_id IN ($4, $5)) AS pending_orders,
+		COUNT(*) FILTER (WHERE order_status_id = $6) AS cancelled_orders,
+		COALESCE(SUM(order_total) FILTER (WHERE order_status_id <> $6), 0) AS order_value,
+		COUNT(DISTINCT user_id) AS ordered_users
+	FROM orders
+	WHERE order_date BETWEEN $1 AND $2`
+	err := c.DB.WithContext(ctx).Raw(orderQuery, dateRange.StartDate, dateRange.EndDate,
+		domain.OrderStatusDelivered, domain.OrderStatusPending, domain.OrderStatusShipped, domain.OrderStatusCancelled).Scan(&dashboard).Error
+	if err != nil {
+		return dashboard, fmt.Errorf("failed to count orders: %w", err)
+	}
+
+	// cancelled and returned quantities are kept on the lines, only count what is still ordered
+	itemQuery := `SELECT COALESCE(SUM(ol.qty - ol.cancelled_qty - ol.returned_qty), 0)
+	FROM order_lines ol
+	JOIN orders o ON o.id = ol.order_id
+	WHERE o.order_date BETWEEN $1 AND $2`
+	if err := c.DB.WithContext(ctx).Raw(itemQuery, dateRange.StartDate, dateRange.EndDate).Scan(&dashboard.TotalOrderItems).Error; err != nil {
+		return dashboard, fmt.Errorf("failed to count order items: %w", err)
+	}
+
+	// what was collected is what paid orders took less what was refunded of them
+	amountQuery := `SELECT
+		COALESCE(SUM(pd.order_total - COALESCE(r.refunded, 0)) FILTER (WHERE pd.payment_status_id IN ($3, $4)), 0) AS credited_amount,
+		COALESCE(SUM(pd.order_total) FILTER (WHERE pd.payment_status_id = $5 AND o.order_status_id <> $8), 0) AS pending_amount
+	FROM payment_details pd
+	JOIN orders o ON o.id = pd.orders_id
+	LEFT JOIN (
+		SELECT order_id, SUM(amount) AS refunded FROM refunds
+		WHERE status IN ($6, $7)
+		GROUP BY order_id
+	) r ON r.order_id = o.id
+	WHERE o.order_date BETWEEN $1 AND $2`
+	var amounts struct {
+		CreditedAmount float64
+		PendingAmount  float64
+	}
+	err = c.DB.WithContext(ctx).Raw(amountQuery, dateRange.StartDate, dateRange.EndDate, domain.PaymentStatusPaid, domain.PaymentStatusPartiallyRefunded,
+		domain.PaymentStatusPending, domain.RefundApproved, domain.RefundProcessed, domain.OrderStatusCancelled).Scan(&amounts).Error
+	if err != nil {
+		return dashboard, fmt.Errorf("failed to sum payments: %w", err)
+	}
+	dashboard.CreditedAmount = amounts.CreditedAmount
+	dashboard.PendingAmount = amounts.PendingAmount
+
+	userQuery := `SELECT
+		COUNT(*) AS total_users,
+		COUNT(*) FILTER (WHERE is_blocked = false) AS verified_users
+	FROM users
+	WHERE created_at BETWEEN $1 AND $2`
+	var users struct {
+		TotalUsers    int
+		VerifiedUsers int
+	}
+	if err := c.DB.WithContext(ctx).Raw(userQuery, dateRange.StartDate, dateRange.EndDate).Scan(&users).Error; err != nil {
+		return dashboard, fmt.Errorf("failed to count users: %w", err)
+	}
+	dashboard.TotalUsers = users.TotalUsers
+	dashboard.VerifiedUsers = users.VerifiedUsers
+
+	return dashboard, nil
+}
+
+func (c *AdminDB) SalesReport(ctx context.Context, dateRange requests.DateRange) ([]response.SalesReport, error) {
+	var report []response.SalesReport
+
+	// cancelled and returned orders are not counted as sales
+	query := `SELECT o.id::text AS id, u.name, pm.payment_method, o.order_date,
+		o.order_total, u.mobile, a.house_number, a.pincode
+	FROM orders o
+	JOIN users u ON o.user_id = u.id
+	JOIN payment_methods pm ON o.payment_method_id = pm.id
+	JOIN addresses a ON o.shipping_address_id = a.id
+	WHERE o.order_date BETWEEN $1 AND $2 AND o.order_status_id NOT IN ($3, $4)
+	ORDER BY o.order_date`
+	err := c.DB.WithContext(ctx).Raw(query, dateRange.StartDate, dateRange.EndDate, domain.OrderStatusCancelled, domain.OrderStatusReturned).Scan(&report).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sales report: %w", err)
+	}
+	return report, nil
+}
@@ -4,20 +4,24 @@ import (
 	"context"
 	"ecommerce/pkg/commonhelp/response"
 	"ecommerce/pkg/domain"
+	"ecommerce/pkg/pricing"
 	interfaces "ecommerce/pkg/repository/interface"
 	"errors"
 	"fmt"
+	"time"
 
 	"gorm.io/gorm"
 )
 
 type cartDB struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	pricer *pricing.Pricer
 }
 
-func NewecartRepository(DB *gorm.DB) interfaces.CartRepo {
+func NewecartRepository(DB *gorm.DB, pricer *pricing.Pricer) interfaces.CartRepo {
 	return &cartDB{
-		DB: DB,
+		DB:     DB,
+		pricer: pricer,
 	}
 }
 func (c *cartDB) SaveCart(ctx context.Context, Userid int) (uint, error) {
@@ -39,26 +43,32 @@ func (c *cartDB) FindCartByUserID(ctx context.Context, UserID int) (domain.Cart,
 }
 
 func (c *cartDB) AddCartItem(ctx context.Context, CartItem domain.CartItem) error {
-	Query := `INSERT INTO cart_items(cart_id,product_id,qty)VALUES($1,$2,$3)`
-	if c.DB.Raw(Query, CartItem.CartID, CartItem.ProductId, 1).Scan(&CartItem).Error != nil {
+	tx := c.DB.Begin()
+	Query := `INSERT INTO cart_items(cart_id,product_id,variant_id,qty)VALUES($1,$2,$3,$4)`
+	if tx.Exec(Query, CartItem.CartID, CartItem.ProductId, CartItem.VariantID, 1).Error != nil {
+		tx.Rollback()
 		return errors.New("cant add this item")
 	}
-
-	return nil
+	return c.repriceAndCommit(tx, CartItem.CartID)
 }
 
-func (c *cartDB) FindCartIDNproductId(ctx context.Context, cart_id uint, product_id uint) (cartItem domain.CartItem, err error) {
+func (c *cartDB) FindCartItemByVariant(ctx context.Context, cart_id uint, variant_id uint) (cartItem domain.CartItem, err error) {
 
-	Query := `SELECT * FROM cart_items WHERE cart_id = $1 AND product_id = $2`
-	if c.DB.Raw(Query, cart_id, product_id).Scan(&cartItem).Error != nil {
-		return cartItem, errors.New("cant find cartitem coresponding this cart id ,product id")
+	Query := `SELECT * FROM cart_items WHERE cart_id = $1 AND variant_id = $2`
+	if c.DB.Raw(Query, cart_id, variant_id).Scan(&cartItem).Error != nil {
+		return cartItem, errors.New("cant find cartitem coresponding this cart id ,variant id")
 	}
 	return cartItem, nil
 }
+
+// FindVariants lists the variants of a product, cheapest first
+func (c *cartDB) FindVariants(ctx context.Context, productId uint) ([]domain.ProductVariant, error) {
+	return findVariants(c.DB, productId)
+}
 func (c *cartDB) FindProduct(ctx context.Context, id uint) (response.Product, error) {
 	var product response.Product
 	query := `SELECT p.id,p.product_name as name,p.description,p.brand,p.prize,p.category_id,p.qty_in_stock,c.category_name,p.created_at,p.updated_at FROM products p 
-		JOIN categories c ON p.category_id=c.id WHERE p.id=$1`
+		JOIN categories c ON p.category_id=c.id WHERE p.id=$1 AND p.deleted_at IS NULL`
 	err := c.DB.Raw(query, id).Scan(&product).Error
 	if err != nil {
 		fmt.Printf("[FindProduct] DB error for product_id=%d: %v\n", id, err)
@@ -68,29 +78,117 @@ func (c *cartDB) FindProduct(ctx context.Context, id uint) (response.Product, er
 	return product, err
 }
 func (c *cartDB) RemoveCartItem(ctx context.Context, CartItemid uint) error {
-	RemoveQuery := `DELETE FROM cart_items WHERE id=$1	`
-	if c.DB.Exec(RemoveQuery, CartItemid).Error != nil {
+	tx := c.DB.Begin()
+	var cartId uint
+	RemoveQuery := `DELETE FROM cart_items WHERE id=$1 RETURNING cart_id`
+	if tx.Raw(RemoveQuery, CartItemid).Scan(&cartId).Error != nil {
+		tx.Rollback()
 		return errors.New("faild to remove product_items from cart")
 	}
-	return nil
+	return c.repriceAndCommit(tx, cartId)
 }
 func (c *cartDB) AddQuantity(ctx context.Context, cartItemid uint, qty uint) error {
-
-	query := `UPDATE cart_items SET qty = $1 WHERE id = $2`
-	if c.DB.Exec(query, qty, cartItemid).Error != nil {
+	tx := c.DB.Begin()
+	var cartId uint
+	query := `UPDATE cart_items SET qty = $1 WHERE id = $2 RETURNING cart_id`
+	if tx.Raw(query, qty, cartItemid).Scan(&cartId).Error != nil {
+		tx.Rollback()
 		return errors.New("faild to add  qty of ")
 	}
+	return c.repriceAndCommit(tx, cartId)
+}
+
+// RepriceCart works the cart totals out again from the current variant prices
+func (c *cartDB) RepriceCart(ctx context.Context, cartID uint) (domain.Cart, error) {
+	tx := c.DB.Begin()
+	cart, err := repriceCart(tx, c.pricer, cartID)
+	if err != nil {
+		tx.Rollback()
+		return domain.Cart{}, err
+	}
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.Cart{}, err
+	}
+	return cart, nil
+}
+
+func (c *cartDB) repriceAndCommit(tx *gorm.DB, cartID uint) error {
+	if _, err := repriceCart(tx, c.pricer, cartID); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
 	return nil
 }
 func (c *cartDB) FindCartlistByCartID(ctx context.Context, cartID uint) (cartitems []response.Cartres, err error) {
 
-	query := `SELECT ci.product_id, p.product_name, ci.qty, p.prize, p.qty_in_stock 
+	query := `SELECT ci.product_id, ci.variant_id, p.product_name, v.sku, v.attributes, ci.qty, v.price AS prize, v.qty_in_stock,
+		ci.qty * v.price AS line_total, pi.thumbnail_key
 	FROM cart_items ci 
 	INNER JOIN products p ON ci.product_id = p.id 
-	WHERE ci.cart_id = ?;
+	INNER JOIN product_variants v ON ci.variant_id = v.id
+	LEFT JOIN product_images pi ON pi.product_id = p.id AND pi.is_primary
+	WHERE ci.cart_id = ?
+	ORDER BY ci.id;
 	`
 	if c.DB.Raw(query, cartID).Scan(&cartitems).Error != nil {
 		return cartitems, errors.New("failed to show cartitems")
 	}
 	return cartitems, err
 }
+
+// repriceCart recomputes the cart's subtotal, discount, tax, shipping and total
+// inside tx from its items at the current variant prices. A coupon that no
+// longer applies to the cart is taken off
+func repriceCart(tx *gorm.DB, pricer *pricing.Pricer, cartID uint) (domain.Cart, error) {
+	var cart domain.Cart
+	if err := tx.Raw(`SELECT * FROM carts WHERE id=$1 FOR UPDATE`, cartID).Scan(&cart).Error; err != nil {
+		return domain.Cart{}, err
+	}
+	if cart.Id == 0 {
+		return domain.Cart{}, errors.New("cart not found")
+	}
+
+	var items []pricing.Item
+	findItems := `SELECT ci.product_id, ci.qty, v.price
+	FROM cart_items ci
+	JOIN product_variants v ON v.id = ci.variant_id
+	WHERE ci.cart_id=$1`
+	if err := tx.Raw(findItems, cartID).Scan(&items).Error; err != nil {
+		return domain.Cart{}, err
+	}
+
+	var coupon *domain.Coupon
+	if cart.CouponID != 0 {
+		var applied domain.Coupon
+		if err := tx.Raw(`SELECT * FROM coupons WHERE id=$1 AND deleted_at IS NULL`, cart.CouponID).Scan(&applied).Error; err != nil {
+			return domain.Cart{}, err
+		}
+		if applied.Id != 0 {
+			coupon = &applied
+		}
+	}
+
+	breakdown := pricer.Price(items, coupon, time.Now())
+	cart.Subtotal = breakdown.Subtotal
+	cart.Discount = breakdown.Discount
+	cart.Tax = breakdown.Tax
+	cart.Shipping = breakdown.Shipping
+	cart.Total_price = breakdown.Total
+	cart.CouponID = breakdown.CouponID
+	cart.Is_applied = breakdown.CouponID != 0
+
+	update := `UPDATE carts SET subtotal=$1,discount=$2,tax=$3,shipping=$4,total_price=$5,
+		coupon_id=NULLIF($6::bigint,0),is_applied=$7
+	WHERE id=$8`
+	err := tx.Exec(update, cart.Subtotal, cart.Discount, cart.Tax, cart.Shipping, cart.Total_price,
+		cart.CouponID, cart.Is_applied, cart.Id).Error
+	if err != nil {
+		return domain.Cart{}, err
+	}
+	return cart, nil
+}
@@ -0,0 +1,86 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"testing"
+)
+
+func TestCategoryCantMoveBelowItself(t *testing.T) {
+	DB := openTestDatabase(t)
+	categories := &productDB{DB: DB}
+	ctx := context.Background()
+
+	clothing, err := categories.Addcategory(ctx, requests.Category{Name: "clothing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	men, err := categories.Addcategory(ctx, requests.Category{Name: "men", ParentID: &clothing.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shirts, err := categories.Addcategory(ctx, requests.Category{Name: "shirts", ParentID: &men.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, parent := range []uint{clothing.ID, shirts.ID} {
+		if _, err := categories.UpdateCategory(ctx, requests.Category{Name: "clothing", ParentID: &parent}, int(clothing.ID)); err == nil {
+			t.Fatalf("expected clothing not to move below category %d", parent)
+		}
+	}
+	moved, err := categories.UpdateCategory(ctx, requests.Category{Name: "shirts", ParentID: &clothing.ID}, int(shirts.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if moved.ParentID != clothing.ID {
+		t.Fatalf("expected shirts below clothing, got parent %d", moved.ParentID)
+	}
+}
+
+func TestDeleteCategoryKeepsItsProductsAndSubcategories(t *testing.T) {
+	DB := openTestDatabase(t)
+	categories := &productDB{DB: DB}
+	ctx := context.Background()
+
+	clothing, err := categories.Addcategory(ctx, requests.Category{Name: "clothing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	men, err := categories.Addcategory(ctx, requests.Category{Name: "men", ParentID: &clothing.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shirts, err := categories.Addcategory(ctx, requests.Category{Name: "shirts", ParentID: &men.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Exec(`INSERT INTO products (product_name, prize, qty_in_stock, category_id) VALUES ('Oxford', 900, 1, $1)`, men.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := categories.DeleteCategory(ctx, int(men.ID), 0); err == nil {
+		t.Fatal("expected a category with products to need a reassignment")
+	}
+	if err := categories.DeleteCategory(ctx, int(men.ID), int(men.ID)); err == nil {
+		t.Fatal("expected the products not to move to the category being deleted")
+	}
+	if err := categories.DeleteCategory(ctx, int(men.ID), int(clothing.ID)); err != nil {
+		t.Fatal(err)
+	}
+
+	var category uint
+	if err := DB.Raw(`SELECT category_id FROM products WHERE product_name = 'Oxford'`).Scan(&category).Error; err != nil {
+		t.Fatal(err)
+	}
+	if category != clothing.ID {
+		t.Fatalf("expected the product to move to clothing, it is in %d", category)
+	}
+	shirts, err = categories.ShowCatagory(ctx, int(shirts.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shirts.ParentID != clothing.ID {
+		t.Fatalf("expected shirts to move up to clothing, got parent %d", shirts.ParentID)
+	}
+}
@@ -3,6 +3,7 @@ package repository
 import (
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/domain"
+	"ecommerce/pkg/pricing"
 	interfaces "ecommerce/pkg/repository/interface"
 	"errors"
 	"fmt"
@@ -13,12 +14,14 @@ import (
 )
 
 type CouponDB struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	pricer *pricing.Pricer
 }
 
-func NewCouponrepo(DB *gorm.DB) interfaces.CouponRepo {
+func NewCouponrepo(DB *gorm.DB, pricer *pricing.Pricer) interfaces.CouponRepo {
 	return &CouponDB{
-		DB: DB,
+		DB:     DB,
+		pricer: pricer,
 	}
 }
 
@@ -39,7 +42,7 @@ func (c *CouponDB) AddCoupon(ctx context.Context, coupon domain.Coupon) error {
 
 func (c *CouponDB) UpdateCouponById(ctx context.Context, CouponId int, coupon requests.Coupon) (UpdatedCoupon domain.Coupon, err error) {
 	updateCoupon := `UPDATE coupons SET discount_percent=$1, usage_limits=$2, maximum_discount_price=$3, minimum_purchase_price=$4, expiry_date=$5
-		 WHERE id=$6
+		 WHERE id=$6 AND deleted_at IS NULL
 		 RETURNING id, discount_percent, usage_limits, maximum_discount_price, minimum_purchase_price, expiry_date`
 
 	err = c.DB.Raw(updateCoupon,
@@ -54,34 +57,57 @@ func (c *CouponDB) UpdateCouponById(ctx context.Context, CouponId int, coupon re
 	return UpdatedCoupon, err
 }
 
+// DeleteCoupon archives the coupon, orders that used it keep their discount.
+// Carts it is applied to lose it the next time they are priced
 func (c *CouponDB) DeleteCoupon(ctx context.Context, CouponId int) (err error) {
-	delete := `DELETE FROM coupons WHERE id=?`
-
-	fmt.Println(CouponId)
-	err = c.DB.Exec(delete, CouponId).Error
-	if err != nil {
+	var archived []uint
+	delete := `UPDATE coupons SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING id`
+	if err = c.DB.Raw(delete, CouponId).Scan(&archived).Error; err != nil {
 		return err
 	}
+	if len(archived) == 0 {
+		return errors.New("coupon not found")
+	}
 	return nil
 }
 
+func (c *CouponDB) RestoreCoupon(ctx context.Context, CouponId int) (domain.Coupon, error) {
+	var coupon domain.Coupon
+	restore := `UPDATE coupons SET deleted_at = NULL WHERE id = $1 AND deleted_at IS NOT NULL RETURNING *`
+	if err := c.DB.Raw(restore, CouponId).Scan(&coupon).Error; err != nil {
+		return coupon, err
+	}
+	if coupon.Id == 0 {
+		return coupon, errors.New("no archived coupon with this id")
+	}
+	return coupon, nil
+}
+
 func (c *CouponDB) ViewCoupons(ctx context.Context) ([]domain.Coupon, error) {
 	var listofcoupens []domain.Coupon
-	viewall := `SELECT * FROM coupons`
+	viewall := `SELECT * FROM coupons WHERE deleted_at IS NULL`
 	err := c.DB.Raw(viewall).Scan(&listofcoupens).Error
 	return listofcoupens, err
 
 }
 
+// ViewDeletedCoupons lists the archived coupons, last archived first
+func (c *CouponDB) ViewDeletedCoupons(ctx context.Context) ([]domain.Coupon, error) {
+	var coupons []domain.Coupon
+	query := `SELECT * FROM coupons WHERE deleted_at IS NOT NULL ORDER BY deleted_at DESC, id`
+	err := c.DB.Raw(query).Scan(&coupons).Error
+	return coupons, err
+}
+
 func (c *CouponDB) ViewCoupon(ctx context.Context, couponID int) (domain.Coupon, error) {
 	var coupon domain.Coupon
-	CoupenDetails := `SELECT * FROM coupons WHERE id=$1`
+	CoupenDetails := `SELECT * FROM coupons WHERE id=$1 AND deleted_at IS NULL`
 	err := c.DB.Raw(CoupenDetails, couponID).Scan(&coupon).Error
 	return coupon, err
 }
 
 func (c *CouponDB) GetByCode(ctx context.Context, couponCode string) (coupon domain.Coupon, err error) {
-	quary := `SELECT * FROM coupons WHERE code=$1`
+	quary := `SELECT * FROM coupons WHERE code=$1 AND deleted_at IS NULL`
 	if err := c.DB.Raw(quary, couponCode).Scan(&coupon).Error; err != nil {
 		return coupon, err
 	}
@@ -91,7 +117,7 @@ func (c *CouponDB) GetByCode(ctx context.Context, couponCode string) (coupon dom
 func (c *CouponDB) UpdateCouponByCode(ctx context.Context, code string, coupon domain.Coupon) error {
 	var Updated domain.Coupon
 	updateCoupon := `UPDATE coupons SET discount_percent=$1, usage_limits=$2, maximum_discount_price=$3, minimum_purchase_price=$4, expiry_date=$5
-		 WHERE code=$6
+		 WHERE code=$6 AND deleted_at IS NULL
 		 RETURNING code, discount_percent, usage_limits, maximum_discount_price, minimum_purchase_price, expiry_date`
 
 	err := c.DB.Raw(updateCoupon,
@@ -108,7 +134,7 @@ func (c *CouponDB) UpdateCouponByCode(ctx context.Context, code string, coupon d
 func (c *CouponDB) ApplyCoupontoCart(ctx context.Context, userID int, Code string) (float64, error) {
 	tx := c.DB.Begin()
 	var coupon domain.Coupon
-	findCoupon := `SELECT * FROM coupons WHERE code = $1`
+	findCoupon := `SELECT * FROM coupons WHERE code = $1 AND deleted_at IS NULL`
 	err := tx.Raw(findCoupon, Code).First(&coupon).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
@@ -142,35 +168,36 @@ func (c *CouponDB) ApplyCoupontoCart(ctx context.Context, userID int, Code strin
 	if cart.Is_applied {
 		return 0, fmt.Errorf("this coupen is allready applied")
 	}
-	var discount float64
 	//check there is some thing inside the cart
-	if cart.Total_price == 0 {
+	cart, err = repriceCart(tx, c.pricer, cart.Id)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if cart.Subtotal == 0 {
 		tx.Rollback()
 		return 0, fmt.Errorf("no product is in the cart to apply coupen")
 	}
 
-	fmt.Println(coupon)
-	// Apply the discount to the cart or mark it as invalid
-	if coupon.MinimumPurchasePrice < float64(cart.Total_price) {
-		discount = (float64(cart.Total_price) * float64(coupon.DiscountPercent)) / 100
-		if discount > float64(coupon.MaximumDiscountPrice) {
-			discount = float64(coupon.MaximumDiscountPrice)
-		}
-	} else {
-		return 0, fmt.Errorf("minimum cart prize required")
+	// the discount itself is worked out by the pricing rules on every cart change
+	if _, err = pricing.CouponDiscount(coupon, cart.Subtotal, time.Now()); err != nil {
+		tx.Rollback()
+		return 0, err
 	}
 	coupon.UsageLimits--
 
-	updatecouponuse := `UPDATE coupons SET usage_limits =$1`
-	err = tx.Exec(updatecouponuse, coupon.UsageLimits).Error
+	updatecouponuse := `UPDATE coupons SET usage_limits =$1 WHERE id=$2`
+	err = tx.Exec(updatecouponuse, coupon.UsageLimits, coupon.Id).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	err = tx.Exec(`UPDATE carts SET coupon_id=$1 WHERE id=$2`, coupon.Id, cart.Id).Error
 	if err != nil {
 		tx.Rollback()
 		return 0, err
 	}
-	fmt.Println(discount)
-	// update the cart total with the subtotal - discount amount
-	updatedCart := `UPDATE carts SET total_price=$1,is_applied='T' WHERE id=$2`
-	err = tx.Exec(updatedCart, cart.Total_price-discount, cart.Id).Error
+	cart, err = repriceCart(tx, c.pricer, cart.Id)
 	if err != nil {
 		tx.Rollback()
 		return 0, err
@@ -179,7 +206,7 @@ func (c *CouponDB) ApplyCoupontoCart(ctx context.Context, userID int, Code strin
 		tx.Rollback()
 		return 0, err
 	}
-	return (cart.Total_price - discount), nil
+	return cart.Total_price, nil
 
 }
 
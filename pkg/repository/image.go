@@ -0,0 +1,178 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/domain"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// SaveProductImage adds the image after the product's other images, the first
+// image of a product becomes its primary image
+func (c *productDB) SaveProductImage(ctx context.Context, image domain.ProductImage) (domain.ProductImage, error) {
+	tx := c.DB.Begin()
+	// the product row is locked so two uploads don't take the same position
+	var productId uint
+	if err := tx.Raw(`SELECT id FROM products WHERE id = $1 FOR UPDATE`, image.ProductID).Scan(&productId).Error; err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+	if productId == 0 {
+		tx.Rollback()
+		return domain.ProductImage{}, errors.New("no product found with this id")
+	}
+
+	var newImage domain.ProductImage
+	query := `INSERT INTO product_images (product_id, storage_key, thumbnail_key, content_type, width, height, position, is_primary, created_at)
+	VALUES ($1, $2, $3, $4, $5, $6,
+		(SELECT COALESCE(MAX(position), 0) + 1 FROM product_images WHERE product_id = $1),
+		NOT EXISTS (SELECT 1 FROM product_images WHERE product_id = $1 AND is_primary),
+		NOW())
+	RETURNING *`
+	err := tx.Raw(query, image.ProductID, image.StorageKey, image.ThumbnailKey, image.ContentType, image.Width, image.Height).
+		Scan(&newImage).Error
+	if err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+	return newImage, nil
+}
+
+func (c *productDB) FindProductImages(ctx context.Context, productId int) ([]domain.ProductImage, error) {
+	return findProductImages(c.DB, uint(productId))
+}
+
+func (c *productDB) FindProductImage(ctx context.Context, imageId int) (domain.ProductImage, error) {
+	var image domain.ProductImage
+	err := c.DB.Raw(`SELECT * FROM product_images WHERE id = $1`, imageId).Scan(&image).Error
+	return image, err
+}
+
+// SetPrimaryImage makes the image its product's primary image in place of the current one
+func (c *productDB) SetPrimaryImage(ctx context.Context, imageId int) (domain.ProductImage, error) {
+	tx := c.DB.Begin()
+	image, err := findProductImage(tx, uint(imageId))
+	if err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+
+	// cleared first, the unique index allows one primary per product at any moment
+	err = tx.Exec(`UPDATE product_images SET is_primary = FALSE WHERE product_id = $1 AND is_primary`, image.ProductID).Error
+	if err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+	if err = tx.Raw(`UPDATE product_images SET is_primary = TRUE WHERE id = $1 RETURNING *`, image.ID).Scan(&image).Error; err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+	return image, nil
+}
+
+// ReorderProductImages numbers the product's images in the order of imageIds,
+// which has to name every image of the product once
+func (c *productDB) ReorderProductImages(ctx context.Context, productId int, imageIds []uint) ([]domain.ProductImage, error) {
+	tx := c.DB.Begin()
+	if err := tx.Exec(`SELECT id FROM product_images WHERE product_id = $1 FOR UPDATE`, productId).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	images, err := findProductImages(tx, uint(productId))
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	existing := make(map[uint]bool, len(images))
+	for _, image := range images {
+		existing[image.ID] = true
+	}
+	if len(imageIds) != len(images) {
+		tx.Rollback()
+		return nil, fmt.Errorf("the product has %d images, all of them have to be ordered", len(images))
+	}
+	for position, id := range imageIds {
+		if !existing[id] {
+			tx.Rollback()
+			return nil, fmt.Errorf("image %d is not an image of this product or is listed twice", id)
+		}
+		delete(existing, id)
+
+		err = tx.Exec(`UPDATE product_images SET position = $1 WHERE id = $2`, position+1, id).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+	}
+
+	if images, err = findProductImages(tx, uint(productId)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return images, nil
+}
+
+// DeleteProductImage removes the image and returns it so its files can be
+// deleted. When it was the primary image the next image in order takes over
+func (c *productDB) DeleteProductImage(ctx context.Context, imageId int) (domain.ProductImage, error) {
+	tx := c.DB.Begin()
+	image, err := findProductImage(tx, uint(imageId))
+	if err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+
+	if err = tx.Exec(`DELETE FROM product_images WHERE id = $1`, image.ID).Error; err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+	if image.IsPrimary {
+		query := `UPDATE product_images SET is_primary = TRUE
+		WHERE id = (SELECT id FROM product_images WHERE product_id = $1 ORDER BY position, id LIMIT 1)`
+		if err = tx.Exec(query, image.ProductID).Error; err != nil {
+			tx.Rollback()
+			return domain.ProductImage{}, err
+		}
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.ProductImage{}, err
+	}
+	return image, nil
+}
+
+func findProductImages(db *gorm.DB, productId uint) ([]domain.ProductImage, error) {
+	var images []domain.ProductImage
+	err := db.Raw(`SELECT * FROM product_images WHERE product_id = $1 ORDER BY position, id`, productId).Scan(&images).Error
+	return images, err
+}
+
+// findProductImage locks the image row for the rest of tx
+func findProductImage(tx *gorm.DB, imageId uint) (domain.ProductImage, error) {
+	var image domain.ProductImage
+	if err := tx.Raw(`SELECT * FROM product_images WHERE id = $1 FOR UPDATE`, imageId).Scan(&image).Error; err != nil {
+		return domain.ProductImage{}, err
+	}
+	if image.ID == 0 {
+		return domain.ProductImage{}, errors.New("image not found")
+	}
+	return image, nil
+}
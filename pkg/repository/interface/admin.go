@@ -10,8 +10,19 @@ import (
 type AdminRepository interface {
 	FindAdmin(ctx context.Context, admin domain.Admin) (domain.Admin, error)
 	SaveAdmin(ctx context.Context, admin domain.Admin) error
+	FindAdminByID(ctx context.Context, adminId uint) (domain.Admin, error)
+	HasSuperAdmin(ctx context.Context) (bool, error)
+	FindAdmins(ctx context.Context, pagination requests.Pagination) ([]response.Admin, error)
+	UpdateAdminRole(ctx context.Context, adminId uint, role string) (response.Admin, error)
 	FindAllUser(ctx context.Context, pagination requests.Pagination) (users []response.UserValue, err error)
 	BlockUser(body requests.BlockUser, AdminId int) error
-	UnblockUser(id int) error
+	UnblockUser(id int, adminId int) error
+	UnblockExpired(ctx context.Context) (int64, error)
+	FindBlockHistory(ctx context.Context, userId int, pagination requests.Pagination) ([]response.BlockRecord, error)
 	FindUserbyId(ctx context.Context, userID int) (domain.Users, error)
+	DeleteUser(ctx context.Context, userID int) error
+	RestoreUser(ctx context.Context, userID int) (response.UserValue, error)
+	FindDeletedUsers(ctx context.Context, pagination requests.Pagination) ([]response.UserValue, error)
+	Dashboard(ctx context.Context, dateRange requests.DateRange) (response.AdminDashboard, error)
+	SalesReport(ctx context.Context, dateRange requests.DateRange) ([]response.SalesReport, error)
 }
@@ -9,10 +9,12 @@ import (
 type CartRepo interface {
 	SaveCart(ctx context.Context, Userid int) (uint, error)
 	AddCartItem(ctx context.Context, Cartitem domain.CartItem) error
-	FindCartIDNproductId(ctx context.Context, cart_id uint, product_id uint) (cartItem domain.CartItem, err error)
+	FindCartItemByVariant(ctx context.Context, cart_id uint, variant_id uint) (cartItem domain.CartItem, err error)
+	FindVariants(ctx context.Context, productId uint) ([]domain.ProductVariant, error)
 	FindCartByUserID(ctx context.Context, UserID int) (domain.Cart, error)
 	FindProduct(ctx context.Context, id uint) (response.Product, error)
 	RemoveCartItem(ctx context.Context, CartItemid uint) error
 	AddQuantity(ctx context.Context, cartItemid uint, qty uint) error
 	FindCartlistByCartID(ctx context.Context, cartID uint) (cartitems []response.Cartres, err error)
+	RepriceCart(ctx context.Context, cartID uint) (domain.Cart, error)
 }
@@ -0,0 +1,18 @@
+package interfaces
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+)
+
+type InventoryRepo interface {
+	ReserveCart(ctx context.Context, userId int, razorpayOrderId string) ([]domain.StockReservation, error)
+	ReleaseReservations(ctx context.Context, razorpayOrderId string) error
+	ReleaseExpiredReservations(ctx context.Context) (int64, error)
+	AdjustStock(ctx context.Context, movement domain.StockMovement) (domain.StockMovement, error)
+	FindStockMovements(ctx context.Context, productId int, pagination requests.Pagination) ([]domain.StockMovement, error)
+	SetReorderThreshold(ctx context.Context, productId, threshold int) error
+	FindLowStockProducts(ctx context.Context, pagination requests.Pagination) ([]response.LowStockProduct, error)
+}
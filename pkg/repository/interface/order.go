@@ -8,12 +8,28 @@ import (
 )
 
 type OrderRepo interface {
-	OrderAll(ctx context.Context, UserID, paymentTypeId int) (domain.Orders, error)
-	CancelOrder(ctx context.Context, orderId, userId int) error
+	OrderAll(ctx context.Context, UserID, paymentTypeId int, useWallet bool) (domain.Orders, error)
+	OrderRazorpay(ctx context.Context, payment requests.RazorPayRequest) (domain.Orders, error)
+	FindOrderByRazorpayPaymentID(ctx context.Context, razorpayPaymentId string) (domain.Orders, error)
+	MarkRazorpayPaymentPaid(ctx context.Context, razorpayPaymentId string) error
+	SaveRazorpayCheckout(ctx context.Context, checkout domain.RazorpayCheckout) error
+	FindRazorpayCheckout(ctx context.Context, razorpayOrderId string) (domain.RazorpayCheckout, error)
+	ClaimRazorpayCheckoutRefund(ctx context.Context, razorpayOrderId, razorpayPaymentId, note string) (bool, error)
+	SaveRazorpayCheckoutRefund(ctx context.Context, checkout domain.RazorpayCheckout, amount float64) error
+	MarkRazorpayOrderFailed(ctx context.Context, razorpayOrderId, razorpayPaymentId, reason string) (bool, error)
+	FindRazorpayCheckouts(ctx context.Context, status string, pagination requests.Pagination) ([]domain.RazorpayCheckout, error)
+	IsWebhookEventProcessed(ctx context.Context, eventId string) (bool, error)
+	SaveWebhookEvent(ctx context.Context, eventId, event string, payload []byte) error
+	CancelOrder(ctx context.Context, orderId, userId int, refundTo string, actor domain.Actor) (domain.Refund, error)
 	Listorders(ctx context.Context) ([]response.OrderResponse, error)
 	Listorder(ctx context.Context, Orderid int, UserId int) (order domain.Orders, err error)
-	ReturnOrder(userId, orderId int) (float64, error)
+	ReturnOrder(userId, orderId int, reason, refundTo string) (float64, error)
+	CancelOrderLine(ctx context.Context, userId, orderId, lineId, qty int, refundTo string) (domain.Refund, error)
+	ReturnOrderLine(ctx context.Context, userId, orderId, lineId, qty int, reason, refundTo string) (float64, error)
+	FindOrderLines(ctx context.Context, userId, orderId int) ([]domain.OrderLine, error)
 	AdminListorders(ctx context.Context, pagination requests.Pagination) (orders []domain.Orders, err error)
 	ListofOrderStatuses(ctx context.Context) (status []domain.OrderStatus, err error)
-	UpdateOrderStatus(ctx context.Context, update requests.Update) error
+	UpdateOrderStatus(ctx context.Context, update requests.Update, adminId int) error
+	FindOrderByID(ctx context.Context, orderId int) (domain.Orders, error)
+	FindOrderStatusHistory(ctx context.Context, orderId int) ([]response.OrderTimeline, error)
 }
@@ -4,20 +4,33 @@ import (
 	"context"
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
 )
 
 type ProductRepo interface {
 	Addcategory(ctx context.Context, req requests.Category) (response.Category, error)
 	UpdateCategory(ctx context.Context, category requests.Category, id int) (response.Category, error)
-	DeleteCategory(ctx context.Context, Id int) error
+	DeleteCategory(ctx context.Context, Id int, reassignTo int) error
 	Listallcategory(ctx context.Context) ([]response.Category, error)
 	ShowCatagory(ctx context.Context, Id int) (response.Category, error)
-	SaveProduct(ctx context.Context, product requests.Product) (response.Product, error)
-	UpdateProduct(ctx context.Context, id int, product requests.Product) (response.Product, error)
+	RestoreCategory(ctx context.Context, Id int) (response.Category, error)
+	FindDeletedCategories(ctx context.Context) ([]response.Category, error)
+	SaveProduct(ctx context.Context, product requests.Product, adminId int) (response.Product, error)
+	UpdateProduct(ctx context.Context, id int, product requests.Product, adminId int) (response.Product, error)
 	DeleteProduct(ctx context.Context, id int) error
-	ViewAllProducts(ctx context.Context, pagination requests.Pagination) (products []response.Product, err error)
+	RestoreProduct(ctx context.Context, id int) error
+	FindDeletedProducts(ctx context.Context, pagination requests.Pagination) ([]response.Product, error)
+	ViewAllProducts(ctx context.Context, filter requests.ProductFilter) (response.ProductList, error)
 	ViewProduct(ctx context.Context, id int) (response.Product, error)
-	SearchProducts(ctx context.Context, query string) ([]requests.Product, error)
-	FilterByPriceRange(min, max float64) ([]requests.Product, error)
-	GetMinMaxPrice() (float64, float64, error)
+	SearchProducts(ctx context.Context, search requests.ProductSearch) (response.ProductSearch, error)
+	FindVariants(ctx context.Context, productId int) ([]domain.ProductVariant, error)
+	SaveVariant(ctx context.Context, variant domain.ProductVariant, adminId int) (domain.ProductVariant, error)
+	UpdateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error)
+	DeleteVariant(ctx context.Context, variantId int) error
+	SaveProductImage(ctx context.Context, image domain.ProductImage) (domain.ProductImage, error)
+	FindProductImages(ctx context.Context, productId int) ([]domain.ProductImage, error)
+	FindProductImage(ctx context.Context, imageId int) (domain.ProductImage, error)
+	SetPrimaryImage(ctx context.Context, imageId int) (domain.ProductImage, error)
+	ReorderProductImages(ctx context.Context, productId int, imageIds []uint) ([]domain.ProductImage, error)
+	DeleteProductImage(ctx context.Context, imageId int) (domain.ProductImage, error)
 }
@@ -0,0 +1,16 @@
+package interfaces
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/domain"
+)
+
+type RefundRepo interface {
+	FindRefunds(ctx context.Context, status string, pagination requests.Pagination) ([]domain.Refund, error)
+	FindRefundByID(ctx context.Context, refundId uint) (domain.Refund, error)
+	ApproveRefund(ctx context.Context, refundId uint, amount float64, adminId uint) (domain.Refund, error)
+	DenyRefund(ctx context.Context, refundId uint, reason string, adminId uint) error
+	UpdateRefundStatus(ctx context.Context, refundId uint, status, gatewayRefundId string) error
+	MarkGatewayRefundProcessed(ctx context.Context, gatewayRefundId string) error
+}
@@ -0,0 +1,22 @@
+package interfaces
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+)
+
+type ReviewRepo interface {
+	IsVerifiedBuyer(ctx context.Context, userId, productId uint) (bool, error)
+	FindReview(ctx context.Context, reviewId uint) (domain.Review, error)
+	FindUserReview(ctx context.Context, userId, productId uint) (domain.Review, error)
+	SaveReview(ctx context.Context, review domain.Review) (domain.Review, error)
+	UpdateReview(ctx context.Context, review domain.Review) (domain.Review, error)
+	DeleteReview(ctx context.Context, reviewId uint) error
+	FindProductReviews(ctx context.Context, productId uint, sort string, pagination requests.Pagination) ([]response.Review, error)
+	FindReviews(ctx context.Context, status string, pagination requests.Pagination) ([]response.Review, error)
+	SetReviewStatus(ctx context.Context, reviewId uint, status, reason string, adminId uint) (domain.Review, error)
+	AddHelpfulVote(ctx context.Context, reviewId, userId uint) error
+	RemoveHelpfulVote(ctx context.Context, reviewId, userId uint) error
+}
@@ -0,0 +1,17 @@
+package interfaces
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+)
+
+type TokenRepo interface {
+	CreateSession(ctx context.Context, session domain.Session, refresh domain.RefreshToken) error
+	RotateRefreshToken(ctx context.Context, tokenHash, audience, accessJti string, next domain.RefreshToken) (domain.RefreshToken, error)
+	IsSessionActive(ctx context.Context, sessionId, accessJti string) (bool, error)
+	FindSessions(ctx context.Context, subjectId uint, audience string) ([]response.Session, error)
+	RevokeSessionByToken(ctx context.Context, tokenHash string) error
+	RevokeSession(ctx context.Context, subjectId uint, audience, sessionId string) error
+	RevokeSessions(ctx context.Context, subjectId uint, audience, except string) error
+}
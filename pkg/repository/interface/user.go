@@ -12,6 +12,11 @@ type UserRepository interface {
 	UserSignup(ctx context.Context, user requests.Usersign) (response.UserValue, error)
 	UserLogin(ctx context.Context, Email string) (domain.Users, error)
 	OtpLogin(mbnum string) (int, error)
+	FindActiveBlock(ctx context.Context, userId uint) (domain.UserStatus, error)
+	FindUserByID(ctx context.Context, userId uint) (domain.Users, error)
+	UpdatePassword(ctx context.Context, userId uint, passwordHash string) error
+	SavePasswordReset(ctx context.Context, reset domain.PasswordReset) error
+	ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uint, error)
 	AddAdress(ctx context.Context, UserID int, address requests.AddressReq) (domain.Address, error)
 	UpdateAdress(ctx context.Context, UserID int, address requests.AddressReq) (domain.Address, error)
 	VeiwAdress(ctx context.Context, UserID int) (domain.Address, error)
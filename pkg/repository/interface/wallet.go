@@ -0,0 +1,12 @@
+package interfaces
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/domain"
+)
+
+type WalletRepo interface {
+	FindWalletByUserID(ctx context.Context, userID uint) (domain.Wallet, error)
+	FindTransactionsByUserID(ctx context.Context, userID uint, pagination requests.Pagination) ([]domain.Transaction, error)
+}
@@ -0,0 +1,247 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type inventoryDB struct {
+	DB             *gorm.DB
+	reservationTTL time.Duration
+}
+
+func NewInventoryRepository(DB *gorm.DB, reservationTTL time.Duration) interfaces.InventoryRepo {
+	return &inventoryDB{
+		DB:             DB,
+		reservationTTL: reservationTTL,
+	}
+}
+
+// ReserveCart takes the stock of the user's cart off the products and holds it
+// for the razorpay order until it is paid or the reservation expires
+func (c *inventoryDB) ReserveCart(ctx context.Context, userId int, razorpayOrderId string) ([]domain.StockReservation, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+
+	// a new checkout abandons the user's earlier unpaid ones, their holds go back
+	if _, err := releaseReservedStock(tx, domain.ReservationReleased, 0, `user_id = $5`, userId); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	var items []struct {
+		ProductID uint
+		VariantID uint
+		Qty       int
+	}
+	// variants are locked in id order so two checkouts can't deadlock each other
+	findItems := `SELECT ci.product_id, ci.variant_id, SUM(ci.qty) AS qty FROM cart_items ci
+	JOIN carts c ON c.id = ci.cart_id
+	WHERE c.user_id = $1
+	GROUP BY ci.product_id, ci.variant_id
+	ORDER BY ci.variant_id`
+	if err := tx.Raw(findItems, userId).Scan(&items).Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if len(items) == 0 {
+		tx.Rollback()
+		return nil, fmt.Errorf("there is no products in your list")
+	}
+
+	reservations := make([]domain.StockReservation, 0, len(items))
+	for _, item := range items {
+		_, err := moveStock(tx, domain.StockMovement{
+			VariantID: item.VariantID,
+			QtyChange: -item.Qty,
+			Reason:    domain.StockReserve,
+			Note:      "razorpay order " + razorpayOrderId,
+		})
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		var reservation domain.StockReservation
+		insert := `INSERT INTO stock_reservations (user_id, product_id, variant_id, qty, razorpay_order_id, status, expires_at, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, NOW() + $7 * INTERVAL '1 second', NOW(), NOW())
+		RETURNING *`
+		err = tx.Raw(insert, userId, item.ProductID, item.VariantID, item.Qty, razorpayOrderId, domain.ReservationHeld, c.reservationTTL.Seconds()).
+			Scan(&reservation).Error
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		reservations = append(reservations, reservation)
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	return reservations, nil
+}
+
+// ReleaseReservations puts the stock held for a razorpay order back on the products
+func (c *inventoryDB) ReleaseReservations(ctx context.Context, razorpayOrderId string) error {
+	_, err := releaseReservedStock(c.DB.WithContext(ctx), domain.ReservationReleased, 0, `razorpay_order_id = $5`, razorpayOrderId)
+	return err
+}
+
+// ReleaseExpiredReservations gives back the stock of checkouts that were never
+// paid, it returns how many variants got stock back
+func (c *inventoryDB) ReleaseExpiredReservations(ctx context.Context) (int64, error) {
+	return releaseReservedStock(c.DB.WithContext(ctx), domain.ReservationReleased, 0, `expires_at < NOW()`)
+}
+
+// AdjustStock applies an admin's correction or restock and records it
+func (c *inventoryDB) AdjustStock(ctx context.Context, movement domain.StockMovement) (domain.StockMovement, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	movement, err := moveStock(tx, movement)
+	if err != nil {
+		tx.Rollback()
+		return domain.StockMovement{}, err
+	}
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.StockMovement{}, err
+	}
+	return movement, nil
+}
+
+// FindStockMovements is the product's stock ledger, newest first
+func (c *inventoryDB) FindStockMovements(ctx context.Context, productId int, pagination requests.Pagination) ([]domain.StockMovement, error) {
+	var movements []domain.StockMovement
+
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	query := `SELECT * FROM stock_movements WHERE product_id = $1
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2 OFFSET $3`
+	if err := c.DB.WithContext(ctx).Raw(query, productId, limit, offset).Scan(&movements).Error; err != nil {
+		return nil, errors.New("failed to get stock history")
+	}
+	return movements, nil
+}
+
+func (c *inventoryDB) SetReorderThreshold(ctx context.Context, productId, threshold int) error {
+	result := c.DB.WithContext(ctx).Exec(`UPDATE products SET reorder_threshold = $1 WHERE id = $2`, threshold, productId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no product found with this id")
+	}
+	return nil
+}
+
+// FindLowStockProducts lists products at or below their reorder threshold,
+// the emptiest first. Products without a threshold are never reported
+func (c *inventoryDB) FindLowStockProducts(ctx context.Context, pagination requests.Pagination) ([]response.LowStockProduct, error) {
+	var products []response.LowStockProduct
+
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	query := `SELECT p.id AS product_id, p.product_name, p.brand, c.category_name, p.qty_in_stock, p.reorder_threshold,
+		COALESCE((SELECT SUM(r.qty) FROM stock_reservations r WHERE r.product_id = p.id AND r.status = $1), 0) AS reserved_qty
+	FROM products p
+	LEFT JOIN categories c ON c.id = p.category_id
+	WHERE p.deleted_at IS NULL AND p.reorder_threshold > 0 AND p.qty_in_stock <= p.reorder_threshold
+	ORDER BY p.qty_in_stock - p.reorder_threshold, p.id
+	LIMIT $2 OFFSET $3`
+	if err := c.DB.WithContext(ctx).Raw(query, domain.ReservationHeld, limit, offset).Scan(&products).Error; err != nil {
+		return nil, errors.New("failed to get low stock products")
+	}
+	return products, nil
+}
+
+// moveStock changes the variant's stock by movement.QtyChange inside tx and
+// records it in the ledger. A decrement only happens while enough stock is
+// left, so stock never goes negative even when checkouts race for the last items.
+// The product's total stock moves with it
+func moveStock(tx *gorm.DB, movement domain.StockMovement) (domain.StockMovement, error) {
+	var moved []struct {
+		ProductID  uint
+		QtyInStock int
+	}
+	update := `UPDATE product_variants SET qty_in_stock = qty_in_stock + $1, updated_at = NOW()
+	WHERE id = $2 AND qty_in_stock + $1 >= 0
+	RETURNING product_id, qty_in_stock`
+	if err := tx.Raw(update, movement.QtyChange, movement.VariantID).Scan(&moved).Error; err != nil {
+		return domain.StockMovement{}, err
+	}
+	if len(moved) == 0 {
+		var variant struct {
+			ProductName string
+			SKU         string `gorm:"column:sku"`
+			QtyInStock  int
+		}
+		findVariant := `SELECT p.product_name, v.sku, v.qty_in_stock FROM product_variants v
+		JOIN products p ON p.id = v.product_id
+		WHERE v.id = $1`
+		if err := tx.Raw(findVariant, movement.VariantID).Scan(&variant).Error; err != nil {
+			return domain.StockMovement{}, err
+		}
+		if variant.SKU == "" {
+			return domain.StockMovement{}, fmt.Errorf("product variant %d no longer exists", movement.VariantID)
+		}
+		return domain.StockMovement{}, fmt.Errorf("out of stock: only %d of %s (%s) left", variant.QtyInStock, variant.ProductName, variant.SKU)
+	}
+
+	updateProduct := `UPDATE products SET qty_in_stock = qty_in_stock + $1 WHERE id = $2`
+	if err := tx.Exec(updateProduct, movement.QtyChange, moved[0].ProductID).Error; err != nil {
+		return domain.StockMovement{}, err
+	}
+
+	movement.ProductID = moved[0].ProductID
+	movement.QtyAfter = moved[0].QtyInStock
+	return saveStockMovement(tx, movement)
+}
+
+func saveStockMovement(tx *gorm.DB, movement domain.StockMovement) (domain.StockMovement, error) {
+	var saved domain.StockMovement
+	insert := `INSERT INTO stock_movements (product_id, variant_id, qty_change, qty_after, reason, order_id, admin_id, note, created_at)
+	VALUES ($1, NULLIF($2::bigint, 0), $3, $4, $5, NULLIF($6::bigint, 0), NULLIF($7::bigint, 0), NULLIF($8, ''), NOW())
+	RETURNING *`
+	err := tx.Raw(insert, movement.ProductID, movement.VariantID, movement.QtyChange, movement.QtyAfter, movement.Reason,
+		movement.OrderID, movement.AdminID, movement.Note).Scan(&saved).Error
+	return saved, err
+}
+
+// releaseReservedStock moves the held reservations matching where to status,
+// adds their qty back to the variants and products and records the release in
+// the ledger, all in one statement. orderId is the order that consumed them,
+// zero if none. where can use $5 onwards for args
+func releaseReservedStock(tx *gorm.DB, status string, orderId uint, where string, args ...interface{}) (int64, error) {
+	query := `WITH released AS (
+		UPDATE stock_reservations SET status = $1, updated_at = NOW()
+		WHERE status = $2 AND ` + where + `
+		RETURNING product_id, variant_id, qty, razorpay_order_id
+	), restocked AS (
+		UPDATE product_variants v SET qty_in_stock = v.qty_in_stock + r.qty, updated_at = NOW()
+		FROM (
+			SELECT variant_id, SUM(qty) AS qty, string_agg(DISTINCT razorpay_order_id, ', ') AS razorpay_order_ids
+			FROM released GROUP BY variant_id
+		) r
+		WHERE v.id = r.variant_id
+		RETURNING v.id, v.product_id, r.qty, v.qty_in_stock, r.razorpay_order_ids
+	), products_restocked AS (
+		UPDATE products p SET qty_in_stock = p.qty_in_stock + r.qty
+		FROM (SELECT product_id, SUM(qty) AS qty FROM released GROUP BY product_id) r
+		WHERE p.id = r.product_id
+	)
+	INSERT INTO stock_movements (product_id, variant_id, qty_change, qty_after, reason, order_id, note, created_at)
+	SELECT product_id, id, qty, qty_in_stock, $3, NULLIF($4::bigint, 0), 'razorpay order ' || razorpay_order_ids, NOW()
+	FROM restocked`
+	params := append([]interface{}{status, domain.ReservationHeld, domain.StockRelease, orderId}, args...)
+	result := tx.Exec(query, params...)
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,249 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/db"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/pricing"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// these tests need a postgres they can create schemas in, they are skipped
+// unless TEST_DATABASE_DSN is set, e.g.
+// TEST_DATABASE_DSN="host=localhost user=postgres dbname=ecommerce_test port=5432 password=postgres" go test ./pkg/repository/
+const testDatabaseDSN = "TEST_DATABASE_DSN"
+
+// openTestDatabase migrates a schema of its own and drops it when the test ends
+func openTestDatabase(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv(testDatabaseDSN)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseDSN)
+	}
+
+	schema := fmt.Sprintf("test_%d", time.Now().UnixNano())
+	admin, err := gorm.Open(postgres.Open(dsn), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = admin.Exec(`CREATE SCHEMA ` + schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		admin.Exec(`DROP SCHEMA ` + schema + ` CASCADE`)
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	// every pooled connection gets the search path, the parallel checkouts run on many
+	if strings.Contains(dsn, "://") {
+		separator := "?"
+		if strings.Contains(dsn, "?") {
+			separator = "&"
+		}
+		dsn += separator + "search_path=" + schema + ",public"
+	} else {
+		dsn += " search_path=" + schema + ",public"
+	}
+	DB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		if sqlDB, err := DB.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+	if _, err = db.MigrateUp(DB); err != nil {
+		t.Fatal(err)
+	}
+	return DB
+}
+
+// seedLastUnits puts a product with stock units left and buyers users who each
+// have one of it in their cart, it returns the variant and the buyers' ids
+func seedLastUnits(t *testing.T, DB *gorm.DB, stock, buyers int) (uint, []int) {
+	t.Helper()
+	var productId, variantId uint
+	err := DB.Raw(`INSERT INTO products (product_name, brand, prize, qty_in_stock) VALUES ('last units', 'test', 100, $1) RETURNING id`, stock).
+		Scan(&productId).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = DB.Raw(`INSERT INTO product_variants (product_id, sku, price, qty_in_stock) VALUES ($1, 'LAST-1', 100, $2) RETURNING id`, productId, stock).
+		Scan(&variantId).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	userIds := make([]int, 0, buyers)
+	for i := 0; i < buyers; i++ {
+		var userId, cartId int
+		err = DB.Raw(`INSERT INTO users (name, email, mobile, password) VALUES ($1, $2, $3, 'x') RETURNING id`,
+			fmt.Sprintf("buyer %d", i), fmt.Sprintf("buyer%d@example.com", i), fmt.Sprintf("90000%05d", i)).Scan(&userId).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = DB.Exec(`INSERT INTO addresses (user_id, house_number, street, city, district, pincode, landmark)
+		VALUES ($1, '1', 'street', 'city', 'district', '600001', 'landmark')`, userId).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = DB.Raw(`INSERT INTO carts (user_id) VALUES ($1) RETURNING id`, userId).Scan(&cartId).Error; err != nil {
+			t.Fatal(err)
+		}
+		err = DB.Exec(`INSERT INTO cart_items (cart_id, product_id, variant_id, qty) VALUES ($1, $2, $3, 1)`, cartId, productId, variantId).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+		userIds = append(userIds, userId)
+	}
+	return variantId, userIds
+}
+
+// checkoutAtOnce runs checkout for every user at the same moment and returns
+// how many went through
+func checkoutAtOnce(t *testing.T, userIds []int, checkout func(userId int) error) int {
+	t.Helper()
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		succeeded int
+		start     = make(chan struct{})
+	)
+	for _, userId := range userIds {
+		wg.Add(1)
+		go func(userId int) {
+			defer wg.Done()
+			<-start
+			err := checkout(userId)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				succeeded++
+				return
+			}
+			if !strings.Contains(err.Error(), "out of stock") {
+				t.Errorf("user %d: expected out of stock, got %v", userId, err)
+			}
+		}(userId)
+	}
+	close(start)
+	wg.Wait()
+	return succeeded
+}
+
+func assertStockLeft(t *testing.T, DB *gorm.DB, variantId uint, want int) {
+	t.Helper()
+	var stock struct {
+		Variant int
+		Product int
+	}
+	findStock := `SELECT v.qty_in_stock AS variant, p.qty_in_stock AS product FROM product_variants v
+	JOIN products p ON p.id = v.product_id WHERE v.id = $1`
+	if err := DB.Raw(findStock, variantId).Scan(&stock).Error; err != nil {
+		t.Fatal(err)
+	}
+	if stock.Variant != want || stock.Product != want {
+		t.Fatalf("expected %d left, the variant has %d and the product %d", want, stock.Variant, stock.Product)
+	}
+
+	var negative int64
+	if err := DB.Raw(`SELECT COUNT(*) FROM stock_movements WHERE qty_after < 0`).Scan(&negative).Error; err != nil {
+		t.Fatal(err)
+	}
+	if negative != 0 {
+		t.Fatalf("%d stock movements went below zero", negative)
+	}
+}
+
+func TestReserveCartHoldsOnlyTheLastUnits(t *testing.T) {
+	DB := openTestDatabase(t)
+	variantId, userIds := seedLastUnits(t, DB, 3, 12)
+	inventory := NewInventoryRepository(DB, 15*time.Minute)
+
+	succeeded := checkoutAtOnce(t, userIds, func(userId int) error {
+		_, err := inventory.ReserveCart(context.Background(), userId, fmt.Sprintf("order_%d", userId))
+		return err
+	})
+	if succeeded != 3 {
+		t.Fatalf("expected 3 checkouts to hold stock, %d did", succeeded)
+	}
+	assertStockLeft(t, DB, variantId, 0)
+
+	var held []string
+	if err := DB.Raw(`SELECT razorpay_order_id FROM stock_reservations WHERE status = $1`, domain.ReservationHeld).Scan(&held).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := inventory.ReleaseReservations(context.Background(), held[0]); err != nil {
+		t.Fatal(err)
+	}
+	assertStockLeft(t, DB, variantId, 1)
+}
+
+func TestOrderAllSellsOnlyTheLastUnits(t *testing.T) {
+	DB := openTestDatabase(t)
+	variantId, userIds := seedLastUnits(t, DB, 3, 12)
+	orders := NewOrderRepository(DB, &pricing.Pricer{})
+
+	succeeded := checkoutAtOnce(t, userIds, func(userId int) error {
+		_, err := orders.OrderAll(context.Background(), userId, 1, false)
+		return err
+	})
+	if succeeded != 3 {
+		t.Fatalf("expected 3 orders to be placed, %d were", succeeded)
+	}
+	assertStockLeft(t, DB, variantId, 0)
+}
+
+func TestStockMovementsFollowTheStock(t *testing.T) {
+	DB := openTestDatabase(t)
+	variantId, userIds := seedLastUnits(t, DB, 5, 1)
+	inventory := NewInventoryRepository(DB, 15*time.Minute)
+	ctx := context.Background()
+
+	if _, err := inventory.ReserveCart(ctx, userIds[0], "order_1"); err != nil {
+		t.Fatal(err)
+	}
+	if err := inventory.ReleaseReservations(ctx, "order_1"); err != nil {
+		t.Fatal(err)
+	}
+	restock, err := inventory.AdjustStock(ctx, domain.StockMovement{VariantID: variantId, QtyChange: 3, Reason: domain.StockRestock, AdminID: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restock.QtyAfter != 8 {
+		t.Fatalf("expected 8 after the restock, got %d", restock.QtyAfter)
+	}
+	if _, err = inventory.AdjustStock(ctx, domain.StockMovement{VariantID: variantId, QtyChange: -9, Reason: domain.StockAdjustment}); err == nil {
+		t.Fatal("expected an adjustment below zero to be refused")
+	}
+	assertStockLeft(t, DB, variantId, 8)
+
+	var productId int
+	if err = DB.Raw(`SELECT product_id FROM product_variants WHERE id = $1`, variantId).Scan(&productId).Error; err != nil {
+		t.Fatal(err)
+	}
+	movements, err := inventory.FindStockMovements(ctx, productId, requests.Pagination{Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var reasons []string
+	for _, movement := range movements {
+		reasons = append(reasons, fmt.Sprintf("%s %+d = %d", movement.Reason, movement.QtyChange, movement.QtyAfter))
+	}
+	want := []string{"restock +3 = 8", "release +1 = 5", "reserve -1 = 4"}
+	if strings.Join(reasons, ", ") != strings.Join(want, ", ") {
+		t.Fatalf("expected the ledger %v, got %v", want, reasons)
+	}
+}
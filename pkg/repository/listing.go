@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// productSort is a listing order, products are ordered by expr and then by id
+// in the same direction so every product has a fixed place to resume from
+type productSort struct {
+	expr string
+	// cast is the sql type a cursor's value is read back as, the value has to
+	// come back from text unchanged or the next page skips or repeats rows
+	cast string
+	desc bool
+}
+
+// relevance ranks are floats, rounded to numeric they print and parse back exactly
+var productSorts = map[string]productSort{
+	"newest":     {expr: "p.created_at", cast: "timestamptz", desc: true},
+	"price_asc":  {expr: "p.prize", cast: "bigint"},
+	"price_desc": {expr: "p.prize", cast: "bigint", desc: true},
+	"popularity": {expr: "COALESCE(s.sold, 0)", cast: "bigint", desc: true},
+	"rating":     {expr: "p.rating_avg", cast: "numeric", desc: true},
+	"relevance":  {expr: "ROUND(ts_rank_cd(p.search_vector, websearch_to_tsquery('english', @q))::numeric, 6)", cast: "numeric", desc: true},
+}
+
+// productCursor is where the next page starts, the sort value and id of the
+// last product on the page
+type productCursor struct {
+	Sort  string `json:"s"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+func encodeProductCursor(cursor productCursor) string {
+	body, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(body)
+}
+
+func decodeProductCursor(text, sort string) (productCursor, error) {
+	var cursor productCursor
+	body, err := base64.RawURLEncoding.DecodeString(text)
+	if err != nil || json.Unmarshal(body, &cursor) != nil || cursor.ID == 0 {
+		return cursor, errors.New("invalid cursor")
+	}
+	if cursor.Sort != sort {
+		return cursor, errors.New("the cursor belongs to a different sort order")
+	}
+	return cursor, nil
+}
+
+// ViewAllProducts lists the products matching every filter that is set in
+// the asked order. Popularity is the quantity still ordered after
+// cancellations and returns
+func (c *productDB) ViewAllProducts(ctx context.Context, filter requests.ProductFilter) (response.ProductList, error) {
+	list := response.ProductList{PerPage: filter.PerPage}
+	sort, ok := productSorts[filter.Sort]
+	if !ok {
+		return list, fmt.Errorf("unknown sort %q", filter.Sort)
+	}
+	if filter.Sort == "relevance" && filter.Query == "" {
+		return list, errors.New("sorting by relevance needs search text in q")
+	}
+
+	args := map[string]interface{}{}
+	filters := []string{`p.deleted_at IS NULL`}
+	if len(filter.CategoryIDs) > 0 {
+		filters = append(filters, `p.category_id IN (`+categorySubtree+`)`)
+		args["categories"] = filter.CategoryIDs
+	}
+	if len(filter.Brands) > 0 {
+		brands := make([]string, len(filter.Brands))
+		for i, brand := range filter.Brands {
+			brands[i] = strings.ToLower(brand)
+		}
+		filters = append(filters, `LOWER(p.brand) IN @brands`)
+		args["brands"] = brands
+	}
+	if filter.MinPrice != nil {
+		filters = append(filters, `p.prize >= @min_price`)
+		args["min_price"] = *filter.MinPrice
+	}
+	if filter.MaxPrice != nil {
+		filters = append(filters, `p.prize <= @max_price`)
+		args["max_price"] = *filter.MaxPrice
+	}
+	if filter.InStock {
+		filters = append(filters, `p.qty_in_stock > 0`)
+	}
+	if filter.Query != "" {
+		filters = append(filters, `p.search_vector @@ websearch_to_tsquery('english', @q)`)
+		args["q"] = filter.Query
+	}
+
+	where := strings.Join(filters, " AND ")
+	if err := c.DB.Raw(`SELECT COUNT(*) FROM products p WHERE `+where, args).Scan(&list.Total).Error; err != nil {
+		return list, errors.New("failed to count products")
+	}
+
+	direction, after := "ASC", ">"
+	if sort.desc {
+		direction, after = "DESC", "<"
+	}
+	page := where
+	if filter.Cursor != "" {
+		cursor, err := decodeProductCursor(filter.Cursor, filter.Sort)
+		if err != nil {
+			return list, err
+		}
+		page += fmt.Sprintf(` AND (%s, p.id) %s (CAST(@cursor_value AS %s), @cursor_id)`, sort.expr, after, sort.cast)
+		args["cursor_value"] = cursor.Value
+		args["cursor_id"] = cursor.ID
+	} else {
+		list.Page = filter.Page
+		args["offset"] = (filter.Page - 1) * filter.PerPage
+	}
+	// one extra row tells whether there is a next page
+	args["limit"] = filter.PerPage + 1
+
+	query := `SELECT p.id, p.product_name AS name, p.description, p.brand, p.prize, p.qty_in_stock,
+		p.category_id, c.category_name, pi.thumbnail_key, p.rating_avg AS rating, p.rating_count AS review_count,
+		` + sort.expr + `::text AS sort_value
+	FROM products p
+	LEFT JOIN categories c ON c.id = p.category_id
+	LEFT JOIN product_images pi ON pi.product_id = p.id AND pi.is_primary
+	LEFT JOIN (
+		SELECT product_id, SUM(qty - cancelled_qty - returned_qty) AS sold
+		FROM order_lines GROUP BY product_id
+	) s ON s.product_id = p.id
+	WHERE ` + page + `
+	ORDER BY ` + sort.expr + ` ` + direction + `, p.id ` + direction + `
+	LIMIT @limit`
+	if filter.Cursor == "" {
+		query += ` OFFSET @offset`
+	}
+
+	var rows []struct {
+		response.Product
+		SortValue string
+	}
+	if err := c.DB.Raw(query, args).Scan(&rows).Error; err != nil {
+		return list, errors.New("failed to get products from database")
+	}
+
+	if uint(len(rows)) > filter.PerPage {
+		rows = rows[:filter.PerPage]
+		last := rows[len(rows)-1]
+		list.NextCursor = encodeProductCursor(productCursor{Sort: filter.Sort, Value: last.SortValue, ID: uint(last.Id)})
+	}
+	list.Products = make([]response.Product, len(rows))
+	for i, row := range rows {
+		list.Products[i] = row.Product
+	}
+	return list, nil
+}
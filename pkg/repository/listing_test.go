@@ -0,0 +1,93 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestProductCursorRoundTrip(t *testing.T) {
+	cursor := productCursor{Sort: "relevance", Value: "0.123457", ID: 42}
+
+	decoded, err := decodeProductCursor(encodeProductCursor(cursor), "relevance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decoded != cursor {
+		t.Fatalf("expected %+v back, got %+v", cursor, decoded)
+	}
+}
+
+func TestDecodeProductCursorRefusesBadCursors(t *testing.T) {
+	tests := []struct {
+		name   string
+		cursor string
+		sort   string
+		want   string
+	}{
+		{name: "not base64", cursor: "%%%", sort: "newest", want: "invalid cursor"},
+		{name: "not json", cursor: base64.RawURLEncoding.EncodeToString([]byte("newest|42")), sort: "newest", want: "invalid cursor"},
+		{name: "no product id", cursor: encodeProductCursor(productCursor{Sort: "newest", Value: "x"}), sort: "newest", want: "invalid cursor"},
+		{name: "another sort", cursor: encodeProductCursor(productCursor{Sort: "price_asc", Value: "500", ID: 3}), sort: "price_desc", want: "different sort order"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := decodeProductCursor(tt.cursor, tt.sort)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %q, got %v", tt.want, err)
+			}
+		})
+	}
+}
+
+func TestViewAllProductsChecksTheSortFirst(t *testing.T) {
+	// both are refused before the database is used
+	products := &productDB{}
+	if _, err := products.ViewAllProducts(context.Background(), requests.ProductFilter{Sort: "cheapest", Page: 1, PerPage: 10}); err == nil {
+		t.Fatal("expected an unknown sort to be refused")
+	}
+	if _, err := products.ViewAllProducts(context.Background(), requests.ProductFilter{Sort: "relevance", Page: 1, PerPage: 10}); err == nil {
+		t.Fatal("expected relevance without search text to be refused")
+	}
+}
+
+func TestProductCursorWalksEveryProductOnce(t *testing.T) {
+	DB := openTestDatabase(t)
+	seedSearchProducts(t, DB)
+	products := &productDB{DB: DB}
+
+	for _, filter := range []requests.ProductFilter{
+		{Sort: "price_asc"},
+		{Sort: "newest"},
+		{Sort: "relevance", Query: "steel"},
+	} {
+		want, err := products.ViewAllProducts(context.Background(), requests.ProductFilter{Sort: filter.Sort, Query: filter.Query, Page: 1, PerPage: 10})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		seen := map[int]bool{}
+		filter.Page, filter.PerPage = 1, 2
+		for {
+			list, err := products.ViewAllProducts(context.Background(), filter)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, product := range list.Products {
+				if seen[product.Id] {
+					t.Fatalf("%s: product %d came back twice", filter.Sort, product.Id)
+				}
+				seen[product.Id] = true
+			}
+			if list.NextCursor == "" {
+				break
+			}
+			filter.Cursor = list.NextCursor
+		}
+		if len(seen) != want.Total {
+			t.Fatalf("%s: expected %d products, walked %d", filter.Sort, want.Total, len(seen))
+		}
+	}
+}
@@ -5,24 +5,39 @@ import (
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
 	"ecommerce/pkg/domain"
+	"ecommerce/pkg/pricing"
 	interfaces "ecommerce/pkg/repository/interface"
 	"errors"
 	"fmt"
+	"math"
 
 	"gorm.io/gorm"
 )
 
 type OrderDB struct {
-	DB *gorm.DB
+	DB     *gorm.DB
+	pricer *pricing.Pricer
 }
 
-func NewOrderRepository(DB *gorm.DB) interfaces.OrderRepo {
+func NewOrderRepository(DB *gorm.DB, pricer *pricing.Pricer) interfaces.OrderRepo {
 	return &OrderDB{
-		DB: DB,
+		DB:     DB,
+		pricer: pricer,
 	}
 }
 
-func (c *OrderDB) OrderAll(ctx context.Context, UserID, paymentMethodId int) (domain.Orders, error) {
+func (c *OrderDB) OrderAll(ctx context.Context, UserID, paymentMethodId int, useWallet bool) (domain.Orders, error) {
+	return c.placeOrder(ctx, UserID, paymentMethodId, useWallet, requests.RazorPayRequest{})
+}
+
+// OrderRazorpay places the order for an already verified razorpay payment, the
+// payment is stored as paid together with the order so both happen or neither does.
+// The wallet pays what was decided when the checkout started
+func (c *OrderDB) OrderRazorpay(ctx context.Context, payment requests.RazorPayRequest) (domain.Orders, error) {
+	return c.placeOrder(ctx, payment.UserID, 2, false, payment)
+}
+
+func (c *OrderDB) placeOrder(ctx context.Context, UserID, paymentMethodId int, useWallet bool, payment requests.RazorPayRequest) (domain.Orders, error) {
 	tx := c.DB.Begin()
 	var cart domain.Cart
 	findquery := `SELECT *FROM carts WHERE user_id=?`
@@ -31,14 +46,20 @@ func (c *OrderDB) OrderAll(ctx context.Context, UserID, paymentMethodId int) (do
 		tx.Rollback()
 		return domain.Orders{}, err
 	}
-
-	if cart.Total_price == 0 {
+	if cart.Id == 0 {
 		tx.Rollback()
 		return domain.Orders{}, fmt.Errorf("please makesure you add those items to cart")
 	}
+
+	// charge what the items cost now, not what they cost when they were added
+	cart, err = repriceCart(tx, c.pricer, cart.Id)
+	if err != nil {
+		tx.Rollback()
+		return domain.Orders{}, err
+	}
 	if cart.Total_price == 0 {
 		tx.Rollback()
-		return domain.Orders{}, fmt.Errorf("cart 2")
+		return domain.Orders{}, fmt.Errorf("please makesure you add those items to cart")
 	}
 	// -------AddressFetch
 	var address domain.Address
@@ -53,51 +74,129 @@ func (c *OrderDB) OrderAll(ctx context.Context, UserID, paymentMethodId int) (do
 		return domain.Orders{}, fmt.Errorf("address er")
 	}
 
+	// -------Wallet, payment method 3 pays everything from the wallet
+	var walletAmount float64
+	if payment.RazorPayOrderId != "" {
+		var checkout domain.RazorpayCheckout
+		findCheckout := `SELECT * FROM razorpay_checkouts WHERE razorpay_order_id=$1 FOR UPDATE`
+		err = tx.Raw(findCheckout, payment.RazorPayOrderId).Scan(&checkout).Error
+		if err != nil {
+			tx.Rollback()
+			return domain.Orders{}, err
+		}
+		if checkout.RazorpayOrderID == "" || checkout.UserID != uint(UserID) {
+			tx.Rollback()
+			return domain.Orders{}, fmt.Errorf("no checkout found for this razorpay order")
+		}
+		if checkout.Status != domain.CheckoutPending && checkout.Status != domain.CheckoutFailed {
+			tx.Rollback()
+			return domain.Orders{}, fmt.Errorf("the checkout is already %s", checkout.Status)
+		}
+		walletAmount = checkout.WalletAmount
+	} else if paymentMethodId == 3 || useWallet {
+		var balance float64
+		findBalance := `SELECT balance FROM wallets WHERE user_id=$1 FOR UPDATE`
+		err = tx.Raw(findBalance, UserID).Scan(&balance).Error
+		if err != nil {
+			tx.Rollback()
+			return domain.Orders{}, err
+		}
+		if paymentMethodId == 3 && balance < cart.Total_price {
+			tx.Rollback()
+			return domain.Orders{}, fmt.Errorf("insufficient wallet balance")
+		}
+		walletAmount = cart.Total_price
+		if balance < walletAmount {
+			walletAmount = balance
+		}
+	}
+
+	if payment.RazorPayOrderId != "" && math.Abs(cart.Total_price-walletAmount-payment.Amount) > 0.01 {
+		tx.Rollback()
+		return domain.Orders{}, fmt.Errorf("paid amount %.2f does not match the order amount %.2f", payment.Amount, cart.Total_price-walletAmount)
+	}
+
 	var order domain.Orders
 
-	insetOrder := `INSERT INTO orders (user_id,order_date,payment_method_id,shipping_address_id,order_total,order_status_id)
-		VALUES($1,NOW(),$2,$3,$4,1) RETURNING *`
-	err = tx.Raw(insetOrder, UserID, paymentMethodId, address.ID, cart.Total_price).Scan(&order).Error
+	var couponCode string
+	if cart.CouponID != 0 {
+		if err = tx.Raw(`SELECT code FROM coupons WHERE id=$1`, cart.CouponID).Scan(&couponCode).Error; err != nil {
+			tx.Rollback()
+			return domain.Orders{}, err
+		}
+	}
+
+	insetOrder := `INSERT INTO orders (user_id,order_date,payment_method_id,shipping_address_id,discount,tax,shipping,order_total,coupon_code,wallet_amount,order_status_id)
+		VALUES($1,NOW(),$2,$3,$4,$5,$6,$7,NULLIF($8,''),$9,$10) RETURNING *`
+	err = tx.Raw(insetOrder, UserID, paymentMethodId, address.ID, cart.Discount, cart.Tax, cart.Shipping, cart.Total_price,
+		couponCode, walletAmount, domain.OrderStatusPending).Scan(&order).Error
 	if err != nil {
 		tx.Rollback()
 		return domain.Orders{}, err
 	}
-
-	var cartItemes []requests.CartItems
-	cartDetail := `SELECT ci.product_id,ci.qty,p.prize,p.qty_in_stock  from cart_items ci join products p on ci.product_id = p.id where ci.cart_id=$1`
-	err = tx.Raw(cartDetail, cart.Id).Scan(&cartItemes).Error
+	err = saveOrderStatusHistory(tx, order.ID, 0, domain.OrderStatusPending, domain.Actor{Type: domain.ChangedByUser, ID: uint(UserID)}, "order placed")
 	if err != nil {
 		tx.Rollback()
 		return domain.Orders{}, err
 	}
 
-	//Add the items in the cart into the orderline
-	for _, items := range cartItemes {
-		if items.Qty > items.Qty_In_Stock {
-			return domain.Orders{}, fmt.Errorf("out of stock")
+	if walletAmount > 0 {
+		err = debitWallet(tx, uint(UserID), order.ID, walletAmount, fmt.Sprintf("payment for order #%d", order.ID))
+		if err != nil {
+			tx.Rollback()
+			return domain.Orders{}, err
 		}
-		insetOrder := `INSERT INTO order_lines (order_id,product_id,qty,price) VALUES($1,$2,$3,$4)`
-		err = tx.Exec(insetOrder, order.ID, items.ProductId, items.Qty, items.Price).Error
+	}
 
+	// the razorpay checkout already holds stock for this cart, it goes back on the
+	// products here and is taken again below with the rest, all in this tx
+	var held int64
+	if payment.RazorPayOrderId != "" {
+		held, err = releaseReservedStock(tx, domain.ReservationConsumed, order.ID, `razorpay_order_id = $5`, payment.RazorPayOrderId)
 		if err != nil {
 			tx.Rollback()
 			return domain.Orders{}, err
 		}
 	}
 
-	//Remove the product from the cart_items
+	var cartItemes []requests.CartItems
+	cartDetail := `SELECT ci.product_id,ci.variant_id,ci.qty,v.price,v.qty_in_stock from cart_items ci join product_variants v on ci.variant_id = v.id where ci.cart_id=$1
+	ORDER BY ci.variant_id`
+	err = tx.Raw(cartDetail, cart.Id).Scan(&cartItemes).Error
+	if err != nil {
+		tx.Rollback()
+		return domain.Orders{}, err
+	}
+
+	//Take the stock and add the items in the cart into the orderline
 	for _, items := range cartItemes {
-		removefromCart := `DELETE FROM cart_items WHERE cart_id =$1 AND product_id=$2`
-		err = tx.Exec(removefromCart, cart.Id, items.ProductId).Error
+		_, err = moveStock(tx, domain.StockMovement{
+			VariantID: uint(items.VariantId),
+			QtyChange: -items.Qty,
+			Reason:    domain.StockSale,
+			OrderID:   order.ID,
+		})
+		if err != nil {
+			tx.Rollback()
+			if payment.RazorPayOrderId != "" && held == 0 {
+				// the hold ran out or was given back before the payment came in
+				return domain.Orders{}, fmt.Errorf("the items were no longer held for this payment, %v", err)
+			}
+			return domain.Orders{}, err
+		}
+		insetOrder := `INSERT INTO order_lines (order_id,product_id,variant_id,qty,price) VALUES($1,$2,$3,$4,$5)`
+		err = tx.Exec(insetOrder, order.ID, items.ProductId, items.VariantId, items.Qty, items.Price).Error
+
 		if err != nil {
 			tx.Rollback()
 			return domain.Orders{}, err
 		}
 	}
 
+	//Remove the product from the cart_items
 	for _, items := range cartItemes {
-		updateQty := `UPDATE products SET qty_in_stock=products.qty_in_stock-$1 WHERE id=$2`
-		err = tx.Exec(updateQty, items.Qty, items.ProductId).Error
+		removefromCart := `DELETE FROM cart_items WHERE cart_id =$1 AND variant_id=$2`
+		err = tx.Exec(removefromCart, cart.Id, items.VariantId).Error
 		if err != nil {
 			tx.Rollback()
 			return domain.Orders{}, err
@@ -109,19 +208,39 @@ func (c *OrderDB) OrderAll(ctx context.Context, UserID, paymentMethodId int) (do
 			order_total,
 			payment_method_id,
 			payment_status_id,
+			razorpay_order_id,
+			razorpay_payment_id,
 			updated_at)
-			VALUES($1,$2,$3,$4,NOW())`
-	if err = tx.Exec(PaymentDetails, order.ID, order.OrderTotal, paymentMethodId, 1).Error; err != nil {
+			VALUES($1,$2,$3,$4,NULLIF($5,''),NULLIF($6,''),NOW())`
+	// wallet only and verified razorpay orders are paid at once, everything else starts as pending
+	paymentStatusId := domain.PaymentStatusPending
+	if paymentMethodId == 3 || payment.RazorPayPaymentId != "" {
+		paymentStatusId = domain.PaymentStatusPaid
+	}
+	if err = tx.Exec(PaymentDetails, order.ID, order.OrderTotal, paymentMethodId, paymentStatusId, payment.RazorPayOrderId, payment.RazorPayPaymentId).Error; err != nil {
 		tx.Rollback()
 		return domain.Orders{}, err
 	}
+	if payment.RazorPayOrderId != "" {
+		updateCheckout := `UPDATE razorpay_checkouts SET status=$1,razorpay_payment_id=$2,updated_at=NOW() WHERE razorpay_order_id=$3`
+		err = tx.Exec(updateCheckout, domain.CheckoutPaid, payment.RazorPayPaymentId, payment.RazorPayOrderId).Error
+		if err != nil {
+			tx.Rollback()
+			return domain.Orders{}, err
+		}
+	}
 
-	updatedCart := `UPDATE carts SET is_applied='F' WHERE id=$1`
+	// the coupon is used up with this order, the now empty cart is priced at zero
+	updatedCart := `UPDATE carts SET is_applied='F',coupon_id=NULL WHERE id=$1`
 	err = tx.Exec(updatedCart, cart.Id).Error
 	if err != nil {
 		tx.Rollback()
 		return domain.Orders{}, err
 	}
+	if _, err = repriceCart(tx, c.pricer, cart.Id); err != nil {
+		tx.Rollback()
+		return domain.Orders{}, err
+	}
 	if err = tx.Commit().Error; err != nil {
 		tx.Rollback()
 		return domain.Orders{}, err
@@ -130,50 +249,302 @@ func (c *OrderDB) OrderAll(ctx context.Context, UserID, paymentMethodId int) (do
 
 }
 
-func (c *OrderDB) CancelOrder(ctx context.Context, orderId, userId int) error {
+// CancelOrder cancels the order and refunds what was paid, the returned refund
+// is still approved when the payment gateway has to pay it
+func (c *OrderDB) CancelOrder(ctx context.Context, orderId, userId int, refundTo string, actor domain.Actor) (domain.Refund, error) {
 	tx := c.DB.Begin()
 
-	//find the orderd product and qty and update the product with those
-	var items []requests.CartItems
-	findProducts := `SELECT product_id,qty FROM order_lines WHERE order_id=?`
-	err := tx.Raw(findProducts, orderId).Scan(&items).Error
+	var order domain.Orders
+	findOrder := `SELECT * FROM orders WHERE id=$1 AND user_id=$2 FOR UPDATE`
+	err := tx.Raw(findOrder, orderId, userId).Scan(&order).Error
 	if err != nil {
 		tx.Rollback()
-		return err
+		return domain.Refund{}, err
 	}
-	if len(items) == 0 {
-		return fmt.Errorf("no order found with this id")
+	if order.ID == 0 {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("no order found with this id")
+	}
+	if order.OrderStatusID == domain.OrderStatusCancelled {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("the order is already cancelled")
+	}
+	if !domain.CanChangeOrderStatus(order.OrderStatusID, domain.OrderStatusCancelled) {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("a %s order can't be cancelled", domain.OrderStatusName(order.OrderStatusID))
+	}
+
+	//cancel whatever is left on every line and put it back in stock
+	lines, err := findOrderLines(tx, order.ID)
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
 	}
-	for _, item := range items {
-		updateProductItem := `UPDATE products SET qty_in_stock=qty_in_stock+$1 WHERE id=$2`
-		err = tx.Exec(updateProductItem, item.Qty, item.ProductId).Error
+	if len(lines) == 0 {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("no order found with this id")
+	}
+	for _, line := range lines {
+		if line.ActiveQty() == 0 {
+			continue
+		}
+		if _, err = removeLineQty(tx, line, line.ActiveQty(), false, actor); err != nil {
+			tx.Rollback()
+			return domain.Refund{}, err
+		}
+	}
+	//update the order status as canceled
+	err = changeOrderStatus(tx, order, domain.OrderStatusCancelled, actor, "order cancelled")
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+
+	// give back what was already paid, a paid order is refunded in full
+	// and an unpaid one only gets back the wallet part
+	var paymentStatusId uint
+	findPayment := `SELECT payment_status_id FROM payment_details WHERE orders_id=$1`
+	err = tx.Raw(findPayment, orderId).Scan(&paymentStatusId).Error
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+	refund := domain.Refund{
+		Amount:   order.WalletAmount,
+		Reason:   "order cancelled",
+		RefundTo: domain.RefundToWallet,
+	}
+	if isPaid(paymentStatusId) {
+		refund.Amount = order.OrderTotal
+		refund.RefundTo = refundTo
+	}
+	if refund.Amount > 0 {
+		refund, err = settleRefund(tx, order, refund)
 		if err != nil {
 			tx.Rollback()
-			return err
+			return domain.Refund{}, err
 		}
 	}
-	//Remove the items from order_lines
-	remove := `DELETE FROM order_lines WHERE order_id=$1`
-	err = tx.Exec(remove, orderId).Error
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+	return refund, nil
+}
+
+// CancelOrderLine cancels qty of one line of an order that is not delivered yet.
+// The order total and coupon discount shrink by the line's share and the
+// money for it is refunded the same way a whole cancel would
+func (c *OrderDB) CancelOrderLine(ctx context.Context, userId, orderId, lineId, qty int, refundTo string) (domain.Refund, error) {
+	tx := c.DB.Begin()
+
+	order, line, err := findOrderLine(tx, userId, orderId, lineId)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return domain.Refund{}, err
 	}
-	//update the order status as canceled
-	cancelOrder := `UPDATE orders SET order_status_id=$1 WHERE id=$2 AND user_id=$3`
-	err = tx.Exec(cancelOrder, 5, orderId, userId).Error
+	if !domain.CanChangeOrderStatus(order.OrderStatusID, domain.OrderStatusCancelled) {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("only pending or shipped orders can be cancelled")
+	}
+	if qty == 0 {
+		qty = line.ActiveQty()
+	}
+	if qty < 1 || qty > line.ActiveQty() {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("qty must be between 1 and %d", line.ActiveQty())
+	}
+
+	actor := domain.Actor{Type: domain.ChangedByUser, ID: uint(userId)}
+	share, err := removeLineQty(tx, line, qty, false, actor)
 	if err != nil {
 		tx.Rollback()
-		return err
+		return domain.Refund{}, err
+	}
+
+	var paymentStatusId uint
+	err = tx.Raw(`SELECT payment_status_id FROM payment_details WHERE orders_id=$1`, order.ID).Scan(&paymentStatusId).Error
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+
+	remaining := 0
+	lines, err := findOrderLines(tx, order.ID)
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+	for _, l := range lines {
+		remaining += l.ActiveQty()
+	}
+	// the last item takes whatever is left of the order with it, shipping included
+	if remaining == 0 {
+		share.Value = order.OrderTotal
+	}
+
+	refund := domain.Refund{
+		OrderLineID: line.ID,
+		Qty:         qty,
+		Reason:      fmt.Sprintf("cancelled %d of product %d", qty, line.ProductID),
+		RefundTo:    refundTo,
+	}
+	newTotal := roundAmount(order.OrderTotal - share.Value)
+	walletAmount := order.WalletAmount
+	if isPaid(paymentStatusId) {
+		refund.Amount = share.Value
+	} else if walletAmount > newTotal {
+		// nothing else is collected yet, only the wallet part above the new total goes back
+		refund.Amount = roundAmount(walletAmount - newTotal)
+		refund.RefundTo = domain.RefundToWallet
+		walletAmount = newTotal
+	}
+
+	if remaining == 0 {
+		// the order is cancelled with its totals kept as they were
+		err = changeOrderStatus(tx, order, domain.OrderStatusCancelled, actor, "last item cancelled")
+	} else {
+		updateOrder := `UPDATE orders SET order_total=$1,discount=$2,tax=$3,wallet_amount=$4 WHERE id=$5`
+		err = tx.Exec(updateOrder, newTotal, roundAmount(order.Discount-share.Discount), roundAmount(order.Tax-share.Tax), walletAmount, order.ID).Error
+	}
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+
+	if refund.Amount > 0 {
+		refund, err = settleRefund(tx, order, refund)
+		if err != nil {
+			tx.Rollback()
+			return domain.Refund{}, err
+		}
 	}
 
 	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+	return refund, nil
+}
+
+func (c *OrderDB) FindOrderLines(ctx context.Context, userId, orderId int) ([]domain.OrderLine, error) {
+	var lines []domain.OrderLine
+	query := `SELECT ol.* FROM order_lines ol
+	JOIN orders o ON o.id = ol.order_id
+	WHERE o.user_id=$1 AND o.id=$2
+	ORDER BY ol.id`
+	err := c.DB.Raw(query, userId, orderId).Scan(&lines).Error
+	return lines, err
+}
+
+func (c *OrderDB) FindOrderByRazorpayPaymentID(ctx context.Context, razorpayPaymentId string) (domain.Orders, error) {
+	var order domain.Orders
+	query := `SELECT o.* FROM orders o
+	JOIN payment_details pd ON pd.orders_id = o.id
+	WHERE pd.razorpay_payment_id = $1`
+	err := c.DB.Raw(query, razorpayPaymentId).Scan(&order).Error
+	return order, err
+}
+
+// MarkRazorpayPaymentPaid marks a pending or failed payment as paid, a payment
+// that was refunded since keeps its refund status
+func (c *OrderDB) MarkRazorpayPaymentPaid(ctx context.Context, razorpayPaymentId string) error {
+	query := `UPDATE payment_details SET payment_status_id=$1,updated_at=NOW() WHERE razorpay_payment_id=$2 AND payment_status_id IN ($3,$4)`
+	return c.DB.Exec(query, domain.PaymentStatusPaid, razorpayPaymentId, domain.PaymentStatusPending, domain.PaymentStatusFailed).Error
+}
+
+func (c *OrderDB) SaveRazorpayCheckout(ctx context.Context, checkout domain.RazorpayCheckout) error {
+	insert := `INSERT INTO razorpay_checkouts (razorpay_order_id,user_id,amount,wallet_amount,status,created_at,updated_at)
+	VALUES($1,$2,$3,$4,$5,NOW(),NOW())`
+	return c.DB.Exec(insert, checkout.RazorpayOrderID, checkout.UserID, checkout.Amount, checkout.WalletAmount, domain.CheckoutPending).Error
+}
+
+func (c *OrderDB) FindRazorpayCheckout(ctx context.Context, razorpayOrderId string) (domain.RazorpayCheckout, error) {
+	var checkout domain.RazorpayCheckout
+	err := c.DB.Raw(`SELECT * FROM razorpay_checkouts WHERE razorpay_order_id=$1`, razorpayOrderId).Scan(&checkout).Error
+	return checkout, err
+}
+
+// ClaimRazorpayCheckoutRefund moves an unpaid checkout to refunding, it
+// returns false when the checkout was already paid or is refunded by someone else
+func (c *OrderDB) ClaimRazorpayCheckoutRefund(ctx context.Context, razorpayOrderId, razorpayPaymentId, note string) (bool, error) {
+	update := `UPDATE razorpay_checkouts SET status=$1,razorpay_payment_id=$2,note=$3,updated_at=NOW()
+	WHERE razorpay_order_id=$4 AND status IN ($5,$6)`
+	result := c.DB.Exec(update, domain.CheckoutRefunding, razorpayPaymentId, note, razorpayOrderId, domain.CheckoutPending, domain.CheckoutFailed)
+	return result.RowsAffected > 0, result.Error
+}
+
+// SaveRazorpayCheckoutRefund records how a claimed checkout was refunded. A
+// refund to the wallet credits amount to the user's wallet in the same tx
+func (c *OrderDB) SaveRazorpayCheckoutRefund(ctx context.Context, checkout domain.RazorpayCheckout, amount float64) error {
+	tx := c.DB.Begin()
+
+	update := `UPDATE razorpay_checkouts SET status=$1,refund_to=$2,gateway_refund_id=NULLIF($3,''),updated_at=NOW()
+	WHERE razorpay_order_id=$4 AND status=$5`
+	result := tx.Exec(update, domain.CheckoutRefunded, checkout.RefundTo, checkout.GatewayRefundID, checkout.RazorpayOrderID, domain.CheckoutRefunding)
+	if result.Error != nil {
+		tx.Rollback()
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		tx.Rollback()
+		return fmt.Errorf("the checkout is not being refunded")
+	}
+	if checkout.RefundTo == domain.RefundToWallet {
+		description := fmt.Sprintf("refund for razorpay payment %s", checkout.RazorpayPaymentID)
+		if err := creditWallet(tx, checkout.UserID, 0, amount, description); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
 		tx.Rollback()
 		return err
 	}
 	return nil
 }
 
+// MarkRazorpayOrderFailed records a failed payment attempt on a checkout that
+// is not paid yet, it returns false when there was nothing to mark
+func (c *OrderDB) MarkRazorpayOrderFailed(ctx context.Context, razorpayOrderId, razorpayPaymentId, reason string) (bool, error) {
+	query := `UPDATE razorpay_checkouts SET status=$1,razorpay_payment_id=$2,note=$3,updated_at=NOW()
+	WHERE razorpay_order_id=$4 AND status IN ($1,$5)`
+	result := c.DB.Exec(query, domain.CheckoutFailed, razorpayPaymentId, reason, razorpayOrderId, domain.CheckoutPending)
+	return result.RowsAffected > 0, result.Error
+}
+
+// FindRazorpayCheckouts lists checkouts newest first, an empty status lists all of them
+func (c *OrderDB) FindRazorpayCheckouts(ctx context.Context, status string, pagination requests.Pagination) ([]domain.RazorpayCheckout, error) {
+	var checkouts []domain.RazorpayCheckout
+
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	query := `SELECT * FROM razorpay_checkouts
+	WHERE ($1 = '' OR status = $1)
+	ORDER BY updated_at DESC, razorpay_order_id
+	LIMIT $2 OFFSET $3`
+	if err := c.DB.Raw(query, status, limit, offset).Scan(&checkouts).Error; err != nil {
+		return nil, errors.New("failed to list checkouts")
+	}
+	return checkouts, nil
+}
+
+func (c *OrderDB) IsWebhookEventProcessed(ctx context.Context, eventId string) (bool, error) {
+	var exists bool
+	query := `SELECT EXISTS(SELECT 1 FROM webhook_events WHERE event_id=$1)`
+	err := c.DB.Raw(query, eventId).Scan(&exists).Error
+	return exists, err
+}
+
+func (c *OrderDB) SaveWebhookEvent(ctx context.Context, eventId, event string, payload []byte) error {
+	query := `INSERT INTO webhook_events (event_id,event,payload,processed_at) VALUES($1,$2,$3,NOW())
+	ON CONFLICT (event_id) DO NOTHING`
+	return c.DB.Exec(query, eventId, event, string(payload)).Error
+}
+
 func (c *OrderDB) Listorders(ctx context.Context) ([]response.OrderResponse, error) {
 	var orders []response.OrderResponse
 	Query := `SELECT o.id, o.user_id, o.order_date, o.payment_method_id, pm.payment_method, o.shipping_address_id,a.house_number,a.street,a.city,a.district,a.pincode,a.landmark,o.order_total, o.order_status_id, os.order_status, o.delivery_updated_at
@@ -207,25 +578,128 @@ func (c *OrderDB) Listorder(ctx context.Context, Orderid int, UserId int) (order
 	return order, err
 }
 
-func (c *OrderDB) ReturnOrder(userId, orderId int) (float64, error) {
+// ReturnOrder asks for a return of everything left in a delivered order,
+// the refund waits for an admin to approve or deny it
+func (c *OrderDB) ReturnOrder(userId, orderId int, reason, refundTo string) (float64, error) {
+	tx := c.DB.Begin()
 	var orders domain.Orders
-	Query := `SELECT * FROM orders WHERE user_id=$1 AND id=$2`
-	err := c.DB.Raw(Query, userId, orderId).Scan(&orders).Error
+	Query := `SELECT * FROM orders WHERE user_id=$1 AND id=$2 FOR UPDATE`
+	err := tx.Raw(Query, userId, orderId).Scan(&orders).Error
 	if err != nil {
+		tx.Rollback()
 		return 0, err
 	}
-	if orders.OrderStatusID != 3 {
+	if orders.OrderStatusID != domain.OrderStatusDelivered {
+		tx.Rollback()
 		return 0, fmt.Errorf("the order is not deleverd")
 	}
-	returnOder := `UPDATE orders SET order_status_id=$1 WHERE id=$2`
-	err = c.DB.Exec(returnOder, 6, orderId).Error
+
+	lines, err := findOrderLines(tx, orders.ID)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	for _, line := range lines {
+		if line.Status == domain.OrderLineReturnRequested {
+			tx.Rollback()
+			return 0, fmt.Errorf("an item of this order is already waiting for a return")
+		}
+	}
+
+	actor := domain.Actor{Type: domain.ChangedByUser, ID: uint(userId)}
+	err = changeOrderStatus(tx, orders, domain.OrderStatusReturnRequested, actor, reason)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	returnLines := `UPDATE order_lines SET status=$1,updated_at=NOW() WHERE order_id=$2 AND qty-cancelled_qty-returned_qty > 0`
+	err = tx.Exec(returnLines, domain.OrderLineReturnRequested, orderId).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	// a delivered order is fully paid, so the whole total is asked for
+	_, err = saveRefund(tx, domain.Refund{
+		OrderID:  orders.ID,
+		UserID:   orders.UserID,
+		Amount:   orders.OrderTotal,
+		Reason:   reason,
+		RefundTo: refundTo,
+		Status:   domain.RefundPending,
+	})
 	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
 		return 0, err
 	}
 	return orders.OrderTotal, nil
 
 }
 
+// ReturnOrderLine asks for a return of qty of one line of a delivered order,
+// the amount asked for is the line's value after its share of the coupon discount
+func (c *OrderDB) ReturnOrderLine(ctx context.Context, userId, orderId, lineId, qty int, reason, refundTo string) (float64, error) {
+	tx := c.DB.Begin()
+
+	order, line, err := findOrderLine(tx, userId, orderId, lineId)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if order.OrderStatusID != domain.OrderStatusDelivered {
+		tx.Rollback()
+		return 0, fmt.Errorf("the order is not deleverd")
+	}
+	if line.Status == domain.OrderLineReturnRequested {
+		tx.Rollback()
+		return 0, fmt.Errorf("this item is already waiting for a return")
+	}
+	if qty == 0 {
+		qty = line.ActiveQty()
+	}
+	if qty < 1 || qty > line.ActiveQty() {
+		tx.Rollback()
+		return 0, fmt.Errorf("qty must be between 1 and %d", line.ActiveQty())
+	}
+
+	share, err := lineValue(tx, line, qty)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	err = tx.Exec(`UPDATE order_lines SET status=$1,updated_at=NOW() WHERE id=$2`, domain.OrderLineReturnRequested, line.ID).Error
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	_, err = saveRefund(tx, domain.Refund{
+		OrderID:     order.ID,
+		UserID:      order.UserID,
+		OrderLineID: line.ID,
+		Qty:         qty,
+		Amount:      share.Value,
+		Reason:      reason,
+		RefundTo:    refundTo,
+		Status:      domain.RefundPending,
+	})
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return share.Value, nil
+}
+
 //------order_management for adminside-------//
 
 // show aall orderstatuses for  for admin
@@ -249,12 +723,225 @@ func (c *OrderDB) AdminListorders(ctx context.Context, pagination requests.Pagin
 	return orders, err
 }
 
-func (c *OrderDB) UpdateOrderStatus(ctx context.Context, update requests.Update) error {
-	fmt.Println(update.OrderId, update.StatusId)
-	Quary := `UPDATE orders SET order_status_id=$1 WHERE id=$2`
-	err := c.DB.Exec(Quary, update.StatusId, update.OrderId).Error
+// UpdateOrderStatus moves an order along the state machine for an admin.
+// Cancelling and returns have their own flows because they restock and refund
+func (c *OrderDB) UpdateOrderStatus(ctx context.Context, update requests.Update, adminId int) error {
+	tx := c.DB.Begin()
+
+	var order domain.Orders
+	err := tx.Raw(`SELECT * FROM orders WHERE id=$1 FOR UPDATE`, update.OrderId).Scan(&order).Error
 	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if order.ID == 0 {
+		tx.Rollback()
+		return fmt.Errorf("no order found with this id")
+	}
+
+	to := uint(update.StatusId)
+	switch {
+	case to == domain.OrderStatusReturnRequested:
+		tx.Rollback()
+		return fmt.Errorf("only the customer can ask for a return")
+	case order.OrderStatusID == domain.OrderStatusReturnRequested:
+		tx.Rollback()
+		return fmt.Errorf("approve or deny the return refund instead")
+	case to == domain.OrderStatusReturned:
+		tx.Rollback()
+		return fmt.Errorf("an order is returned once its return refund is approved")
+	}
+
+	actor := domain.Actor{Type: domain.ChangedByAdmin, ID: uint(adminId)}
+	if err = changeOrderStatus(tx, order, to, actor, update.Note); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
 		return err
 	}
 	return nil
 }
+
+func (c *OrderDB) FindOrderByID(ctx context.Context, orderId int) (domain.Orders, error) {
+	var order domain.Orders
+	err := c.DB.Raw(`SELECT * FROM orders WHERE id=$1`, orderId).Scan(&order).Error
+	return order, err
+}
+
+// FindOrderStatusHistory is the order's timeline, oldest change first
+func (c *OrderDB) FindOrderStatusHistory(ctx context.Context, orderId int) ([]response.OrderTimeline, error) {
+	var timeline []response.OrderTimeline
+	query := `SELECT h.from_status_id, fs.order_status AS from_status, h.to_status_id, ts.order_status AS to_status,
+		h.changed_by, h.changed_by_id, h.note, h.created_at AS changed_at
+	FROM order_status_history h
+	LEFT JOIN order_statuses fs ON fs.id = h.from_status_id
+	JOIN order_statuses ts ON ts.id = h.to_status_id
+	WHERE h.order_id=$1
+	ORDER BY h.created_at, h.id`
+	err := c.DB.Raw(query, orderId).Scan(&timeline).Error
+	return timeline, err
+}
+
+func findOrderLines(tx *gorm.DB, orderId uint) ([]domain.OrderLine, error) {
+	var lines []domain.OrderLine
+	err := tx.Raw(`SELECT * FROM order_lines WHERE order_id=$1 ORDER BY id FOR UPDATE`, orderId).Scan(&lines).Error
+	return lines, err
+}
+
+// findOrderLine locks the user's order and one of its lines
+func findOrderLine(tx *gorm.DB, userId, orderId, lineId int) (domain.Orders, domain.OrderLine, error) {
+	var order domain.Orders
+	err := tx.Raw(`SELECT * FROM orders WHERE id=$1 AND user_id=$2 FOR UPDATE`, orderId, userId).Scan(&order).Error
+	if err != nil {
+		return domain.Orders{}, domain.OrderLine{}, err
+	}
+	if order.ID == 0 {
+		return domain.Orders{}, domain.OrderLine{}, fmt.Errorf("no order found with this id")
+	}
+
+	var line domain.OrderLine
+	err = tx.Raw(`SELECT * FROM order_lines WHERE id=$1 AND order_id=$2 FOR UPDATE`, lineId, orderId).Scan(&line).Error
+	if err != nil {
+		return domain.Orders{}, domain.OrderLine{}, err
+	}
+	if line.ID == 0 {
+		return domain.Orders{}, domain.OrderLine{}, fmt.Errorf("no item found with this id in the order")
+	}
+	return order, line, nil
+}
+
+// lineShare is the part of an order that some qty of one of its lines accounts for
+type lineShare struct {
+	Value    float64
+	Discount float64
+	Tax      float64
+}
+
+// lineValue is what qty of a line is worth once the order's coupon discount
+// and tax are spread over the active items by price. Shipping stays with the order
+func lineValue(tx *gorm.DB, line domain.OrderLine, qty int) (lineShare, error) {
+	var order domain.Orders
+	if err := tx.Raw(`SELECT * FROM orders WHERE id=$1`, line.OrderID).Scan(&order).Error; err != nil {
+		return lineShare{}, err
+	}
+	// the discount and tax left on the order belong to the items that are still active
+	var subtotal float64
+	findSubtotal := `SELECT COALESCE(SUM((qty-cancelled_qty-returned_qty)*price),0) FROM order_lines WHERE order_id=$1`
+	err := tx.Raw(findSubtotal, line.OrderID).Scan(&subtotal).Error
+	if err != nil {
+		return lineShare{}, err
+	}
+	if subtotal <= 0 {
+		return lineShare{}, nil
+	}
+
+	portion := float64(qty) * line.Price / subtotal
+	return lineShare{
+		Value:    roundAmount((order.OrderTotal - order.Shipping) * portion),
+		Discount: roundAmount(order.Discount * portion),
+		Tax:      roundAmount(order.Tax * portion),
+	}, nil
+}
+
+// removeLineQty cancels or returns qty of a line inside tx, puts it back in
+// stock and records it on the line and in the stock ledger. It returns what
+// the removed qty was worth
+func removeLineQty(tx *gorm.DB, line domain.OrderLine, qty int, returned bool, actor domain.Actor) (lineShare, error) {
+	share, err := lineValue(tx, line, qty)
+	if err != nil {
+		return lineShare{}, err
+	}
+
+	if returned {
+		line.ReturnedQty += qty
+	} else {
+		line.CancelledQty += qty
+	}
+	updateLine := `UPDATE order_lines SET cancelled_qty=$1,returned_qty=$2,status=$3,updated_at=NOW() WHERE id=$4`
+	if err = tx.Exec(updateLine, line.CancelledQty, line.ReturnedQty, orderLineStatus(line), line.ID).Error; err != nil {
+		return lineShare{}, err
+	}
+
+	movement := domain.StockMovement{
+		VariantID: line.VariantID,
+		QtyChange: qty,
+		Reason:    domain.StockCancel,
+		OrderID:   line.OrderID,
+	}
+	if returned {
+		movement.Reason = domain.StockReturn
+	}
+	if actor.Type == domain.ChangedByAdmin {
+		movement.AdminID = actor.ID
+	}
+	if _, err = moveStock(tx, movement); err != nil {
+		return lineShare{}, err
+	}
+	return share, nil
+}
+
+// orderLineStatus works out a line's status from what was cancelled and returned
+func orderLineStatus(line domain.OrderLine) string {
+	switch {
+	case line.ActiveQty() == 0 && line.ReturnedQty > 0:
+		return domain.OrderLineReturned
+	case line.ActiveQty() == 0:
+		return domain.OrderLineCancelled
+	case line.ReturnedQty > 0:
+		return domain.OrderLinePartiallyReturned
+	case line.CancelledQty > 0:
+		return domain.OrderLinePartiallyCancelled
+	default:
+		return domain.OrderLinePlaced
+	}
+}
+
+// paid, refunded and partially refunded payments all mean money was collected
+func isPaid(paymentStatusId uint) bool {
+	return paymentStatusId == domain.PaymentStatusPaid || paymentStatusId == domain.PaymentStatusRefunded ||
+		paymentStatusId == domain.PaymentStatusPartiallyRefunded
+}
+
+func roundAmount(amount float64) float64 {
+	return math.Round(amount*100) / 100
+}
+
+// changeOrderStatus moves the order to status inside tx when the state machine
+// allows it and records who did it in the order's history. Reaching delivered
+// stamps the delivery time and marks a cash on delivery payment paid
+func changeOrderStatus(tx *gorm.DB, order domain.Orders, to uint, actor domain.Actor, note string) error {
+	if !domain.CanChangeOrderStatus(order.OrderStatusID, to) {
+		return fmt.Errorf("a %s order can't be changed to %s", domain.OrderStatusName(order.OrderStatusID), domain.OrderStatusName(to))
+	}
+
+	update := `UPDATE orders SET order_status_id=$1,
+		delivery_updated_at=CASE WHEN $1=$2 THEN NOW() ELSE delivery_updated_at END
+	WHERE id=$3 AND order_status_id=$4`
+	result := tx.Exec(update, to, domain.OrderStatusDelivered, order.ID, order.OrderStatusID)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("the order status was changed by someone else, try again")
+	}
+	if to == domain.OrderStatusDelivered {
+		// payment method 1 = cash on delivery. What is collected is the order
+		// total left after any items were cancelled
+		markPaid := `UPDATE payment_details pd SET payment_status_id=$1,order_total=o.order_total,updated_at=NOW()
+		FROM orders o
+		WHERE o.id=pd.orders_id AND pd.orders_id=$2 AND pd.payment_method_id=1 AND pd.payment_status_id=$3`
+		if err := tx.Exec(markPaid, domain.PaymentStatusPaid, order.ID, domain.PaymentStatusPending).Error; err != nil {
+			return err
+		}
+	}
+	return saveOrderStatusHistory(tx, order.ID, order.OrderStatusID, to, actor, note)
+}
+
+func saveOrderStatusHistory(tx *gorm.DB, orderId, from, to uint, actor domain.Actor, note string) error {
+	insert := `INSERT INTO order_status_history (order_id,from_status_id,to_status_id,changed_by,changed_by_id,note,created_at)
+	VALUES($1,NULLIF($2::bigint,0),$3,$4,NULLIF($5::bigint,0),$6,NOW())`
+	return tx.Exec(insert, orderId, from, to, actor.Type, actor.ID, note).Error
+}
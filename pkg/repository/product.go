@@ -3,6 +3,7 @@ package repository
 import (
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
 	interfaces "ecommerce/pkg/repository/interface"
 	"errors"
 	"fmt"
@@ -23,131 +24,453 @@ func NewproductRepository(DB *gorm.DB) interfaces.ProductRepo {
 
 func (p *productDB) Addcategory(ctx context.Context, req requests.Category) (response.Category, error) {
 	var categoryname response.Category
-	query := `INSERT INTO categories (category_name ,created_at)VAlues($1,NOW())RETURNING id,category_name as name`
-	err := p.DB.Raw(query, req.Name).Scan(&categoryname).Error
+	var parentId uint
+	if req.ParentID != nil {
+		parentId = *req.ParentID
+	}
+	if parentId != 0 {
+		if err := findCategory(p.DB, parentId); err != nil {
+			return categoryname, err
+		}
+	}
+	query := `INSERT INTO categories (category_name, parent_id, created_at) VALUES ($1, NULLIF($2, 0), NOW())
+	RETURNING id, category_name, COALESCE(parent_id, 0) AS parent_id`
+	err := p.DB.Raw(query, req.Name, parentId).Scan(&categoryname).Error
 	return categoryname, err
 
 }
+
+// UpdateCategory renames the category and moves it when a parent is given,
+// never below itself or one of its own subcategories
 func (c *productDB) UpdateCategory(ctx context.Context, category requests.Category, id int) (response.Category, error) {
 	var updatedCategory response.Category
-	query := `UPDATE  categories SET category_name = $1 , updated_at =NOW() WHERE id=$2 RETURNING id,category_name `
-	err := c.DB.Raw(query, category.Name, id).Scan(&updatedCategory).Error
-	return updatedCategory, err
+	tx := c.DB.Begin()
+	var parentId uint
+	if category.ParentID != nil {
+		parentId = *category.ParentID
+		// moves take turns so two of them can't close a loop between them
+		if err := tx.Exec(`LOCK TABLE categories IN SHARE ROW EXCLUSIVE MODE`).Error; err != nil {
+			tx.Rollback()
+			return updatedCategory, err
+		}
+	}
+	if parentId != 0 {
+		if err := findCategory(tx, parentId); err != nil {
+			tx.Rollback()
+			return updatedCategory, err
+		}
+		var below bool
+		if err := tx.Raw(`SELECT EXISTS (`+categorySubtree+` WHERE id = @parent)`,
+			map[string]interface{}{"categories": []int{id}, "parent": parentId}).Scan(&below).Error; err != nil {
+			tx.Rollback()
+			return updatedCategory, err
+		}
+		if below {
+			tx.Rollback()
+			return updatedCategory, errors.New("a category can't be moved below itself or one of its subcategories")
+		}
+	}
+
+	query := `UPDATE categories SET category_name = $1,
+		parent_id = CASE WHEN $2 THEN NULLIF($3, 0) ELSE parent_id END, updated_at = NOW()
+	WHERE id = $4 AND deleted_at IS NULL
+	RETURNING id, category_name, COALESCE(parent_id, 0) AS parent_id`
+	if err := tx.Raw(query, category.Name, category.ParentID != nil, parentId, id).Scan(&updatedCategory).Error; err != nil {
+		tx.Rollback()
+		return updatedCategory, err
+	}
+	if updatedCategory.ID == 0 {
+		tx.Rollback()
+		return updatedCategory, errors.New("category not found")
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return updatedCategory, err
+	}
+	return updatedCategory, nil
+}
+
+// DeleteCategory archives a category, its subcategories move up to its parent.
+// A category that still has products is only archived when reassignTo names
+// the category they move to
+func (c *productDB) DeleteCategory(ctx context.Context, Id int, reassignTo int) error {
+	tx := c.DB.Begin()
+	var category response.Category
+	query := `SELECT id, category_name, COALESCE(parent_id, 0) AS parent_id FROM categories
+	WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`
+	if err := tx.Raw(query, Id).Scan(&category).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if category.ID == 0 {
+		tx.Rollback()
+		return errors.New("category not found")
+	}
+
+	var products int
+	if err := tx.Raw(`SELECT COUNT(*) FROM products WHERE category_id = $1 AND deleted_at IS NULL`, Id).Scan(&products).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if products > 0 && reassignTo == 0 {
+		tx.Rollback()
+		return fmt.Errorf("category still has %d products, reassign them to another category", products)
+	}
+	if reassignTo != 0 {
+		if reassignTo == Id {
+			tx.Rollback()
+			return errors.New("products can't be reassigned to the category being deleted")
+		}
+		if err := findCategory(tx, uint(reassignTo)); err != nil {
+			tx.Rollback()
+			return err
+		}
+		// archived products move too so they come back in a live category
+		if err := tx.Exec(`UPDATE products SET category_id = $1, updated_at = NOW() WHERE category_id = $2`, reassignTo, Id).Error; err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	moveChildren := `UPDATE categories SET parent_id = NULLIF($1, 0), updated_at = NOW() WHERE parent_id = $2 AND deleted_at IS NULL`
+	if err := tx.Exec(moveChildren, category.ParentID, Id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Exec(`UPDATE categories SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1`, Id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// RestoreCategory brings an archived category back under its old parent, or
+// at the top level when the parent is archived too
+func (c *productDB) RestoreCategory(ctx context.Context, Id int) (response.Category, error) {
+	tx := c.DB.Begin()
+	var category response.Category
+	findCategory := `SELECT c.id, c.category_name, COALESCE(p.id, 0) AS parent_id FROM categories c
+	LEFT JOIN categories p ON p.id = c.parent_id AND p.deleted_at IS NULL
+	WHERE c.id = $1 AND c.deleted_at IS NOT NULL
+	FOR UPDATE OF c`
+	if err := tx.Raw(findCategory, Id).Scan(&category).Error; err != nil {
+		tx.Rollback()
+		return category, err
+	}
+	if category.ID == 0 {
+		tx.Rollback()
+		return category, errors.New("no archived category with this id")
+	}
+	if err := checkCategoryNameFree(tx, category.CategoryName, category.ParentID, category.ID); err != nil {
+		tx.Rollback()
+		return response.Category{}, err
+	}
+
+	query := `UPDATE categories SET deleted_at = NULL, updated_at = NOW(), parent_id = NULLIF($1, 0)
+	WHERE id = $2
+	RETURNING id, category_name, COALESCE(parent_id, 0) AS parent_id`
+	if err := tx.Raw(query, category.ParentID, Id).Scan(&category).Error; err != nil {
+		tx.Rollback()
+		return response.Category{}, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return response.Category{}, err
+	}
+	return category, nil
 }
-func (c *productDB) DeleteCategory(ctx context.Context, Id int) error {
-	Query := `DELETE FROM categories WHERE id=?`
-	err := c.DB.Exec(Query, Id).Error
-	return err
 
+// FindDeletedCategories lists the archived categories, last archived first
+func (c *productDB) FindDeletedCategories(ctx context.Context) ([]response.Category, error) {
+	var categories []response.Category
+	query := `SELECT id, category_name, COALESCE(parent_id, 0) AS parent_id, deleted_at FROM categories
+	WHERE deleted_at IS NOT NULL
+	ORDER BY deleted_at DESC, id`
+	err := c.DB.Raw(query).Scan(&categories).Error
+	return categories, err
 }
 func (c *productDB) Listallcategory(ctx context.Context) ([]response.Category, error) {
 	var Allcatagory []response.Category
-	Query := `SELECT * FROM categories`
+	Query := `SELECT id, category_name, COALESCE(parent_id, 0) AS parent_id FROM categories WHERE deleted_at IS NULL ORDER BY category_name, id`
 	err := c.DB.Raw(Query).Scan(&Allcatagory).Error
-	fmt.Println(Allcatagory)
 	return Allcatagory, err
 }
 func (c *productDB) ShowCatagory(ctx context.Context, Id int) (response.Category, error) {
 	var catagory response.Category
 
-	Query := `SELECT *FROM categories WHERE id=$1`
+	Query := `SELECT id, category_name, COALESCE(parent_id, 0) AS parent_id FROM categories WHERE id=$1 AND deleted_at IS NULL`
 	err := c.DB.Raw(Query, Id).Scan(&catagory).Error
 	return catagory, err
 }
-func (c *productDB) SaveProduct(ctx context.Context, product requests.Product) (response.Product, error) {
+
+// categorySubtree selects the ids of the @categories and of every category
+// below them
+const categorySubtree = `WITH RECURSIVE subtree AS (
+	SELECT id FROM categories WHERE id IN @categories
+	UNION
+	SELECT child.id FROM categories child JOIN subtree ON child.parent_id = subtree.id WHERE child.deleted_at IS NULL
+) SELECT id FROM subtree`
+
+// checkCategoryNameFree fails when a live category under the same parent
+// already has the name, names are compared without case like the unique index
+func checkCategoryNameFree(db *gorm.DB, name string, parentId, categoryId uint) error {
+	var taken bool
+	query := `SELECT EXISTS(SELECT 1 FROM categories
+		WHERE COALESCE(parent_id, 0) = $1 AND LOWER(category_name) = LOWER($2) AND id <> $3 AND deleted_at IS NULL)`
+	if err := db.Raw(query, parentId, name, categoryId).Scan(&taken).Error; err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("a category named %s already exists here", name)
+	}
+	return nil
+}
+
+// checkProductNameFree fails when another live product already has the name,
+// an archived product's name can be used again
+func checkProductNameFree(db *gorm.DB, name string, productId int) error {
+	var taken bool
+	query := `SELECT EXISTS(SELECT 1 FROM products WHERE product_name = $1 AND id <> $2 AND deleted_at IS NULL)`
+	if err := db.Raw(query, name, productId).Scan(&taken).Error; err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("a product named %s already exists", name)
+	}
+	return nil
+}
+
+func findCategory(db *gorm.DB, id uint) error {
+	var exists bool
+	if err := db.Raw(`SELECT EXISTS (SELECT 1 FROM categories WHERE id = $1 AND deleted_at IS NULL)`, id).Scan(&exists).Error; err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("category %d not found", id)
+	}
+	return nil
+}
+
+// SaveProduct creates the product with a first variant that carries its price
+// and stock, more variants can be added after
+func (c *productDB) SaveProduct(ctx context.Context, product requests.Product, adminId int) (response.Product, error) {
 	var Newproduct response.Product
 	var exits bool
-	query1 := `select exists(select 1 from categories where id=?)`
+	query1 := `select exists(select 1 from categories where id=? and deleted_at is null)`
 	c.DB.Raw(query1, product.Category_Id).Scan(&exits)
 	if !exits {
 		return response.Product{}, fmt.Errorf("this catagory is not found ")
 	}
+	if product.Qty_in_stock < 0 {
+		return response.Product{}, fmt.Errorf("stock can't be negative")
+	}
 
-	query := `INSERT INTO products (product_name, description ,brand ,prize,qty_in_stock,category_id, created_at)VALUES($1,$2,$3,$4,$5,$6,NOW())
-	RETURNING id, product_name as name, description, brand, prize, category_id `
-	fmt.Println(product)
-	err := c.DB.Raw(query, product.Name, product.Description, product.Brand, product.Prize, product.Qty_in_stock, product.Category_Id).
+	tx := c.DB.Begin()
+	if err := checkProductNameFree(tx, product.Name, 0); err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
+	query := `INSERT INTO products (product_name, description ,brand ,prize,qty_in_stock,category_id, created_at)VALUES($1,$2,$3,$4,0,$5,NOW())
+	RETURNING id, product_name as name, description, brand, prize, qty_in_stock, category_id `
+	err := tx.Raw(query, product.Name, product.Description, product.Brand, product.Prize, product.Category_Id).
 		Scan(&Newproduct).Error
+	if err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
+
+	variant, err := saveVariant(tx, domain.ProductVariant{
+		ProductID:  uint(Newproduct.Id),
+		SKU:        product.Sku,
+		Price:      product.Prize,
+		QtyInStock: product.Qty_in_stock,
+	}, uint(adminId))
+	if err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
 
-	return Newproduct, err
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
+	Newproduct.Qty_in_stock = variant.QtyInStock
+	Newproduct.Variants = []domain.ProductVariant{variant}
+	return Newproduct, nil
 
 }
-func (c *productDB) UpdateProduct(ctx context.Context, id int, product requests.Product) (response.Product, error) {
+
+// UpdateProduct replaces the product details. Price and stock belong to the
+// variants, they can only be changed here while the product has a single
+// variant and a stock change is recorded as an adjustment by the admin
+func (c *productDB) UpdateProduct(ctx context.Context, id int, product requests.Product, adminId int) (response.Product, error) {
 
 	var Newproduct response.Product
+	if product.Qty_in_stock < 0 {
+		return response.Product{}, fmt.Errorf("stock can't be negative")
+	}
+
+	tx := c.DB.Begin()
+	var current []struct {
+		Prize      int
+		QtyInStock int
+	}
+	if err := tx.Raw(`SELECT prize, qty_in_stock FROM products WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, id).Scan(&current).Error; err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
+	if len(current) == 0 {
+		tx.Rollback()
+		return response.Product{}, fmt.Errorf("no product found with this id")
+	}
+	if err := checkProductNameFree(tx, product.Name, id); err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
+
+	variants, err := findVariants(tx, uint(id))
+	if err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
+	if len(variants) == 1 {
+		variant := variants[0]
+		if product.Prize != variant.Price {
+			err = tx.Exec(`UPDATE product_variants SET price = $1, updated_at = NOW() WHERE id = $2`, product.Prize, variant.ID).Error
+			if err != nil {
+				tx.Rollback()
+				return response.Product{}, err
+			}
+		}
+		if change := product.Qty_in_stock - variant.QtyInStock; change != 0 {
+			_, err = moveStock(tx, domain.StockMovement{
+				VariantID: variant.ID,
+				QtyChange: change,
+				Reason:    domain.StockAdjustment,
+				AdminID:   uint(adminId),
+				Note:      "product updated",
+			})
+			if err != nil {
+				tx.Rollback()
+				return response.Product{}, err
+			}
+		}
+	} else if product.Prize != current[0].Prize || product.Qty_in_stock != current[0].QtyInStock {
+		tx.Rollback()
+		return response.Product{}, fmt.Errorf("the product has %d variants, change their price and stock instead", len(variants))
+	}
+	if err = syncProductSummary(tx, uint(id)); err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
 
-	query := `UPDATE products SET product_name = $1, description = $2, brand = $3, prize = $4, qty_in_stock = $5, category_id = $6, updated_at = NOW() WHERE id = $7 
+	query := `UPDATE products SET product_name = $1, description = $2, brand = $3, category_id = $4, updated_at = NOW() WHERE id = $5 
 	RETURNING id, product_name as name, description, brand, prize, qty_in_stock, category_id`
 
-	err := c.DB.Raw(query, product.Name, product.Description, product.Brand,
-		product.Prize, product.Qty_in_stock, product.Category_Id, id).Scan(&Newproduct).Error
+	err = tx.Raw(query, product.Name, product.Description, product.Brand, product.Category_Id, id).Scan(&Newproduct).Error
+	if err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
 
-	return Newproduct, err
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return response.Product{}, err
+	}
+	return Newproduct, nil
 
 }
+
+// DeleteProduct archives the product, past orders, reviews and wishlists keep
+// pointing at it. It is taken out of carts since it can't be bought anymore
 func (c *productDB) DeleteProduct(ctx context.Context, id int) error {
-	query := `DELETE FROM products WHERE id=$1`
-	err := c.DB.Exec(query, id).Error
-	return err
+	tx := c.DB.Begin()
+	var archived []uint
+	query := `UPDATE products SET deleted_at = NOW(), updated_at = NOW() WHERE id = $1 AND deleted_at IS NULL RETURNING id`
+	if err := tx.Raw(query, id).Scan(&archived).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if len(archived) == 0 {
+		tx.Rollback()
+		return fmt.Errorf("no product found with this id")
+	}
+	// carts are priced again the next time they are viewed or checked out
+	if err := tx.Exec(`DELETE FROM cart_items WHERE product_id = $1`, id).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
 }
 
-func (c *productDB) ViewAllProducts(ctx context.Context, pagination requests.Pagination) ([]response.Product, error) {
-	var products []response.Product
+// RestoreProduct puts an archived product back on sale, its category has to
+// be live
+func (c *productDB) RestoreProduct(ctx context.Context, id int) error {
+	var product struct {
+		Name           string
+		Archived       bool
+		CategoryActive bool
+	}
+	findProduct := `SELECT p.product_name AS name, p.deleted_at IS NOT NULL AS archived, COALESCE(c.deleted_at IS NULL, false) AS category_active
+	FROM products p
+	LEFT JOIN categories c ON c.id = p.category_id
+	WHERE p.id = $1`
+	if err := c.DB.Raw(findProduct, id).Scan(&product).Error; err != nil {
+		return err
+	}
+	if !product.Archived {
+		return fmt.Errorf("no archived product with this id")
+	}
+	if !product.CategoryActive {
+		return fmt.Errorf("the product's category is archived, restore it or move the product first")
+	}
+	if err := checkProductNameFree(c.DB, product.Name, id); err != nil {
+		return err
+	}
+	return c.DB.Exec(`UPDATE products SET deleted_at = NULL, updated_at = NOW() WHERE id = $1`, id).Error
+}
 
+// FindDeletedProducts lists the archived products, last archived first
+func (c *productDB) FindDeletedProducts(ctx context.Context, pagination requests.Pagination) ([]response.Product, error) {
 	limit := pagination.PerPage
-	offset := (pagination.Page - 1) * pagination.PerPage
-
-	// aliase :: p := product; c := category
-	query := `
-        SELECT p.id, p.product_name, p.description, p.brand, p.prize, p.qty_in_stock, 
-               p.category_id, c.category_name, p.created_at, p.updated_at
-        FROM products p 
-        LEFT JOIN categories c ON p.category_id = c.id
-        ORDER BY p.created_at DESC 
-        LIMIT $1 OFFSET $2
-    `
+	offset := (pagination.Page - 1) * limit
 
+	var products []response.Product
+	query := `SELECT p.id, p.product_name AS name, p.description, p.brand, p.prize, p.qty_in_stock,
+		p.category_id, c.category_name, pi.thumbnail_key, p.rating_avg AS rating, p.rating_count AS review_count, p.deleted_at
+	FROM products p
+	LEFT JOIN categories c ON c.id = p.category_id
+	LEFT JOIN product_images pi ON pi.product_id = p.id AND pi.is_primary
+	WHERE p.deleted_at IS NOT NULL
+	ORDER BY p.deleted_at DESC, p.id
+	LIMIT $1 OFFSET $2`
 	if err := c.DB.Raw(query, limit, offset).Scan(&products).Error; err != nil {
-		return nil, errors.New("failed to get products from database")
+		return nil, errors.New("failed to get archived products")
 	}
-
 	return products, nil
 }
 
 func (c *productDB) ViewProduct(ctx context.Context, id int) (response.Product, error) {
 	var product response.Product
-	query := `SELECT p.id,p.product_name as name,p.description,p.brand,p.prize,p.category_id,p.qty_in_stock,c.category_name,p.created_at,p.updated_at FROM products p 
-		JOIN categories c ON p.category_id=c.id WHERE p.id=$1`
-	err := c.DB.Raw(query, id).Scan(&product).Error
-	return product, err
-}
-func (c *productDB) SearchProducts(ctx context.Context, query string) ([]requests.Product, error) {
-	var products []requests.Product
-	if err := c.DB.WithContext(ctx).
-		Where("product_name ILIKE ?", query+"%"). // use LIKE if MySQL
-		Find(&products).Error; err != nil {
-		return nil, err
+	query := `SELECT p.id,p.product_name as name,p.description,p.brand,p.prize,p.category_id,p.qty_in_stock,c.category_name,p.created_at,p.updated_at,pi.thumbnail_key,
+		p.rating_avg AS rating,p.rating_count AS review_count FROM products p 
+		JOIN categories c ON p.category_id=c.id
+		LEFT JOIN product_images pi ON pi.product_id = p.id AND pi.is_primary WHERE p.id=$1 AND p.deleted_at IS NULL`
+	if err := c.DB.Raw(query, id).Scan(&product).Error; err != nil {
+		return product, err
 	}
-	return products, nil
-}
-func (c *productDB) FilterByPriceRange(min, max float64) ([]requests.Product, error) {
-	var products []requests.Product
-	if err := c.DB.Where("prize BETWEEN ? AND ?", min, max).Find(&products).Error; err != nil {
-		return nil, err
+	if product.Id == 0 {
+		return product, nil
 	}
-	return products, nil
-}
-
-// fetch min & max price from DB
-func (c *productDB) GetMinMaxPrice() (float64, float64, error) {
-	var min, max float64
-
-	if err := c.DB.Model(&requests.Product{}).Select("MIN(prize)").Scan(&min).Error; err != nil {
-		return 0, 0, err
-	}
-	if err := c.DB.Model(&requests.Product{}).Select("MAX(prize)").Scan(&max).Error; err != nil {
-		return 0, 0, err
-	}
-
-	return min, max, nil
+	variants, err := findVariants(c.DB, uint(id))
+	product.Variants = variants
+	return product, err
 }
@@ -0,0 +1,374 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type refundDB struct {
+	DB *gorm.DB
+}
+
+func NewRefundRepository(DB *gorm.DB) interfaces.RefundRepo {
+	return &refundDB{
+		DB: DB,
+	}
+}
+
+// FindRefunds lists refunds newest first, an empty status lists all of them
+func (c *refundDB) FindRefunds(ctx context.Context, status string, pagination requests.Pagination) ([]domain.Refund, error) {
+	var refunds []domain.Refund
+
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	query := `SELECT * FROM refunds
+	WHERE ($1 = '' OR status = $1)
+	ORDER BY created_at DESC, id DESC
+	LIMIT $2 OFFSET $3`
+	if err := c.DB.WithContext(ctx).Raw(query, status, limit, offset).Scan(&refunds).Error; err != nil {
+		return nil, errors.New("failed to list refunds")
+	}
+	return refunds, nil
+}
+
+func (c *refundDB) FindRefundByID(ctx context.Context, refundId uint) (domain.Refund, error) {
+	var refund domain.Refund
+	query := `SELECT * FROM refunds WHERE id = $1`
+	err := c.DB.WithContext(ctx).Raw(query, refundId).Scan(&refund).Error
+	return refund, err
+}
+
+// ApproveRefund approves a pending return refund for amount, a zero amount
+// approves the full amount that was asked for. The returned items go back in
+// stock and the wallet part is paid at once, the returned refund is left
+// approved when the gateway still has to pay it
+func (c *refundDB) ApproveRefund(ctx context.Context, refundId uint, amount float64, adminId uint) (domain.Refund, error) {
+	tx := c.DB.Begin()
+
+	var refund domain.Refund
+	err := tx.Raw(`SELECT * FROM refunds WHERE id = $1 FOR UPDATE`, refundId).Scan(&refund).Error
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+	if refund.ID == 0 {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("no refund found with this id")
+	}
+	if refund.Status != domain.RefundPending {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("the refund is already %s", refund.Status)
+	}
+	if amount < 0 || amount > refund.Amount {
+		tx.Rollback()
+		return domain.Refund{}, fmt.Errorf("refund amount must be between 0 and %.2f", refund.Amount)
+	}
+	if amount > 0 {
+		refund.Amount = amount
+	}
+
+	var order domain.Orders
+	err = tx.Raw(`SELECT * FROM orders WHERE id = $1 FOR UPDATE`, refund.OrderID).Scan(&order).Error
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+	actor := domain.Actor{Type: domain.ChangedByAdmin, ID: adminId}
+	if refund.OrderLineID == 0 {
+		err = approveOrderReturn(tx, order, actor)
+	} else {
+		err = approveLineReturn(tx, order, refund, actor)
+	}
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+
+	refund, err = settleRefund(tx, order, refund)
+	if err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.Refund{}, err
+	}
+	return refund, nil
+}
+
+// DenyRefund rejects a pending return, the order goes back to delivered
+func (c *refundDB) DenyRefund(ctx context.Context, refundId uint, reason string, adminId uint) error {
+	tx := c.DB.Begin()
+
+	var refund domain.Refund
+	err := tx.Raw(`SELECT * FROM refunds WHERE id = $1 FOR UPDATE`, refundId).Scan(&refund).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if refund.ID == 0 {
+		tx.Rollback()
+		return fmt.Errorf("no refund found with this id")
+	}
+	if refund.Status != domain.RefundPending {
+		tx.Rollback()
+		return fmt.Errorf("the refund is already %s", refund.Status)
+	}
+
+	deny := `UPDATE refunds SET status = $1, reason = COALESCE(NULLIF($2, ''), reason), updated_at = NOW() WHERE id = $3`
+	if err = tx.Exec(deny, domain.RefundDenied, reason, refund.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	var order domain.Orders
+	err = tx.Raw(`SELECT * FROM orders WHERE id = $1 FOR UPDATE`, refund.OrderID).Scan(&order).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if refund.OrderLineID == 0 && order.OrderStatusID == domain.OrderStatusReturnRequested {
+		actor := domain.Actor{Type: domain.ChangedByAdmin, ID: adminId}
+		if err = changeOrderStatus(tx, order, domain.OrderStatusDelivered, actor, "return denied"); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	// the items keep what happened to them before the return was asked for
+	var lines []domain.OrderLine
+	findLines := `SELECT * FROM order_lines WHERE order_id = $1 AND status = $2 AND ($3 = 0 OR id = $3) FOR UPDATE`
+	err = tx.Raw(findLines, refund.OrderID, domain.OrderLineReturnRequested, refund.OrderLineID).Scan(&lines).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	for _, line := range lines {
+		err = tx.Exec(`UPDATE order_lines SET status = $1, updated_at = NOW() WHERE id = $2`, orderLineStatus(line), line.ID).Error
+		if err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+func (c *refundDB) UpdateRefundStatus(ctx context.Context, refundId uint, status, gatewayRefundId string) error {
+	query := `UPDATE refunds SET status = $1, gateway_refund_id = COALESCE(NULLIF($2, ''), gateway_refund_id), updated_at = NOW()
+	WHERE id = $3`
+	return c.DB.WithContext(ctx).Exec(query, status, gatewayRefundId, refundId).Error
+}
+
+// MarkGatewayRefundProcessed is called when razorpay reports the refund as
+// done. The payment's refund status is worked out again from every refund of
+// the order, so a partial refund stays partial
+func (c *refundDB) MarkGatewayRefundProcessed(ctx context.Context, gatewayRefundId string) error {
+	tx := c.DB.WithContext(ctx).Begin()
+
+	var refund domain.Refund
+	query := `UPDATE refunds SET status = $1, updated_at = NOW() WHERE gateway_refund_id = $2 RETURNING *`
+	if err := tx.Raw(query, domain.RefundProcessed, gatewayRefundId).Scan(&refund).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	// refunds of payments that never became an order are kept on their checkout
+	if refund.ID != 0 {
+		if err := refreshPaymentStatus(tx, refund.OrderID); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// approveOrderReturn returns everything left in an order that asked for a whole
+// return, the order keeps its totals as the record of what was sold
+func approveOrderReturn(tx *gorm.DB, order domain.Orders, actor domain.Actor) error {
+	if order.OrderStatusID != domain.OrderStatusReturnRequested {
+		return fmt.Errorf("the order has no pending return")
+	}
+	lines, err := findOrderLines(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	for _, line := range lines {
+		if line.Status != domain.OrderLineReturnRequested || line.ActiveQty() == 0 {
+			continue
+		}
+		if _, err = removeLineQty(tx, line, line.ActiveQty(), true, actor); err != nil {
+			return err
+		}
+	}
+	return changeOrderStatus(tx, order, domain.OrderStatusReturned, actor, "return approved")
+}
+
+// approveLineReturn returns the refund's qty of one line. The order total,
+// discount and tax shrink by the line's share unless nothing is left in the
+// order, then the order is marked returned
+func approveLineReturn(tx *gorm.DB, order domain.Orders, refund domain.Refund, actor domain.Actor) error {
+	var line domain.OrderLine
+	err := tx.Raw(`SELECT * FROM order_lines WHERE id = $1 FOR UPDATE`, refund.OrderLineID).Scan(&line).Error
+	if err != nil {
+		return err
+	}
+	if line.Status != domain.OrderLineReturnRequested || refund.Qty > line.ActiveQty() {
+		return fmt.Errorf("the item has no pending return")
+	}
+
+	share, err := removeLineQty(tx, line, refund.Qty, true, actor)
+	if err != nil {
+		return err
+	}
+
+	lines, err := findOrderLines(tx, order.ID)
+	if err != nil {
+		return err
+	}
+	remaining := 0
+	for _, l := range lines {
+		remaining += l.ActiveQty()
+	}
+	if remaining == 0 {
+		return changeOrderStatus(tx, order, domain.OrderStatusReturned, actor, "last item returned")
+	}
+	updateOrder := `UPDATE orders SET order_total = $1, discount = $2, tax = $3 WHERE id = $4`
+	return tx.Exec(updateOrder, roundAmount(order.OrderTotal-share.Value), roundAmount(order.Discount-share.Discount),
+		roundAmount(order.Tax-share.Tax), order.ID).Error
+}
+
+// settleRefund pays out refund inside tx. Wallet refunds are credited at once.
+// A refund to the source is left approved for the payment gateway, anything
+// above what razorpay collected and has not refunded yet goes to the wallet
+// as a separate refund row
+func settleRefund(tx *gorm.DB, order domain.Orders, refund domain.Refund) (domain.Refund, error) {
+	refund.OrderID = order.ID
+	refund.UserID = order.UserID
+
+	var payment domain.PaymentDetails
+	err := tx.Raw(`SELECT * FROM payment_details WHERE orders_id = $1`, order.ID).Scan(&payment).Error
+	if err != nil {
+		return domain.Refund{}, err
+	}
+
+	var gatewayAmount float64
+	if refund.RefundTo == domain.RefundToSource && payment.RazorpayPaymentID != "" {
+		// payment_details keeps the total that was charged, razorpay took all of it but the wallet part
+		refunded, err := sumRefunds(tx, order.ID, domain.RefundToSource)
+		if err != nil {
+			return domain.Refund{}, err
+		}
+		gatewayAmount = payment.OrderTotal - order.WalletAmount - refunded
+		if gatewayAmount > refund.Amount {
+			gatewayAmount = refund.Amount
+		}
+		if gatewayAmount < 0 {
+			gatewayAmount = 0
+		}
+	}
+
+	if walletAmount := refund.Amount - gatewayAmount; walletAmount > 0 {
+		err = creditWallet(tx, order.UserID, order.ID, walletAmount, fmt.Sprintf("refund for order #%d", order.ID))
+		if err != nil {
+			return domain.Refund{}, err
+		}
+		walletRefund := refund
+		walletRefund.Amount = walletAmount
+		walletRefund.RefundTo = domain.RefundToWallet
+		walletRefund.Status = domain.RefundProcessed
+		if gatewayAmount > 0 {
+			// the gateway part keeps the original row
+			walletRefund.ID = 0
+		}
+		walletRefund, err = saveRefund(tx, walletRefund)
+		if err != nil {
+			return domain.Refund{}, err
+		}
+		if gatewayAmount == 0 {
+			refund = walletRefund
+		}
+	}
+
+	if gatewayAmount > 0 {
+		refund.Amount = gatewayAmount
+		refund.GatewayPaymentID = payment.RazorpayPaymentID
+		refund.Status = domain.RefundApproved
+		refund, err = saveRefund(tx, refund)
+		if err != nil {
+			return domain.Refund{}, err
+		}
+	}
+
+	if err = refreshPaymentStatus(tx, order.ID); err != nil {
+		return domain.Refund{}, err
+	}
+	return refund, nil
+}
+
+// refreshPaymentStatus marks a paid order's payment refunded once its refunds
+// add up to what was paid and partially refunded before that
+func refreshPaymentStatus(tx *gorm.DB, orderId uint) error {
+	var payment domain.PaymentDetails
+	err := tx.Raw(`SELECT * FROM payment_details WHERE orders_id = $1 FOR UPDATE`, orderId).Scan(&payment).Error
+	if err != nil {
+		return err
+	}
+	if !isPaid(payment.PaymentStatusID) {
+		return nil
+	}
+	refunded, err := sumRefunds(tx, orderId, "")
+	if err != nil {
+		return err
+	}
+	paymentStatusId := domain.PaymentStatusPaid
+	if refunded >= payment.OrderTotal-0.01 {
+		paymentStatusId = domain.PaymentStatusRefunded
+	} else if refunded > 0 {
+		paymentStatusId = domain.PaymentStatusPartiallyRefunded
+	}
+	return tx.Exec(`UPDATE payment_details SET payment_status_id = $1, updated_at = NOW() WHERE orders_id = $2`, paymentStatusId, orderId).Error
+}
+
+// sumRefunds adds up the approved and processed refunds of an order, an empty
+// refundTo counts both wallet and gateway refunds
+func sumRefunds(tx *gorm.DB, orderId uint, refundTo string) (float64, error) {
+	var refunded float64
+	query := `SELECT COALESCE(SUM(amount), 0) FROM refunds
+	WHERE order_id = $1 AND ($2 = '' OR refund_to = $2) AND status IN ($3, $4)`
+	err := tx.Raw(query, orderId, refundTo, domain.RefundApproved, domain.RefundProcessed).Scan(&refunded).Error
+	return refunded, err
+}
+
+// saveRefund inserts a new refund row or updates an existing one
+func saveRefund(tx *gorm.DB, refund domain.Refund) (domain.Refund, error) {
+	var saved domain.Refund
+	if refund.ID == 0 {
+		insert := `INSERT INTO refunds (order_id, user_id, order_line_id, qty, amount, reason, refund_to, gateway_payment_id, status, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3::bigint, 0), $4, $5, $6, $7, NULLIF($8, ''), $9, NOW(), NOW())
+		RETURNING *`
+		err := tx.Raw(insert, refund.OrderID, refund.UserID, refund.OrderLineID, refund.Qty, refund.Amount, refund.Reason,
+			refund.RefundTo, refund.GatewayPaymentID, refund.Status).Scan(&saved).Error
+		return saved, err
+	}
+	update := `UPDATE refunds SET amount = $1, refund_to = $2, gateway_payment_id = NULLIF($3, ''), status = $4, updated_at = NOW()
+	WHERE id = $5
+	RETURNING *`
+	err := tx.Raw(update, refund.Amount, refund.RefundTo, refund.GatewayPaymentID, refund.Status, refund.ID).Scan(&saved).Error
+	return saved, err
+}
@@ -0,0 +1,237 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+type reviewDB struct {
+	DB *gorm.DB
+}
+
+func NewReviewRepository(DB *gorm.DB) interfaces.ReviewRepo {
+	return &reviewDB{
+		DB: DB,
+	}
+}
+
+// reviewSorts are the orders reviews can be listed in, newest first on ties
+var reviewSorts = map[string]string{
+	"helpful": "r.helpful_count DESC",
+	"newest":  "r.created_at DESC",
+	"highest": "r.rating DESC",
+	"lowest":  "r.rating ASC",
+}
+
+// IsVerifiedBuyer tells whether the user got the product delivered in an order
+// and did not cancel all of it before that
+func (c *reviewDB) IsVerifiedBuyer(ctx context.Context, userId, productId uint) (bool, error) {
+	var verified bool
+	query := `SELECT EXISTS (
+		SELECT 1 FROM order_lines ol
+		JOIN orders o ON o.id = ol.order_id
+		WHERE o.user_id = $1 AND ol.product_id = $2
+		AND o.order_status_id IN ($3, $4, $5)
+		AND ol.qty > ol.cancelled_qty
+	)`
+	err := c.DB.Raw(query, userId, productId,
+		domain.OrderStatusDelivered, domain.OrderStatusReturnRequested, domain.OrderStatusReturned).Scan(&verified).Error
+	return verified, err
+}
+
+func (c *reviewDB) FindReview(ctx context.Context, reviewId uint) (domain.Review, error) {
+	var review domain.Review
+	err := c.DB.Raw(`SELECT * FROM product_reviews WHERE id = $1`, reviewId).Scan(&review).Error
+	return review, err
+}
+
+func (c *reviewDB) FindUserReview(ctx context.Context, userId, productId uint) (domain.Review, error) {
+	var review domain.Review
+	err := c.DB.Raw(`SELECT * FROM product_reviews WHERE user_id = $1 AND product_id = $2`, userId, productId).Scan(&review).Error
+	return review, err
+}
+
+func (c *reviewDB) SaveReview(ctx context.Context, review domain.Review) (domain.Review, error) {
+	tx := c.DB.Begin()
+	var newReview domain.Review
+	query := `INSERT INTO product_reviews (product_id, user_id, rating, body, status, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, NOW(), NOW())
+	ON CONFLICT (product_id, user_id) DO NOTHING
+	RETURNING *`
+	err := tx.Raw(query, review.ProductID, review.UserID, review.Rating, review.Body, domain.ReviewPublished).Scan(&newReview).Error
+	if err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	if newReview.ID == 0 {
+		tx.Rollback()
+		return domain.Review{}, errors.New("you have already reviewed this product")
+	}
+
+	if err = syncProductRating(tx, newReview.ProductID); err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	return newReview, nil
+}
+
+// UpdateReview changes the rating and text, a hidden review stays hidden
+func (c *reviewDB) UpdateReview(ctx context.Context, review domain.Review) (domain.Review, error) {
+	tx := c.DB.Begin()
+	var updated domain.Review
+	query := `UPDATE product_reviews SET rating = $1, body = $2, updated_at = NOW() WHERE id = $3 RETURNING *`
+	if err := tx.Raw(query, review.Rating, review.Body, review.ID).Scan(&updated).Error; err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	if updated.ID == 0 {
+		tx.Rollback()
+		return domain.Review{}, errors.New("review not found")
+	}
+
+	if err := syncProductRating(tx, updated.ProductID); err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	return updated, nil
+}
+
+func (c *reviewDB) DeleteReview(ctx context.Context, reviewId uint) error {
+	tx := c.DB.Begin()
+	var productId uint
+	if err := tx.Raw(`DELETE FROM product_reviews WHERE id = $1 RETURNING product_id`, reviewId).Scan(&productId).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if productId == 0 {
+		tx.Rollback()
+		return errors.New("review not found")
+	}
+
+	if err := syncProductRating(tx, productId); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// FindProductReviews lists the published reviews of a product in the given order
+func (c *reviewDB) FindProductReviews(ctx context.Context, productId uint, sort string, pagination requests.Pagination) ([]response.Review, error) {
+	order, ok := reviewSorts[sort]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort %q", sort)
+	}
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	var reviews []response.Review
+	query := `SELECT r.id, r.product_id, u.name AS user_name, r.rating, r.body, r.helpful_count, r.created_at, r.updated_at
+	FROM product_reviews r
+	JOIN users u ON u.id = r.user_id
+	WHERE r.product_id = $1 AND r.status = $2
+	ORDER BY ` + order + `, r.created_at DESC, r.id DESC
+	LIMIT $3 OFFSET $4`
+	if err := c.DB.Raw(query, productId, domain.ReviewPublished, limit, offset).Scan(&reviews).Error; err != nil {
+		return nil, errors.New("failed to list reviews")
+	}
+	return reviews, nil
+}
+
+// FindReviews lists reviews for moderation newest first, an empty status lists all of them
+func (c *reviewDB) FindReviews(ctx context.Context, status string, pagination requests.Pagination) ([]response.Review, error) {
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	var reviews []response.Review
+	query := `SELECT r.id, r.product_id, u.name AS user_name, r.rating, r.body, r.helpful_count, r.status,
+		COALESCE(r.hidden_reason, '') AS hidden_reason, r.created_at, r.updated_at
+	FROM product_reviews r
+	JOIN users u ON u.id = r.user_id
+	WHERE ($1 = '' OR r.status = $1)
+	ORDER BY r.created_at DESC, r.id DESC
+	LIMIT $2 OFFSET $3`
+	if err := c.DB.Raw(query, status, limit, offset).Scan(&reviews).Error; err != nil {
+		return nil, errors.New("failed to list reviews")
+	}
+	return reviews, nil
+}
+
+// SetReviewStatus hides or publishes a review for an admin, the reason is
+// cleared when it is published again
+func (c *reviewDB) SetReviewStatus(ctx context.Context, reviewId uint, status, reason string, adminId uint) (domain.Review, error) {
+	tx := c.DB.Begin()
+	var review domain.Review
+	query := `UPDATE product_reviews SET status = $1, hidden_reason = NULLIF($2, ''), moderated_by = $3, updated_at = NOW()
+	WHERE id = $4 RETURNING *`
+	if err := tx.Raw(query, status, reason, adminId, reviewId).Scan(&review).Error; err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	if review.ID == 0 {
+		tx.Rollback()
+		return domain.Review{}, errors.New("review not found")
+	}
+
+	if err := syncProductRating(tx, review.ProductID); err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.Review{}, err
+	}
+	return review, nil
+}
+
+// AddHelpfulVote counts the user's vote once, voting again changes nothing
+func (c *reviewDB) AddHelpfulVote(ctx context.Context, reviewId, userId uint) error {
+	query := `WITH vote AS (
+		INSERT INTO review_votes (review_id, user_id, created_at) VALUES ($1, $2, NOW())
+		ON CONFLICT DO NOTHING
+		RETURNING review_id
+	)
+	UPDATE product_reviews SET helpful_count = helpful_count + 1 WHERE id IN (SELECT review_id FROM vote)`
+	return c.DB.Exec(query, reviewId, userId).Error
+}
+
+func (c *reviewDB) RemoveHelpfulVote(ctx context.Context, reviewId, userId uint) error {
+	query := `WITH vote AS (
+		DELETE FROM review_votes WHERE review_id = $1 AND user_id = $2
+		RETURNING review_id
+	)
+	UPDATE product_reviews SET helpful_count = helpful_count - 1 WHERE id IN (SELECT review_id FROM vote)`
+	return c.DB.Exec(query, reviewId, userId).Error
+}
+
+// syncProductRating recomputes the product's rating summary inside tx from
+// its published reviews. The product is locked first so the counts are read
+// after any other review change to it has committed
+func syncProductRating(tx *gorm.DB, productId uint) error {
+	if err := tx.Exec(`SELECT id FROM products WHERE id = $1 FOR UPDATE`, productId).Error; err != nil {
+		return err
+	}
+	query := `UPDATE products SET
+		rating_avg = COALESCE((SELECT ROUND(AVG(rating), 2) FROM product_reviews WHERE product_id = $1 AND status = $2), 0),
+		rating_count = (SELECT COUNT(*) FROM product_reviews WHERE product_id = $1 AND status = $2)
+	WHERE id = $1`
+	return tx.Exec(query, productId, domain.ReviewPublished).Error
+}
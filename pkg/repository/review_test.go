@@ -0,0 +1,85 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/pricing"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func assertProductRating(t *testing.T, DB *gorm.DB, productId uint, wantAvg float64, wantCount int) {
+	t.Helper()
+	var rating struct {
+		RatingAvg   float64
+		RatingCount int
+	}
+	if err := DB.Raw(`SELECT rating_avg, rating_count FROM products WHERE id = $1`, productId).Scan(&rating).Error; err != nil {
+		t.Fatal(err)
+	}
+	if rating.RatingAvg != wantAvg || rating.RatingCount != wantCount {
+		t.Fatalf("expected a rating of %.2f from %d reviews, got %.2f from %d", wantAvg, wantCount, rating.RatingAvg, rating.RatingCount)
+	}
+}
+
+func TestReviewsKeepTheProductRating(t *testing.T) {
+	DB := openTestDatabase(t)
+	variantId, userIds := seedLastUnits(t, DB, 5, 3)
+	var productId uint
+	if err := DB.Raw(`SELECT product_id FROM product_variants WHERE id = $1`, variantId).Scan(&productId).Error; err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	orders := NewOrderRepository(DB, &pricing.Pricer{})
+	reviews := NewReviewRepository(DB)
+
+	// the first two buyers get their order delivered, the third is still waiting
+	for i, userId := range userIds {
+		order, err := orders.OrderAll(ctx, userId, 1, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if i < 2 {
+			if err = DB.Exec(`UPDATE orders SET order_status_id = $1 WHERE id = $2`, domain.OrderStatusDelivered, order.ID).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	for i, userId := range userIds {
+		verified, err := reviews.IsVerifiedBuyer(ctx, uint(userId), productId)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if verified != (i < 2) {
+			t.Fatalf("buyer %d: expected verified to be %v", i, i < 2)
+		}
+	}
+
+	first, err := reviews.SaveReview(ctx, domain.Review{ProductID: productId, UserID: uint(userIds[0]), Rating: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reviews.SaveReview(ctx, domain.Review{ProductID: productId, UserID: uint(userIds[1]), Rating: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = reviews.SaveReview(ctx, domain.Review{ProductID: productId, UserID: uint(userIds[0]), Rating: 1}); err == nil {
+		t.Fatal("expected a second review by the same user to be refused")
+	}
+	assertProductRating(t, DB, productId, 3.5, 2)
+
+	if _, err = reviews.SetReviewStatus(ctx, first.ID, domain.ReviewHidden, "spam", 1); err != nil {
+		t.Fatal(err)
+	}
+	assertProductRating(t, DB, productId, 2, 1)
+
+	// a vote is counted once however often it is sent
+	for i := 0; i < 2; i++ {
+		if err = reviews.AddHelpfulVote(ctx, first.ID, uint(userIds[1])); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if review, _ := reviews.FindReview(ctx, first.ID); review.HelpfulCount != 1 {
+		t.Fatalf("expected one helpful vote, got %d", review.HelpfulCount)
+	}
+}
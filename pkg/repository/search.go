@@ -0,0 +1,142 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"fmt"
+	"strings"
+)
+
+// priceBuckets are where the price facets split, a product priced at a bound
+// falls in the bucket starting there
+var priceBuckets = []int{500, 1000, 5000, 10000, 50000}
+
+const maxSuggestions = 5
+
+// SearchProducts ranks the products matching the text by ts_rank_cd over the
+// weighted search_vector. Facets are counted over the text match alone so
+// picking one doesn't hide the others
+func (c *productDB) SearchProducts(ctx context.Context, search requests.ProductSearch) (response.ProductSearch, error) {
+	result := response.ProductSearch{Page: search.Page, PerPage: search.PerPage}
+
+	args := map[string]interface{}{
+		"q":      search.Query,
+		"limit":  search.PerPage,
+		"offset": (search.Page - 1) * search.PerPage,
+	}
+	match := `p.deleted_at IS NULL AND p.search_vector @@ websearch_to_tsquery('english', @q)`
+	filters := []string{match}
+	if search.CategoryID != 0 {
+		filters = append(filters, `p.category_id IN (`+categorySubtree+`)`)
+		args["categories"] = []uint{search.CategoryID}
+	}
+	if search.Brand != "" {
+		filters = append(filters, `LOWER(p.brand) = LOWER(@brand)`)
+		args["brand"] = search.Brand
+	}
+	if search.MinPrice != nil {
+		filters = append(filters, `p.prize >= @min_price`)
+		args["min_price"] = *search.MinPrice
+	}
+	if search.MaxPrice != nil {
+		filters = append(filters, `p.prize <= @max_price`)
+		args["max_price"] = *search.MaxPrice
+	}
+	where := strings.Join(filters, " AND ")
+
+	query := `SELECT p.id, p.product_name AS name, p.description, p.brand, p.prize, p.qty_in_stock,
+		p.category_id, c.category_name, pi.thumbnail_key, p.rating_avg AS rating, p.rating_count AS review_count
+	FROM products p
+	LEFT JOIN categories c ON c.id = p.category_id
+	LEFT JOIN product_images pi ON pi.product_id = p.id AND pi.is_primary
+	WHERE ` + where + `
+	ORDER BY ts_rank_cd(p.search_vector, websearch_to_tsquery('english', @q)) DESC, p.id
+	LIMIT @limit OFFSET @offset`
+	if err := c.DB.Raw(query, args).Scan(&result.Products).Error; err != nil {
+		return response.ProductSearch{}, err
+	}
+	if err := c.DB.Raw(`SELECT COUNT(*) FROM products p WHERE `+where, args).Scan(&result.Total).Error; err != nil {
+		return response.ProductSearch{}, err
+	}
+
+	facets, err := c.searchFacets(match, args)
+	if err != nil {
+		return response.ProductSearch{}, err
+	}
+	result.Facets = facets
+
+	if result.Total == 0 {
+		if result.Suggestions, err = c.searchSuggestions(search.Query); err != nil {
+			return response.ProductSearch{}, err
+		}
+	}
+	return result, nil
+}
+
+func (c *productDB) searchFacets(match string, args map[string]interface{}) (response.SearchFacets, error) {
+	var facets response.SearchFacets
+
+	query := `SELECT p.category_id, c.category_name, COUNT(*) AS count
+	FROM products p
+	LEFT JOIN categories c ON c.id = p.category_id
+	WHERE ` + match + `
+	GROUP BY p.category_id, c.category_name
+	ORDER BY count DESC, c.category_name`
+	if err := c.DB.Raw(query, args).Scan(&facets.Categories).Error; err != nil {
+		return facets, err
+	}
+
+	query = `SELECT p.brand, COUNT(*) AS count
+	FROM products p
+	WHERE ` + match + `
+	GROUP BY p.brand
+	ORDER BY count DESC, p.brand`
+	if err := c.DB.Raw(query, args).Scan(&facets.Brands).Error; err != nil {
+		return facets, err
+	}
+
+	bounds := make([]string, len(priceBuckets))
+	for i, bound := range priceBuckets {
+		bounds[i] = fmt.Sprint(bound)
+	}
+	var buckets []struct {
+		Bucket int
+		Count  int
+	}
+	query = `SELECT WIDTH_BUCKET(p.prize, ARRAY[` + strings.Join(bounds, ",") + `]) AS bucket, COUNT(*) AS count
+	FROM products p
+	WHERE ` + match + `
+	GROUP BY bucket
+	ORDER BY bucket`
+	if err := c.DB.Raw(query, args).Scan(&buckets).Error; err != nil {
+		return facets, err
+	}
+	for _, bucket := range buckets {
+		facet := response.PriceFacet{Count: bucket.Count}
+		if bucket.Bucket > 0 {
+			facet.Min = priceBuckets[bucket.Bucket-1]
+		}
+		if bucket.Bucket < len(priceBuckets) {
+			max := priceBuckets[bucket.Bucket] - 1
+			facet.Max = &max
+		}
+		facets.Prices = append(facets.Prices, facet)
+	}
+	return facets, nil
+}
+
+// searchSuggestions are the product names and brands closest to the text by
+// trigram word similarity, for when a typo matched nothing
+func (c *productDB) searchSuggestions(text string) ([]string, error) {
+	var suggestions []string
+	query := `SELECT term FROM (
+		SELECT product_name AS term FROM products WHERE deleted_at IS NULL AND @q <% product_name
+		UNION
+		SELECT brand FROM products WHERE deleted_at IS NULL AND @q <% brand
+	) t
+	ORDER BY WORD_SIMILARITY(@q, term) DESC, term
+	LIMIT @limit`
+	err := c.DB.Raw(query, map[string]interface{}{"q": text, "limit": maxSuggestions}).Scan(&suggestions).Error
+	return suggestions, err
+}
@@ -0,0 +1,84 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func seedSearchProducts(t *testing.T, DB *gorm.DB) {
+	t.Helper()
+	var watches, clocks uint
+	if err := DB.Raw(`INSERT INTO categories (category_name) VALUES ('watches') RETURNING id`).Scan(&watches).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := DB.Raw(`INSERT INTO categories (category_name) VALUES ('clocks') RETURNING id`).Scan(&clocks).Error; err != nil {
+		t.Fatal(err)
+	}
+	products := []struct {
+		name, brand, description string
+		price                    int
+		category                 uint
+	}{
+		{"Steel Diver", "Titan", "a steel watch for swimming", 4500, watches},
+		{"Steel Chrono", "Casio", "stopwatch with a steel strap", 12000, watches},
+		{"Wall Clock", "Titan", "a clock with a steel frame", 800, clocks},
+		{"Leather Classic", "Fossil", "leather strap", 7000, watches},
+	}
+	for _, p := range products {
+		err := DB.Exec(`INSERT INTO products (product_name, brand, description, prize, qty_in_stock, category_id) VALUES ($1, $2, $3, $4, 1, $5)`,
+			p.name, p.brand, p.description, p.price, p.category).Error
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestSearchProductsRanksNameMatchesFirst(t *testing.T) {
+	DB := openTestDatabase(t)
+	seedSearchProducts(t, DB)
+	products := &productDB{DB: DB}
+
+	result, err := products.SearchProducts(context.Background(), requests.ProductSearch{Query: "steel", Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 3 || len(result.Products) != 3 {
+		t.Fatalf("expected 3 steel products, got %d", result.Total)
+	}
+	if name := result.Products[2].Name; name != "Wall Clock" {
+		t.Fatalf("a description match should rank below name matches, last is %s", name)
+	}
+
+	// facets count the text match, not the narrowed results
+	result, err = products.SearchProducts(context.Background(), requests.ProductSearch{Query: "steel", Brand: "titan", Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 2 || len(result.Facets.Brands) != 2 || len(result.Facets.Categories) != 2 {
+		t.Fatalf("unexpected result %+v", result)
+	}
+	prices := 0
+	for _, facet := range result.Facets.Prices {
+		prices += facet.Count
+	}
+	if prices != 3 {
+		t.Fatalf("expected the price facets to count 3 products, got %d", prices)
+	}
+}
+
+func TestSearchProductsSuggestsOnTypos(t *testing.T) {
+	DB := openTestDatabase(t)
+	seedSearchProducts(t, DB)
+	products := &productDB{DB: DB}
+
+	result, err := products.SearchProducts(context.Background(), requests.ProductSearch{Query: "fosil", Page: 1, PerPage: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Total != 0 || len(result.Suggestions) == 0 || result.Suggestions[0] != "Fossil" {
+		t.Fatalf("expected Fossil to be suggested, got %+v", result.Suggestions)
+	}
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestArchivedProductNameCanBeUsedAgain(t *testing.T) {
+	DB := openTestDatabase(t)
+	products := &productDB{DB: DB}
+	ctx := context.Background()
+
+	watches, err := products.Addcategory(ctx, requests.Category{Name: "watches"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	diver := requests.Product{Name: "Steel Diver", Brand: "Titan", Prize: 4500, Qty_in_stock: 2, Category_Id: strconv.Itoa(int(watches.ID))}
+	first, err := products.SaveProduct(ctx, diver, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.SaveProduct(ctx, diver, 1); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected a readable duplicate name error, got %v", err)
+	}
+
+	if err := products.DeleteProduct(ctx, first.Id); err != nil {
+		t.Fatal(err)
+	}
+	second, err := products.SaveProduct(ctx, diver, 1)
+	if err != nil {
+		t.Fatalf("expected the archived product's name to be free, got %v", err)
+	}
+	if err := products.RestoreProduct(ctx, first.Id); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected the restore to be refused while the name is taken, got %v", err)
+	}
+
+	if _, err := products.UpdateProduct(ctx, second.Id, requests.Product{Name: "Steel Diver II", Brand: "Titan", Prize: 4500, Qty_in_stock: 2, Category_Id: diver.Category_Id}, 1); err != nil {
+		t.Fatal(err)
+	}
+	if err := products.RestoreProduct(ctx, first.Id); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := products.UpdateProduct(ctx, second.Id, diver, 1); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected a readable duplicate name error on update, got %v", err)
+	}
+}
+
+func TestRestoreCategory(t *testing.T) {
+	DB := openTestDatabase(t)
+	categories := &productDB{DB: DB}
+	ctx := context.Background()
+
+	clothing, err := categories.Addcategory(ctx, requests.Category{Name: "clothing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	shirts, err := categories.Addcategory(ctx, requests.Category{Name: "shirts", ParentID: &clothing.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := categories.DeleteCategory(ctx, int(shirts.ID), 0); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := categories.Addcategory(ctx, requests.Category{Name: "Shirts", ParentID: &clothing.ID}); err != nil {
+		t.Fatalf("expected the archived category's name to be free, got %v", err)
+	}
+
+	if _, err := categories.RestoreCategory(ctx, int(shirts.ID)); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Fatalf("expected the restore to be refused next to a live sibling with the name, got %v", err)
+	}
+	if _, err := categories.RestoreCategory(ctx, int(clothing.ID)); err == nil {
+		t.Fatal("expected a live category not to be restored")
+	}
+
+	// with its parent archived too the category comes back at the top level
+	men, err := categories.Addcategory(ctx, requests.Category{Name: "men", ParentID: &clothing.ID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := categories.DeleteCategory(ctx, int(men.ID), 0); err != nil {
+		t.Fatal(err)
+	}
+	if err := categories.DeleteCategory(ctx, int(clothing.ID), 0); err != nil {
+		t.Fatal(err)
+	}
+	restored, err := categories.RestoreCategory(ctx, int(men.ID))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if restored.ParentID != 0 || restored.CategoryName != "men" {
+		t.Fatalf("expected men back at the top level, got %+v", restored)
+	}
+}
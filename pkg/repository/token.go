@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"ecommerce/pkg/token"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type tokenDB struct {
+	DB *gorm.DB
+}
+
+func NewTokenRepository(DB *gorm.DB) interfaces.TokenRepo {
+	return &tokenDB{
+		DB: DB,
+	}
+}
+
+// CreateSession saves a new sign in with its first refresh token
+func (c *tokenDB) CreateSession(ctx context.Context, session domain.Session, refresh domain.RefreshToken) error {
+	tx := c.DB.Begin()
+	query := `INSERT INTO sessions (id, subject_id, audience, access_jti, user_agent, ip_address, created_at, last_seen_at, expires_at)
+	VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW(), $7)`
+	err := tx.Exec(query, session.ID, session.SubjectID, session.Audience, session.AccessJTI,
+		session.UserAgent, session.IPAddress, session.ExpiresAt).Error
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := saveRefreshToken(tx, session.ID, session.SubjectID, session.Audience, refresh); err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// RotateRefreshToken uses up the audience's token and saves next in its
+// session, which now only accepts the access token with accessJti. The used
+// token is returned. A token that was already used is taken as stolen, the
+// session is revoked so neither copy works anymore
+func (c *tokenDB) RotateRefreshToken(ctx context.Context, tokenHash, audience, accessJti string, next domain.RefreshToken) (domain.RefreshToken, error) {
+	tx := c.DB.Begin()
+	var current domain.RefreshToken
+	if err := tx.Raw(`SELECT * FROM refresh_tokens WHERE token_hash = $1 FOR UPDATE`, tokenHash).Scan(&current).Error; err != nil {
+		tx.Rollback()
+		return domain.RefreshToken{}, err
+	}
+	if current.ID == 0 || current.Audience != audience || current.ExpiresAt.Before(time.Now()) {
+		tx.Rollback()
+		return domain.RefreshToken{}, token.ErrInvalid
+	}
+	if current.UsedAt != nil {
+		if err := tx.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND revoked_at IS NULL`, current.FamilyID).Error; err != nil {
+			tx.Rollback()
+			return domain.RefreshToken{}, err
+		}
+		if err := tx.Commit().Error; err != nil {
+			tx.Rollback()
+			return domain.RefreshToken{}, err
+		}
+		return domain.RefreshToken{}, errors.New("refresh token was already used, sign in again")
+	}
+
+	var renewed []string
+	query := `UPDATE sessions SET access_jti = $1, last_seen_at = NOW(), expires_at = $2
+	WHERE id = $3 AND revoked_at IS NULL RETURNING id`
+	if err := tx.Raw(query, accessJti, next.ExpiresAt, current.FamilyID).Scan(&renewed).Error; err != nil {
+		tx.Rollback()
+		return domain.RefreshToken{}, err
+	}
+	if len(renewed) == 0 {
+		tx.Rollback()
+		return domain.RefreshToken{}, token.ErrInvalid
+	}
+	if err := tx.Exec(`UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, current.ID).Error; err != nil {
+		tx.Rollback()
+		return domain.RefreshToken{}, err
+	}
+	if err := saveRefreshToken(tx, current.FamilyID, current.SubjectID, current.Audience, next); err != nil {
+		tx.Rollback()
+		return domain.RefreshToken{}, err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.RefreshToken{}, err
+	}
+	return current, nil
+}
+
+// IsSessionActive tells whether the access token with accessJti is still the
+// current one of a live session
+func (c *tokenDB) IsSessionActive(ctx context.Context, sessionId, accessJti string) (bool, error) {
+	var active bool
+	query := `SELECT EXISTS (
+		SELECT 1 FROM sessions
+		WHERE id = $1 AND access_jti = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	)`
+	err := c.DB.WithContext(ctx).Raw(query, sessionId, accessJti).Scan(&active).Error
+	return active, err
+}
+
+// FindSessions lists the live sessions of the subject, last used first
+func (c *tokenDB) FindSessions(ctx context.Context, subjectId uint, audience string) ([]response.Session, error) {
+	var sessions []response.Session
+	query := `SELECT id, user_agent, ip_address, created_at, last_seen_at, expires_at
+	FROM sessions
+	WHERE subject_id = $1 AND audience = $2 AND revoked_at IS NULL AND expires_at > NOW()
+	ORDER BY last_seen_at DESC, created_at DESC`
+	if err := c.DB.WithContext(ctx).Raw(query, subjectId, audience).Scan(&sessions).Error; err != nil {
+		return nil, errors.New("failed to list sessions")
+	}
+	return sessions, nil
+}
+
+// RevokeSessionByToken signs out the session the refresh token belongs to,
+// an unknown token is ignored
+func (c *tokenDB) RevokeSessionByToken(ctx context.Context, tokenHash string) error {
+	query := `UPDATE sessions SET revoked_at = NOW()
+	WHERE id = (SELECT family_id FROM refresh_tokens WHERE token_hash = $1) AND revoked_at IS NULL`
+	return c.DB.WithContext(ctx).Exec(query, tokenHash).Error
+}
+
+func (c *tokenDB) RevokeSession(ctx context.Context, subjectId uint, audience, sessionId string) error {
+	var revoked []string
+	query := `UPDATE sessions SET revoked_at = NOW()
+	WHERE id = $1 AND subject_id = $2 AND audience = $3 AND revoked_at IS NULL RETURNING id`
+	if err := c.DB.WithContext(ctx).Raw(query, sessionId, subjectId, audience).Scan(&revoked).Error; err != nil {
+		return err
+	}
+	if len(revoked) == 0 {
+		return errors.New("session not found")
+	}
+	return nil
+}
+
+func (c *tokenDB) RevokeSessions(ctx context.Context, subjectId uint, audience, except string) error {
+	return revokeSessions(c.DB.WithContext(ctx), subjectId, audience, except)
+}
+
+func saveRefreshToken(db *gorm.DB, familyId string, subjectId uint, audience string, refresh domain.RefreshToken) error {
+	query := `INSERT INTO refresh_tokens (token_hash, family_id, subject_id, audience, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, $5, NOW())`
+	return db.Exec(query, refresh.TokenHash, familyId, subjectId, audience, refresh.ExpiresAt).Error
+}
+
+// revokeSessions signs the subject out everywhere but the session except,
+// which may be empty
+func revokeSessions(db *gorm.DB, subjectId uint, audience, except string) error {
+	query := `UPDATE sessions SET revoked_at = NOW()
+	WHERE subject_id = $1 AND audience = $2 AND id <> $3 AND revoked_at IS NULL`
+	return db.Exec(query, subjectId, audience, except).Error
+}
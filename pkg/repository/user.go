@@ -31,16 +31,90 @@ func (c *userDatabase) UserSignup(ctx context.Context, user requests.Usersign) (
 
 func (c *userDatabase) UserLogin(ctx context.Context, Email string) (domain.Users, error) {
 	var userData domain.Users
-	err := c.DB.Raw("SELECT * FROM users WHERE email=?", Email).Scan(&userData).Error
+	err := c.DB.Raw("SELECT * FROM users WHERE email=? AND deleted_at IS NULL", Email).Scan(&userData).Error
 	return userData, err
 }
 
 func (c *userDatabase) OtpLogin(mbnum string) (int, error) {
 	var id int
-	query := "SELECT id FROM users WHERE mobile=?"
+	query := "SELECT id FROM users WHERE mobile=? AND deleted_at IS NULL"
 	err := c.DB.Raw(query, mbnum).Scan(&id).Error
 	return id, err
 }
+
+// FindActiveBlock is the block the user is under, a zero ID when not blocked
+func (c *userDatabase) FindActiveBlock(ctx context.Context, userId uint) (domain.UserStatus, error) {
+	var status domain.UserStatus
+	query := `SELECT * FROM user_statuses WHERE users_id = $1 AND ` + activeBlock + `
+	ORDER BY blocked_until DESC NULLS FIRST LIMIT 1`
+	err := c.DB.WithContext(ctx).Raw(query, userId).Scan(&status).Error
+	return status, err
+}
+
+func (c *userDatabase) FindUserByID(ctx context.Context, userId uint) (domain.Users, error) {
+	var user domain.Users
+	err := c.DB.WithContext(ctx).Raw("SELECT * FROM users WHERE id = $1 AND deleted_at IS NULL", userId).Scan(&user).Error
+	return user, err
+}
+
+func (c *userDatabase) UpdatePassword(ctx context.Context, userId uint, passwordHash string) error {
+	return c.DB.WithContext(ctx).Exec("UPDATE users SET password = $1 WHERE id = $2", passwordHash, userId).Error
+}
+
+// SavePasswordReset keeps the new reset token, dropping the user's unused
+// ones so only the latest works
+func (c *userDatabase) SavePasswordReset(ctx context.Context, reset domain.PasswordReset) error {
+	tx := c.DB.WithContext(ctx).Begin()
+	if err := tx.Exec("DELETE FROM password_resets WHERE user_id = $1 AND used_at IS NULL", reset.UserID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	query := `INSERT INTO password_resets (user_id, token_hash, channel, expires_at, created_at)
+	VALUES ($1, $2, $3, $4, NOW())`
+	if err := tx.Exec(query, reset.UserID, reset.TokenHash, reset.Channel, reset.ExpiresAt).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// ResetPassword uses up the reset token and sets the password of its user,
+// whose id is returned
+func (c *userDatabase) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uint, error) {
+	tx := c.DB.WithContext(ctx).Begin()
+	var userIds []uint
+	query := `UPDATE password_resets SET used_at = NOW()
+	WHERE token_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	RETURNING user_id`
+	if err := tx.Raw(query, tokenHash).Scan(&userIds).Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if len(userIds) == 0 {
+		tx.Rollback()
+		return 0, errors.New("reset token is invalid or expired")
+	}
+
+	var updated []uint
+	if err := tx.Raw("UPDATE users SET password = $1 WHERE id = $2 AND deleted_at IS NULL RETURNING id", passwordHash, userIds[0]).Scan(&updated).Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	if len(updated) == 0 {
+		tx.Rollback()
+		return 0, errors.New("reset token is invalid or expired")
+	}
+	if err := tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+	return userIds[0], nil
+}
+
 func (c *userDatabase) AddAdress(ctx context.Context, UserID int, address requests.AddressReq) (domain.Address, error) {
 	var existaddress, newAddress domain.Address
 
@@ -104,10 +178,11 @@ func (c *userDatabase) FindAllWishListItemsByUserID(ctx context.Context, userID
 
 	var wishLists []response.Wishlist
 
-	favourite := ` SELECT *
+	favourite := ` SELECT p.id AS product_id, p.product_name, p.prize AS price, p.qty_in_stock, pi.thumbnail_key
 	FROM products p
 	JOIN wish_lists w ON w.product_id = p.id
-	WHERE w.user_id = ?`
+	LEFT JOIN product_images pi ON pi.product_id = p.id AND pi.is_primary
+	WHERE w.user_id = ? AND p.deleted_at IS NULL`
 
 	if c.DB.Raw(favourite, userID).Scan(&wishLists).Error != nil {
 		return wishLists, errors.New("faild to get wish_list items")
@@ -137,7 +212,7 @@ func (c *userDatabase) RemoveWishListItem(ctx context.Context, wishList domain.W
 func (c *userDatabase) FindProduct(ctx context.Context, id uint) (response.Product, error) {
 	var product response.Product
 	query := `SELECT p.id,p.product_name as name,p.description,p.brand,p.prize,p.category_id,p.qty_in_stock,c.category_name,p.created_at,p.updated_at FROM products p 
-		JOIN categories c ON p.category_id=c.id WHERE p.id=$1`
+		JOIN categories c ON p.category_id=c.id WHERE p.id=$1 AND p.deleted_at IS NULL`
 	err := c.DB.Raw(query, id).Scan(&product).Error
 	return product, err
 }
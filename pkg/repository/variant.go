@@ -0,0 +1,229 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/domain"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+func (c *productDB) FindVariants(ctx context.Context, productId int) ([]domain.ProductVariant, error) {
+	return findVariants(c.DB, uint(productId))
+}
+
+// SaveVariant adds a variant to a product, its opening stock goes in the ledger
+func (c *productDB) SaveVariant(ctx context.Context, variant domain.ProductVariant, adminId int) (domain.ProductVariant, error) {
+	tx := c.DB.Begin()
+
+	var exists bool
+	if err := tx.Raw(`SELECT EXISTS(SELECT 1 FROM products WHERE id = $1)`, variant.ProductID).Scan(&exists).Error; err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, err
+	}
+	if !exists {
+		tx.Rollback()
+		return domain.ProductVariant{}, fmt.Errorf("no product found with this id")
+	}
+
+	variant, err := saveVariant(tx, variant, uint(adminId))
+	if err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, err
+	}
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, err
+	}
+	return variant, nil
+}
+
+// UpdateVariant changes the sku, attributes and price of a variant. An empty
+// sku, nil attributes or a negative price are left as they are
+func (c *productDB) UpdateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error) {
+	tx := c.DB.Begin()
+
+	current, err := findVariant(tx, variant.ID)
+	if err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, err
+	}
+	if variant.SKU == "" {
+		variant.SKU = current.SKU
+	}
+	if variant.Attributes == nil {
+		variant.Attributes = current.Attributes
+	}
+	if variant.Price < 0 {
+		variant.Price = current.Price
+	}
+	if err = checkSkuFree(tx, variant.SKU, variant.ID); err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, err
+	}
+
+	var updated domain.ProductVariant
+	update := `UPDATE product_variants SET sku = $1, attributes = $2, price = $3, updated_at = NOW() WHERE id = $4
+	RETURNING *`
+	if err = tx.Raw(update, variant.SKU, variant.Attributes, variant.Price, variant.ID).Scan(&updated).Error; err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, err
+	}
+	if err = syncProductSummary(tx, updated.ProductID); err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return domain.ProductVariant{}, err
+	}
+	return updated, nil
+}
+
+// DeleteVariant removes a variant that was never ordered. It is taken out of
+// carts too, a product always keeps at least one variant
+func (c *productDB) DeleteVariant(ctx context.Context, variantId int) error {
+	tx := c.DB.Begin()
+
+	variant, err := findVariant(tx, uint(variantId))
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	var usage struct {
+		Variants int
+		Ordered  bool
+		Held     bool
+	}
+	findUsage := `SELECT
+		(SELECT COUNT(*) FROM product_variants WHERE product_id = $1) AS variants,
+		EXISTS(SELECT 1 FROM order_lines WHERE variant_id = $2) AS ordered,
+		EXISTS(SELECT 1 FROM stock_reservations WHERE variant_id = $2 AND status = $3) AS held`
+	if err = tx.Raw(findUsage, variant.ProductID, variant.ID, domain.ReservationHeld).Scan(&usage).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	switch {
+	case usage.Variants <= 1:
+		tx.Rollback()
+		return fmt.Errorf("a product needs at least one variant")
+	case usage.Ordered:
+		tx.Rollback()
+		return fmt.Errorf("the variant has been ordered, it can't be deleted")
+	case usage.Held:
+		tx.Rollback()
+		return fmt.Errorf("the variant is held by a checkout, try again later")
+	}
+
+	// carts are priced again the next time they are viewed or checked out
+	if err = tx.Exec(`DELETE FROM cart_items WHERE variant_id = $1`, variant.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = tx.Exec(`DELETE FROM product_variants WHERE id = $1`, variant.ID).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	if err = syncProductSummary(tx, variant.ProductID); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err = tx.Commit().Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+	return nil
+}
+
+// findVariants lists the variants of a product, cheapest first
+func findVariants(db *gorm.DB, productId uint) ([]domain.ProductVariant, error) {
+	var variants []domain.ProductVariant
+	query := `SELECT * FROM product_variants WHERE product_id = $1 ORDER BY price, id`
+	err := db.Raw(query, productId).Scan(&variants).Error
+	return variants, err
+}
+
+// findVariant locks the variant inside tx
+func findVariant(tx *gorm.DB, variantId uint) (domain.ProductVariant, error) {
+	var variant domain.ProductVariant
+	if err := tx.Raw(`SELECT * FROM product_variants WHERE id = $1 FOR UPDATE`, variantId).Scan(&variant).Error; err != nil {
+		return domain.ProductVariant{}, err
+	}
+	if variant.ID == 0 {
+		return domain.ProductVariant{}, fmt.Errorf("no product variant found with this id")
+	}
+	return variant, nil
+}
+
+// saveVariant inserts the variant inside tx and books its stock as a restock.
+// A variant without a sku gets one made from the product and variant ids
+func saveVariant(tx *gorm.DB, variant domain.ProductVariant, adminId uint) (domain.ProductVariant, error) {
+	if variant.QtyInStock < 0 {
+		return domain.ProductVariant{}, fmt.Errorf("stock can't be negative")
+	}
+	if variant.Price < 0 {
+		return domain.ProductVariant{}, fmt.Errorf("price can't be negative")
+	}
+
+	var id uint
+	if err := tx.Raw(`SELECT nextval(pg_get_serial_sequence('product_variants', 'id'))`).Scan(&id).Error; err != nil {
+		return domain.ProductVariant{}, err
+	}
+	if variant.SKU == "" {
+		variant.SKU = fmt.Sprintf("P%d-V%d", variant.ProductID, id)
+	}
+	if err := checkSkuFree(tx, variant.SKU, id); err != nil {
+		return domain.ProductVariant{}, err
+	}
+	if variant.Attributes == nil {
+		variant.Attributes = domain.VariantAttributes{}
+	}
+
+	var saved domain.ProductVariant
+	insert := `INSERT INTO product_variants (id, product_id, sku, attributes, price, qty_in_stock, created_at, updated_at)
+	VALUES ($1, $2, $3, $4, $5, 0, NOW(), NOW())
+	RETURNING *`
+	err := tx.Raw(insert, id, variant.ProductID, variant.SKU, variant.Attributes, variant.Price).Scan(&saved).Error
+	if err != nil {
+		return domain.ProductVariant{}, err
+	}
+
+	if variant.QtyInStock > 0 {
+		movement, err := moveStock(tx, domain.StockMovement{
+			VariantID: saved.ID,
+			QtyChange: variant.QtyInStock,
+			Reason:    domain.StockRestock,
+			AdminID:   adminId,
+			Note:      "opening stock",
+		})
+		if err != nil {
+			return domain.ProductVariant{}, err
+		}
+		saved.QtyInStock = movement.QtyAfter
+	}
+	return saved, syncProductSummary(tx, saved.ProductID)
+}
+
+func checkSkuFree(tx *gorm.DB, sku string, variantId uint) error {
+	var taken bool
+	query := `SELECT EXISTS(SELECT 1 FROM product_variants WHERE sku = $1 AND id <> $2)`
+	if err := tx.Raw(query, sku, variantId).Scan(&taken).Error; err != nil {
+		return err
+	}
+	if taken {
+		return fmt.Errorf("sku %s is already in use", sku)
+	}
+	return nil
+}
+
+// syncProductSummary keeps the product's price at its cheapest variant and its
+// stock at the total of its variants, listings and filters read them from there
+func syncProductSummary(tx *gorm.DB, productId uint) error {
+	update := `UPDATE products p SET prize = COALESCE(v.price, p.prize), qty_in_stock = COALESCE(v.qty, 0)
+	FROM (SELECT MIN(price) AS price, SUM(qty_in_stock) AS qty FROM product_variants WHERE product_id = $1) v
+	WHERE p.id = $1`
+	return tx.Exec(update, productId).Error
+}
@@ -0,0 +1,82 @@
+package repository
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"errors"
+
+	"gorm.io/gorm"
+)
+
+type walletDB struct {
+	DB *gorm.DB
+}
+
+func NewWalletRepository(DB *gorm.DB) interfaces.WalletRepo {
+	return &walletDB{
+		DB: DB,
+	}
+}
+
+// FindWalletByUserID returns an empty wallet when the user has never been credited
+func (c *walletDB) FindWalletByUserID(ctx context.Context, userID uint) (domain.Wallet, error) {
+	var wallet domain.Wallet
+	query := `SELECT * FROM wallets WHERE user_id = $1`
+	if err := c.DB.WithContext(ctx).Raw(query, userID).Scan(&wallet).Error; err != nil {
+		return wallet, errors.New("failed to find wallet")
+	}
+	wallet.UserID = userID
+	return wallet, nil
+}
+
+func (c *walletDB) FindTransactionsByUserID(ctx context.Context, userID uint, pagination requests.Pagination) ([]domain.Transaction, error) {
+	var transactions []domain.Transaction
+
+	limit := pagination.PerPage
+	offset := (pagination.Page - 1) * limit
+
+	query := `SELECT t.* FROM transactions t
+	JOIN wallets w ON w.id = t.wallet_id
+	WHERE w.user_id = $1
+	ORDER BY t.created_at DESC, t.id DESC
+	LIMIT $2 OFFSET $3`
+	if err := c.DB.WithContext(ctx).Raw(query, userID, limit, offset).Scan(&transactions).Error; err != nil {
+		return nil, errors.New("failed to get wallet transactions")
+	}
+	return transactions, nil
+}
+
+// creditWallet adds amount to the user's wallet inside tx, creating the wallet on first use
+func creditWallet(tx *gorm.DB, userID, orderID uint, amount float64, description string) error {
+	var walletID uint
+	upsert := `INSERT INTO wallets (user_id, balance, updated_at) VALUES ($1, $2, NOW())
+	ON CONFLICT (user_id) DO UPDATE SET balance = wallets.balance + EXCLUDED.balance, updated_at = NOW()
+	RETURNING id`
+	if err := tx.Raw(upsert, userID, amount).Scan(&walletID).Error; err != nil {
+		return err
+	}
+	return saveTransaction(tx, walletID, orderID, amount, domain.TransactionCredit, description)
+}
+
+// debitWallet takes amount from the user's wallet inside tx, it never lets the balance go negative
+func debitWallet(tx *gorm.DB, userID, orderID uint, amount float64, description string) error {
+	var walletID uint
+	update := `UPDATE wallets SET balance = balance - $1, updated_at = NOW()
+	WHERE user_id = $2 AND balance >= $1
+	RETURNING id`
+	if err := tx.Raw(update, amount, userID).Scan(&walletID).Error; err != nil {
+		return err
+	}
+	if walletID == 0 {
+		return errors.New("insufficient wallet balance")
+	}
+	return saveTransaction(tx, walletID, orderID, amount, domain.TransactionDebit, description)
+}
+
+func saveTransaction(tx *gorm.DB, walletID, orderID uint, amount float64, transactionType, description string) error {
+	insert := `INSERT INTO transactions (wallet_id, order_id, amount, transaction_type, description, created_at)
+	VALUES ($1, NULLIF($2::bigint, 0), $3, $4, $5, NOW())`
+	return tx.Exec(insert, walletID, orderID, amount, transactionType, description).Error
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLocalStorage(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewLocalStorage(dir, "http://localhost:3000/images/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err = store.Save(ctx, "products/4/a1.jpg", strings.NewReader("image"), "image/jpeg"); err != nil {
+		t.Fatal(err)
+	}
+	file, err := store.Open(ctx, "products/4/a1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(file)
+	file.Close()
+	if string(body) != "image" {
+		t.Fatalf("got %q back", body)
+	}
+	if url := store.URL("products/4/a1.jpg"); url != "http://localhost:3000/images/products/4/a1.jpg" {
+		t.Fatalf("unexpected url %s", url)
+	}
+
+	// the temporary file is renamed into place, nothing else is left behind
+	entries, err := os.ReadDir(filepath.Join(dir, "products", "4"))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("expected only the saved file, got %v (%v)", entries, err)
+	}
+
+	if err = store.Delete(ctx, "products/4/a1.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if err = store.Delete(ctx, "products/4/a1.jpg"); err != nil {
+		t.Fatalf("deleting a missing file should not fail: %v", err)
+	}
+	if _, err = store.Open(ctx, "products/4/a1.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected not found, got %v", err)
+	}
+}
+
+func TestLocalStorageStaysInsideItsDirectory(t *testing.T) {
+	root := t.TempDir()
+	dir := filepath.Join(root, "uploads")
+	store, err := NewLocalStorage(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err = store.Save(ctx, "../escaped.jpg", strings.NewReader("x"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = os.Stat(filepath.Join(root, "escaped.jpg")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatal("a key climbing out of the directory was written outside it")
+	}
+	if _, err = os.Stat(filepath.Join(dir, "escaped.jpg")); err != nil {
+		t.Fatalf("expected the file inside the storage directory: %v", err)
+	}
+
+	for _, key := range []string{"", "/", `products\4\a1.jpg`} {
+		if err = store.Save(ctx, key, strings.NewReader("x"), ""); err == nil {
+			t.Fatalf("expected key %q to be refused", key)
+		}
+	}
+}
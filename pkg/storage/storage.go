@@ -0,0 +1,33 @@
+package storage
+
+import (
+	"context"
+	"ecommerce/pkg/config"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// ErrNotFound is returned by Open when nothing is stored under the key
+var ErrNotFound = errors.New("file not found")
+
+// Storage keeps uploaded files under slash separated keys like
+// products/12/3f9c.jpg. The local disk is the only backend for now, an S3
+// compatible one only has to implement this
+type Storage interface {
+	Save(ctx context.Context, key string, body io.Reader, contentType string) error
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+	// URL is where clients download the file stored under key
+	URL(key string) string
+}
+
+// NewStorage picks the backend from STORAGE_BACKEND, local unless it is set otherwise
+func NewStorage(cfg config.Config) (Storage, error) {
+	switch cfg.STORAGE_BACKEND {
+	case "", "local":
+		return NewLocalStorage(cfg.STORAGE_DIR, cfg.STORAGE_BASE_URL)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.STORAGE_BACKEND)
+	}
+}
@@ -0,0 +1,183 @@
+package token
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"ecommerce/pkg/config"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt"
+)
+
+// Audiences keep user and admin tokens apart, a token only passes the
+// middleware of its own audience
+const (
+	AudienceUser  = "user"
+	AudienceAdmin = "admin"
+)
+
+const issuer = "ecommerce"
+
+// minKeyLength is the shortest HMAC secret accepted, in bytes
+const minKeyLength = 32
+
+// ErrInvalid is returned for any token that can't be trusted, the reason is
+// left out so callers don't leak it
+var ErrInvalid = errors.New("invalid token")
+
+// Claims are what an access token carries. Subject is the user or admin id,
+// Session the session it was issued for and Id its jti
+type Claims struct {
+	jwt.StandardClaims
+	Session string `json:"sid,omitempty"`
+}
+
+// SubjectID is the id of the user or admin the token was issued to
+func (c Claims) SubjectID() uint {
+	id, _ := strconv.ParseUint(c.Subject, 10, 64)
+	return uint(id)
+}
+
+// Manager signs and verifies access tokens. Every key in JWT_KEYS verifies
+// tokens, only the one named by JWT_SIGNING_KID signs new ones, so a new key
+// can be rolled out before the old one is dropped
+type Manager struct {
+	keys       map[string][]byte
+	signingKid string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+}
+
+// NewManager reads the keys from JWT_KEYS, a comma separated list of kid:secret
+// pairs. The first key signs unless JWT_SIGNING_KID picks another
+func NewManager(cfg config.Config) (*Manager, error) {
+	keys, order, err := parseKeys(cfg.JWT_KEYS)
+	if err != nil {
+		return nil, err
+	}
+	signingKid := cfg.JWT_SIGNING_KID
+	if signingKid == "" {
+		signingKid = order[0]
+	}
+	if _, ok := keys[signingKid]; !ok {
+		return nil, fmt.Errorf("JWT_SIGNING_KID %q is not one of JWT_KEYS", signingKid)
+	}
+	if cfg.ACCESS_TOKEN_MINUTES <= 0 || cfg.REFRESH_TOKEN_DAYS <= 0 {
+		return nil, fmt.Errorf("ACCESS_TOKEN_MINUTES and REFRESH_TOKEN_DAYS must be positive")
+	}
+	return &Manager{
+		keys:       keys,
+		signingKid: signingKid,
+		accessTTL:  time.Duration(cfg.ACCESS_TOKEN_MINUTES) * time.Minute,
+		refreshTTL: time.Duration(cfg.REFRESH_TOKEN_DAYS) * 24 * time.Hour,
+	}, nil
+}
+
+func parseKeys(value string) (map[string][]byte, []string, error) {
+	keys := make(map[string][]byte)
+	var order []string
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		kid, secret, ok := strings.Cut(pair, ":")
+		if !ok || kid == "" {
+			return nil, nil, fmt.Errorf("JWT_KEYS entries must look like kid:secret")
+		}
+		if len(secret) < minKeyLength {
+			return nil, nil, fmt.Errorf("JWT_KEYS secret for %q must be at least %d bytes", kid, minKeyLength)
+		}
+		if _, dup := keys[kid]; dup {
+			return nil, nil, fmt.Errorf("JWT_KEYS has kid %q twice", kid)
+		}
+		keys[kid] = []byte(secret)
+		order = append(order, kid)
+	}
+	if len(order) == 0 {
+		return nil, nil, fmt.Errorf("JWT_KEYS is empty")
+	}
+	return keys, order, nil
+}
+
+// AccessTTL is how long an access token lasts
+func (m *Manager) AccessTTL() time.Duration {
+	return m.accessTTL
+}
+
+// RefreshTTL is how long a refresh token lasts when it isn't used
+func (m *Manager) RefreshTTL() time.Duration {
+	return m.refreshTTL
+}
+
+// Issue signs a short lived access token with the id jti for the subject in
+// the audience
+func (m *Manager) Issue(subjectId uint, audience, session, jti string) (string, Claims, error) {
+	now := time.Now()
+	claims := Claims{
+		StandardClaims: jwt.StandardClaims{
+			Id:        jti,
+			Subject:   strconv.FormatUint(uint64(subjectId), 10),
+			Audience:  audience,
+			Issuer:    issuer,
+			IssuedAt:  now.Unix(),
+			ExpiresAt: now.Add(m.accessTTL).Unix(),
+		},
+		Session: session,
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	token.Header["kid"] = m.signingKid
+	signed, err := token.SignedString(m.keys[m.signingKid])
+	if err != nil {
+		return "", Claims{}, err
+	}
+	return signed, claims, nil
+}
+
+// Verify checks the signature with the key named in the kid header, the
+// expiry and that the token belongs to the audience
+func (m *Manager) Verify(tokenString, audience string) (Claims, error) {
+	var claims Claims
+	parsed, err := jwt.ParseWithClaims(tokenString, &claims, func(t *jwt.Token) (interface{}, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+		}
+		kid, _ := t.Header["kid"].(string)
+		key, ok := m.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("unknown key %q", kid)
+		}
+		return key, nil
+	})
+	if err != nil || !parsed.Valid {
+		return Claims{}, ErrInvalid
+	}
+	if !claims.VerifyAudience(audience, true) || !claims.VerifyIssuer(issuer, true) ||
+		claims.ExpiresAt == 0 || claims.SubjectID() == 0 {
+		return Claims{}, ErrInvalid
+	}
+	return claims, nil
+}
+
+// RandomString is n random bytes in url safe base64, for token ids and
+// refresh tokens
+func RandomString(n int) (string, error) {
+	buf := make([]byte, n)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+// Hash is how a refresh token is kept in the database, so a leaked table
+// can't be used to sign in
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
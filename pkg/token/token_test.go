@@ -0,0 +1,97 @@
+package token
+
+import (
+	"ecommerce/pkg/config"
+	"strings"
+	"testing"
+)
+
+var (
+	oldKey = "old:" + strings.Repeat("o", minKeyLength)
+	newKey = "new:" + strings.Repeat("n", minKeyLength)
+)
+
+func newTestManager(t *testing.T, keys, signingKid string) *Manager {
+	t.Helper()
+	manager, err := NewManager(config.Config{
+		JWT_KEYS:             keys,
+		JWT_SIGNING_KID:      signingKid,
+		ACCESS_TOKEN_MINUTES: 15,
+		REFRESH_TOKEN_DAYS:   30,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return manager
+}
+
+func TestKeyRollover(t *testing.T) {
+	before := newTestManager(t, oldKey, "")
+	issued, _, err := before.Issue(7, AudienceUser, "family", "jti")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the new key is rolled out first, tokens signed with the old one still pass
+	during := newTestManager(t, oldKey+","+newKey, "new")
+	claims, err := during.Verify(issued, AudienceUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SubjectID() != 7 || claims.Session != "family" {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+	fresh, _, err := during.Issue(7, AudienceUser, "family", "jti")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// once the old key is dropped only tokens signed with the new one pass
+	after := newTestManager(t, newKey, "")
+	if _, err := after.Verify(issued, AudienceUser); err != ErrInvalid {
+		t.Fatalf("expected a token signed with a dropped key to be refused, got %v", err)
+	}
+	if _, err := after.Verify(fresh, AudienceUser); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestVerifyRefusesOtherAudiencesAndGarbage(t *testing.T) {
+	manager := newTestManager(t, oldKey, "")
+	issued, _, err := manager.Issue(7, AudienceAdmin, "family", "jti")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, tokenString := range []string{issued, "", "not.a.token", issued + "x"} {
+		if _, err := manager.Verify(tokenString, AudienceUser); err != ErrInvalid {
+			t.Fatalf("expected %q to be refused for users, got %v", tokenString, err)
+		}
+	}
+}
+
+func TestNewManagerChecksTheKeys(t *testing.T) {
+	tests := []struct {
+		name       string
+		keys       string
+		signingKid string
+	}{
+		{name: "no keys", keys: " , "},
+		{name: "missing kid", keys: ":" + strings.Repeat("a", minKeyLength)},
+		{name: "short secret", keys: "k1:short"},
+		{name: "kid twice", keys: oldKey + "," + oldKey},
+		{name: "unknown signing kid", keys: oldKey, signingKid: "new"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewManager(config.Config{
+				JWT_KEYS:             tt.keys,
+				JWT_SIGNING_KID:      tt.signingKid,
+				ACCESS_TOKEN_MINUTES: 15,
+				REFRESH_TOKEN_DAYS:   30,
+			})
+			if err == nil {
+				t.Fatal("expected the keys to be refused")
+			}
+		})
+	}
+}
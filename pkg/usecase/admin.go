@@ -6,10 +6,12 @@ import (
 	"ecommerce/pkg/commonhelp/response"
 	"ecommerce/pkg/domain"
 	interfaces "ecommerce/pkg/repository/interface"
+	"ecommerce/pkg/token"
 	services "ecommerce/pkg/usecase/interface"
+	"fmt"
+	"log"
 	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/jinzhu/copier"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
@@ -17,15 +19,21 @@ import (
 
 type AdminUsecase struct {
 	AdminRepo interfaces.AdminRepository
+	Tokens    services.TokenUseCase
 }
 
-func NewAdminUseCase(repo interfaces.AdminRepository) services.AdminUsecase {
+func NewAdminUseCase(repo interfaces.AdminRepository, tokens services.TokenUseCase) services.AdminUsecase {
 	return &AdminUsecase{
 		AdminRepo: repo,
+		Tokens:    tokens,
 	}
 }
 
 func (c *AdminUsecase) SaveAdmin(ctx context.Context, admin domain.Admin) error {
+	if !domain.IsAdminRole(admin.Role) {
+		return fmt.Errorf("role must be one of %s, %s, %s or %s", domain.AdminRoleSuperAdmin,
+			domain.AdminRoleCatalogManager, domain.AdminRoleOrderManager, domain.AdminRoleSupport)
+	}
 
 	if admin, err := c.AdminRepo.FindAdmin(ctx, admin); err != nil {
 		return err
@@ -43,30 +51,67 @@ func (c *AdminUsecase) SaveAdmin(ctx context.Context, admin domain.Admin) error
 
 	return c.AdminRepo.SaveAdmin(ctx, admin)
 }
-func (c *AdminUsecase) LoginAdmin(ctx context.Context, admin domain.Admin) (string, error) {
-	DBadmin, err := c.AdminRepo.FindAdmin(ctx, admin)
+
+// CreateSuperAdmin saves the first super admin, once there is one the others
+// are created by it
+func (c *AdminUsecase) CreateSuperAdmin(ctx context.Context, admin domain.Admin) error {
+	exists, err := c.AdminRepo.HasSuperAdmin(ctx)
 	if err != nil {
+		return err
+	} else if exists {
+		return errors.New("a super admin already exists, sign in as it to create admins")
+	}
+	admin.Role = domain.AdminRoleSuperAdmin
+	return c.SaveAdmin(ctx, admin)
+}
 
+// AdminRole is the current role of the admin, read on every request so a
+// role change applies at once
+func (c *AdminUsecase) AdminRole(ctx context.Context, adminId uint) (string, error) {
+	admin, err := c.AdminRepo.FindAdminByID(ctx, adminId)
+	if err != nil {
 		return "", err
+	} else if admin.ID == 0 {
+		return "", errors.New("no admin found")
+	}
+	return admin.Role, nil
+}
+
+func (c *AdminUsecase) ListAdmins(ctx context.Context, pagination requests.Pagination) ([]response.Admin, error) {
+	return c.AdminRepo.FindAdmins(ctx, pagination)
+}
+
+func (c *AdminUsecase) ChangeAdminRole(ctx context.Context, adminId uint, role string) (response.Admin, error) {
+	if !domain.IsAdminRole(role) {
+		return response.Admin{}, fmt.Errorf("unknown role %q", role)
+	}
+	return c.AdminRepo.UpdateAdminRole(ctx, adminId, role)
+}
+
+func (c *AdminUsecase) LoginAdmin(ctx context.Context, admin domain.Admin, device requests.Device) (response.TokenPair, error) {
+	DBadmin, err := c.AdminRepo.FindAdmin(ctx, admin)
+	if err != nil {
+
+		return response.TokenPair{}, err
 
 	} else if DBadmin.ID == 0 {
-		return "", errors.New("this id not found")
+		return response.TokenPair{}, errors.New("this id not found")
 	}
 	if bcrypt.CompareHashAndPassword([]byte(DBadmin.Password), []byte(admin.Password)) != nil {
-		return "", errors.New("incorrect password")
+		return response.TokenPair{}, errors.New("incorrect password")
 	}
 
-	claims := &jwt.MapClaims{
-		"id":  admin.ID,
-		"exp": time.Now().Add(time.Hour * 72).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString([]byte("secret"))
-	if err != nil {
-		return "", err
-	}
+	return c.Tokens.IssueTokens(ctx, DBadmin.ID, token.AudienceAdmin, device)
+}
 
-	return ss, nil
+// LogoutAdmin ends the session of the refresh token
+func (c *AdminUsecase) LogoutAdmin(ctx context.Context, refreshToken string) error {
+	return c.Tokens.Revoke(ctx, refreshToken)
+}
+
+// LogoutAdminEverywhere ends every session of the admin
+func (c *AdminUsecase) LogoutAdminEverywhere(ctx context.Context, adminId uint) error {
+	return c.Tokens.RevokeAll(ctx, adminId, token.AudienceAdmin)
 }
 func (c *AdminUsecase) FindAllUser(ctx context.Context, Pagination requests.Pagination) (users []response.UserValue, err error) {
 	users, err = c.AdminRepo.FindAllUser(ctx, Pagination)
@@ -83,12 +128,91 @@ func (c *AdminUsecase) BlockUser(body requests.BlockUser, adminId int) error {
 	return err
 }
 
-func (c *AdminUsecase) UnblockUser(id int) error {
-	err := c.AdminRepo.UnblockUser(id)
+func (c *AdminUsecase) UnblockUser(id int, adminId int) error {
+	err := c.AdminRepo.UnblockUser(id, adminId)
 	return err
 }
 
+func (c *AdminUsecase) BlockHistory(ctx context.Context, userId int, pagination requests.Pagination) ([]response.BlockRecord, error) {
+	return c.AdminRepo.FindBlockHistory(ctx, userId, pagination)
+}
+
+// SweepExpiredBlocks unblocks users whose timed block ran out. Logins and
+// requests already let them in once it passes, this keeps is_blocked and the
+// history in step. It runs every `every` until ctx is done
+func SweepExpiredBlocks(ctx context.Context, adminRepo interfaces.AdminRepository, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			unblocked, err := adminRepo.UnblockExpired(ctx)
+			if err != nil {
+				log.Printf("[SweepExpiredBlocks] failed to unblock users: %v", err)
+				continue
+			}
+			if unblocked > 0 {
+				log.Printf("[SweepExpiredBlocks] %d users unblocked after their block ran out", unblocked)
+			}
+		}
+	}
+}
+
 func (c *AdminUsecase) FindUserbyId(ctx context.Context, userID int) (domain.Users, error) {
 	user, err := c.AdminRepo.FindUserbyId(ctx, userID)
 	return user, err
 }
+
+func (c *AdminUsecase) DeleteUser(ctx context.Context, userID int) error {
+	return c.AdminRepo.DeleteUser(ctx, userID)
+}
+
+func (c *AdminUsecase) RestoreUser(ctx context.Context, userID int) (response.UserValue, error) {
+	return c.AdminRepo.RestoreUser(ctx, userID)
+}
+
+func (c *AdminUsecase) ArchivedUsers(ctx context.Context, pagination requests.Pagination) ([]response.UserValue, error) {
+	return c.AdminRepo.FindDeletedUsers(ctx, pagination)
+}
+
+func (c *AdminUsecase) Dashboard(ctx context.Context, dateRange requests.DateRange) (response.AdminDashboard, error) {
+	if dateRange.EndDate.IsZero() {
+		dateRange.EndDate = time.Now()
+	}
+	if dateRange.StartDate.After(dateRange.EndDate) {
+		return response.AdminDashboard{}, errors.New("start date must be before end date")
+	}
+	return c.AdminRepo.Dashboard(ctx, dateRange)
+}
+
+// SalesReport resolves the period into a date range, the custom period uses the given range as is
+func (c *AdminUsecase) SalesReport(ctx context.Context, period string, dateRange requests.DateRange) ([]response.SalesReport, error) {
+	now := time.Now()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	switch period {
+	case "daily":
+		dateRange = requests.DateRange{StartDate: today, EndDate: now}
+	case "weekly":
+		// weeks start on monday
+		offset := (int(today.Weekday()) + 6) % 7
+		dateRange = requests.DateRange{StartDate: today.AddDate(0, 0, -offset), EndDate: now}
+	case "monthly":
+		dateRange = requests.DateRange{StartDate: today.AddDate(0, 0, -today.Day()+1), EndDate: now}
+	case "yearly":
+		dateRange = requests.DateRange{StartDate: time.Date(now.Year(), 1, 1, 0, 0, 0, 0, now.Location()), EndDate: now}
+	case "custom":
+		if dateRange.StartDate.IsZero() || dateRange.EndDate.IsZero() {
+			return nil, errors.New("start_date and end_date are required for a custom report")
+		}
+		if dateRange.StartDate.After(dateRange.EndDate) {
+			return nil, errors.New("start date must be before end date")
+		}
+	default:
+		return nil, errors.New("period must be one of daily, weekly, monthly, yearly or custom")
+	}
+
+	return c.AdminRepo.SalesReport(ctx, dateRange)
+}
@@ -0,0 +1,231 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"errors"
+	"testing"
+	"time"
+)
+
+// dashboardAdminRepo records the range the dashboard was asked for
+type dashboardAdminRepo struct {
+	interfaces.AdminRepository
+	asked *requests.DateRange
+}
+
+func (r *dashboardAdminRepo) Dashboard(ctx context.Context, dateRange requests.DateRange) (response.AdminDashboard, error) {
+	r.asked = &dateRange
+	return response.AdminDashboard{}, nil
+}
+
+func TestDashboardDateRange(t *testing.T) {
+	day := time.Date(2024, 3, 10, 0, 0, 0, 0, time.Local)
+
+	t.Run("open ended range runs up to now", func(t *testing.T) {
+		repo := &dashboardAdminRepo{}
+		if _, err := NewAdminUseCase(repo, nil).Dashboard(context.Background(), requests.DateRange{StartDate: day}); err != nil {
+			t.Fatal(err)
+		}
+		if repo.asked == nil || time.Since(repo.asked.EndDate) > time.Minute {
+			t.Fatalf("end date should default to now, asked for %+v", repo.asked)
+		}
+	})
+
+	t.Run("start after end is refused", func(t *testing.T) {
+		repo := &dashboardAdminRepo{}
+		_, err := NewAdminUseCase(repo, nil).Dashboard(context.Background(), requests.DateRange{StartDate: day, EndDate: day.Add(-time.Hour)})
+		if err == nil {
+			t.Fatal("expected an error for a reversed range")
+		}
+		if repo.asked != nil {
+			t.Fatal("the repository shouldn't be asked for a reversed range")
+		}
+	})
+}
+
+// roleAdminRepo keeps admins in memory
+type roleAdminRepo struct {
+	interfaces.AdminRepository
+	admins []domain.Admin
+}
+
+func (r *roleAdminRepo) FindAdmin(ctx context.Context, admin domain.Admin) (domain.Admin, error) {
+	for _, saved := range r.admins {
+		if saved.Email == admin.Email || saved.AdminName == admin.AdminName {
+			return saved, nil
+		}
+	}
+	return admin, nil
+}
+
+func (r *roleAdminRepo) SaveAdmin(ctx context.Context, admin domain.Admin) error {
+	admin.ID = uint(len(r.admins) + 1)
+	r.admins = append(r.admins, admin)
+	return nil
+}
+
+func (r *roleAdminRepo) FindAdminByID(ctx context.Context, adminId uint) (domain.Admin, error) {
+	for _, admin := range r.admins {
+		if admin.ID == adminId {
+			return admin, nil
+		}
+	}
+	return domain.Admin{}, nil
+}
+
+func (r *roleAdminRepo) HasSuperAdmin(ctx context.Context) (bool, error) {
+	for _, admin := range r.admins {
+		if admin.Role == domain.AdminRoleSuperAdmin {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *roleAdminRepo) UpdateAdminRole(ctx context.Context, adminId uint, role string) (response.Admin, error) {
+	for i, admin := range r.admins {
+		if admin.ID == adminId {
+			r.admins[i].Role = role
+			return response.Admin{ID: admin.ID, AdminName: admin.AdminName, Email: admin.Email, Role: role}, nil
+		}
+	}
+	return response.Admin{}, errors.New("no admin found")
+}
+
+func TestAdminRolePermissions(t *testing.T) {
+	permissions := []string{
+		domain.PermissionAdmins, domain.PermissionUsers, domain.PermissionCatalog, domain.PermissionCoupons,
+		domain.PermissionOrders, domain.PermissionReviews, domain.PermissionReports,
+	}
+	tests := []struct {
+		role    string
+		allowed []string
+	}{
+		{role: domain.AdminRoleSuperAdmin, allowed: permissions},
+		{role: domain.AdminRoleCatalogManager, allowed: []string{domain.PermissionCatalog, domain.PermissionCoupons, domain.PermissionReviews}},
+		{role: domain.AdminRoleOrderManager, allowed: []string{domain.PermissionOrders, domain.PermissionReports}},
+		{role: domain.AdminRoleSupport, allowed: []string{domain.PermissionUsers, domain.PermissionOrders, domain.PermissionReviews}},
+		{role: "owner"},
+		{role: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.role, func(t *testing.T) {
+			admins := NewAdminUseCase(&roleAdminRepo{admins: []domain.Admin{{ID: 1, AdminName: "admin", Role: tt.role}}}, nil)
+			// the same lookup and check the admin middleware does on every request
+			role, err := admins.AdminRole(context.Background(), 1)
+			if err != nil {
+				t.Fatal(err)
+			}
+			allowed := map[string]bool{}
+			for _, permission := range tt.allowed {
+				allowed[permission] = true
+			}
+			for _, permission := range permissions {
+				if got := domain.AdminCan(role, permission); got != allowed[permission] {
+					t.Errorf("%s on %s: expected allowed %v, got %v", tt.role, permission, allowed[permission], got)
+				}
+			}
+		})
+	}
+}
+
+func TestRoleChangeAppliesAtOnce(t *testing.T) {
+	repo := &roleAdminRepo{admins: []domain.Admin{{ID: 1, AdminName: "admin", Role: domain.AdminRoleSupport}}}
+	admins := NewAdminUseCase(repo, nil)
+
+	if _, err := admins.ChangeAdminRole(context.Background(), 1, "owner"); err == nil {
+		t.Fatal("expected an unknown role to be refused")
+	}
+	if _, err := admins.ChangeAdminRole(context.Background(), 1, domain.AdminRoleCatalogManager); err != nil {
+		t.Fatal(err)
+	}
+	role, err := admins.AdminRole(context.Background(), 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !domain.AdminCan(role, domain.PermissionCatalog) || domain.AdminCan(role, domain.PermissionUsers) {
+		t.Fatalf("expected the catalog manager's permissions, the role is %q", role)
+	}
+
+	if _, err := admins.AdminRole(context.Background(), 2); err == nil {
+		t.Fatal("expected an unknown admin to have no role")
+	}
+}
+
+func TestCreatingAdmins(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing []domain.Admin
+		create   func(admins *AdminUsecase) error
+		wantErr  bool
+		wantRole string
+	}{
+		{
+			name: "first super admin",
+			create: func(admins *AdminUsecase) error {
+				return admins.CreateSuperAdmin(context.Background(), domain.Admin{AdminName: "root", Email: "root@example.com", Password: "password123"})
+			},
+			wantRole: domain.AdminRoleSuperAdmin,
+		},
+		{
+			name:     "second super admin through signup",
+			existing: []domain.Admin{{ID: 1, AdminName: "root", Email: "root@example.com", Role: domain.AdminRoleSuperAdmin}},
+			create: func(admins *AdminUsecase) error {
+				return admins.CreateSuperAdmin(context.Background(), domain.Admin{AdminName: "other", Email: "other@example.com", Password: "password123"})
+			},
+			wantErr: true,
+		},
+		{
+			name:     "super admin adds a support admin",
+			existing: []domain.Admin{{ID: 1, AdminName: "root", Email: "root@example.com", Role: domain.AdminRoleSuperAdmin}},
+			create: func(admins *AdminUsecase) error {
+				return admins.SaveAdmin(context.Background(), domain.Admin{AdminName: "help", Email: "help@example.com", Password: "password123", Role: domain.AdminRoleSupport})
+			},
+			wantRole: domain.AdminRoleSupport,
+		},
+		{
+			name: "unknown role",
+			create: func(admins *AdminUsecase) error {
+				return admins.SaveAdmin(context.Background(), domain.Admin{AdminName: "help", Email: "help@example.com", Password: "password123", Role: "owner"})
+			},
+			wantErr: true,
+		},
+		{
+			name:     "email taken",
+			existing: []domain.Admin{{ID: 1, AdminName: "root", Email: "root@example.com", Role: domain.AdminRoleSuperAdmin}},
+			create: func(admins *AdminUsecase) error {
+				return admins.SaveAdmin(context.Background(), domain.Admin{AdminName: "help", Email: "root@example.com", Password: "password123", Role: domain.AdminRoleSupport})
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &roleAdminRepo{admins: append([]domain.Admin(nil), tt.existing...)}
+			err := tt.create(NewAdminUseCase(repo, nil).(*AdminUsecase))
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected the admin to be refused")
+				}
+				if len(repo.admins) != len(tt.existing) {
+					t.Fatalf("expected no admin saved, there are %d", len(repo.admins))
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved := repo.admins[len(repo.admins)-1]
+			if saved.Role != tt.wantRole {
+				t.Fatalf("expected role %q, got %q", tt.wantRole, saved.Role)
+			}
+			if saved.Password == "password123" {
+				t.Fatal("the password should be saved hashed")
+			}
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"ecommerce/pkg/commonhelp/response"
 	"ecommerce/pkg/domain"
 	interfaces "ecommerce/pkg/repository/interface"
+	"ecommerce/pkg/storage"
 	services "ecommerce/pkg/usecase/interface"
 	"log"
 
@@ -14,11 +15,13 @@ import (
 
 type CartUsecase struct {
 	CartRepo interfaces.CartRepo
+	Storage  storage.Storage
 }
 
-func NewCartUsecase(cartRepo interfaces.CartRepo) services.CartUsecase {
+func NewCartUsecase(cartRepo interfaces.CartRepo, storage storage.Storage) services.CartUsecase {
 	return &CartUsecase{
 		CartRepo: cartRepo,
+		Storage:  storage,
 	}
 }
 
@@ -30,9 +33,16 @@ func (c *CartUsecase) AddCartItem(ctx context.Context, body requests.Cartreq) er
 		return errors.New("invalid product")
 	}
 	log.Printf("[AddCartItem] Product fetched: %+v", product)
-	log.Printf("[AddCartItem] Product qty_in_stock: %d", product.Qty_in_stock)
-	if product.Qty_in_stock == 0 {
-		log.Printf("[AddCartItem] Product out of stock: product_id=%d", body.ProductId)
+	if product.Id == 0 {
+		return errors.New("product is unavailable")
+	}
+	variant, err := c.findVariant(ctx, body.ProductId, body.VariantId)
+	if err != nil {
+		return err
+	}
+	log.Printf("[AddCartItem] Variant %s qty_in_stock: %d", variant.SKU, variant.QtyInStock)
+	if variant.QtyInStock == 0 {
+		log.Printf("[AddCartItem] Product out of stock: product_id=%d, variant_id=%d", body.ProductId, variant.ID)
 		return errors.New("product is currently out of stock")
 	}
 
@@ -54,20 +64,21 @@ func (c *CartUsecase) AddCartItem(ctx context.Context, body requests.Cartreq) er
 	}
 
 	// a. check if product already exists in cart
-	cartitem, err := c.CartRepo.FindCartIDNproductId(ctx, cart.Id, uint(body.ProductId))
+	cartitem, err := c.CartRepo.FindCartItemByVariant(ctx, cart.Id, variant.ID)
 	if err != nil {
-		log.Printf("[AddCartItem] Failed to check cart items: cart_id=%d, product_id=%d, err=%v", cart.Id, body.ProductId, err)
+		log.Printf("[AddCartItem] Failed to check cart items: cart_id=%d, variant_id=%d, err=%v", cart.Id, variant.ID, err)
 		return errors.Wrap(err, "failed to check cart items")
 	}
 	// b. if product already exists in cart
 	if cartitem.Id != 0 {
-		log.Printf("[AddCartItem] Product already exists in cart: cart_id=%d, product_id=%d", cart.Id, body.ProductId)
+		log.Printf("[AddCartItem] Product already exists in cart: cart_id=%d, variant_id=%d", cart.Id, variant.ID)
 		return errors.New("product already exists in cart")
 	}
 
 	cartItem := domain.CartItem{
 		CartID:    cart.Id,
 		ProductId: uint(body.ProductId),
+		VariantID: variant.ID,
 	}
 
 	if err := c.CartRepo.AddCartItem(ctx, cartItem); err != nil {
@@ -108,8 +119,13 @@ func (c *CartUsecase) RemoveFromCart(ctx context.Context, body requests.Cartreq)
 		return errors.New("cannot remove from cart - cart is empty")
 	}
 
+	variant, err := c.findVariant(ctx, body.ProductId, body.VariantId)
+	if err != nil {
+		return err
+	}
+
 	// a. check if product exists in cart
-	cartitem, err := c.CartRepo.FindCartIDNproductId(ctx, cart.Id, uint(body.ProductId))
+	cartitem, err := c.CartRepo.FindCartItemByVariant(ctx, cart.Id, variant.ID)
 	if err != nil {
 		return errors.Wrap(err, "failed to check cart items")
 	}
@@ -134,7 +150,11 @@ func (c *CartUsecase) AddQuantity(ctx context.Context, body requests.Addcount) e
 		return errors.New("product is unavailable")
 	}
 
-	if body.Count > uint(product.Qty_in_stock) {
+	variant, err := c.findVariant(ctx, body.ProductId, body.VariantId)
+	if err != nil {
+		return err
+	}
+	if body.Count > uint(variant.QtyInStock) {
 		return errors.New("insufficient product quantity in stock")
 	}
 
@@ -143,7 +163,7 @@ func (c *CartUsecase) AddQuantity(ctx context.Context, body requests.Addcount) e
 		return errors.New("user has no cart")
 	}
 
-	cartitem, err := c.CartRepo.FindCartIDNproductId(ctx, cart.Id, uint(body.ProductId))
+	cartitem, err := c.CartRepo.FindCartItemByVariant(ctx, cart.Id, variant.ID)
 	if err != nil {
 		return errors.Wrap(err, "failed to check cart items")
 	}
@@ -164,5 +184,41 @@ func (c *CartUsecase) FindCartlistByCartID(ctx context.Context, cartID uint) ([]
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to get cart items")
 	}
+	c.setImages(cartitems)
 	return cartitems, nil
 }
+
+// ViewCart prices the cart again at the current product prices and returns its items with the breakdown
+func (c *CartUsecase) ViewCart(ctx context.Context, cartID uint) (response.CartView, error) {
+	cart, err := c.CartRepo.RepriceCart(ctx, cartID)
+	if err != nil {
+		return response.CartView{}, errors.Wrap(err, "failed to price cart")
+	}
+	cartitems, err := c.CartRepo.FindCartlistByCartID(ctx, cartID)
+	if err != nil {
+		return response.CartView{}, errors.Wrap(err, "failed to get cart items")
+	}
+	c.setImages(cartitems)
+	return response.CartView{
+		Items:    cartitems,
+		Subtotal: cart.Subtotal,
+		Discount: cart.Discount,
+		Tax:      cart.Tax,
+		Shipping: cart.Shipping,
+		Total:    cart.Total_price,
+	}, nil
+}
+
+func (c *CartUsecase) findVariant(ctx context.Context, productId, variantId int) (domain.ProductVariant, error) {
+	variants, err := c.CartRepo.FindVariants(ctx, uint(productId))
+	if err != nil {
+		return domain.ProductVariant{}, errors.Wrap(err, "failed to get product variants")
+	}
+	return pickVariant(variants, uint(variantId))
+}
+
+func (c *CartUsecase) setImages(cartitems []response.Cartres) {
+	for i := range cartitems {
+		cartitems[i].Image = thumbnailURL(c.Storage, cartitems[i].ThumbnailKey)
+	}
+}
@@ -0,0 +1,70 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/response"
+)
+
+// CategoryTree lists the top level categories with their subcategories nested
+// under them
+func (p *ProductUsecase) CategoryTree(ctx context.Context) ([]response.Category, error) {
+	categories, err := p.ProductRepo.Listallcategory(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return categoryTree(categories, 0), nil
+}
+
+// setBreadcrumbs fills in the category path of every product from one read of
+// the categories
+func (p *ProductUsecase) setBreadcrumbs(ctx context.Context, products []response.Product) error {
+	if len(products) == 0 {
+		return nil
+	}
+	categories, err := p.ProductRepo.Listallcategory(ctx)
+	if err != nil {
+		return err
+	}
+	byId := make(map[uint]response.Category, len(categories))
+	for _, category := range categories {
+		byId[category.ID] = category
+	}
+	for i := range products {
+		products[i].Breadcrumbs = breadcrumbs(byId, products[i].Category_Id)
+	}
+	return nil
+}
+
+// categoryTree nests the categories below parent, 0 being the top level
+func categoryTree(categories []response.Category, parent uint) []response.Category {
+	children := make(map[uint][]response.Category)
+	for _, category := range categories {
+		children[category.ParentID] = append(children[category.ParentID], category)
+	}
+	var nest func(parent uint) []response.Category
+	nest = func(parent uint) []response.Category {
+		nodes := children[parent]
+		for i := range nodes {
+			nodes[i].Children = nest(nodes[i].ID)
+		}
+		return nodes
+	}
+	return nest(parent)
+}
+
+// breadcrumbs is the path from the top level down to the category. The walk
+// stops at a repeated category so a broken parent link can't loop forever
+func breadcrumbs(byId map[uint]response.Category, id uint) []response.Category {
+	var path []response.Category
+	seen := make(map[uint]bool)
+	for id != 0 && !seen[id] {
+		category, ok := byId[id]
+		if !ok {
+			break
+		}
+		seen[id] = true
+		path = append([]response.Category{{ID: category.ID, CategoryName: category.CategoryName, ParentID: category.ParentID}}, path...)
+		id = category.ParentID
+	}
+	return path
+}
@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/response"
+	"testing"
+)
+
+// shop is clothing > men > shirts, with footwear on its own at the top
+var shop = []response.Category{
+	{ID: 1, CategoryName: "clothing"},
+	{ID: 2, CategoryName: "men", ParentID: 1},
+	{ID: 3, CategoryName: "shirts", ParentID: 2},
+	{ID: 4, CategoryName: "footwear"},
+}
+
+func categoryIds(categories []response.Category) []uint {
+	ids := make([]uint, 0, len(categories))
+	for _, category := range categories {
+		ids = append(ids, category.ID)
+	}
+	return ids
+}
+
+func sameIds(got, want []uint) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestBreadcrumbs(t *testing.T) {
+	// 5 and 6 point at each other and 7 hangs below a category that is gone
+	broken := append([]response.Category{
+		{ID: 5, CategoryName: "loop a", ParentID: 6},
+		{ID: 6, CategoryName: "loop b", ParentID: 5},
+		{ID: 7, CategoryName: "orphan", ParentID: 99},
+	}, shop...)
+	byId := make(map[uint]response.Category, len(broken))
+	for _, category := range broken {
+		byId[category.ID] = category
+	}
+
+	tests := []struct {
+		name     string
+		category uint
+		want     []uint
+	}{
+		{name: "top level down to the category", category: 3, want: []uint{1, 2, 3}},
+		{name: "top level category", category: 4, want: []uint{4}},
+		{name: "no category", category: 0, want: []uint{}},
+		{name: "unknown category", category: 42, want: []uint{}},
+		{name: "missing parent stops the walk", category: 7, want: []uint{7}},
+		{name: "parent loop stops at the repeat", category: 5, want: []uint{6, 5}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := breadcrumbs(byId, tt.category)
+			if got := categoryIds(path); !sameIds(got, tt.want) {
+				t.Fatalf("expected the path %v, got %v", tt.want, got)
+			}
+			for _, crumb := range path {
+				if crumb.Children != nil {
+					t.Fatalf("expected breadcrumbs without children, got %+v", crumb)
+				}
+			}
+		})
+	}
+}
+
+func TestCategoryTree(t *testing.T) {
+	usecase := NewProductUsecase(&variantProductRepo{categories: shop}, &stockInventoryRepo{}, nil)
+
+	tree, err := usecase.CategoryTree(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := categoryIds(tree); !sameIds(got, []uint{1, 4}) {
+		t.Fatalf("expected clothing and footwear at the top, got %v", got)
+	}
+	men := tree[0].Children
+	if len(men) != 1 || men[0].ID != 2 || len(men[0].Children) != 1 || men[0].Children[0].ID != 3 {
+		t.Fatalf("expected clothing > men > shirts, got %+v", tree[0])
+	}
+	if tree[1].Children != nil {
+		t.Fatalf("expected footwear to have no subcategories, got %+v", tree[1].Children)
+	}
+}
+
+func TestProductsCarryTheirBreadcrumbs(t *testing.T) {
+	products := []response.Product{{Id: 1, Category_Id: 3}, {Id: 2, Category_Id: 4}}
+	usecase := &ProductUsecase{ProductRepo: &variantProductRepo{categories: shop}}
+
+	if err := usecase.setBreadcrumbs(context.Background(), products); err != nil {
+		t.Fatal(err)
+	}
+	if got := categoryIds(products[0].Breadcrumbs); !sameIds(got, []uint{1, 2, 3}) {
+		t.Fatalf("expected clothing > men > shirts, got %v", got)
+	}
+	if got := categoryIds(products[1].Breadcrumbs); !sameIds(got, []uint{4}) {
+		t.Fatalf("expected footwear, got %v", got)
+	}
+}
@@ -75,6 +75,14 @@ func (c *couponUsecase) DeleteCoupon(ctx context.Context, CouponId int) (err err
 	return err
 }
 
+func (c *couponUsecase) RestoreCoupon(ctx context.Context, CouponId int) (domain.Coupon, error) {
+	return c.CouponRepo.RestoreCoupon(ctx, CouponId)
+}
+
+func (c *couponUsecase) ArchivedCoupons(ctx context.Context) ([]domain.Coupon, error) {
+	return c.CouponRepo.ViewDeletedCoupons(ctx)
+}
+
 func (c *couponUsecase) ViewCoupon(ctx context.Context, couponID int) (domain.Coupon, error) {
 	coupon, err := c.CouponRepo.ViewCoupon(ctx, couponID)
 	return coupon, err
@@ -0,0 +1,186 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/imaging"
+	"ecommerce/pkg/storage"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"strings"
+)
+
+const thumbnailSize = 320
+
+// UploadProductImages checks every file before any of them is stored, so a
+// bad file fails the whole upload. It returns all images of the product
+func (p *ProductUsecase) UploadProductImages(ctx context.Context, productId int, uploads []requests.ImageUpload) ([]response.ProductImage, error) {
+	if len(uploads) == 0 {
+		return nil, fmt.Errorf("no images uploaded")
+	}
+	if len(uploads) > requests.MaxImageUploads {
+		return nil, fmt.Errorf("at most %d images can be uploaded at once", requests.MaxImageUploads)
+	}
+	product, err := p.ProductRepo.ViewProduct(ctx, productId)
+	if err != nil {
+		return nil, err
+	} else if product.Id == 0 {
+		return nil, fmt.Errorf("no product found with this id")
+	}
+
+	images := make([]domain.ProductImage, len(uploads))
+	thumbnails := make([][]byte, len(uploads))
+	for i, upload := range uploads {
+		img, format, err := imaging.Decode(upload.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", upload.Filename, err)
+		}
+		if thumbnails[i], err = imaging.Thumbnail(img, thumbnailSize); err != nil {
+			return nil, fmt.Errorf("%s: failed to make thumbnail: %v", upload.Filename, err)
+		}
+
+		name, err := randomName()
+		if err != nil {
+			return nil, err
+		}
+		images[i] = domain.ProductImage{
+			ProductID:    uint(productId),
+			StorageKey:   fmt.Sprintf("products/%d/%s%s", productId, name, imaging.Extensions[format]),
+			ThumbnailKey: fmt.Sprintf("products/%d/%s_thumb.jpg", productId, name),
+			ContentType:  imaging.ContentTypes[format],
+			Width:        img.Bounds().Dx(),
+			Height:       img.Bounds().Dy(),
+		}
+	}
+
+	for i, image := range images {
+		if err := p.Storage.Save(ctx, image.StorageKey, bytes.NewReader(uploads[i].Body), image.ContentType); err != nil {
+			return nil, fmt.Errorf("failed to store %s: %v", uploads[i].Filename, err)
+		}
+		if err := p.Storage.Save(ctx, image.ThumbnailKey, bytes.NewReader(thumbnails[i]), "image/jpeg"); err != nil {
+			p.deleteImageFiles(ctx, image)
+			return nil, fmt.Errorf("failed to store %s: %v", uploads[i].Filename, err)
+		}
+		if _, err := p.ProductRepo.SaveProductImage(ctx, image); err != nil {
+			p.deleteImageFiles(ctx, image)
+			return nil, err
+		}
+	}
+
+	return p.ListProductImages(ctx, productId)
+}
+
+func (p *ProductUsecase) ListProductImages(ctx context.Context, productId int) ([]response.ProductImage, error) {
+	images, err := p.ProductRepo.FindProductImages(ctx, productId)
+	if err != nil {
+		return nil, err
+	}
+	return productImages(p.Storage, images), nil
+}
+
+func (p *ProductUsecase) SetPrimaryImage(ctx context.Context, imageId int) (response.ProductImage, error) {
+	image, err := p.ProductRepo.SetPrimaryImage(ctx, imageId)
+	if err != nil {
+		return response.ProductImage{}, err
+	}
+	return productImage(p.Storage, image), nil
+}
+
+func (p *ProductUsecase) ReorderProductImages(ctx context.Context, productId int, order requests.ImageOrder) ([]response.ProductImage, error) {
+	images, err := p.ProductRepo.ReorderProductImages(ctx, productId, order.ImageIDs)
+	if err != nil {
+		return nil, err
+	}
+	return productImages(p.Storage, images), nil
+}
+
+// DeleteProductImage removes the image, its files go after the row so a failed
+// delete never leaves an image pointing at missing files
+func (p *ProductUsecase) DeleteProductImage(ctx context.Context, imageId int) error {
+	image, err := p.ProductRepo.DeleteProductImage(ctx, imageId)
+	if err != nil {
+		return err
+	}
+	p.deleteImageFiles(ctx, image)
+	return nil
+}
+
+// OpenImage reads a stored image or thumbnail by its key. Only product images
+// are served, anything else in storage is reported as not found
+func (p *ProductUsecase) OpenImage(ctx context.Context, key string) (io.ReadCloser, error) {
+	if !isImageKey(key) {
+		return nil, storage.ErrNotFound
+	}
+	return p.Storage.Open(ctx, key)
+}
+
+// isImageKey tells whether key names a product image or thumbnail the way
+// UploadProductImages stores them. Temporary files of unfinished saves start
+// with a dot and have no image extension, so they never match
+func isImageKey(key string) bool {
+	if key != path.Clean(key) || !strings.HasPrefix(key, "products/") {
+		return false
+	}
+	if strings.HasPrefix(path.Base(key), ".") {
+		return false
+	}
+	ext := path.Ext(key)
+	for _, known := range imaging.Extensions {
+		if ext == known {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *ProductUsecase) deleteImageFiles(ctx context.Context, image domain.ProductImage) {
+	for _, key := range []string{image.StorageKey, image.ThumbnailKey} {
+		if err := p.Storage.Delete(ctx, key); err != nil {
+			log.Printf("failed to delete image file %s: %v", key, err)
+		}
+	}
+}
+
+func productImage(store storage.Storage, image domain.ProductImage) response.ProductImage {
+	return response.ProductImage{
+		ID:           image.ID,
+		URL:          store.URL(image.StorageKey),
+		ThumbnailURL: store.URL(image.ThumbnailKey),
+		ContentType:  image.ContentType,
+		Width:        image.Width,
+		Height:       image.Height,
+		Position:     image.Position,
+		IsPrimary:    image.IsPrimary,
+	}
+}
+
+func productImages(store storage.Storage, images []domain.ProductImage) []response.ProductImage {
+	list := make([]response.ProductImage, len(images))
+	for i, image := range images {
+		list[i] = productImage(store, image)
+	}
+	return list
+}
+
+// thumbnailURL is where the primary image's thumbnail is served, empty when there is none
+func thumbnailURL(store storage.Storage, key string) string {
+	if key == "" {
+		return ""
+	}
+	return store.URL(key)
+}
+
+func randomName() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
@@ -0,0 +1,150 @@
+package usecase
+
+import (
+	"bytes"
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/storage"
+	"errors"
+	"image"
+	"image/png"
+	"io"
+	"strings"
+	"testing"
+)
+
+// imageProductRepo has product 4 and keeps its images in memory
+type imageProductRepo struct {
+	variantProductRepo
+	images []domain.ProductImage
+}
+
+func (r *imageProductRepo) ViewProduct(ctx context.Context, id int) (response.Product, error) {
+	if id != 4 {
+		return response.Product{}, nil
+	}
+	return response.Product{Id: 4}, nil
+}
+
+func (r *imageProductRepo) SaveProductImage(ctx context.Context, image domain.ProductImage) (domain.ProductImage, error) {
+	image.ID = uint(len(r.images) + 1)
+	image.Position = len(r.images)
+	image.IsPrimary = len(r.images) == 0
+	r.images = append(r.images, image)
+	return image, nil
+}
+
+func (r *imageProductRepo) FindProductImages(ctx context.Context, productId int) ([]domain.ProductImage, error) {
+	return r.images, nil
+}
+
+func newImageFixture(t *testing.T) (*ProductUsecase, *imageProductRepo, *storage.LocalStorage) {
+	t.Helper()
+	store, err := storage.NewLocalStorage(t.TempDir(), "http://localhost:3000/images")
+	if err != nil {
+		t.Fatal(err)
+	}
+	products := &imageProductRepo{}
+	return NewProductUsecase(products, &stockInventoryRepo{}, store).(*ProductUsecase), products, store
+}
+
+func pngImage(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestUploadProductImages(t *testing.T) {
+	usecase, products, _ := newImageFixture(t)
+	ctx := context.Background()
+
+	images, err := usecase.UploadProductImages(ctx, 4, []requests.ImageUpload{
+		{Filename: "front.png", Body: pngImage(t, 800, 400)},
+		{Filename: "back.png", Body: pngImage(t, 20, 20)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(images) != 2 || !images[0].IsPrimary || images[0].Width != 800 || images[0].ContentType != "image/png" {
+		t.Fatalf("unexpected images %+v", images)
+	}
+	if !strings.HasPrefix(images[0].URL, "http://localhost:3000/images/products/4/") {
+		t.Fatalf("unexpected image url %s", images[0].URL)
+	}
+
+	// both the image and its thumbnail can be downloaded again
+	for _, key := range []string{products.images[0].StorageKey, products.images[0].ThumbnailKey} {
+		file, err := usecase.OpenImage(ctx, key)
+		if err != nil {
+			t.Fatalf("%s: %v", key, err)
+		}
+		file.Close()
+	}
+}
+
+func TestUploadProductImagesChecksEveryFileFirst(t *testing.T) {
+	usecase, products, _ := newImageFixture(t)
+
+	_, err := usecase.UploadProductImages(context.Background(), 4, []requests.ImageUpload{
+		{Filename: "front.png", Body: pngImage(t, 10, 10)},
+		{Filename: "notes.txt", Body: []byte("not an image")},
+	})
+	if err == nil || !strings.Contains(err.Error(), "notes.txt") {
+		t.Fatalf("expected notes.txt to be refused, got %v", err)
+	}
+	if len(products.images) != 0 {
+		t.Fatal("no image should be saved when one of the files is bad")
+	}
+
+	tooMany := make([]requests.ImageUpload, requests.MaxImageUploads+1)
+	if _, err = usecase.UploadProductImages(context.Background(), 4, tooMany); err == nil {
+		t.Fatal("expected too many images to be refused")
+	}
+	if _, err = usecase.UploadProductImages(context.Background(), 9, []requests.ImageUpload{{Filename: "a.png", Body: pngImage(t, 1, 1)}}); err == nil {
+		t.Fatal("expected an unknown product to be refused")
+	}
+}
+
+func TestOpenImageServesOnlyProductImages(t *testing.T) {
+	usecase, _, store := newImageFixture(t)
+	ctx := context.Background()
+	for _, key := range []string{"products/4/a1.jpg", "products/4/.upload-123", "products/4/notes.txt", "private/key.png"} {
+		if err := store.Save(ctx, key, strings.NewReader("x"), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		key   string
+		found bool
+	}{
+		{key: "products/4/a1.jpg", found: true},
+		{key: "products/4/.upload-123"},
+		{key: "products/4/notes.txt"},
+		{key: "private/key.png"},
+		{key: "products/../private/key.png"},
+		{key: "products/4/missing.webp"},
+	}
+	for _, tt := range tests {
+		file, err := usecase.OpenImage(ctx, tt.key)
+		if tt.found {
+			if err != nil {
+				t.Fatalf("%s: %v", tt.key, err)
+			}
+			body, _ := io.ReadAll(file)
+			file.Close()
+			if string(body) != "x" {
+				t.Fatalf("%s: got %q", tt.key, body)
+			}
+			continue
+		}
+		if !errors.Is(err, storage.ErrNotFound) {
+			t.Fatalf("%s: expected not found, got %v", tt.key, err)
+		}
+	}
+}
@@ -10,9 +10,21 @@ import (
 
 type AdminUsecase interface {
 	SaveAdmin(ctx context.Context, admin domain.Admin) error
-	LoginAdmin(ctx context.Context, admin domain.Admin) (string, error)
+	CreateSuperAdmin(ctx context.Context, admin domain.Admin) error
+	AdminRole(ctx context.Context, adminId uint) (string, error)
+	ListAdmins(ctx context.Context, pagination requests.Pagination) ([]response.Admin, error)
+	ChangeAdminRole(ctx context.Context, adminId uint, role string) (response.Admin, error)
+	LoginAdmin(ctx context.Context, admin domain.Admin, device requests.Device) (response.TokenPair, error)
+	LogoutAdmin(ctx context.Context, refreshToken string) error
+	LogoutAdminEverywhere(ctx context.Context, adminId uint) error
 	FindAllUser(ctx context.Context, pagination requests.Pagination) (users []response.UserValue, err error)
 	BlockUser(body requests.BlockUser, AdminId int) error
-	UnblockUser(id int) error
+	UnblockUser(id int, adminId int) error
+	BlockHistory(ctx context.Context, userId int, pagination requests.Pagination) ([]response.BlockRecord, error)
 	FindUserbyId(ctx context.Context, userID int) (domain.Users, error)
+	DeleteUser(ctx context.Context, userID int) error
+	RestoreUser(ctx context.Context, userID int) (response.UserValue, error)
+	ArchivedUsers(ctx context.Context, pagination requests.Pagination) ([]response.UserValue, error)
+	Dashboard(ctx context.Context, dateRange requests.DateRange) (response.AdminDashboard, error)
+	SalesReport(ctx context.Context, period string, dateRange requests.DateRange) ([]response.SalesReport, error)
 }
@@ -13,4 +13,5 @@ type CartUsecase interface {
 	FindUserCart(ctx context.Context, userID int) (cart domain.Cart, err error)
 	AddQuantity(ctx context.Context, body requests.Addcount) error
 	FindCartlistByCartID(ctx context.Context, cartID uint) (cartitems []response.Cartres, err error)
+	ViewCart(ctx context.Context, cartID uint) (response.CartView, error)
 }
@@ -5,20 +5,38 @@ import (
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
 	"ecommerce/pkg/domain"
+	"errors"
 )
 
+// ErrInvalidSignature is returned when a razorpay webhook signature does not match
+var ErrInvalidSignature = errors.New("invalid razorpay signature")
+
+// ErrPaymentRefunded is returned when a captured razorpay payment could not
+// become an order and was given back
+var ErrPaymentRefunded = errors.New("the payment was refunded because the order could not be placed")
+
 type Orderusecase interface {
-	PlaceOrder(ctx context.Context, UserID, paymentTypeId int) (domain.Orders, error)
-	Razorpay(ctx context.Context, UserID, paymentMethodId int) (response.RazorPayResponse, error)
-	VerifyRazorPay(ctx context.Context, body requests.RazorPayRequest) error
-	CancelOrder(ctx context.Context, orderId, userId int) error
+	PlaceOrder(ctx context.Context, UserID, paymentTypeId int, useWallet bool) (domain.Orders, error)
+	Razorpay(ctx context.Context, UserID, paymentMethodId int, useWallet bool) (response.RazorPayResponse, error)
+	VerifyRazorPay(ctx context.Context, body requests.RazorPayRequest) (domain.Orders, error)
+	RazorpayWebhook(ctx context.Context, eventId, signature string, payload []byte) error
+	CancelOrder(ctx context.Context, orderId, userId int, refundTo string) error
 	Listorders(ctx context.Context, userid int) ([]response.OrderResponse, error)
 	Listorder(ctx context.Context, Orderid int, UserId int) (order domain.Orders, err error)
-	ReturnOrder(userId, orderId int) (float64, error)
+	ReturnOrder(userId, orderId int, reason, refundTo string) (float64, error)
+	CancelOrderLine(ctx context.Context, userId, orderId, lineId, qty int, refundTo string) error
+	ReturnOrderLine(ctx context.Context, userId, orderId, lineId, qty int, reason, refundTo string) (float64, error)
+	ListOrderLines(ctx context.Context, userId, orderId int) ([]domain.OrderLine, error)
 	ListofOrderStatuses(ctx context.Context) (status []domain.OrderStatus, err error)
 	AdminListorders(ctx context.Context, pagination requests.Pagination) (orders []domain.Orders, err error)
-	UpdateOrderStatus(ctx context.Context, update requests.Update) error
+	UpdateOrderStatus(ctx context.Context, update requests.Update, adminId int) error
+	OrderTimeline(ctx context.Context, orderId, userId int) ([]response.OrderTimeline, error)
+	AdminOrderTimeline(ctx context.Context, orderId int) ([]response.OrderTimeline, error)
+	ListRefunds(ctx context.Context, status string, pagination requests.Pagination) ([]domain.Refund, error)
+	ApproveRefund(ctx context.Context, refundId uint, amount float64, adminId int) (domain.Refund, error)
+	DenyRefund(ctx context.Context, refundId uint, reason string, adminId int) error
 
-	// GetUserWallet(ctx context.Context, userID uint) (wallet domain.Wallet, err error)
-	// GetUserWalletTransactions(ctx context.Context,userID uint, pagination requests.Pagination) (transactions []domain.Transaction, err error)
+	GetUserWallet(ctx context.Context, userID uint) (wallet domain.Wallet, err error)
+	GetUserWalletTransactions(ctx context.Context, userID uint, pagination requests.Pagination) (transactions []domain.Transaction, err error)
+	ListRazorpayCheckouts(ctx context.Context, status string, pagination requests.Pagination) ([]domain.RazorpayCheckout, error)
 }
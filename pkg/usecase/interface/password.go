@@ -0,0 +1,13 @@
+package interfaces
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+)
+
+type PasswordUseCase interface {
+	ForgotPassword(ctx context.Context, forgot requests.ForgotPassword) error
+	VerifyResetOtp(ctx context.Context, otpDetails requests.Otpverifier) (string, error)
+	ResetPassword(ctx context.Context, reset requests.ResetPassword) error
+	ChangePassword(ctx context.Context, userId uint, currentSession string, change requests.ChangePassword) error
+}
@@ -4,19 +4,39 @@ import (
 	"context"
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	"io"
 )
 
 type ProductUsecase interface {
 	Addcategory(ctx context.Context, req requests.Category) (response.Category, error)
 	UpdateCategory(ctx context.Context, category requests.Category, id int) (response.Category, error)
-	DeleteCategory(ctx context.Context, Id int) error
+	DeleteCategory(ctx context.Context, Id int, reassignTo int) error
 	Listallcategory(ctx context.Context) ([]response.Category, error)
+	CategoryTree(ctx context.Context) ([]response.Category, error)
 	ShowCatagory(ctx context.Context, Id int) (response.Category, error)
-	SaveProduct(ctx context.Context, product requests.Product) (response.Product, error)
-	UpdateProduct(ctx context.Context, id int, product requests.Product) (response.Product, error)
+	RestoreCategory(ctx context.Context, Id int) (response.Category, error)
+	ArchivedCategories(ctx context.Context) ([]response.Category, error)
+	SaveProduct(ctx context.Context, product requests.Product, adminId int) (response.Product, error)
+	UpdateProduct(ctx context.Context, id int, product requests.Product, adminId int) (response.Product, error)
 	DeleteProduct(ctx context.Context, id int) error
-	ViewAllProducts(ctx context.Context, pagination requests.Pagination) (products []response.Product, err error)
+	RestoreProduct(ctx context.Context, id int) (response.Product, error)
+	ArchivedProducts(ctx context.Context, pagination requests.Pagination) ([]response.Product, error)
+	ViewAllProducts(ctx context.Context, filter requests.ProductFilter) (response.ProductList, error)
 	VeiwProduct(ctx context.Context, id int) (response.Product, error)
-	SearchProducts(ctx context.Context, query string) ([]requests.Product, error)
-	GetProductsByPriceRange(min, max *float64) ([]requests.Product, error)
+	SearchProducts(ctx context.Context, search requests.ProductSearch) (response.ProductSearch, error)
+	StockHistory(ctx context.Context, productId int, pagination requests.Pagination) ([]domain.StockMovement, error)
+	AdjustStock(ctx context.Context, productId, adminId int, adjustment requests.StockAdjustment) (domain.StockMovement, error)
+	ListVariants(ctx context.Context, productId int) ([]domain.ProductVariant, error)
+	AddVariant(ctx context.Context, productId, adminId int, variant requests.Variant) (domain.ProductVariant, error)
+	UpdateVariant(ctx context.Context, variantId int, variant requests.Variant) (domain.ProductVariant, error)
+	DeleteVariant(ctx context.Context, variantId int) error
+	SetReorderThreshold(ctx context.Context, productId, threshold int) error
+	LowStockReport(ctx context.Context, pagination requests.Pagination) ([]response.LowStockProduct, error)
+	UploadProductImages(ctx context.Context, productId int, uploads []requests.ImageUpload) ([]response.ProductImage, error)
+	ListProductImages(ctx context.Context, productId int) ([]response.ProductImage, error)
+	SetPrimaryImage(ctx context.Context, imageId int) (response.ProductImage, error)
+	ReorderProductImages(ctx context.Context, productId int, order requests.ImageOrder) ([]response.ProductImage, error)
+	DeleteProductImage(ctx context.Context, imageId int) error
+	OpenImage(ctx context.Context, key string) (io.ReadCloser, error)
 }
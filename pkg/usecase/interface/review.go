@@ -0,0 +1,20 @@
+package interfaces
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+)
+
+type ReviewUseCase interface {
+	AddReview(ctx context.Context, userId, productId uint, review requests.Review) (domain.Review, error)
+	UpdateReview(ctx context.Context, userId, reviewId uint, review requests.Review) (domain.Review, error)
+	DeleteReview(ctx context.Context, userId, reviewId uint) error
+	ProductReviews(ctx context.Context, productId uint, sort string, pagination requests.Pagination) ([]response.Review, error)
+	MarkHelpful(ctx context.Context, userId, reviewId uint) error
+	UnmarkHelpful(ctx context.Context, userId, reviewId uint) error
+	ListReviews(ctx context.Context, status string, pagination requests.Pagination) ([]response.Review, error)
+	HideReview(ctx context.Context, reviewId uint, reason string, adminId uint) (domain.Review, error)
+	PublishReview(ctx context.Context, reviewId uint, adminId uint) (domain.Review, error)
+}
@@ -0,0 +1,19 @@
+package interfaces
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/token"
+)
+
+type TokenUseCase interface {
+	IssueTokens(ctx context.Context, subjectId uint, audience string, device requests.Device) (response.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken, audience string) (response.TokenPair, error)
+	Revoke(ctx context.Context, refreshToken string) error
+	Authenticate(ctx context.Context, accessToken, audience string) (token.Claims, error)
+	Sessions(ctx context.Context, subjectId uint, audience, currentSession string) ([]response.Session, error)
+	RevokeSession(ctx context.Context, subjectId uint, audience, sessionId string) error
+	RevokeAll(ctx context.Context, subjectId uint, audience string) error
+	RevokeOthers(ctx context.Context, subjectId uint, audience, keepSession string) error
+}
@@ -9,8 +9,13 @@ import (
 
 type UserUseCase interface {
 	UserSignup(ctx context.Context, user requests.Usersign) (response.UserValue, error)
-	UserLogin(ctx context.Context, user requests.Login) (string, error)
-	OtpLogin(mobno string) (string, error)
+	UserLogin(ctx context.Context, user requests.Login, device requests.Device) (response.TokenPair, error)
+	UserLogout(ctx context.Context, refreshToken string) error
+	LogoutEverywhere(ctx context.Context, userId uint) error
+	Sessions(ctx context.Context, userId uint, currentSession string) ([]response.Session, error)
+	LogoutSession(ctx context.Context, userId uint, sessionId string) error
+	OtpLogin(ctx context.Context, mobno string, device requests.Device) (response.TokenPair, error)
+	CheckBlocked(ctx context.Context, userId uint) error
 	AddAdress(ctx context.Context, UserID int, address requests.AddressReq) (domain.Address, error)
 	UpdateAdress(ctx context.Context, UserID int, address requests.AddressReq) (domain.Address, error)
 	VeiwAdress(ctx context.Context, UserID int) (domain.Address, error)
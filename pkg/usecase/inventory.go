@@ -0,0 +1,30 @@
+package usecase
+
+import (
+	"context"
+	interfaces "ecommerce/pkg/repository/interface"
+	"log"
+	"time"
+)
+
+// SweepExpiredReservations gives back the stock held by razorpay checkouts
+// that were never paid. It runs every `every` until ctx is done
+func SweepExpiredReservations(ctx context.Context, inventoryRepo interfaces.InventoryRepo, every time.Duration) {
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			restocked, err := inventoryRepo.ReleaseExpiredReservations(ctx)
+			if err != nil {
+				log.Printf("[SweepExpiredReservations] failed to release reservations: %v", err)
+				continue
+			}
+			if restocked > 0 {
+				log.Printf("[SweepExpiredReservations] stock given back to %d product variants", restocked)
+			}
+		}
+	}
+}
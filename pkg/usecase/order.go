@@ -2,115 +2,331 @@ package usecase
 
 import (
 	"context"
-	"crypto/hmac"
 	"crypto/sha256"
-	"crypto/subtle"
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
-	"ecommerce/pkg/config"
 	"ecommerce/pkg/domain"
+	"ecommerce/pkg/payment"
 	interfaces "ecommerce/pkg/repository/interface"
 	services "ecommerce/pkg/usecase/interface"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"strconv"
+	"time"
 
 	"github.com/pkg/errors"
-	"github.com/razorpay/razorpay-go"
 )
 
 type Orderusecase struct {
-	cartRepo  interfaces.CartRepo
-	orderRepo interfaces.OrderRepo
+	cartRepo      interfaces.CartRepo
+	orderRepo     interfaces.OrderRepo
+	walletRepo    interfaces.WalletRepo
+	refundRepo    interfaces.RefundRepo
+	inventoryRepo interfaces.InventoryRepo
+	gateway       payment.PaymentGateway
 }
 
-func NewOrderUseCase(orderRepo interfaces.OrderRepo, cartRepo interfaces.CartRepo) services.Orderusecase {
+func NewOrderUseCase(orderRepo interfaces.OrderRepo, cartRepo interfaces.CartRepo, walletRepo interfaces.WalletRepo, refundRepo interfaces.RefundRepo, inventoryRepo interfaces.InventoryRepo, gateway payment.PaymentGateway) services.Orderusecase {
 	return &Orderusecase{
-		orderRepo: orderRepo,
-		cartRepo:  cartRepo,
+		orderRepo:     orderRepo,
+		cartRepo:      cartRepo,
+		walletRepo:    walletRepo,
+		refundRepo:    refundRepo,
+		inventoryRepo: inventoryRepo,
+		gateway:       gateway,
 	}
 }
 
-func (c *Orderusecase) PlaceOrder(ctx context.Context, UserID, paymentMethodId int) (domain.Orders, error) {
-	order, err := c.orderRepo.OrderAll(ctx, UserID, paymentMethodId)
+func (c *Orderusecase) PlaceOrder(ctx context.Context, UserID, paymentMethodId int, useWallet bool) (domain.Orders, error) {
+	order, err := c.orderRepo.OrderAll(ctx, UserID, paymentMethodId, useWallet)
 	return order, err
 }
-func (c *Orderusecase) Razorpay(ctx context.Context, UserID, paymentMethodId int) (response.RazorPayResponse, error) {
+func (c *Orderusecase) Razorpay(ctx context.Context, UserID, paymentMethodId int, useWallet bool) (response.RazorPayResponse, error) {
 
 	cart, err := c.cartRepo.FindCartByUserID(ctx, UserID)
 	if err != nil {
 		return response.RazorPayResponse{}, err
 	}
+	if cart.Id == 0 {
+		return response.RazorPayResponse{}, fmt.Errorf("there is no products in your list")
+	}
+	// razorpay has to collect what the items cost now
+	cart, err = c.cartRepo.RepriceCart(ctx, cart.Id)
+	if err != nil {
+		return response.RazorPayResponse{}, err
+	}
 	if cart.Total_price == 0 {
 		return response.RazorPayResponse{}, fmt.Errorf("there is no products in your list")
 	}
 
-	razorpayKey := config.GetConfig().RAZOR_PAY_KEY
-	razorpaySecret := config.GetConfig().RAZOR_PAY_SECRET
-
-	client := razorpay.NewClient(razorpayKey, razorpaySecret)
-
-	razorPayAmount := cart.Total_price * 100
+	// the wallet pays first and razorpay collects the remainder
+	amountToPay := cart.Total_price
+	var walletUsed float64
+	if useWallet {
+		wallet, err := c.walletRepo.FindWalletByUserID(ctx, uint(UserID))
+		if err != nil {
+			return response.RazorPayResponse{}, err
+		}
+		walletUsed = wallet.Balance
+		if walletUsed >= amountToPay {
+			return response.RazorPayResponse{}, fmt.Errorf("wallet balance covers this order, pay with the wallet instead")
+		}
+		amountToPay -= walletUsed
+	}
 
-	data := map[string]interface{}{
-		"amount":   razorPayAmount,
-		"currency": "INR",
-		"receipt":  "reciept_id",
+	receipt := fmt.Sprintf("user_%d_%d", UserID, time.Now().Unix())
+	notes := map[string]string{
+		"user_id": strconv.Itoa(UserID),
 	}
 	// create an order on razor pay
-	order, err := client.Order.Create(data, nil)
+	order, err := c.gateway.CreateOrder(amountToPay, receipt, notes)
+	if err != nil {
+		return response.RazorPayResponse{}, err
+	}
+
+	// hold the stock while the user pays, an unpaid hold expires and is given back
+	reservations, err := c.inventoryRepo.ReserveCart(ctx, UserID, order.ID)
+	if err != nil {
+		return response.RazorPayResponse{}, err
+	}
 
+	// the wallet split is kept here, what the browser posts back is not trusted
+	err = c.orderRepo.SaveRazorpayCheckout(ctx, domain.RazorpayCheckout{
+		RazorpayOrderID: order.ID,
+		UserID:          uint(UserID),
+		Amount:          amountToPay,
+		WalletAmount:    walletUsed,
+	})
 	if err != nil {
-		return response.RazorPayResponse{}, fmt.Errorf("faild to create razorpay order, %s", err.Error())
+		return response.RazorPayResponse{}, err
 	}
 
 	return response.RazorPayResponse{
 		Email:       "",
 		PhoneNumber: "",
-		RazorpayKey: razorpayKey,
+		RazorpayKey: c.gateway.Key(),
 		PaymentId:   uint(paymentMethodId),
-		OrderId:     order["id"],
-		Total:       razorPayAmount,
-		AmountToPay: cart.Total_price,
+		OrderId:     order.ID,
+		Total:       amountToPay * 100,
+		AmountToPay: amountToPay,
+		UseWallet:   useWallet,
+		WalletUsed:  walletUsed,
+		// every hold of one checkout expires at the same time
+		ReservedUntil: reservations[0].ExpiresAt,
 	}, nil
 }
 
-func (c *Orderusecase) VerifyRazorPay(ctx context.Context, body requests.RazorPayRequest) error {
-	razorpayKey := config.GetConfig().RAZOR_PAY_KEY
-	razorPaySecret := config.GetConfig().RAZOR_PAY_SECRET
-
+func (c *Orderusecase) VerifyRazorPay(ctx context.Context, body requests.RazorPayRequest) (domain.Orders, error) {
 	//varify signature
-	data := body.RazorPayOrderId + "|" + body.RazorPayPaymentId
-	h := hmac.New(sha256.New, []byte(razorPaySecret))
-	_, err := h.Write([]byte(data))
+	if !c.gateway.VerifySignature(body.RazorPayOrderId, body.RazorPayPaymentId, body.Razorpay_signature) {
+		return domain.Orders{}, errors.New("razorpay signature not match")
+	}
+
+	// the success callback can be posted more than once, the first one creates the order
+	if order, err := c.findRazorpayOrder(ctx, body); err != nil || order.ID != 0 {
+		return order, err
+	}
+
+	// fetch payment and vefify
+	payment, err := c.gateway.FetchPayment(body.RazorPayPaymentId)
 	if err != nil {
-		return errors.New("faild to veify signature")
+		return domain.Orders{}, err
 	}
 
-	sha := hex.EncodeToString(h.Sum(nil))
-	if subtle.ConstantTimeCompare([]byte(sha), []byte(body.Razorpay_signature)) != 1 {
-		return errors.New("razorpay signature not match")
+	// check payment status
+	if payment.Status != "captured" {
+		return domain.Orders{}, errors.New("faild to verify razorpay payment")
 	}
+	if payment.OrderID != body.RazorPayOrderId {
+		return domain.Orders{}, errors.New("razorpay payment does not belong to this order")
+	}
+	body.Amount = payment.Amount
 
-	// then vefiy payment
-	client := razorpay.NewClient(razorpayKey, razorPaySecret)
+	return c.createRazorpayOrder(ctx, body)
+}
 
-	// fetch payment and vefify
-	payment, err := client.Payment.Fetch(body.RazorPayPaymentId, nil, nil)
+// createRazorpayOrder places the order for a captured payment. The money is
+// already taken, so when no order can be placed the payment is refunded
+func (c *Orderusecase) createRazorpayOrder(ctx context.Context, body requests.RazorPayRequest) (domain.Orders, error) {
+	order, err := c.orderRepo.OrderRazorpay(ctx, body)
+	if err != nil {
+		// a concurrent callback or webhook for the same payment may have won the race
+		existing, findErr := c.findRazorpayOrder(ctx, body)
+		if findErr != nil {
+			return domain.Orders{}, findErr
+		}
+		if existing.ID != 0 {
+			return existing, nil
+		}
+		return domain.Orders{}, c.refundCheckout(body, err)
+	}
+	return order, nil
+}
 
+// refundCheckout gives back a captured payment that no order could be placed
+// for. The gateway refunds it, or the wallet is credited when the gateway
+// can't, and the checkout keeps what happened. cause is returned wrapped in
+// ErrPaymentRefunded once the refund is recorded
+func (c *Orderusecase) refundCheckout(body requests.RazorPayRequest, cause error) error {
+	// the request context may already be gone, the money has to go back anyway
+	ctx := context.Background()
+	checkout, err := c.orderRepo.FindRazorpayCheckout(ctx, body.RazorPayOrderId)
+	if err != nil {
+		return err
+	}
+	if checkout.RazorpayOrderID == "" {
+		return cause
+	}
+	claimed, err := c.orderRepo.ClaimRazorpayCheckoutRefund(ctx, checkout.RazorpayOrderID, body.RazorPayPaymentId, cause.Error())
 	if err != nil {
 		return err
 	}
+	if !claimed {
+		// refunded by a concurrent callback or webhook, or already refunded before
+		if checkout, err = c.orderRepo.FindRazorpayCheckout(ctx, body.RazorPayOrderId); err != nil {
+			return err
+		}
+		if checkout.Status == domain.CheckoutRefunding || checkout.Status == domain.CheckoutRefunded {
+			return errors.Wrap(services.ErrPaymentRefunded, checkout.Note)
+		}
+		return cause
+	}
 
-	// check payment status
-	if payment["status"] != "captured" {
-		return errors.New("faild to verify razorpay payment")
+	checkout.RazorpayPaymentID = body.RazorPayPaymentId
+	checkout.RefundTo = domain.RefundToSource
+	notes := map[string]string{
+		"razorpay_order_id": checkout.RazorpayOrderID,
+		"reason":            "order could not be placed",
 	}
+	gatewayRefund, err := c.gateway.Refund(body.RazorPayPaymentId, body.Amount, notes)
+	if err != nil {
+		checkout.RefundTo = domain.RefundToWallet
+	}
+	checkout.GatewayRefundID = gatewayRefund.ID
+	if err = c.orderRepo.SaveRazorpayCheckoutRefund(ctx, checkout, body.Amount); err != nil {
+		return err
+	}
+	// no order took the held stock, it goes back on the shelf
+	if err = c.inventoryRepo.ReleaseReservations(ctx, checkout.RazorpayOrderID); err != nil {
+		return err
+	}
+	return errors.Wrap(services.ErrPaymentRefunded, cause.Error())
+}
 
-	return nil
+// RazorpayWebhook reconciles razorpay server events with payment_details. It
+// creates the order for a captured payment when the browser never came back
+// to the success callback
+func (c *Orderusecase) RazorpayWebhook(ctx context.Context, eventId, signature string, payload []byte) error {
+	if !c.gateway.VerifyWebhookSignature(payload, signature) {
+		return services.ErrInvalidSignature
+	}
+
+	var webhook requests.RazorpayWebhook
+	if err := json.Unmarshal(payload, &webhook); err != nil {
+		return errors.Wrap(err, "invalid webhook payload")
+	}
+
+	// razorpay retries deliveries, so the same event can arrive more than once
+	if eventId == "" {
+		sum := sha256.Sum256(payload)
+		eventId = hex.EncodeToString(sum[:])
+	}
+	processed, err := c.orderRepo.IsWebhookEventProcessed(ctx, eventId)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return nil
+	}
+
+	switch webhook.Event {
+	case "payment.captured":
+		err = c.reconcileCapturedPayment(ctx, webhook.Payload.Payment.Entity)
+	case "payment.failed":
+		err = c.failCheckout(ctx, webhook.Payload.Payment.Entity)
+	case "refund.processed":
+		err = c.refundRepo.MarkGatewayRefundProcessed(ctx, webhook.Payload.Refund.Entity.ID)
+	default:
+		// not an event we act on, just remember it
+	}
+	if err != nil {
+		return err
+	}
+
+	return c.orderRepo.SaveWebhookEvent(ctx, eventId, webhook.Event, payload)
 }
 
-func (c *Orderusecase) CancelOrder(ctx context.Context, orderId, userId int) error {
-	err := c.orderRepo.CancelOrder(ctx, orderId, userId)
+func (c *Orderusecase) reconcileCapturedPayment(ctx context.Context, payment requests.RazorpayPayment) error {
+	order, err := c.orderRepo.FindOrderByRazorpayPaymentID(ctx, payment.ID)
+	if err != nil {
+		return err
+	}
+	if order.ID != 0 {
+		return c.orderRepo.MarkRazorpayPaymentPaid(ctx, payment.ID)
+	}
+
+	// the order was never created, the checkout tells us whose cart it was
+	checkout, err := c.orderRepo.FindRazorpayCheckout(ctx, payment.OrderID)
+	if err != nil {
+		return err
+	}
+	if checkout.RazorpayOrderID == "" {
+		return fmt.Errorf("no checkout found for razorpay order %s", payment.OrderID)
+	}
+	if checkout.Status == domain.CheckoutRefunding || checkout.Status == domain.CheckoutRefunded {
+		return nil
+	}
+
+	_, err = c.createRazorpayOrder(ctx, requests.RazorPayRequest{
+		RazorPayPaymentId: payment.ID,
+		RazorPayOrderId:   payment.OrderID,
+		UserID:            int(checkout.UserID),
+		Amount:            float64(payment.Amount) / 100,
+	})
+	// a refunded payment is settled, razorpay doesn't need to send it again
+	if errors.Is(err, services.ErrPaymentRefunded) {
+		return nil
+	}
+	return err
+}
+
+// failCheckout records a failed payment on its checkout and gives the held
+// stock back. The user can still pay the same razorpay order again, the
+// stock is then taken when the order is placed
+func (c *Orderusecase) failCheckout(ctx context.Context, payment requests.RazorpayPayment) error {
+	reason := payment.ErrorDescription
+	if reason == "" {
+		reason = "payment failed"
+	}
+	failed, err := c.orderRepo.MarkRazorpayOrderFailed(ctx, payment.OrderID, payment.ID, reason)
+	if err != nil || !failed {
+		return err
+	}
+	return c.inventoryRepo.ReleaseReservations(ctx, payment.OrderID)
+}
+
+func (c *Orderusecase) findRazorpayOrder(ctx context.Context, body requests.RazorPayRequest) (domain.Orders, error) {
+	order, err := c.orderRepo.FindOrderByRazorpayPaymentID(ctx, body.RazorPayPaymentId)
+	if err != nil {
+		return domain.Orders{}, err
+	}
+	if order.ID != 0 && order.UserID != uint(body.UserID) {
+		return domain.Orders{}, errors.New("razorpay payment belongs to another user")
+	}
+	return order, nil
+}
+
+func (c *Orderusecase) CancelOrder(ctx context.Context, orderId, userId int, refundTo string) error {
+	if err := validRefundTo(refundTo); err != nil {
+		return err
+	}
+	actor := domain.Actor{Type: domain.ChangedByUser, ID: uint(userId)}
+	refund, err := c.orderRepo.CancelOrder(ctx, orderId, userId, refundTo, actor)
+	if err != nil {
+		return err
+	}
+	_, err = c.payoutRefund(refund)
 	return err
 }
 
@@ -125,11 +341,120 @@ func (c *Orderusecase) Listorder(ctx context.Context, Orderid int, UserId int) (
 	return order, err
 }
 
-func (c *Orderusecase) ReturnOrder(userId, orderId int) (float64, error) {
-	total, err := c.orderRepo.ReturnOrder(userId, orderId)
+func (c *Orderusecase) ReturnOrder(userId, orderId int, reason, refundTo string) (float64, error) {
+	if err := validRefundTo(refundTo); err != nil {
+		return 0, err
+	}
+	total, err := c.orderRepo.ReturnOrder(userId, orderId, reason, refundTo)
 	return total, err
 }
 
+// CancelOrderLine cancels qty of one item, zero cancels all that is left of it
+func (c *Orderusecase) CancelOrderLine(ctx context.Context, userId, orderId, lineId, qty int, refundTo string) error {
+	if err := validRefundTo(refundTo); err != nil {
+		return err
+	}
+	if qty < 0 {
+		return errors.New("qty can't be negative")
+	}
+	refund, err := c.orderRepo.CancelOrderLine(ctx, userId, orderId, lineId, qty, refundTo)
+	if err != nil {
+		return err
+	}
+	_, err = c.payoutRefund(refund)
+	return err
+}
+
+// ReturnOrderLine asks for a return of qty of one item, zero returns all that is left of it
+func (c *Orderusecase) ReturnOrderLine(ctx context.Context, userId, orderId, lineId, qty int, reason, refundTo string) (float64, error) {
+	if err := validRefundTo(refundTo); err != nil {
+		return 0, err
+	}
+	if qty < 0 {
+		return 0, errors.New("qty can't be negative")
+	}
+	amount, err := c.orderRepo.ReturnOrderLine(ctx, userId, orderId, lineId, qty, reason, refundTo)
+	return amount, err
+}
+
+func (c *Orderusecase) ListOrderLines(ctx context.Context, userId, orderId int) ([]domain.OrderLine, error) {
+	lines, err := c.orderRepo.FindOrderLines(ctx, userId, orderId)
+	return lines, err
+}
+
+func (c *Orderusecase) ListRefunds(ctx context.Context, status string, pagination requests.Pagination) ([]domain.Refund, error) {
+	if pagination.Page < 1 || pagination.PerPage < 1 {
+		return nil, errors.New("invalid pagination parameters")
+	}
+	refunds, err := c.refundRepo.FindRefunds(ctx, status, pagination)
+	return refunds, err
+}
+
+// ApproveRefund approves a pending return refund, a zero amount refunds what
+// was asked for. A refund that failed at the gateway is sent again
+func (c *Orderusecase) ApproveRefund(ctx context.Context, refundId uint, amount float64, adminId int) (domain.Refund, error) {
+	refund, err := c.refundRepo.FindRefundByID(ctx, refundId)
+	if err != nil {
+		return domain.Refund{}, err
+	}
+	if refund.ID == 0 {
+		return domain.Refund{}, errors.New("no refund found with this id")
+	}
+
+	if refund.Status != domain.RefundFailed {
+		refund, err = c.refundRepo.ApproveRefund(ctx, refundId, amount, uint(adminId))
+		if err != nil {
+			return domain.Refund{}, err
+		}
+	}
+	return c.payoutRefund(refund)
+}
+
+func (c *Orderusecase) DenyRefund(ctx context.Context, refundId uint, reason string, adminId int) error {
+	return c.refundRepo.DenyRefund(ctx, refundId, reason, uint(adminId))
+}
+
+// payoutRefund sends an approved or failed refund to the payment gateway,
+// wallet refunds are already paid and are returned as they are
+func (c *Orderusecase) payoutRefund(refund domain.Refund) (domain.Refund, error) {
+	if refund.ID == 0 || refund.RefundTo != domain.RefundToSource {
+		return refund, nil
+	}
+	if refund.Status != domain.RefundApproved && refund.Status != domain.RefundFailed {
+		return refund, nil
+	}
+
+	// the request context may already be gone, the refund row has to be updated anyway
+	ctx := context.Background()
+	notes := map[string]string{
+		"order_id":  strconv.Itoa(int(refund.OrderID)),
+		"refund_id": strconv.Itoa(int(refund.ID)),
+	}
+	gatewayRefund, err := c.gateway.Refund(refund.GatewayPaymentID, refund.Amount, notes)
+	if err != nil {
+		if updateErr := c.refundRepo.UpdateRefundStatus(ctx, refund.ID, domain.RefundFailed, ""); updateErr != nil {
+			return refund, updateErr
+		}
+		return refund, errors.Wrap(err, "the payment gateway could not refund")
+	}
+
+	// razorpay may finish the refund later, the refund.processed webhook marks it then
+	refund.Status = domain.RefundApproved
+	if gatewayRefund.Status == "processed" {
+		refund.Status = domain.RefundProcessed
+	}
+	refund.GatewayRefundID = gatewayRefund.ID
+	err = c.refundRepo.UpdateRefundStatus(ctx, refund.ID, refund.Status, gatewayRefund.ID)
+	return refund, err
+}
+
+func validRefundTo(refundTo string) error {
+	if refundTo != domain.RefundToWallet && refundTo != domain.RefundToSource {
+		return fmt.Errorf("refund_to must be %s or %s", domain.RefundToWallet, domain.RefundToSource)
+	}
+	return nil
+}
+
 func (c *Orderusecase) ListofOrderStatuses(ctx context.Context) ([]domain.OrderStatus, error) {
 	var status []domain.OrderStatus
 	status, err := c.orderRepo.ListofOrderStatuses(ctx)
@@ -141,7 +466,68 @@ func (c *Orderusecase) AdminListorders(ctx context.Context, pagination requests.
 	return orders, err
 }
 
-func (c *Orderusecase) UpdateOrderStatus(ctx context.Context, update requests.Update) error {
-	err := c.orderRepo.UpdateOrderStatus(ctx, update)
+// UpdateOrderStatus moves an order to the next status for an admin. Cancelling
+// goes through the same flow as a customer cancel so the stock and money come back
+func (c *Orderusecase) UpdateOrderStatus(ctx context.Context, update requests.Update, adminId int) error {
+	if uint(update.StatusId) != domain.OrderStatusCancelled {
+		return c.orderRepo.UpdateOrderStatus(ctx, update, adminId)
+	}
+
+	order, err := c.orderRepo.FindOrderByID(ctx, update.OrderId)
+	if err != nil {
+		return err
+	}
+	if order.ID == 0 {
+		return errors.New("no order found with this id")
+	}
+	actor := domain.Actor{Type: domain.ChangedByAdmin, ID: uint(adminId)}
+	refund, err := c.orderRepo.CancelOrder(ctx, update.OrderId, int(order.UserID), domain.RefundToSource, actor)
+	if err != nil {
+		return err
+	}
+	_, err = c.payoutRefund(refund)
 	return err
 }
+
+// OrderTimeline is the status history of one of the user's orders
+func (c *Orderusecase) OrderTimeline(ctx context.Context, orderId, userId int) ([]response.OrderTimeline, error) {
+	order, err := c.orderRepo.FindOrderByID(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	if order.ID == 0 || order.UserID != uint(userId) {
+		return nil, errors.New("no order found with this id")
+	}
+	return c.orderRepo.FindOrderStatusHistory(ctx, orderId)
+}
+
+func (c *Orderusecase) AdminOrderTimeline(ctx context.Context, orderId int) ([]response.OrderTimeline, error) {
+	order, err := c.orderRepo.FindOrderByID(ctx, orderId)
+	if err != nil {
+		return nil, err
+	}
+	if order.ID == 0 {
+		return nil, errors.New("no order found with this id")
+	}
+	return c.orderRepo.FindOrderStatusHistory(ctx, orderId)
+}
+
+func (c *Orderusecase) GetUserWallet(ctx context.Context, userID uint) (domain.Wallet, error) {
+	wallet, err := c.walletRepo.FindWalletByUserID(ctx, userID)
+	return wallet, err
+}
+
+func (c *Orderusecase) GetUserWalletTransactions(ctx context.Context, userID uint, pagination requests.Pagination) ([]domain.Transaction, error) {
+	if pagination.Page < 1 || pagination.PerPage < 1 {
+		return nil, errors.New("invalid pagination parameters")
+	}
+	transactions, err := c.walletRepo.FindTransactionsByUserID(ctx, userID, pagination)
+	return transactions, err
+}
+
+func (c *Orderusecase) ListRazorpayCheckouts(ctx context.Context, status string, pagination requests.Pagination) ([]domain.RazorpayCheckout, error) {
+	if pagination.Page < 1 || pagination.PerPage < 1 {
+		return nil, errors.New("invalid pagination parameters")
+	}
+	return c.orderRepo.FindRazorpayCheckouts(ctx, status, pagination)
+}
@@ -0,0 +1,385 @@
+package usecase
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/payment"
+	interfaces "ecommerce/pkg/repository/interface"
+	services "ecommerce/pkg/usecase/interface"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+	"time"
+)
+
+// checkoutOrderRepo keeps razorpay checkouts and the orders placed for them in
+// memory. Placing an order fails with placeErr when it is set
+type checkoutOrderRepo struct {
+	interfaces.OrderRepo
+	cartTotal float64
+	placeErr  error
+	checkouts map[string]domain.RazorpayCheckout
+	orders    map[string]domain.Orders
+	events    map[string]bool
+}
+
+func newCheckoutOrderRepo(cartTotal float64) *checkoutOrderRepo {
+	return &checkoutOrderRepo{
+		cartTotal: cartTotal,
+		checkouts: map[string]domain.RazorpayCheckout{},
+		orders:    map[string]domain.Orders{},
+		events:    map[string]bool{},
+	}
+}
+
+func (r *checkoutOrderRepo) SaveRazorpayCheckout(ctx context.Context, checkout domain.RazorpayCheckout) error {
+	checkout.Status = domain.CheckoutPending
+	r.checkouts[checkout.RazorpayOrderID] = checkout
+	return nil
+}
+
+func (r *checkoutOrderRepo) FindRazorpayCheckout(ctx context.Context, razorpayOrderId string) (domain.RazorpayCheckout, error) {
+	return r.checkouts[razorpayOrderId], nil
+}
+
+func (r *checkoutOrderRepo) OrderRazorpay(ctx context.Context, body requests.RazorPayRequest) (domain.Orders, error) {
+	checkout, ok := r.checkouts[body.RazorPayOrderId]
+	if !ok || checkout.UserID != uint(body.UserID) {
+		return domain.Orders{}, errors.New("no checkout found for this razorpay order")
+	}
+	if checkout.Status != domain.CheckoutPending && checkout.Status != domain.CheckoutFailed {
+		return domain.Orders{}, fmt.Errorf("the checkout is already %s", checkout.Status)
+	}
+	if r.placeErr != nil {
+		return domain.Orders{}, r.placeErr
+	}
+	if math.Abs(r.cartTotal-checkout.WalletAmount-body.Amount) > 0.01 {
+		return domain.Orders{}, fmt.Errorf("paid amount %.2f does not match the order amount", body.Amount)
+	}
+	order := domain.Orders{
+		ID:              uint(len(r.orders) + 1),
+		UserID:          checkout.UserID,
+		PaymentMethodID: 2,
+		OrderTotal:      r.cartTotal,
+		WalletAmount:    checkout.WalletAmount,
+	}
+	r.orders[body.RazorPayPaymentId] = order
+	checkout.Status = domain.CheckoutPaid
+	checkout.RazorpayPaymentID = body.RazorPayPaymentId
+	r.checkouts[checkout.RazorpayOrderID] = checkout
+	return order, nil
+}
+
+func (r *checkoutOrderRepo) FindOrderByRazorpayPaymentID(ctx context.Context, razorpayPaymentId string) (domain.Orders, error) {
+	return r.orders[razorpayPaymentId], nil
+}
+
+func (r *checkoutOrderRepo) MarkRazorpayPaymentPaid(ctx context.Context, razorpayPaymentId string) error {
+	return nil
+}
+
+func (r *checkoutOrderRepo) ClaimRazorpayCheckoutRefund(ctx context.Context, razorpayOrderId, razorpayPaymentId, note string) (bool, error) {
+	checkout := r.checkouts[razorpayOrderId]
+	if checkout.Status != domain.CheckoutPending && checkout.Status != domain.CheckoutFailed {
+		return false, nil
+	}
+	checkout.Status = domain.CheckoutRefunding
+	checkout.RazorpayPaymentID = razorpayPaymentId
+	checkout.Note = note
+	r.checkouts[razorpayOrderId] = checkout
+	return true, nil
+}
+
+func (r *checkoutOrderRepo) SaveRazorpayCheckoutRefund(ctx context.Context, checkout domain.RazorpayCheckout, amount float64) error {
+	if r.checkouts[checkout.RazorpayOrderID].Status != domain.CheckoutRefunding {
+		return errors.New("the checkout is not being refunded")
+	}
+	checkout.Status = domain.CheckoutRefunded
+	r.checkouts[checkout.RazorpayOrderID] = checkout
+	return nil
+}
+
+func (r *checkoutOrderRepo) MarkRazorpayOrderFailed(ctx context.Context, razorpayOrderId, razorpayPaymentId, reason string) (bool, error) {
+	checkout, ok := r.checkouts[razorpayOrderId]
+	if !ok || (checkout.Status != domain.CheckoutPending && checkout.Status != domain.CheckoutFailed) {
+		return false, nil
+	}
+	checkout.Status = domain.CheckoutFailed
+	checkout.Note = reason
+	r.checkouts[razorpayOrderId] = checkout
+	return true, nil
+}
+
+func (r *checkoutOrderRepo) IsWebhookEventProcessed(ctx context.Context, eventId string) (bool, error) {
+	return r.events[eventId], nil
+}
+
+func (r *checkoutOrderRepo) SaveWebhookEvent(ctx context.Context, eventId, event string, payload []byte) error {
+	r.events[eventId] = true
+	return nil
+}
+
+type checkoutCartRepo struct {
+	interfaces.CartRepo
+	total float64
+}
+
+func (r *checkoutCartRepo) FindCartByUserID(ctx context.Context, UserID int) (domain.Cart, error) {
+	return domain.Cart{Id: 1, User_id: uint(UserID), Total_price: r.total}, nil
+}
+
+func (r *checkoutCartRepo) RepriceCart(ctx context.Context, cartID uint) (domain.Cart, error) {
+	return domain.Cart{Id: cartID, Total_price: r.total}, nil
+}
+
+type checkoutWalletRepo struct {
+	interfaces.WalletRepo
+	balance float64
+}
+
+func (r *checkoutWalletRepo) FindWalletByUserID(ctx context.Context, userID uint) (domain.Wallet, error) {
+	return domain.Wallet{UserID: userID, Balance: r.balance}, nil
+}
+
+func (r *checkoutWalletRepo) FindTransactionsByUserID(ctx context.Context, userID uint, pagination requests.Pagination) ([]domain.Transaction, error) {
+	return []domain.Transaction{{Amount: r.balance, TransactionType: domain.TransactionCredit}}, nil
+}
+
+// checkoutInventoryRepo remembers which razorpay orders had their hold released
+type checkoutInventoryRepo struct {
+	interfaces.InventoryRepo
+	released []string
+}
+
+func (r *checkoutInventoryRepo) ReserveCart(ctx context.Context, userId int, razorpayOrderId string) ([]domain.StockReservation, error) {
+	return []domain.StockReservation{{RazorpayOrderID: razorpayOrderId, Status: domain.ReservationHeld, ExpiresAt: time.Now().Add(15 * time.Minute)}}, nil
+}
+
+func (r *checkoutInventoryRepo) ReleaseReservations(ctx context.Context, razorpayOrderId string) error {
+	r.released = append(r.released, razorpayOrderId)
+	return nil
+}
+
+type checkoutFixture struct {
+	usecase   *Orderusecase
+	orders    *checkoutOrderRepo
+	inventory *checkoutInventoryRepo
+	gateway   *payment.FakeGateway
+}
+
+// newCheckoutFixture is a checkout of a 500 rupee cart with 100 in the wallet
+func newCheckoutFixture(t *testing.T) checkoutFixture {
+	t.Helper()
+	gateway, err := payment.NewFakeGateway()
+	if err != nil {
+		t.Fatal(err)
+	}
+	orders := newCheckoutOrderRepo(500)
+	inventory := &checkoutInventoryRepo{}
+	usecase := NewOrderUseCase(orders, &checkoutCartRepo{total: 500}, &checkoutWalletRepo{balance: 100}, nil, inventory, gateway)
+	return checkoutFixture{
+		usecase:   usecase.(*Orderusecase),
+		orders:    orders,
+		inventory: inventory,
+		gateway:   gateway,
+	}
+}
+
+// pay starts a checkout for user 7 paying part from the wallet and pays it on the fake gateway
+func (f checkoutFixture) pay(t *testing.T) (razorpayOrderId, paymentId, signature string) {
+	t.Helper()
+	checkout, err := f.usecase.Razorpay(context.Background(), 7, 2, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if checkout.AmountToPay != 400 || checkout.WalletUsed != 100 {
+		t.Fatalf("expected 400 by razorpay and 100 from the wallet, got %.2f and %.2f", checkout.AmountToPay, checkout.WalletUsed)
+	}
+	razorpayOrderId = checkout.OrderId.(string)
+	paymentId, signature, err = f.gateway.Pay(razorpayOrderId)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return razorpayOrderId, paymentId, signature
+}
+
+func webhookPayload(event, razorpayOrderId, paymentId string, paise int64) []byte {
+	return []byte(fmt.Sprintf(`{"event":%q,"payload":{"payment":{"entity":{"id":%q,"order_id":%q,"amount":%d,"status":"captured","error_description":"card declined"}}}}`,
+		event, paymentId, razorpayOrderId, paise))
+}
+
+func TestRazorpayCheckoutPlacesTheOrder(t *testing.T) {
+	f := newCheckoutFixture(t)
+	razorpayOrderId, paymentId, signature := f.pay(t)
+
+	body := requests.RazorPayRequest{RazorPayOrderId: razorpayOrderId, RazorPayPaymentId: paymentId, Razorpay_signature: signature, UserID: 7}
+	order, err := f.usecase.VerifyRazorPay(context.Background(), body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if order.ID == 0 || order.WalletAmount != 100 || order.OrderTotal != 500 {
+		t.Fatalf("unexpected order %+v", order)
+	}
+	if status := f.orders.checkouts[razorpayOrderId].Status; status != domain.CheckoutPaid {
+		t.Fatalf("expected the checkout to be paid, it is %s", status)
+	}
+
+	// the success page can be posted again, it gets the same order
+	again, err := f.usecase.VerifyRazorPay(context.Background(), body)
+	if err != nil || again.ID != order.ID {
+		t.Fatalf("expected order %d again, got %d (%v)", order.ID, again.ID, err)
+	}
+}
+
+func TestRazorpayVerifyRefusesBadSignatures(t *testing.T) {
+	f := newCheckoutFixture(t)
+	razorpayOrderId, paymentId, signature := f.pay(t)
+
+	tests := []struct {
+		name string
+		body requests.RazorPayRequest
+	}{
+		{"tampered signature", requests.RazorPayRequest{RazorPayOrderId: razorpayOrderId, RazorPayPaymentId: paymentId, Razorpay_signature: signature[1:] + "0", UserID: 7}},
+		{"signature of another order", requests.RazorPayRequest{RazorPayOrderId: "order_other", RazorPayPaymentId: paymentId, Razorpay_signature: signature, UserID: 7}},
+		{"no signature", requests.RazorPayRequest{RazorPayOrderId: razorpayOrderId, RazorPayPaymentId: paymentId, UserID: 7}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := f.usecase.VerifyRazorPay(context.Background(), tt.body); err == nil {
+				t.Fatal("expected the payment to be refused")
+			}
+		})
+	}
+	if len(f.orders.orders) != 0 {
+		t.Fatalf("no order should be placed, got %d", len(f.orders.orders))
+	}
+}
+
+func TestRazorpayWebhookPlacesTheOrderOnce(t *testing.T) {
+	f := newCheckoutFixture(t)
+	razorpayOrderId, paymentId, _ := f.pay(t)
+
+	payload := webhookPayload("payment.captured", razorpayOrderId, paymentId, 40000)
+	for i := 0; i < 2; i++ {
+		if err := f.usecase.RazorpayWebhook(context.Background(), "evt_1", f.gateway.SignWebhook(payload), payload); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(f.orders.orders) != 1 {
+		t.Fatalf("expected one order, got %d", len(f.orders.orders))
+	}
+	if order := f.orders.orders[paymentId]; order.UserID != 7 || order.WalletAmount != 100 {
+		t.Fatalf("the order should use the user and wallet split of the checkout, got %+v", order)
+	}
+}
+
+func TestRazorpayWebhookRefusesForgedSignatures(t *testing.T) {
+	f := newCheckoutFixture(t)
+	razorpayOrderId, paymentId, _ := f.pay(t)
+	payload := webhookPayload("payment.captured", razorpayOrderId, paymentId, 40000)
+
+	// signed with the secret the fake used to share with everyone
+	mac := hmac.New(sha256.New, []byte("fake_secret"))
+	mac.Write(payload)
+	forged := hex.EncodeToString(mac.Sum(nil))
+
+	err := f.usecase.RazorpayWebhook(context.Background(), "evt_1", forged, payload)
+	if !errors.Is(err, services.ErrInvalidSignature) {
+		t.Fatalf("expected ErrInvalidSignature, got %v", err)
+	}
+	if len(f.orders.orders) != 0 {
+		t.Fatal("a forged webhook placed an order")
+	}
+}
+
+func TestRazorpayPaymentIsRefundedWhenNoOrderCanBePlaced(t *testing.T) {
+	f := newCheckoutFixture(t)
+	f.orders.placeErr = errors.New("out of stock")
+	razorpayOrderId, paymentId, signature := f.pay(t)
+
+	body := requests.RazorPayRequest{RazorPayOrderId: razorpayOrderId, RazorPayPaymentId: paymentId, Razorpay_signature: signature, UserID: 7}
+	_, err := f.usecase.VerifyRazorPay(context.Background(), body)
+	if !errors.Is(err, services.ErrPaymentRefunded) {
+		t.Fatalf("expected ErrPaymentRefunded, got %v", err)
+	}
+	if refunded := f.gateway.RefundedAmount(paymentId); refunded != 400 {
+		t.Fatalf("expected the 400 razorpay took to be refunded, got %.2f", refunded)
+	}
+	checkout := f.orders.checkouts[razorpayOrderId]
+	if checkout.Status != domain.CheckoutRefunded || checkout.RefundTo != domain.RefundToSource || checkout.GatewayRefundID == "" {
+		t.Fatalf("the refund should be recorded on the checkout, got %+v", checkout)
+	}
+	if len(f.inventory.released) != 1 || f.inventory.released[0] != razorpayOrderId {
+		t.Fatalf("expected the hold of %s to be released, released %v", razorpayOrderId, f.inventory.released)
+	}
+
+	// the webhook for the same payment must not refund it a second time
+	payload := webhookPayload("payment.captured", razorpayOrderId, paymentId, 40000)
+	if err := f.usecase.RazorpayWebhook(context.Background(), "evt_1", f.gateway.SignWebhook(payload), payload); err != nil {
+		t.Fatal(err)
+	}
+	if refunded := f.gateway.RefundedAmount(paymentId); refunded != 400 {
+		t.Fatalf("expected 400 refunded once, got %.2f", refunded)
+	}
+}
+
+func TestRazorpayFailedPaymentReleasesTheHold(t *testing.T) {
+	f := newCheckoutFixture(t)
+	checkout, err := f.usecase.Razorpay(context.Background(), 7, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	razorpayOrderId := checkout.OrderId.(string)
+
+	payload := webhookPayload("payment.failed", razorpayOrderId, "pay_failed", 50000)
+	if err := f.usecase.RazorpayWebhook(context.Background(), "evt_1", f.gateway.SignWebhook(payload), payload); err != nil {
+		t.Fatal(err)
+	}
+	if saved := f.orders.checkouts[razorpayOrderId]; saved.Status != domain.CheckoutFailed || saved.Note != "card declined" {
+		t.Fatalf("the failure should be recorded on the checkout, got %+v", saved)
+	}
+	if len(f.inventory.released) != 1 || f.inventory.released[0] != razorpayOrderId {
+		t.Fatalf("expected the hold of %s to be released, released %v", razorpayOrderId, f.inventory.released)
+	}
+}
+
+func TestRazorpayRefusesWhenTheWalletCoversTheOrder(t *testing.T) {
+	tests := []struct {
+		name    string
+		balance float64
+	}{
+		{name: "wallet holds exactly the total", balance: 500},
+		{name: "wallet holds more than the total", balance: 750},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := newCheckoutFixture(t)
+			f.usecase.walletRepo = &checkoutWalletRepo{balance: tt.balance}
+			if _, err := f.usecase.Razorpay(context.Background(), 7, 2, true); err == nil {
+				t.Fatal("expected razorpay to be refused when the wallet can pay for the order")
+			}
+			if len(f.orders.checkouts) != 0 {
+				t.Fatal("no checkout should be started")
+			}
+		})
+	}
+}
+
+func TestWalletTransactionsNeedValidPagination(t *testing.T) {
+	f := newCheckoutFixture(t)
+
+	for _, pagination := range []requests.Pagination{{Page: 0, PerPage: 10}, {Page: 1, PerPage: 0}} {
+		if _, err := f.usecase.GetUserWalletTransactions(context.Background(), 7, pagination); err == nil {
+			t.Fatalf("expected %+v to be refused", pagination)
+		}
+	}
+	transactions, err := f.usecase.GetUserWalletTransactions(context.Background(), 7, requests.Pagination{Page: 1, PerPage: 10})
+	if err != nil || len(transactions) != 1 {
+		t.Fatalf("expected one transaction, got %v (%v)", transactions, err)
+	}
+}
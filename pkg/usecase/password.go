@@ -0,0 +1,148 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/mail"
+	interfaces "ecommerce/pkg/repository/interface"
+	"ecommerce/pkg/token"
+	services "ecommerce/pkg/usecase/interface"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type passwordUseCase struct {
+	userRepo interfaces.UserRepository
+	otp      services.OtpUseCase
+	mailer   mail.Mailer
+	tokens   services.TokenUseCase
+	resetTTL time.Duration
+}
+
+func NewPasswordUseCase(repo interfaces.UserRepository, otp services.OtpUseCase, mailer mail.Mailer,
+	tokens services.TokenUseCase, resetTTL time.Duration) services.PasswordUseCase {
+	return &passwordUseCase{
+		userRepo: repo,
+		otp:      otp,
+		mailer:   mailer,
+		tokens:   tokens,
+		resetTTL: resetTTL,
+	}
+}
+
+// ForgotPassword emails a reset token, or sends an OTP by sms that
+// VerifyResetOtp trades for one. It succeeds quietly when no user has the
+// email or phone so it can't be used to find accounts
+func (c *passwordUseCase) ForgotPassword(ctx context.Context, forgot requests.ForgotPassword) error {
+	switch forgot.Channel {
+	case domain.ResetByEmail:
+		if forgot.Email == "" {
+			return errors.New("email is required")
+		}
+		user, err := c.userRepo.UserLogin(ctx, forgot.Email)
+		if err != nil {
+			return err
+		} else if user.ID == 0 {
+			return nil
+		}
+		resetToken, err := c.newReset(ctx, user.ID, domain.ResetByEmail)
+		if err != nil {
+			return err
+		}
+		body := fmt.Sprintf("Use this code to reset your password, it works once and expires in %d minutes:\n\n%s\n\n"+
+			"If you didn't ask for it you can ignore this email.", int(c.resetTTL.Minutes()), resetToken)
+		return c.mailer.Send(user.Email, "Reset your password", body)
+
+	case domain.ResetBySMS:
+		if forgot.Phone == "" {
+			return errors.New("phone is required")
+		}
+		id, err := c.userRepo.OtpLogin(forgot.Phone)
+		if err != nil {
+			return err
+		} else if id == 0 {
+			return nil
+		}
+		_, err = c.otp.SendOTP(ctx, requests.OTPreq{Phone: forgot.Phone})
+		return err
+
+	default:
+		return fmt.Errorf("unknown channel %q", forgot.Channel)
+	}
+}
+
+// VerifyResetOtp checks the OTP sent by ForgotPassword and hands out a reset token
+func (c *passwordUseCase) VerifyResetOtp(ctx context.Context, otpDetails requests.Otpverifier) (string, error) {
+	if err := c.otp.VerifyOTP(ctx, otpDetails); err != nil {
+		return "", err
+	}
+	id, err := c.userRepo.OtpLogin(otpDetails.Phone)
+	if err != nil {
+		return "", err
+	} else if id == 0 {
+		return "", errors.New("user not exist with given mobile number")
+	}
+	return c.newReset(ctx, uint(id), domain.ResetBySMS)
+}
+
+// ResetPassword sets the new password with a reset token and signs the user
+// out everywhere, whoever knew the old password is locked out
+func (c *passwordUseCase) ResetPassword(ctx context.Context, reset requests.ResetPassword) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(reset.NewPassword), 10)
+	if err != nil {
+		return err
+	}
+	userId, err := c.userRepo.ResetPassword(ctx, token.Hash(reset.Token), string(hash))
+	if err != nil {
+		return err
+	}
+	return c.tokens.RevokeAll(ctx, userId, token.AudienceUser)
+}
+
+// ChangePassword sets a new password for a signed in user that knows the old
+// one, every other session of the user is signed out
+func (c *passwordUseCase) ChangePassword(ctx context.Context, userId uint, currentSession string, change requests.ChangePassword) error {
+	user, err := c.userRepo.FindUserByID(ctx, userId)
+	if err != nil {
+		return err
+	} else if user.ID == 0 {
+		return errors.New("no user found")
+	}
+	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(change.OldPassword)) != nil {
+		return errors.New("old password is incorrect")
+	}
+	if change.OldPassword == change.NewPassword {
+		return errors.New("new password must be different from the old one")
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(change.NewPassword), 10)
+	if err != nil {
+		return err
+	}
+	if err := c.userRepo.UpdatePassword(ctx, userId, string(hash)); err != nil {
+		return err
+	}
+	return c.tokens.RevokeOthers(ctx, userId, token.AudienceUser, currentSession)
+}
+
+// newReset saves a new reset token for the user and returns it
+func (c *passwordUseCase) newReset(ctx context.Context, userId uint, channel string) (string, error) {
+	resetToken, err := token.RandomString(24)
+	if err != nil {
+		return "", err
+	}
+	err = c.userRepo.SavePasswordReset(ctx, domain.PasswordReset{
+		UserID:    userId,
+		TokenHash: token.Hash(resetToken),
+		Channel:   channel,
+		ExpiresAt: time.Now().Add(c.resetTTL),
+	})
+	if err != nil {
+		return "", err
+	}
+	return resetToken, nil
+}
@@ -0,0 +1,258 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/token"
+	services "ecommerce/pkg/usecase/interface"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// sentMail remembers the mails it was asked to send
+type sentMail struct {
+	bodies []string
+}
+
+func (m *sentMail) Send(to, subject, body string) error {
+	m.bodies = append(m.bodies, body)
+	return nil
+}
+
+// resetCode is the code in the last reset email, on its own line
+func (m *sentMail) resetCode(t *testing.T) string {
+	t.Helper()
+	if len(m.bodies) == 0 {
+		t.Fatal("no reset email was sent")
+	}
+	parts := strings.Split(m.bodies[len(m.bodies)-1], "\n\n")
+	if len(parts) < 2 {
+		t.Fatal("the reset email has no code")
+	}
+	return parts[1]
+}
+
+// fixedOtp accepts the pin 123456
+type fixedOtp struct {
+	services.OtpUseCase
+	sent []string
+}
+
+func (o *fixedOtp) SendOTP(ctx context.Context, otp requests.OTPreq) (string, error) {
+	o.sent = append(o.sent, otp.Phone)
+	return "pending", nil
+}
+
+func (o *fixedOtp) VerifyOTP(ctx context.Context, otp requests.Otpverifier) error {
+	if otp.Pin != "123456" {
+		return errors.New("invalid otp")
+	}
+	return nil
+}
+
+func emailResetCode(t *testing.T, passwords services.PasswordUseCase, mailer *sentMail) string {
+	t.Helper()
+	err := passwords.ForgotPassword(context.Background(), requests.ForgotPassword{Channel: domain.ResetByEmail, Email: "user@example.com"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mailer.resetCode(t)
+}
+
+func smsResetCode(t *testing.T, passwords services.PasswordUseCase) string {
+	t.Helper()
+	err := passwords.ForgotPassword(context.Background(), requests.ForgotPassword{Channel: domain.ResetBySMS, Phone: "9876543210"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := passwords.VerifyResetOtp(context.Background(), requests.Otpverifier{Pin: "123456", Phone: "9876543210"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code
+}
+
+func TestResetTokensWorkOnce(t *testing.T) {
+	tests := []struct {
+		name     string
+		resetTTL time.Duration
+		// code returns the reset code to try
+		code    func(t *testing.T, passwords services.PasswordUseCase, mailer *sentMail) string
+		wantErr bool
+	}{
+		{
+			name:     "emailed code",
+			resetTTL: time.Hour,
+			code:     emailResetCode,
+		},
+		{
+			name:     "code for a verified sms otp",
+			resetTTL: time.Hour,
+			code: func(t *testing.T, passwords services.PasswordUseCase, mailer *sentMail) string {
+				return smsResetCode(t, passwords)
+			},
+		},
+		{
+			name:     "code already used",
+			resetTTL: time.Hour,
+			code: func(t *testing.T, passwords services.PasswordUseCase, mailer *sentMail) string {
+				code := emailResetCode(t, passwords, mailer)
+				if err := passwords.ResetPassword(context.Background(), requests.ResetPassword{Token: code, NewPassword: "first-password"}); err != nil {
+					t.Fatal(err)
+				}
+				return code
+			},
+			wantErr: true,
+		},
+		{
+			name:     "code replaced by a newer one",
+			resetTTL: time.Hour,
+			code: func(t *testing.T, passwords services.PasswordUseCase, mailer *sentMail) string {
+				code := emailResetCode(t, passwords, mailer)
+				smsResetCode(t, passwords)
+				return code
+			},
+			wantErr: true,
+		},
+		{
+			name:     "code expired",
+			resetTTL: -time.Minute,
+			code:     emailResetCode,
+			wantErr:  true,
+		},
+		{
+			name:     "made up code",
+			resetTTL: time.Hour,
+			code: func(t *testing.T, passwords services.PasswordUseCase, mailer *sentMail) string {
+				return "not-a-reset-code"
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &memoryUserRepo{users: []domain.Users{testUser(t, "old-password")}}
+			mailer, tokens := &sentMail{}, newTestTokenUseCase(t)
+			passwords := NewPasswordUseCase(users, &fixedOtp{}, mailer, tokens, tt.resetTTL)
+			session := signIn(t, tokens, 1, token.AudienceUser)
+			code := tt.code(t, passwords, mailer)
+			before := users.users[0].Password
+
+			err := passwords.ResetPassword(context.Background(), requests.ResetPassword{Token: code, NewPassword: "new-password"})
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected the reset code to be refused")
+				}
+				if users.users[0].Password != before {
+					t.Fatal("the password should not change")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(users.users[0].Password), []byte("new-password")) != nil {
+				t.Fatal("expected the new password to be set")
+			}
+			// whoever had the old password is signed out
+			assertAccess(t, tokens, session, token.AudienceUser, false)
+
+			err = passwords.ResetPassword(context.Background(), requests.ResetPassword{Token: code, NewPassword: "another-password"})
+			if err == nil {
+				t.Fatal("expected the code to work only once")
+			}
+		})
+	}
+}
+
+func TestForgotPasswordDoesntTellWhoHasAnAccount(t *testing.T) {
+	tests := []struct {
+		name   string
+		forgot requests.ForgotPassword
+	}{
+		{name: "unknown email", forgot: requests.ForgotPassword{Channel: domain.ResetByEmail, Email: "nobody@example.com"}},
+		{name: "unknown phone", forgot: requests.ForgotPassword{Channel: domain.ResetBySMS, Phone: "9000000000"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &memoryUserRepo{users: []domain.Users{testUser(t, "old-password")}}
+			mailer, otp := &sentMail{}, &fixedOtp{}
+			passwords := NewPasswordUseCase(users, otp, mailer, newTestTokenUseCase(t), time.Hour)
+
+			if err := passwords.ForgotPassword(context.Background(), tt.forgot); err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if len(mailer.bodies) != 0 || len(otp.sent) != 0 || len(users.resets) != 0 {
+				t.Fatal("nothing should be sent or saved for an unknown account")
+			}
+		})
+	}
+}
+
+func TestVerifyResetOtpRefusesAWrongPin(t *testing.T) {
+	users := &memoryUserRepo{users: []domain.Users{testUser(t, "old-password")}}
+	passwords := NewPasswordUseCase(users, &fixedOtp{}, &sentMail{}, newTestTokenUseCase(t), time.Hour)
+
+	_, err := passwords.VerifyResetOtp(context.Background(), requests.Otpverifier{Pin: "000000", Phone: "9876543210"})
+	if err == nil {
+		t.Fatal("expected a wrong pin to be refused")
+	}
+	if len(users.resets) != 0 {
+		t.Fatal("no reset code should be saved for a wrong pin")
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  requests.ChangePassword
+		wantErr string
+	}{
+		{name: "changed", change: requests.ChangePassword{OldPassword: "old-password", NewPassword: "new-password"}},
+		{name: "wrong old password", change: requests.ChangePassword{OldPassword: "guess", NewPassword: "new-password"}, wantErr: "incorrect"},
+		{name: "same password", change: requests.ChangePassword{OldPassword: "old-password", NewPassword: "old-password"}, wantErr: "different"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users := &memoryUserRepo{users: []domain.Users{testUser(t, "old-password")}}
+			tokens := newTestTokenUseCase(t)
+			passwords := NewPasswordUseCase(users, &fixedOtp{}, &sentMail{}, tokens, time.Hour)
+			current := signIn(t, tokens, 1, token.AudienceUser)
+			other := signIn(t, tokens, 1, token.AudienceUser)
+			before := users.users[0].Password
+
+			err := passwords.ChangePassword(context.Background(), 1, sessionOf(t, tokens, current, token.AudienceUser), tt.change)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("expected %q, got %v", tt.wantErr, err)
+				}
+				if users.users[0].Password != before {
+					t.Fatal("the password should not change")
+				}
+				assertAccess(t, tokens, other, token.AudienceUser, true)
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(users.users[0].Password), []byte("new-password")) != nil {
+				t.Fatal("expected the new password to be set")
+			}
+
+			// the device that changed the password stays signed in, every other one is signed out
+			assertAccess(t, tokens, current, token.AudienceUser, true)
+			if _, err := tokens.Refresh(context.Background(), current.RefreshToken, token.AudienceUser); err != nil {
+				t.Fatalf("expected the current session to keep refreshing, got %v", err)
+			}
+			assertAccess(t, tokens, other, token.AudienceUser, false)
+			if _, err := tokens.Refresh(context.Background(), other.RefreshToken, token.AudienceUser); err == nil {
+				t.Fatal("expected the other session to be revoked")
+			}
+		})
+	}
+}
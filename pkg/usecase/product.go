@@ -4,17 +4,25 @@ import (
 	"context"
 	"ecommerce/pkg/commonhelp/requests.go"
 	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
 	interfaces "ecommerce/pkg/repository/interface"
+	"ecommerce/pkg/storage"
 	services "ecommerce/pkg/usecase/interface"
+	"fmt"
+	"strings"
 )
 
 type ProductUsecase struct {
-	ProductRepo interfaces.ProductRepo
+	ProductRepo   interfaces.ProductRepo
+	InventoryRepo interfaces.InventoryRepo
+	Storage       storage.Storage
 }
 
-func NewProductUsecase(ProductRepo interfaces.ProductRepo) services.ProductUsecase {
+func NewProductUsecase(ProductRepo interfaces.ProductRepo, InventoryRepo interfaces.InventoryRepo, Storage storage.Storage) services.ProductUsecase {
 	return &ProductUsecase{
-		ProductRepo: ProductRepo,
+		ProductRepo:   ProductRepo,
+		InventoryRepo: InventoryRepo,
+		Storage:       Storage,
 	}
 }
 
@@ -28,8 +36,8 @@ func (P *ProductUsecase) UpdateCategory(ctx context.Context, category requests.C
 	return updatedcategory, err
 }
 
-func (P *ProductUsecase) DeleteCategory(ctx context.Context, Id int) error {
-	err := P.ProductRepo.DeleteCategory(ctx, Id)
+func (P *ProductUsecase) DeleteCategory(ctx context.Context, Id int, reassignTo int) error {
+	err := P.ProductRepo.DeleteCategory(ctx, Id, reassignTo)
 	return err
 }
 func (p *ProductUsecase) Listallcategory(ctx context.Context) ([]response.Category, error) {
@@ -41,18 +49,33 @@ func (p *ProductUsecase) Listallcategory(ctx context.Context) ([]response.Catego
 func (p *ProductUsecase) ShowCatagory(ctx context.Context, Id int) (response.Category, error) {
 
 	yourcategory, err := p.ProductRepo.ShowCatagory(ctx, Id)
+	if err != nil || yourcategory.ID == 0 {
+		return yourcategory, err
+	}
+	categories, err := p.ProductRepo.Listallcategory(ctx)
+	if err != nil {
+		return yourcategory, err
+	}
+	yourcategory.Children = categoryTree(categories, yourcategory.ID)
+	return yourcategory, nil
 
-	return yourcategory, err
+}
+
+func (p *ProductUsecase) RestoreCategory(ctx context.Context, Id int) (response.Category, error) {
+	return p.ProductRepo.RestoreCategory(ctx, Id)
+}
 
+func (p *ProductUsecase) ArchivedCategories(ctx context.Context) ([]response.Category, error) {
+	return p.ProductRepo.FindDeletedCategories(ctx)
 }
 
-func (p *ProductUsecase) SaveProduct(ctx context.Context, product requests.Product) (response.Product, error) {
-	newproduct, err := p.ProductRepo.SaveProduct(ctx, product)
+func (p *ProductUsecase) SaveProduct(ctx context.Context, product requests.Product, adminId int) (response.Product, error) {
+	newproduct, err := p.ProductRepo.SaveProduct(ctx, product, adminId)
 	return newproduct, err
 }
 
-func (p *ProductUsecase) UpdateProduct(ctx context.Context, id int, product requests.Product) (response.Product, error) {
-	updateproduct, err := p.ProductRepo.UpdateProduct(ctx, id, product)
+func (p *ProductUsecase) UpdateProduct(ctx context.Context, id int, product requests.Product, adminId int) (response.Product, error) {
+	updateproduct, err := p.ProductRepo.UpdateProduct(ctx, id, product, adminId)
 	return updateproduct, err
 
 }
@@ -62,31 +85,210 @@ func (p *ProductUsecase) DeleteProduct(ctx context.Context, id int) error {
 
 	return err
 }
-func (p *ProductUsecase) ViewAllProducts(ctx context.Context, pagination requests.Pagination) (products []response.Product, err error) {
-	allProducts, err := p.ProductRepo.ViewAllProducts(ctx, pagination)
-	return allProducts, err
+
+func (p *ProductUsecase) RestoreProduct(ctx context.Context, id int) (response.Product, error) {
+	if err := p.ProductRepo.RestoreProduct(ctx, id); err != nil {
+		return response.Product{}, err
+	}
+	return p.VeiwProduct(ctx, id)
+}
+
+func (p *ProductUsecase) ArchivedProducts(ctx context.Context, pagination requests.Pagination) ([]response.Product, error) {
+	products, err := p.ProductRepo.FindDeletedProducts(ctx, pagination)
+	if err != nil {
+		return nil, err
+	}
+	for i := range products {
+		products[i].Thumbnail = thumbnailURL(p.Storage, products[i].ThumbnailKey)
+	}
+	return products, nil
+}
+
+// ViewAllProducts lists products newest first, or best match first when
+// there is search text, unless another order is asked for
+func (p *ProductUsecase) ViewAllProducts(ctx context.Context, filter requests.ProductFilter) (response.ProductList, error) {
+	filter.Query = strings.TrimSpace(filter.Query)
+	if filter.Sort == "" {
+		filter.Sort = "newest"
+		if filter.Query != "" {
+			filter.Sort = "relevance"
+		}
+	}
+	if filter.Sort == "relevance" && filter.Query == "" {
+		return response.ProductList{}, fmt.Errorf("sorting by relevance needs search text in q")
+	}
+	if filter.MinPrice != nil && filter.MaxPrice != nil && *filter.MinPrice > *filter.MaxPrice {
+		return response.ProductList{}, fmt.Errorf("min_price can't be above max_price")
+	}
+	if filter.Page == 0 {
+		filter.Page = 1
+	}
+	if filter.PerPage == 0 {
+		filter.PerPage = 10
+	}
+
+	list, err := p.ProductRepo.ViewAllProducts(ctx, filter)
+	if err != nil {
+		return response.ProductList{}, err
+	}
+	for i := range list.Products {
+		list.Products[i].Thumbnail = thumbnailURL(p.Storage, list.Products[i].ThumbnailKey)
+	}
+	if err := p.setBreadcrumbs(ctx, list.Products); err != nil {
+		return response.ProductList{}, err
+	}
+	return list, nil
 }
 func (p *ProductUsecase) VeiwProduct(ctx context.Context, id int) (response.Product, error) {
 	product, err := p.ProductRepo.ViewProduct(ctx, id)
-	return product, err
+	if err != nil || product.Id == 0 {
+		return product, err
+	}
+	images, err := p.ProductRepo.FindProductImages(ctx, id)
+	if err != nil {
+		return product, err
+	}
+	product.Thumbnail = thumbnailURL(p.Storage, product.ThumbnailKey)
+	product.Images = productImages(p.Storage, images)
+	products := []response.Product{product}
+	if err := p.setBreadcrumbs(ctx, products); err != nil {
+		return product, err
+	}
+	return products[0], nil
+}
+func (p *ProductUsecase) SearchProducts(ctx context.Context, search requests.ProductSearch) (response.ProductSearch, error) {
+	search.Query = strings.TrimSpace(search.Query)
+	if search.Query == "" {
+		return response.ProductSearch{}, fmt.Errorf("search text can't be empty")
+	}
+	if search.MinPrice != nil && search.MaxPrice != nil && *search.MinPrice > *search.MaxPrice {
+		return response.ProductSearch{}, fmt.Errorf("min_price can't be above max_price")
+	}
+	if search.Page == 0 {
+		search.Page = 1
+	}
+	if search.PerPage == 0 {
+		search.PerPage = 10
+	}
+
+	result, err := p.ProductRepo.SearchProducts(ctx, search)
+	if err != nil {
+		return response.ProductSearch{}, err
+	}
+	for i := range result.Products {
+		result.Products[i].Thumbnail = thumbnailURL(p.Storage, result.Products[i].ThumbnailKey)
+	}
+	if err := p.setBreadcrumbs(ctx, result.Products); err != nil {
+		return response.ProductSearch{}, err
+	}
+	return result, nil
 }
-func (p *ProductUsecase) SearchProducts(ctx context.Context, query string) ([]requests.Product, error) {
-	return p.ProductRepo.SearchProducts(ctx, query)
+func (p *ProductUsecase) StockHistory(ctx context.Context, productId int, pagination requests.Pagination) ([]domain.StockMovement, error) {
+	return p.InventoryRepo.FindStockMovements(ctx, productId, pagination)
 }
-func (p *ProductUsecase) GetProductsByPriceRange(min, max *float64) ([]requests.Product, error) {
-	// if min or max not provided, fetch from DB
-	if min == nil || max == nil {
-		dbMin, dbMax, err := p.ProductRepo.GetMinMaxPrice()
-		if err != nil {
-			return nil, err
-		}
-		if min == nil {
-			min = &dbMin
+
+// AdjustStock is an admin's manual change to a product's stock. A restock can
+// only add stock, an adjustment corrects it either way
+func (p *ProductUsecase) AdjustStock(ctx context.Context, productId, adminId int, adjustment requests.StockAdjustment) (domain.StockMovement, error) {
+	reason := adjustment.Reason
+	if reason == "" {
+		reason = domain.StockAdjustment
+	}
+	if reason != domain.StockAdjustment && reason != domain.StockRestock {
+		return domain.StockMovement{}, fmt.Errorf("reason must be %s or %s", domain.StockAdjustment, domain.StockRestock)
+	}
+	if adjustment.Change == 0 {
+		return domain.StockMovement{}, fmt.Errorf("change can't be zero")
+	}
+	if reason == domain.StockRestock && adjustment.Change < 0 {
+		return domain.StockMovement{}, fmt.Errorf("a restock must add stock")
+	}
+
+	variants, err := p.ProductRepo.FindVariants(ctx, productId)
+	if err != nil {
+		return domain.StockMovement{}, err
+	}
+	variant, err := pickVariant(variants, adjustment.VariantID)
+	if err != nil {
+		return domain.StockMovement{}, err
+	}
+
+	return p.InventoryRepo.AdjustStock(ctx, domain.StockMovement{
+		VariantID: variant.ID,
+		QtyChange: adjustment.Change,
+		Reason:    reason,
+		AdminID:   uint(adminId),
+		Note:      adjustment.Note,
+	})
+}
+
+func (p *ProductUsecase) ListVariants(ctx context.Context, productId int) ([]domain.ProductVariant, error) {
+	return p.ProductRepo.FindVariants(ctx, productId)
+}
+
+func (p *ProductUsecase) AddVariant(ctx context.Context, productId, adminId int, variant requests.Variant) (domain.ProductVariant, error) {
+	if variant.Price == nil {
+		return domain.ProductVariant{}, fmt.Errorf("price is required")
+	}
+	return p.ProductRepo.SaveVariant(ctx, domain.ProductVariant{
+		ProductID:  uint(productId),
+		SKU:        variant.Sku,
+		Attributes: variant.Attributes,
+		Price:      *variant.Price,
+		QtyInStock: variant.QtyInStock,
+	}, adminId)
+}
+
+// UpdateVariant edits a variant's sku, attributes and price. Its stock only
+// changes through AdjustStock so every change is in the ledger
+func (p *ProductUsecase) UpdateVariant(ctx context.Context, variantId int, variant requests.Variant) (domain.ProductVariant, error) {
+	if variant.QtyInStock != 0 {
+		return domain.ProductVariant{}, fmt.Errorf("stock is changed with a stock adjustment")
+	}
+	// a negative price keeps the current one
+	price := -1
+	if variant.Price != nil {
+		price = *variant.Price
+	}
+	return p.ProductRepo.UpdateVariant(ctx, domain.ProductVariant{
+		ID:         uint(variantId),
+		SKU:        variant.Sku,
+		Attributes: variant.Attributes,
+		Price:      price,
+	})
+}
+
+func (p *ProductUsecase) DeleteVariant(ctx context.Context, variantId int) error {
+	return p.ProductRepo.DeleteVariant(ctx, variantId)
+}
+
+func (p *ProductUsecase) SetReorderThreshold(ctx context.Context, productId, threshold int) error {
+	if threshold < 0 {
+		return fmt.Errorf("threshold can't be negative")
+	}
+	return p.InventoryRepo.SetReorderThreshold(ctx, productId, threshold)
+}
+
+func (p *ProductUsecase) LowStockReport(ctx context.Context, pagination requests.Pagination) ([]response.LowStockProduct, error) {
+	return p.InventoryRepo.FindLowStockProducts(ctx, pagination)
+}
+
+// pickVariant finds variantId among a product's variants. Zero picks the only
+// variant of a product that has just one
+func pickVariant(variants []domain.ProductVariant, variantId uint) (domain.ProductVariant, error) {
+	if len(variants) == 0 {
+		return domain.ProductVariant{}, fmt.Errorf("product is unavailable")
+	}
+	if variantId == 0 {
+		if len(variants) > 1 {
+			return domain.ProductVariant{}, fmt.Errorf("the product has %d variants, choose one with variant_id", len(variants))
 		}
-		if max == nil {
-			max = &dbMax
+		return variants[0], nil
+	}
+	for _, variant := range variants {
+		if variant.ID == variantId {
+			return variant, nil
 		}
 	}
-
-	return p.ProductRepo.FilterByPriceRange(*min, *max)
+	return domain.ProductVariant{}, fmt.Errorf("the product has no variant %d", variantId)
 }
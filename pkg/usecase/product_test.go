@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"testing"
+)
+
+// variantProductRepo serves product 4 with the variants it is given, lists
+// the categories it is given and remembers the variant edits it was asked to
+// save
+type variantProductRepo struct {
+	interfaces.ProductRepo
+	variants   []domain.ProductVariant
+	updated    []domain.ProductVariant
+	categories []response.Category
+}
+
+func (r *variantProductRepo) Listallcategory(ctx context.Context) ([]response.Category, error) {
+	return r.categories, nil
+}
+
+func (r *variantProductRepo) FindVariants(ctx context.Context, productId int) ([]domain.ProductVariant, error) {
+	if productId != 4 {
+		return nil, nil
+	}
+	return r.variants, nil
+}
+
+func (r *variantProductRepo) UpdateVariant(ctx context.Context, variant domain.ProductVariant) (domain.ProductVariant, error) {
+	r.updated = append(r.updated, variant)
+	return variant, nil
+}
+
+// stockInventoryRepo remembers the stock movements it was asked to make
+type stockInventoryRepo struct {
+	interfaces.InventoryRepo
+	moved      []domain.StockMovement
+	thresholds map[int]int
+}
+
+func (r *stockInventoryRepo) AdjustStock(ctx context.Context, movement domain.StockMovement) (domain.StockMovement, error) {
+	r.moved = append(r.moved, movement)
+	return movement, nil
+}
+
+func (r *stockInventoryRepo) SetReorderThreshold(ctx context.Context, productId, threshold int) error {
+	r.thresholds[productId] = threshold
+	return nil
+}
+
+func TestAdjustStock(t *testing.T) {
+	tests := []struct {
+		name       string
+		adjustment requests.StockAdjustment
+		wantReason string
+		wantErr    bool
+	}{
+		{name: "adjustment is the default reason", adjustment: requests.StockAdjustment{Change: -2, Note: "damaged"}, wantReason: domain.StockAdjustment},
+		{name: "restock adds stock", adjustment: requests.StockAdjustment{Change: 10, Reason: domain.StockRestock}, wantReason: domain.StockRestock},
+		{name: "restock can't take stock away", adjustment: requests.StockAdjustment{Change: -1, Reason: domain.StockRestock}, wantErr: true},
+		{name: "zero change", adjustment: requests.StockAdjustment{Change: 0}, wantErr: true},
+		{name: "admins can't record a sale", adjustment: requests.StockAdjustment{Change: -1, Reason: domain.StockSale}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inventory := &stockInventoryRepo{}
+			products := NewProductUsecase(&variantProductRepo{variants: []domain.ProductVariant{{ID: 40, ProductID: 4}}}, inventory, nil)
+
+			movement, err := products.AdjustStock(context.Background(), 4, 1, tt.adjustment)
+			if tt.wantErr {
+				if err == nil || len(inventory.moved) != 0 {
+					t.Fatalf("expected the adjustment to be refused before reaching the repository, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if movement.VariantID != 40 || movement.AdminID != 1 || movement.QtyChange != tt.adjustment.Change || movement.Reason != tt.wantReason {
+				t.Fatalf("unexpected movement %+v", movement)
+			}
+		})
+	}
+}
+
+func TestReorderThresholdCantBeNegative(t *testing.T) {
+	inventory := &stockInventoryRepo{thresholds: map[int]int{}}
+	products := NewProductUsecase(nil, inventory, nil)
+
+	if err := products.SetReorderThreshold(context.Background(), 4, -1); err == nil {
+		t.Fatal("expected a negative threshold to be refused")
+	}
+	if err := products.SetReorderThreshold(context.Background(), 4, 0); err != nil {
+		t.Fatal(err)
+	}
+	if threshold, ok := inventory.thresholds[4]; !ok || threshold != 0 {
+		t.Fatalf("expected the threshold to be cleared, got %v", inventory.thresholds)
+	}
+}
+
+func TestPickVariant(t *testing.T) {
+	single := []domain.ProductVariant{{ID: 40, SKU: "W-40"}}
+	several := []domain.ProductVariant{{ID: 40, SKU: "W-40"}, {ID: 41, SKU: "W-41"}}
+
+	tests := []struct {
+		name      string
+		variants  []domain.ProductVariant
+		variantId uint
+		want      uint
+		wantErr   bool
+	}{
+		{name: "the only variant is picked without an id", variants: single, want: 40},
+		{name: "the chosen variant", variants: several, variantId: 41, want: 41},
+		{name: "a product with several variants needs an id", variants: several, wantErr: true},
+		{name: "a variant of another product", variants: several, variantId: 7, wantErr: true},
+		{name: "a product without variants", variants: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variant, err := pickVariant(tt.variants, tt.variantId)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, picked %+v", variant)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if variant.ID != tt.want {
+				t.Fatalf("expected variant %d, got %d", tt.want, variant.ID)
+			}
+		})
+	}
+}
+
+func TestUpdateVariantLeavesStockToAdjustments(t *testing.T) {
+	products := &variantProductRepo{}
+	usecase := NewProductUsecase(products, &stockInventoryRepo{}, nil)
+
+	if _, err := usecase.UpdateVariant(context.Background(), 40, requests.Variant{QtyInStock: 5}); err == nil {
+		t.Fatal("expected a stock change to be refused")
+	}
+	if _, err := usecase.UpdateVariant(context.Background(), 40, requests.Variant{Sku: "W-40-BLK"}); err != nil {
+		t.Fatal(err)
+	}
+	// no price in the request keeps the current one
+	if len(products.updated) != 1 || products.updated[0].Price != -1 || products.updated[0].SKU != "W-40-BLK" {
+		t.Fatalf("unexpected variant update %+v", products.updated)
+	}
+}
+
+func TestAddVariantNeedsAPrice(t *testing.T) {
+	usecase := NewProductUsecase(&variantProductRepo{}, &stockInventoryRepo{}, nil)
+	if _, err := usecase.AddVariant(context.Background(), 4, 1, requests.Variant{Sku: "W-42"}); err == nil {
+		t.Fatal("expected a variant without a price to be refused")
+	}
+}
@@ -0,0 +1,236 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"fmt"
+	"testing"
+)
+
+// memoryRefundRepo keeps refunds in memory, approving one only checks the amount
+type memoryRefundRepo struct {
+	interfaces.RefundRepo
+	refunds map[uint]domain.Refund
+}
+
+func (r *memoryRefundRepo) FindRefundByID(ctx context.Context, refundId uint) (domain.Refund, error) {
+	return r.refunds[refundId], nil
+}
+
+func (r *memoryRefundRepo) ApproveRefund(ctx context.Context, refundId uint, amount float64, adminId uint) (domain.Refund, error) {
+	refund := r.refunds[refundId]
+	if refund.Status != domain.RefundPending {
+		return domain.Refund{}, fmt.Errorf("the refund is already %s", refund.Status)
+	}
+	if amount < 0 || amount > refund.Amount {
+		return domain.Refund{}, fmt.Errorf("refund amount must be between 0 and %.2f", refund.Amount)
+	}
+	if amount > 0 {
+		refund.Amount = amount
+	}
+	refund.Status = domain.RefundProcessed
+	if refund.RefundTo == domain.RefundToSource {
+		refund.Status = domain.RefundApproved
+	}
+	r.refunds[refundId] = refund
+	return refund, nil
+}
+
+func (r *memoryRefundRepo) UpdateRefundStatus(ctx context.Context, refundId uint, status, gatewayRefundId string) error {
+	refund := r.refunds[refundId]
+	refund.Status = status
+	refund.GatewayRefundID = gatewayRefundId
+	r.refunds[refundId] = refund
+	return nil
+}
+
+func (r *memoryRefundRepo) MarkGatewayRefundProcessed(ctx context.Context, gatewayRefundId string) error {
+	for id, refund := range r.refunds {
+		if refund.GatewayRefundID == gatewayRefundId {
+			refund.Status = domain.RefundProcessed
+			r.refunds[id] = refund
+		}
+	}
+	return nil
+}
+
+// newRefundFixture is a checkout fixture whose refunds are kept in memory,
+// refund 1 asks for 400 of a razorpay payment back to its source and refund 2
+// for 100 to the wallet
+func newRefundFixture(t *testing.T) (checkoutFixture, *memoryRefundRepo, string) {
+	t.Helper()
+	f := newCheckoutFixture(t)
+	_, paymentId, _ := f.pay(t)
+
+	refunds := &memoryRefundRepo{refunds: map[uint]domain.Refund{
+		1: {ID: 1, OrderID: 1, UserID: 7, Amount: 400, RefundTo: domain.RefundToSource, GatewayPaymentID: paymentId, Status: domain.RefundPending},
+		2: {ID: 2, OrderID: 1, UserID: 7, Amount: 100, RefundTo: domain.RefundToWallet, Status: domain.RefundPending},
+	}}
+	f.usecase.refundRepo = refunds
+	return f, refunds, paymentId
+}
+
+func TestApproveRefund(t *testing.T) {
+	tests := []struct {
+		name        string
+		refundId    uint
+		amount      float64
+		wantStatus  string
+		wantGateway float64
+		wantErr     bool
+	}{
+		{name: "source refund goes to the gateway", refundId: 1, wantStatus: domain.RefundProcessed, wantGateway: 400},
+		{name: "admin can refund less than asked", refundId: 1, amount: 250, wantStatus: domain.RefundProcessed, wantGateway: 250},
+		{name: "admin can't refund more than asked", refundId: 1, amount: 450, wantErr: true},
+		{name: "wallet refund never reaches the gateway", refundId: 2, wantStatus: domain.RefundProcessed},
+		{name: "unknown refund", refundId: 9, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, refunds, paymentId := newRefundFixture(t)
+
+			refund, err := f.usecase.ApproveRefund(context.Background(), tt.refundId, tt.amount, 1)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected the refund to be refused")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if refund.Status != tt.wantStatus || refunds.refunds[tt.refundId].Status != tt.wantStatus {
+				t.Fatalf("expected the refund to be %s, got %+v", tt.wantStatus, refunds.refunds[tt.refundId])
+			}
+			if refunded := f.gateway.RefundedAmount(paymentId); refunded != tt.wantGateway {
+				t.Fatalf("expected %.2f refunded by the gateway, got %.2f", tt.wantGateway, refunded)
+			}
+		})
+	}
+}
+
+func TestFailedGatewayRefundCanBeSentAgain(t *testing.T) {
+	f, refunds, paymentId := newRefundFixture(t)
+	refund := refunds.refunds[1]
+	refund.GatewayPaymentID = "pay_unknown"
+	refunds.refunds[1] = refund
+
+	if _, err := f.usecase.ApproveRefund(context.Background(), 1, 0, 1); err == nil {
+		t.Fatal("expected the gateway to refuse a payment it doesn't know")
+	}
+	if status := refunds.refunds[1].Status; status != domain.RefundFailed {
+		t.Fatalf("expected the refund to be failed, it is %s", status)
+	}
+
+	// the failed refund is sent again as it is, it isn't approved a second time
+	refund = refunds.refunds[1]
+	refund.GatewayPaymentID = paymentId
+	refunds.refunds[1] = refund
+	if _, err := f.usecase.ApproveRefund(context.Background(), 1, 0, 1); err != nil {
+		t.Fatal(err)
+	}
+	if refunded := f.gateway.RefundedAmount(paymentId); refunded != 400 {
+		t.Fatalf("expected 400 refunded, got %.2f", refunded)
+	}
+}
+
+func TestRefundProcessedWebhookMarksTheRefund(t *testing.T) {
+	f, refunds, paymentId := newRefundFixture(t)
+	refund := refunds.refunds[1]
+	refund.Status = domain.RefundApproved
+	refund.GatewayRefundID = "rfnd_1"
+	refunds.refunds[1] = refund
+
+	payload := []byte(fmt.Sprintf(`{"event":"refund.processed","payload":{"refund":{"entity":{"id":"rfnd_1","payment_id":%q,"amount":40000,"status":"processed"}}}}`, paymentId))
+	if err := f.usecase.RazorpayWebhook(context.Background(), "evt_refund", f.gateway.SignWebhook(payload), payload); err != nil {
+		t.Fatal(err)
+	}
+	if status := refunds.refunds[1].Status; status != domain.RefundProcessed {
+		t.Fatalf("expected the refund to be processed, it is %s", status)
+	}
+}
+
+func TestRefundToMustBeWalletOrSource(t *testing.T) {
+	for _, refundTo := range []string{domain.RefundToWallet, domain.RefundToSource} {
+		if err := validRefundTo(refundTo); err != nil {
+			t.Fatalf("%s should be allowed: %v", refundTo, err)
+		}
+	}
+	for _, refundTo := range []string{"", "card", "Wallet"} {
+		if err := validRefundTo(refundTo); err == nil {
+			t.Fatalf("%q should be refused", refundTo)
+		}
+	}
+}
+
+// lineOrderRepo cancels order lines by handing out one of the fixture refunds
+type lineOrderRepo struct {
+	*checkoutOrderRepo
+	refunds  *memoryRefundRepo
+	refundId uint
+	calls    int
+}
+
+func (r *lineOrderRepo) CancelOrderLine(ctx context.Context, userId, orderId, lineId, qty int, refundTo string) (domain.Refund, error) {
+	r.calls++
+	refund := r.refunds.refunds[r.refundId]
+	refund.Status = domain.RefundApproved
+	if refund.RefundTo == domain.RefundToWallet {
+		refund.Status = domain.RefundProcessed
+	}
+	r.refunds.refunds[r.refundId] = refund
+	return refund, nil
+}
+
+func TestCancelOrderLine(t *testing.T) {
+	tests := []struct {
+		name        string
+		refundId    uint
+		qty         int
+		refundTo    string
+		wantStatus  string
+		wantGateway float64
+		wantErr     bool
+	}{
+		{name: "source refund is paid out by the gateway", refundId: 1, qty: 1, refundTo: domain.RefundToSource, wantStatus: domain.RefundProcessed, wantGateway: 400},
+		{name: "wallet refund never reaches the gateway", refundId: 2, qty: 1, refundTo: domain.RefundToWallet, wantStatus: domain.RefundProcessed},
+		{name: "zero qty cancels what is left", refundId: 1, refundTo: domain.RefundToSource, wantStatus: domain.RefundProcessed, wantGateway: 400},
+		{name: "negative qty", refundId: 1, qty: -1, refundTo: domain.RefundToSource, wantErr: true},
+		{name: "unknown refund_to", refundId: 1, qty: 1, refundTo: "card", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, refunds, paymentId := newRefundFixture(t)
+			orders := &lineOrderRepo{checkoutOrderRepo: f.orders, refunds: refunds, refundId: tt.refundId}
+			f.usecase.orderRepo = orders
+
+			err := f.usecase.CancelOrderLine(context.Background(), 7, 1, 1, tt.qty, tt.refundTo)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("expected the cancel to be refused")
+				}
+				if orders.calls != 0 {
+					t.Fatal("a refused cancel shouldn't reach the repository")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if status := refunds.refunds[tt.refundId].Status; status != tt.wantStatus {
+				t.Fatalf("expected the refund to be %s, it is %s", tt.wantStatus, status)
+			}
+			if refunded := f.gateway.RefundedAmount(paymentId); refunded != tt.wantGateway {
+				t.Fatalf("expected %.2f refunded by the gateway, got %.2f", tt.wantGateway, refunded)
+			}
+		})
+	}
+}
+
+func TestOrderLineActiveQty(t *testing.T) {
+	line := domain.OrderLine{Qty: 5, CancelledQty: 2, ReturnedQty: 1}
+	if qty := line.ActiveQty(); qty != 2 {
+		t.Fatalf("expected 2 still ordered, got %d", qty)
+	}
+}
@@ -0,0 +1,118 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	services "ecommerce/pkg/usecase/interface"
+	"errors"
+	"strings"
+)
+
+type reviewUseCase struct {
+	reviewRepo interfaces.ReviewRepo
+}
+
+func NewReviewUseCase(reviewRepo interfaces.ReviewRepo) services.ReviewUseCase {
+	return &reviewUseCase{
+		reviewRepo: reviewRepo,
+	}
+}
+
+// AddReview lets a user who got the product delivered review it once
+func (c *reviewUseCase) AddReview(ctx context.Context, userId, productId uint, review requests.Review) (domain.Review, error) {
+	verified, err := c.reviewRepo.IsVerifiedBuyer(ctx, userId, productId)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	if !verified {
+		return domain.Review{}, errors.New("only customers who received this product can review it")
+	}
+
+	existing, err := c.reviewRepo.FindUserReview(ctx, userId, productId)
+	if err != nil {
+		return domain.Review{}, err
+	} else if existing.ID != 0 {
+		return domain.Review{}, errors.New("you have already reviewed this product, edit that review instead")
+	}
+
+	return c.reviewRepo.SaveReview(ctx, domain.Review{
+		ProductID: productId,
+		UserID:    userId,
+		Rating:    review.Rating,
+		Body:      strings.TrimSpace(review.Review),
+	})
+}
+
+func (c *reviewUseCase) UpdateReview(ctx context.Context, userId, reviewId uint, review requests.Review) (domain.Review, error) {
+	existing, err := c.findOwnReview(ctx, userId, reviewId)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	existing.Rating = review.Rating
+	existing.Body = strings.TrimSpace(review.Review)
+	return c.reviewRepo.UpdateReview(ctx, existing)
+}
+
+func (c *reviewUseCase) DeleteReview(ctx context.Context, userId, reviewId uint) error {
+	if _, err := c.findOwnReview(ctx, userId, reviewId); err != nil {
+		return err
+	}
+	return c.reviewRepo.DeleteReview(ctx, reviewId)
+}
+
+// ProductReviews lists a product's published reviews, most helpful first unless sort says otherwise
+func (c *reviewUseCase) ProductReviews(ctx context.Context, productId uint, sort string, pagination requests.Pagination) ([]response.Review, error) {
+	if sort == "" {
+		sort = "helpful"
+	}
+	return c.reviewRepo.FindProductReviews(ctx, productId, sort, pagination)
+}
+
+func (c *reviewUseCase) MarkHelpful(ctx context.Context, userId, reviewId uint) error {
+	review, err := c.reviewRepo.FindReview(ctx, reviewId)
+	if err != nil {
+		return err
+	}
+	if review.ID == 0 || review.Status != domain.ReviewPublished {
+		return errors.New("review not found")
+	}
+	if review.UserID == userId {
+		return errors.New("you can't vote for your own review")
+	}
+	return c.reviewRepo.AddHelpfulVote(ctx, reviewId, userId)
+}
+
+func (c *reviewUseCase) UnmarkHelpful(ctx context.Context, userId, reviewId uint) error {
+	return c.reviewRepo.RemoveHelpfulVote(ctx, reviewId, userId)
+}
+
+func (c *reviewUseCase) ListReviews(ctx context.Context, status string, pagination requests.Pagination) ([]response.Review, error) {
+	if status != "" && status != domain.ReviewPublished && status != domain.ReviewHidden {
+		return nil, errors.New("status must be published or hidden")
+	}
+	return c.reviewRepo.FindReviews(ctx, status, pagination)
+}
+
+// HideReview takes a review out of the listing and the product's rating
+func (c *reviewUseCase) HideReview(ctx context.Context, reviewId uint, reason string, adminId uint) (domain.Review, error) {
+	return c.reviewRepo.SetReviewStatus(ctx, reviewId, domain.ReviewHidden, strings.TrimSpace(reason), adminId)
+}
+
+func (c *reviewUseCase) PublishReview(ctx context.Context, reviewId uint, adminId uint) (domain.Review, error) {
+	return c.reviewRepo.SetReviewStatus(ctx, reviewId, domain.ReviewPublished, "", adminId)
+}
+
+// findOwnReview finds the review when it was written by the user
+func (c *reviewUseCase) findOwnReview(ctx context.Context, userId, reviewId uint) (domain.Review, error) {
+	review, err := c.reviewRepo.FindReview(ctx, reviewId)
+	if err != nil {
+		return domain.Review{}, err
+	}
+	if review.ID == 0 || review.UserID != userId {
+		return domain.Review{}, errors.New("review not found")
+	}
+	return review, nil
+}
@@ -0,0 +1,164 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"testing"
+)
+
+// memoryReviewRepo keeps reviews and helpful votes in memory. buyers are the
+// users who got product 4 delivered
+type memoryReviewRepo struct {
+	interfaces.ReviewRepo
+	buyers  map[uint]bool
+	reviews map[uint]domain.Review
+	votes   map[uint][]uint
+}
+
+func newMemoryReviewRepo(buyers ...uint) *memoryReviewRepo {
+	r := &memoryReviewRepo{buyers: map[uint]bool{}, reviews: map[uint]domain.Review{}, votes: map[uint][]uint{}}
+	for _, userId := range buyers {
+		r.buyers[userId] = true
+	}
+	return r
+}
+
+func (r *memoryReviewRepo) IsVerifiedBuyer(ctx context.Context, userId, productId uint) (bool, error) {
+	return productId == 4 && r.buyers[userId], nil
+}
+
+func (r *memoryReviewRepo) FindReview(ctx context.Context, reviewId uint) (domain.Review, error) {
+	return r.reviews[reviewId], nil
+}
+
+func (r *memoryReviewRepo) FindUserReview(ctx context.Context, userId, productId uint) (domain.Review, error) {
+	for _, review := range r.reviews {
+		if review.UserID == userId && review.ProductID == productId {
+			return review, nil
+		}
+	}
+	return domain.Review{}, nil
+}
+
+func (r *memoryReviewRepo) SaveReview(ctx context.Context, review domain.Review) (domain.Review, error) {
+	review.ID = uint(len(r.reviews) + 1)
+	review.Status = domain.ReviewPublished
+	r.reviews[review.ID] = review
+	return review, nil
+}
+
+func (r *memoryReviewRepo) UpdateReview(ctx context.Context, review domain.Review) (domain.Review, error) {
+	r.reviews[review.ID] = review
+	return review, nil
+}
+
+func (r *memoryReviewRepo) DeleteReview(ctx context.Context, reviewId uint) error {
+	delete(r.reviews, reviewId)
+	return nil
+}
+
+func (r *memoryReviewRepo) FindReviews(ctx context.Context, status string, pagination requests.Pagination) ([]response.Review, error) {
+	return nil, nil
+}
+
+func (r *memoryReviewRepo) AddHelpfulVote(ctx context.Context, reviewId, userId uint) error {
+	r.votes[reviewId] = append(r.votes[reviewId], userId)
+	return nil
+}
+
+func TestAddReview(t *testing.T) {
+	ctx := context.Background()
+	reviews := newMemoryReviewRepo(7)
+	usecase := NewReviewUseCase(reviews)
+
+	if _, err := usecase.AddReview(ctx, 8, 4, requests.Review{Rating: 5}); err == nil {
+		t.Fatal("expected a user who never got the product to be refused")
+	}
+	if _, err := usecase.AddReview(ctx, 7, 5, requests.Review{Rating: 5}); err == nil {
+		t.Fatal("expected a review of a product the user didn't buy to be refused")
+	}
+
+	review, err := usecase.AddReview(ctx, 7, 4, requests.Review{Rating: 4, Review: "  keeps good time \n"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if review.Body != "keeps good time" || review.Rating != 4 || review.Status != domain.ReviewPublished {
+		t.Fatalf("unexpected review %+v", review)
+	}
+	if _, err = usecase.AddReview(ctx, 7, 4, requests.Review{Rating: 1}); err == nil {
+		t.Fatal("expected a second review of the same product to be refused")
+	}
+}
+
+func TestOnlyTheAuthorChangesAReview(t *testing.T) {
+	ctx := context.Background()
+	reviews := newMemoryReviewRepo(7)
+	usecase := NewReviewUseCase(reviews)
+	review, err := usecase.AddReview(ctx, 7, 4, requests.Review{Rating: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = usecase.UpdateReview(ctx, 8, review.ID, requests.Review{Rating: 1}); err == nil {
+		t.Fatal("expected another user's edit to be refused")
+	}
+	if err = usecase.DeleteReview(ctx, 8, review.ID); err == nil {
+		t.Fatal("expected another user's delete to be refused")
+	}
+	if updated, err := usecase.UpdateReview(ctx, 7, review.ID, requests.Review{Rating: 2, Review: "stopped after a week"}); err != nil || updated.Rating != 2 {
+		t.Fatalf("expected the author's edit to be saved, got %+v (%v)", updated, err)
+	}
+	if err = usecase.DeleteReview(ctx, 7, review.ID); err != nil {
+		t.Fatal(err)
+	}
+	if len(reviews.reviews) != 0 {
+		t.Fatal("expected the review to be deleted")
+	}
+}
+
+func TestMarkHelpful(t *testing.T) {
+	ctx := context.Background()
+	reviews := newMemoryReviewRepo()
+	reviews.reviews[1] = domain.Review{ID: 1, UserID: 7, Status: domain.ReviewPublished}
+	reviews.reviews[2] = domain.Review{ID: 2, UserID: 7, Status: domain.ReviewHidden}
+	usecase := NewReviewUseCase(reviews)
+
+	tests := []struct {
+		name     string
+		userId   uint
+		reviewId uint
+		wantErr  bool
+	}{
+		{name: "another user's review", userId: 8, reviewId: 1},
+		{name: "own review", userId: 7, reviewId: 1, wantErr: true},
+		{name: "hidden review", userId: 8, reviewId: 2, wantErr: true},
+		{name: "missing review", userId: 8, reviewId: 3, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := usecase.MarkHelpful(ctx, tt.userId, tt.reviewId)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("wantErr %v, got %v", tt.wantErr, err)
+			}
+		})
+	}
+	if votes := reviews.votes[1]; len(votes) != 1 || votes[0] != 8 {
+		t.Fatalf("expected one vote from user 8, got %v", votes)
+	}
+}
+
+func TestListReviewsChecksTheStatus(t *testing.T) {
+	usecase := NewReviewUseCase(newMemoryReviewRepo())
+	pagination := requests.Pagination{Page: 1, PerPage: 10}
+	for _, status := range []string{"", domain.ReviewPublished, domain.ReviewHidden} {
+		if _, err := usecase.ListReviews(context.Background(), status, pagination); err != nil {
+			t.Fatalf("%q should be allowed: %v", status, err)
+		}
+	}
+	if _, err := usecase.ListReviews(context.Background(), "deleted", pagination); err == nil {
+		t.Fatal("expected an unknown status to be refused")
+	}
+}
@@ -0,0 +1,73 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/storage"
+	"testing"
+)
+
+// searchProductRepo remembers the search it was asked for and finds one
+// product with a primary image and one without
+type searchProductRepo struct {
+	variantProductRepo
+	asked []requests.ProductSearch
+}
+
+func (r *searchProductRepo) SearchProducts(ctx context.Context, search requests.ProductSearch) (response.ProductSearch, error) {
+	r.asked = append(r.asked, search)
+	return response.ProductSearch{
+		Products: []response.Product{{Id: 1, ThumbnailKey: "products/1/a_thumb.jpg"}, {Id: 2}},
+		Total:    2,
+		Page:     search.Page,
+		PerPage:  search.PerPage,
+	}, nil
+}
+
+func TestSearchProducts(t *testing.T) {
+	price := func(p int) *int { return &p }
+
+	tests := []struct {
+		name        string
+		search      requests.ProductSearch
+		wantQuery   string
+		wantPage    uint
+		wantPerPage uint
+		wantErr     bool
+	}{
+		{name: "page defaults to the first ten", search: requests.ProductSearch{Query: "  steel watch "}, wantQuery: "steel watch", wantPage: 1, wantPerPage: 10},
+		{name: "pagination is kept", search: requests.ProductSearch{Query: "watch", Page: 3, PerPage: 5}, wantQuery: "watch", wantPage: 3, wantPerPage: 5},
+		{name: "equal prices are a range", search: requests.ProductSearch{Query: "watch", MinPrice: price(500), MaxPrice: price(500)}, wantQuery: "watch", wantPage: 1, wantPerPage: 10},
+		{name: "blank text", search: requests.ProductSearch{Query: "   "}, wantErr: true},
+		{name: "min price above max price", search: requests.ProductSearch{Query: "watch", MinPrice: price(900), MaxPrice: price(100)}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := storage.NewLocalStorage(t.TempDir(), "http://localhost:3000/images")
+			if err != nil {
+				t.Fatal(err)
+			}
+			products := &searchProductRepo{}
+			usecase := NewProductUsecase(products, &stockInventoryRepo{}, store)
+
+			result, err := usecase.SearchProducts(context.Background(), tt.search)
+			if tt.wantErr {
+				if err == nil || len(products.asked) != 0 {
+					t.Fatalf("expected the search to be refused before reaching the repository, got %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			asked := products.asked[0]
+			if asked.Query != tt.wantQuery || asked.Page != tt.wantPage || asked.PerPage != tt.wantPerPage {
+				t.Fatalf("unexpected search %+v", asked)
+			}
+			if result.Products[0].Thumbnail != "http://localhost:3000/images/products/1/a_thumb.jpg" || result.Products[1].Thumbnail != "" {
+				t.Fatalf("unexpected thumbnails %q and %q", result.Products[0].Thumbnail, result.Products[1].Thumbnail)
+			}
+		})
+	}
+}
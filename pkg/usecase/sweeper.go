@@ -0,0 +1,27 @@
+package usecase
+
+import (
+	"context"
+	interfaces "ecommerce/pkg/repository/interface"
+	"time"
+)
+
+// Sweeper runs the background jobs that put expired state right, held stock
+// of unpaid checkouts and timed user blocks
+type Sweeper struct {
+	inventoryRepo interfaces.InventoryRepo
+	adminRepo     interfaces.AdminRepository
+}
+
+func NewSweeper(inventoryRepo interfaces.InventoryRepo, adminRepo interfaces.AdminRepository) *Sweeper {
+	return &Sweeper{
+		inventoryRepo: inventoryRepo,
+		adminRepo:     adminRepo,
+	}
+}
+
+// Start runs every sweep in its own goroutine every `every`, they stop when ctx is done
+func (s *Sweeper) Start(ctx context.Context, every time.Duration) {
+	go SweepExpiredReservations(ctx, s.inventoryRepo, every)
+	go SweepExpiredBlocks(ctx, s.adminRepo, every)
+}
@@ -0,0 +1,151 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	"ecommerce/pkg/token"
+	services "ecommerce/pkg/usecase/interface"
+	"time"
+)
+
+type tokenUseCase struct {
+	tokenRepo interfaces.TokenRepo
+	tokens    *token.Manager
+}
+
+func NewTokenUseCase(repo interfaces.TokenRepo, tokens *token.Manager) services.TokenUseCase {
+	return &tokenUseCase{
+		tokenRepo: repo,
+		tokens:    tokens,
+	}
+}
+
+// IssueTokens starts a new session for the subject on the device
+func (c *tokenUseCase) IssueTokens(ctx context.Context, subjectId uint, audience string, device requests.Device) (response.TokenPair, error) {
+	sessionId, err := token.RandomString(16)
+	if err != nil {
+		return response.TokenPair{}, err
+	}
+	refresh, record, err := c.newRefreshToken()
+	if err != nil {
+		return response.TokenPair{}, err
+	}
+	jti, err := token.RandomString(16)
+	if err != nil {
+		return response.TokenPair{}, err
+	}
+
+	session := domain.Session{
+		ID:        sessionId,
+		SubjectID: subjectId,
+		Audience:  audience,
+		AccessJTI: jti,
+		UserAgent: device.UserAgent,
+		IPAddress: device.IPAddress,
+		ExpiresAt: record.ExpiresAt,
+	}
+	if err := c.tokenRepo.CreateSession(ctx, session, record); err != nil {
+		return response.TokenPair{}, err
+	}
+	return c.pair(subjectId, audience, sessionId, jti, refresh)
+}
+
+// Refresh swaps a refresh token for a new access and refresh token pair,
+// the old refresh token can't be used again
+func (c *tokenUseCase) Refresh(ctx context.Context, refreshToken, audience string) (response.TokenPair, error) {
+	if refreshToken == "" {
+		return response.TokenPair{}, token.ErrInvalid
+	}
+	refresh, next, err := c.newRefreshToken()
+	if err != nil {
+		return response.TokenPair{}, err
+	}
+	jti, err := token.RandomString(16)
+	if err != nil {
+		return response.TokenPair{}, err
+	}
+	used, err := c.tokenRepo.RotateRefreshToken(ctx, token.Hash(refreshToken), audience, jti, next)
+	if err != nil {
+		return response.TokenPair{}, err
+	}
+	return c.pair(used.SubjectID, audience, used.FamilyID, jti, refresh)
+}
+
+// Revoke ends the session the refresh token belongs to
+func (c *tokenUseCase) Revoke(ctx context.Context, refreshToken string) error {
+	if refreshToken == "" {
+		return nil
+	}
+	return c.tokenRepo.RevokeSessionByToken(ctx, token.Hash(refreshToken))
+}
+
+// Authenticate accepts an access token of the audience that is the current
+// one of a live session, so logging out or a refresh turns it away at once
+func (c *tokenUseCase) Authenticate(ctx context.Context, accessToken, audience string) (token.Claims, error) {
+	claims, err := c.tokens.Verify(accessToken, audience)
+	if err != nil {
+		return token.Claims{}, err
+	}
+	active, err := c.tokenRepo.IsSessionActive(ctx, claims.Session, claims.Id)
+	if err != nil {
+		return token.Claims{}, err
+	}
+	if !active {
+		return token.Claims{}, token.ErrInvalid
+	}
+	return claims, nil
+}
+
+// Sessions lists the subject's signed in devices, marking currentSession
+func (c *tokenUseCase) Sessions(ctx context.Context, subjectId uint, audience, currentSession string) ([]response.Session, error) {
+	sessions, err := c.tokenRepo.FindSessions(ctx, subjectId, audience)
+	if err != nil {
+		return nil, err
+	}
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentSession
+	}
+	return sessions, nil
+}
+
+func (c *tokenUseCase) RevokeSession(ctx context.Context, subjectId uint, audience, sessionId string) error {
+	return c.tokenRepo.RevokeSession(ctx, subjectId, audience, sessionId)
+}
+
+// RevokeAll signs the subject out on every device
+func (c *tokenUseCase) RevokeAll(ctx context.Context, subjectId uint, audience string) error {
+	return c.tokenRepo.RevokeSessions(ctx, subjectId, audience, "")
+}
+
+// RevokeOthers signs the subject out on every device but the one of keepSession
+func (c *tokenUseCase) RevokeOthers(ctx context.Context, subjectId uint, audience, keepSession string) error {
+	return c.tokenRepo.RevokeSessions(ctx, subjectId, audience, keepSession)
+}
+
+func (c *tokenUseCase) newRefreshToken() (string, domain.RefreshToken, error) {
+	refresh, err := token.RandomString(32)
+	if err != nil {
+		return "", domain.RefreshToken{}, err
+	}
+	return refresh, domain.RefreshToken{
+		TokenHash: token.Hash(refresh),
+		ExpiresAt: time.Now().Add(c.tokens.RefreshTTL()),
+	}, nil
+}
+
+func (c *tokenUseCase) pair(subjectId uint, audience, sessionId, jti, refresh string) (response.TokenPair, error) {
+	access, _, err := c.tokens.Issue(subjectId, audience, sessionId, jti)
+	if err != nil {
+		return response.TokenPair{}, err
+	}
+	return response.TokenPair{
+		AccessToken:      access,
+		RefreshToken:     refresh,
+		TokenType:        "Bearer",
+		ExpiresIn:        int(c.tokens.AccessTTL().Seconds()),
+		RefreshExpiresIn: int(c.tokens.RefreshTTL().Seconds()),
+	}, nil
+}
@@ -0,0 +1,380 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/config"
+	"ecommerce/pkg/domain"
+	"ecommerce/pkg/token"
+	services "ecommerce/pkg/usecase/interface"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryTokenRepo keeps sessions and refresh tokens in memory and follows the
+// same rules as the repository's sql
+type memoryTokenRepo struct {
+	sessions map[string]*domain.Session
+	refresh  map[string]*domain.RefreshToken
+}
+
+func newMemoryTokenRepo() *memoryTokenRepo {
+	return &memoryTokenRepo{
+		sessions: map[string]*domain.Session{},
+		refresh:  map[string]*domain.RefreshToken{},
+	}
+}
+
+func (r *memoryTokenRepo) CreateSession(ctx context.Context, session domain.Session, refresh domain.RefreshToken) error {
+	session.CreatedAt, session.LastSeenAt = time.Now(), time.Now()
+	r.sessions[session.ID] = &session
+	refresh.FamilyID, refresh.SubjectID, refresh.Audience = session.ID, session.SubjectID, session.Audience
+	r.refresh[refresh.TokenHash] = &refresh
+	return nil
+}
+
+func (r *memoryTokenRepo) RotateRefreshToken(ctx context.Context, tokenHash, audience, accessJti string, next domain.RefreshToken) (domain.RefreshToken, error) {
+	current, ok := r.refresh[tokenHash]
+	if !ok || current.Audience != audience || current.ExpiresAt.Before(time.Now()) {
+		return domain.RefreshToken{}, token.ErrInvalid
+	}
+	session := r.sessions[current.FamilyID]
+	if current.UsedAt != nil {
+		if session.RevokedAt == nil {
+			now := time.Now()
+			session.RevokedAt = &now
+		}
+		return domain.RefreshToken{}, errors.New("refresh token was already used, sign in again")
+	}
+	if session.RevokedAt != nil {
+		return domain.RefreshToken{}, token.ErrInvalid
+	}
+	session.AccessJTI, session.LastSeenAt, session.ExpiresAt = accessJti, time.Now(), next.ExpiresAt
+	now := time.Now()
+	current.UsedAt = &now
+	next.FamilyID, next.SubjectID, next.Audience = current.FamilyID, current.SubjectID, current.Audience
+	r.refresh[next.TokenHash] = &next
+	return *current, nil
+}
+
+func (r *memoryTokenRepo) IsSessionActive(ctx context.Context, sessionId, accessJti string) (bool, error) {
+	session, ok := r.sessions[sessionId]
+	return ok && session.AccessJTI == accessJti && session.RevokedAt == nil && session.ExpiresAt.After(time.Now()), nil
+}
+
+func (r *memoryTokenRepo) FindSessions(ctx context.Context, subjectId uint, audience string) ([]response.Session, error) {
+	var sessions []response.Session
+	for _, session := range r.sessions {
+		if session.SubjectID == subjectId && session.Audience == audience && session.RevokedAt == nil {
+			sessions = append(sessions, response.Session{ID: session.ID, ExpiresAt: session.ExpiresAt})
+		}
+	}
+	return sessions, nil
+}
+
+func (r *memoryTokenRepo) RevokeSessionByToken(ctx context.Context, tokenHash string) error {
+	if refresh, ok := r.refresh[tokenHash]; ok {
+		r.revoke(r.sessions[refresh.FamilyID])
+	}
+	return nil
+}
+
+func (r *memoryTokenRepo) RevokeSession(ctx context.Context, subjectId uint, audience, sessionId string) error {
+	session, ok := r.sessions[sessionId]
+	if !ok || session.SubjectID != subjectId || session.Audience != audience || session.RevokedAt != nil {
+		return errors.New("session not found")
+	}
+	r.revoke(session)
+	return nil
+}
+
+func (r *memoryTokenRepo) RevokeSessions(ctx context.Context, subjectId uint, audience, except string) error {
+	for _, session := range r.sessions {
+		if session.SubjectID == subjectId && session.Audience == audience && session.ID != except {
+			r.revoke(session)
+		}
+	}
+	return nil
+}
+
+func (r *memoryTokenRepo) revoke(session *domain.Session) {
+	if session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+}
+
+func newTestTokenUseCase(t *testing.T) services.TokenUseCase {
+	t.Helper()
+	manager, err := token.NewManager(config.Config{
+		JWT_KEYS:             "k1:" + strings.Repeat("a", 32),
+		ACCESS_TOKEN_MINUTES: 15,
+		REFRESH_TOKEN_DAYS:   30,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewTokenUseCase(newMemoryTokenRepo(), manager)
+}
+
+func signIn(t *testing.T, tokens services.TokenUseCase, subjectId uint, audience string) response.TokenPair {
+	t.Helper()
+	pair, err := tokens.IssueTokens(context.Background(), subjectId, audience, requests.Device{UserAgent: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pair
+}
+
+func assertAccess(t *testing.T, tokens services.TokenUseCase, pair response.TokenPair, audience string, want bool) {
+	t.Helper()
+	_, err := tokens.Authenticate(context.Background(), pair.AccessToken, audience)
+	if (err == nil) != want {
+		t.Fatalf("expected the access token to pass %v, got %v", want, err)
+	}
+}
+
+func TestRefreshRotatesTheTokens(t *testing.T) {
+	tokens := newTestTokenUseCase(t)
+	first := signIn(t, tokens, 1, token.AudienceUser)
+
+	second, err := tokens.Refresh(context.Background(), first.RefreshToken, token.AudienceUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.RefreshToken == first.RefreshToken || second.AccessToken == first.AccessToken {
+		t.Fatal("a refresh should hand out a new pair")
+	}
+	// only the newest access token of the session passes
+	assertAccess(t, tokens, first, token.AudienceUser, false)
+	assertAccess(t, tokens, second, token.AudienceUser, true)
+	claims, err := tokens.Authenticate(context.Background(), second.AccessToken, token.AudienceUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if claims.SubjectID() != 1 {
+		t.Fatalf("expected the refreshed token to keep subject 1, got %d", claims.SubjectID())
+	}
+
+	third, err := tokens.Refresh(context.Background(), second.RefreshToken, token.AudienceUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertAccess(t, tokens, third, token.AudienceUser, true)
+}
+
+func TestRefreshRefusesTokensItCantTrust(t *testing.T) {
+	tests := []struct {
+		name string
+		// refresh returns the refresh token and audience to try
+		refresh func(t *testing.T, tokens services.TokenUseCase, pair response.TokenPair) (string, string)
+		wantErr string
+	}{
+		{
+			name: "used twice",
+			refresh: func(t *testing.T, tokens services.TokenUseCase, pair response.TokenPair) (string, string) {
+				if _, err := tokens.Refresh(context.Background(), pair.RefreshToken, token.AudienceUser); err != nil {
+					t.Fatal(err)
+				}
+				return pair.RefreshToken, token.AudienceUser
+			},
+			wantErr: "already used",
+		},
+		{
+			name: "revoked",
+			refresh: func(t *testing.T, tokens services.TokenUseCase, pair response.TokenPair) (string, string) {
+				if err := tokens.Revoke(context.Background(), pair.RefreshToken); err != nil {
+					t.Fatal(err)
+				}
+				return pair.RefreshToken, token.AudienceUser
+			},
+			wantErr: token.ErrInvalid.Error(),
+		},
+		{
+			name: "other audience",
+			refresh: func(t *testing.T, tokens services.TokenUseCase, pair response.TokenPair) (string, string) {
+				return pair.RefreshToken, token.AudienceAdmin
+			},
+			wantErr: token.ErrInvalid.Error(),
+		},
+		{
+			name: "unknown token",
+			refresh: func(t *testing.T, tokens services.TokenUseCase, pair response.TokenPair) (string, string) {
+				return "not-a-refresh-token", token.AudienceUser
+			},
+			wantErr: token.ErrInvalid.Error(),
+		},
+		{
+			name: "empty token",
+			refresh: func(t *testing.T, tokens services.TokenUseCase, pair response.TokenPair) (string, string) {
+				return "", token.AudienceUser
+			},
+			wantErr: token.ErrInvalid.Error(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := newTestTokenUseCase(t)
+			pair := signIn(t, tokens, 1, token.AudienceUser)
+
+			refreshToken, audience := tt.refresh(t, tokens, pair)
+			_, err := tokens.Refresh(context.Background(), refreshToken, audience)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestReusedRefreshTokenEndsTheSignIn(t *testing.T) {
+	tokens := newTestTokenUseCase(t)
+	leaked := signIn(t, tokens, 1, token.AudienceUser)
+	rotated, err := tokens.Refresh(context.Background(), leaked.RefreshToken, token.AudienceUser)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := tokens.Refresh(context.Background(), leaked.RefreshToken, token.AudienceUser); err == nil {
+		t.Fatal("expected the used refresh token to be refused")
+	}
+	// reuse means the token leaked, the session ends for both copies
+	if _, err := tokens.Refresh(context.Background(), rotated.RefreshToken, token.AudienceUser); err != token.ErrInvalid {
+		t.Fatalf("expected the rotated refresh token to be revoked, got %v", err)
+	}
+	assertAccess(t, tokens, rotated, token.AudienceUser, false)
+
+	// other sign ins of the user are left alone
+	other := signIn(t, tokens, 1, token.AudienceUser)
+	if _, err := tokens.Refresh(context.Background(), other.RefreshToken, token.AudienceUser); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAuthenticateKeepsAudiencesApart(t *testing.T) {
+	tokens := newTestTokenUseCase(t)
+	user := signIn(t, tokens, 1, token.AudienceUser)
+	admin := signIn(t, tokens, 1, token.AudienceAdmin)
+
+	assertAccess(t, tokens, user, token.AudienceUser, true)
+	assertAccess(t, tokens, user, token.AudienceAdmin, false)
+	assertAccess(t, tokens, admin, token.AudienceAdmin, true)
+	assertAccess(t, tokens, admin, token.AudienceUser, false)
+}
+
+func sessionOf(t *testing.T, tokens services.TokenUseCase, pair response.TokenPair, audience string) string {
+	t.Helper()
+	claims, err := tokens.Authenticate(context.Background(), pair.AccessToken, audience)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return claims.Session
+}
+
+func TestSessionRevocation(t *testing.T) {
+	// a, b and c are devices of user 1, other is user 2 and admin is admin 1
+	tests := []struct {
+		name    string
+		revoke  func(t *testing.T, users services.UserUseCase, tokens services.TokenUseCase, pairs map[string]response.TokenPair) error
+		wantErr bool
+		revoked []string
+	}{
+		{
+			name: "logout",
+			revoke: func(t *testing.T, users services.UserUseCase, tokens services.TokenUseCase, pairs map[string]response.TokenPair) error {
+				return users.UserLogout(context.Background(), pairs["a"].RefreshToken)
+			},
+			revoked: []string{"a"},
+		},
+		{
+			name: "logout everywhere",
+			revoke: func(t *testing.T, users services.UserUseCase, tokens services.TokenUseCase, pairs map[string]response.TokenPair) error {
+				return users.LogoutEverywhere(context.Background(), 1)
+			},
+			revoked: []string{"a", "b", "c"},
+		},
+		{
+			name: "logout of one device",
+			revoke: func(t *testing.T, users services.UserUseCase, tokens services.TokenUseCase, pairs map[string]response.TokenPair) error {
+				return users.LogoutSession(context.Background(), 1, sessionOf(t, tokens, pairs["b"], token.AudienceUser))
+			},
+			revoked: []string{"b"},
+		},
+		{
+			name: "logout of another user's device",
+			revoke: func(t *testing.T, users services.UserUseCase, tokens services.TokenUseCase, pairs map[string]response.TokenPair) error {
+				return users.LogoutSession(context.Background(), 2, sessionOf(t, tokens, pairs["a"], token.AudienceUser))
+			},
+			wantErr: true,
+		},
+		{
+			name: "everywhere but this device",
+			revoke: func(t *testing.T, users services.UserUseCase, tokens services.TokenUseCase, pairs map[string]response.TokenPair) error {
+				return tokens.RevokeOthers(context.Background(), 1, token.AudienceUser, sessionOf(t, tokens, pairs["c"], token.AudienceUser))
+			},
+			revoked: []string{"a", "b"},
+		},
+		{
+			name: "logout with an unknown token",
+			revoke: func(t *testing.T, users services.UserUseCase, tokens services.TokenUseCase, pairs map[string]response.TokenPair) error {
+				return users.UserLogout(context.Background(), "not-a-refresh-token")
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens := newTestTokenUseCase(t)
+			users := NewUserUseCase(nil, nil, tokens)
+			pairs := map[string]response.TokenPair{
+				"a":     signIn(t, tokens, 1, token.AudienceUser),
+				"b":     signIn(t, tokens, 1, token.AudienceUser),
+				"c":     signIn(t, tokens, 1, token.AudienceUser),
+				"other": signIn(t, tokens, 2, token.AudienceUser),
+				"admin": signIn(t, tokens, 1, token.AudienceAdmin),
+			}
+
+			err := tt.revoke(t, users, tokens, pairs)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected an error %v, got %v", tt.wantErr, err)
+			}
+
+			revoked := map[string]bool{}
+			for _, device := range tt.revoked {
+				revoked[device] = true
+			}
+			for device, pair := range pairs {
+				audience := token.AudienceUser
+				if device == "admin" {
+					audience = token.AudienceAdmin
+				}
+				// a revoked session turns away its access token at once and can't be refreshed
+				assertAccess(t, tokens, pair, audience, !revoked[device])
+				_, err := tokens.Refresh(context.Background(), pair.RefreshToken, audience)
+				if (err == nil) == revoked[device] {
+					t.Fatalf("%s: expected the refresh to pass %v, got %v", device, !revoked[device], err)
+				}
+			}
+		})
+	}
+}
+
+func TestSessionsMarksTheCurrentOne(t *testing.T) {
+	tokens := newTestTokenUseCase(t)
+	current := sessionOf(t, tokens, signIn(t, tokens, 1, token.AudienceUser), token.AudienceUser)
+	signIn(t, tokens, 1, token.AudienceUser)
+
+	sessions, err := tokens.Sessions(context.Background(), 1, token.AudienceUser, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sessions) != 2 {
+		t.Fatalf("expected 2 sessions, got %d", len(sessions))
+	}
+	for _, session := range sessions {
+		if session.Current != (session.ID == current) {
+			t.Fatalf("session %s marked current %v", session.ID, session.Current)
+		}
+	}
+}
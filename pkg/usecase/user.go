@@ -6,22 +6,27 @@ import (
 	"ecommerce/pkg/commonhelp/response"
 	"ecommerce/pkg/domain"
 	interfaces "ecommerce/pkg/repository/interface"
+	"ecommerce/pkg/storage"
+	"ecommerce/pkg/token"
 	services "ecommerce/pkg/usecase/interface"
 	"fmt"
 	"time"
 
-	"github.com/golang-jwt/jwt"
 	"github.com/pkg/errors"
 	"golang.org/x/crypto/bcrypt"
 )
 
 type userUseCase struct {
 	userRepo interfaces.UserRepository
+	storage  storage.Storage
+	tokens   services.TokenUseCase
 }
 
-func NewUserUseCase(repo interfaces.UserRepository) services.UserUseCase {
+func NewUserUseCase(repo interfaces.UserRepository, storage storage.Storage, tokens services.TokenUseCase) services.UserUseCase {
 	return &userUseCase{
 		userRepo: repo,
+		storage:  storage,
+		tokens:   tokens,
 	}
 }
 
@@ -36,39 +41,61 @@ func (c *userUseCase) UserSignup(ctx context.Context, user requests.Usersign) (r
 	return UserValue, err
 }
 
-func (c *userUseCase) UserLogin(ctx context.Context, user requests.Login) (string, error) {
+func (c *userUseCase) UserLogin(ctx context.Context, user requests.Login, device requests.Device) (response.TokenPair, error) {
 	userData, err := c.userRepo.UserLogin(ctx, user.Email)
-	var userstatus domain.Users
 	if err != nil {
-		return "", err
+		return response.TokenPair{}, err
 	} else if userData.ID == 0 {
-		return "", fmt.Errorf("no user found")
+		return response.TokenPair{}, fmt.Errorf("no user found")
 	}
 
 	if user.Email == "" {
-		return "", fmt.Errorf("no user found")
+		return response.TokenPair{}, fmt.Errorf("no user found")
 	}
 
-	if userstatus.IsBlocked {
-		return "", fmt.Errorf("user is blocked")
-	}
 	err = bcrypt.CompareHashAndPassword([]byte(userData.Password), []byte(user.Password))
 	if err != nil {
-		return "", err
+		return response.TokenPair{}, err
 	}
-
-	claims := jwt.MapClaims{
-		"id":  userData.ID,
-		"exp": time.Now().Add(time.Hour * 72).Unix(),
+	if err := c.CheckBlocked(ctx, userData.ID); err != nil {
+		return response.TokenPair{}, err
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString([]byte("secret"))
+	return c.tokens.IssueTokens(ctx, userData.ID, token.AudienceUser, device)
+}
+
+// CheckBlocked fails with domain.ErrUserBlocked while the user is under a
+// block, saying until when for a timed one
+func (c *userUseCase) CheckBlocked(ctx context.Context, userId uint) error {
+	block, err := c.userRepo.FindActiveBlock(ctx, userId)
 	if err != nil {
-		return "", err
+		return err
 	}
+	if block.ID == 0 {
+		return nil
+	}
+	if block.BlockedUntil != nil {
+		return fmt.Errorf("%w until %s", domain.ErrUserBlocked, block.BlockedUntil.Format(time.RFC3339))
+	}
+	return domain.ErrUserBlocked
+}
+
+// UserLogout ends the session of the refresh token
+func (c *userUseCase) UserLogout(ctx context.Context, refreshToken string) error {
+	return c.tokens.Revoke(ctx, refreshToken)
+}
+
+// LogoutEverywhere ends every session of the user, the current one included
+func (c *userUseCase) LogoutEverywhere(ctx context.Context, userId uint) error {
+	return c.tokens.RevokeAll(ctx, userId, token.AudienceUser)
+}
+
+func (c *userUseCase) Sessions(ctx context.Context, userId uint, currentSession string) ([]response.Session, error) {
+	return c.tokens.Sessions(ctx, userId, token.AudienceUser, currentSession)
+}
 
-	return ss, nil
+func (c *userUseCase) LogoutSession(ctx context.Context, userId uint, sessionId string) error {
+	return c.tokens.RevokeSession(ctx, userId, token.AudienceUser, sessionId)
 }
 func (c *userUseCase) AddAdress(ctx context.Context, UserID int, address requests.AddressReq) (domain.Address, error) {
 	newAddress, err := c.userRepo.AddAdress(ctx, UserID, address)
@@ -85,25 +112,18 @@ func (c *userUseCase) VeiwAdress(ctx context.Context, UserID int) (domain.Addres
 	return adress, err
 }
 
-func (c *userUseCase) OtpLogin(mobno string) (string, error) {
+func (c *userUseCase) OtpLogin(ctx context.Context, mobno string, device requests.Device) (response.TokenPair, error) {
 	id, err := c.userRepo.OtpLogin(mobno)
 	if err != nil {
-		return "", err
+		return response.TokenPair{}, err
 	} else if id == 0 {
-		return "", errors.New("user not exist with given mobile number")
+		return response.TokenPair{}, errors.New("user not exist with given mobile number")
 	}
-
-	claims := jwt.MapClaims{
-		"id":  id,
-		"exp": time.Now().Add(time.Hour * 72).Unix(),
-	}
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	ss, err := token.SignedString([]byte("secret"))
-	if err != nil {
-		return "", err
+	if err := c.CheckBlocked(ctx, uint(id)); err != nil {
+		return response.TokenPair{}, err
 	}
 
-	return ss, nil
+	return c.tokens.IssueTokens(ctx, uint(id), token.AudienceUser, device)
 }
 func (c *userUseCase) AddToWishList(ctx context.Context, wishList domain.WishList) error {
 
@@ -149,5 +169,9 @@ func (c *userUseCase) RemoveFromWishList(ctx context.Context, wishList domain.Wi
 }
 
 func (c *userUseCase) ListWishlist(ctx context.Context, userID uint) ([]response.Wishlist, error) {
-	return c.userRepo.FindAllWishListItemsByUserID(ctx, userID)
+	wishlist, err := c.userRepo.FindAllWishListItemsByUserID(ctx, userID)
+	for i := range wishlist {
+		wishlist[i].Image = thumbnailURL(c.storage, wishlist[i].ThumbnailKey)
+	}
+	return wishlist, err
 }
@@ -0,0 +1,356 @@
+package usecase
+
+import (
+	"context"
+	"ecommerce/pkg/commonhelp/requests.go"
+	"ecommerce/pkg/commonhelp/response"
+	"ecommerce/pkg/domain"
+	interfaces "ecommerce/pkg/repository/interface"
+	services "ecommerce/pkg/usecase/interface"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// memoryUserRepo keeps users, their blocks and password resets in memory and
+// follows the same rules as the repository's sql. The blocks are locked as
+// the sweep closes them from its own goroutine
+type memoryUserRepo struct {
+	interfaces.UserRepository
+	mu     sync.Mutex
+	users  []domain.Users
+	blocks []domain.UserStatus
+	resets []domain.PasswordReset
+	err    error
+}
+
+// testUser is user 1 with the password
+func testUser(t *testing.T, password string) domain.Users {
+	t.Helper()
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return domain.Users{ID: 1, Email: "user@example.com", Mobile: "9876543210", Password: string(hash)}
+}
+
+func (r *memoryUserRepo) UserLogin(ctx context.Context, email string) (domain.Users, error) {
+	for _, user := range r.users {
+		if user.Email == email {
+			return user, nil
+		}
+	}
+	return domain.Users{}, nil
+}
+
+func (r *memoryUserRepo) OtpLogin(mobile string) (int, error) {
+	for _, user := range r.users {
+		if user.Mobile == mobile {
+			return int(user.ID), nil
+		}
+	}
+	return 0, nil
+}
+
+func (r *memoryUserRepo) FindUserByID(ctx context.Context, userId uint) (domain.Users, error) {
+	for _, user := range r.users {
+		if user.ID == userId {
+			return user, nil
+		}
+	}
+	return domain.Users{}, nil
+}
+
+func (r *memoryUserRepo) UpdatePassword(ctx context.Context, userId uint, passwordHash string) error {
+	for i := range r.users {
+		if r.users[i].ID == userId {
+			r.users[i].Password = passwordHash
+		}
+	}
+	return nil
+}
+
+func (r *memoryUserRepo) SavePasswordReset(ctx context.Context, reset domain.PasswordReset) error {
+	kept := r.resets[:0]
+	for _, saved := range r.resets {
+		if saved.UserID != reset.UserID || saved.UsedAt != nil {
+			kept = append(kept, saved)
+		}
+	}
+	r.resets = append(kept, reset)
+	return nil
+}
+
+func (r *memoryUserRepo) ResetPassword(ctx context.Context, tokenHash, passwordHash string) (uint, error) {
+	for i, reset := range r.resets {
+		if reset.TokenHash == tokenHash && reset.UsedAt == nil && reset.ExpiresAt.After(time.Now()) {
+			now := time.Now()
+			r.resets[i].UsedAt = &now
+			return reset.UserID, r.UpdatePassword(ctx, reset.UserID, passwordHash)
+		}
+	}
+	return 0, errors.New("reset token is invalid or expired")
+}
+
+func (r *memoryUserRepo) FindActiveBlock(ctx context.Context, userId uint) (domain.UserStatus, error) {
+	if r.err != nil {
+		return domain.UserStatus{}, r.err
+	}
+	return r.activeBlock(userId, time.Now()), nil
+}
+
+func (r *memoryUserRepo) activeBlock(userId uint, now time.Time) domain.UserStatus {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, block := range r.blocks {
+		if block.UsersID != userId || block.UnblockedAt != nil {
+			continue
+		}
+		if block.BlockedUntil == nil || block.BlockedUntil.After(now) {
+			return block
+		}
+	}
+	return domain.UserStatus{}
+}
+
+func (r *memoryUserRepo) unblockExpired(now time.Time) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var unblocked int64
+	for i, block := range r.blocks {
+		if block.UnblockedAt == nil && block.BlockedUntil != nil && !block.BlockedUntil.After(now) {
+			r.blocks[i].UnblockedAt = block.BlockedUntil
+			unblocked++
+		}
+	}
+	return unblocked
+}
+
+// sweepAdminRepo closes the users' run out blocks. It fails the first
+// failures sweeps and tells swept about every sweep
+type sweepAdminRepo struct {
+	interfaces.AdminRepository
+	users    *memoryUserRepo
+	failures int
+	swept    chan int64
+}
+
+func (r *sweepAdminRepo) UnblockExpired(ctx context.Context) (int64, error) {
+	if r.failures > 0 {
+		r.failures--
+		r.swept <- -1
+		return 0, errors.New("database is down")
+	}
+	unblocked := r.users.unblockExpired(time.Now())
+	r.swept <- unblocked
+	return unblocked, nil
+}
+
+// issuedTokens remembers who got tokens
+type issuedTokens struct {
+	services.TokenUseCase
+	issued []uint
+}
+
+func (t *issuedTokens) IssueTokens(ctx context.Context, subjectId uint, audience string, device requests.Device) (response.TokenPair, error) {
+	t.issued = append(t.issued, subjectId)
+	return response.TokenPair{AccessToken: "access", RefreshToken: "refresh"}, nil
+}
+
+func timeFromNow(d time.Duration) *time.Time {
+	at := time.Now().Add(d)
+	return &at
+}
+
+func TestCheckBlocked(t *testing.T) {
+	tests := []struct {
+		name      string
+		blocks    []domain.UserStatus
+		repoErr   error
+		blocked   bool
+		wantUntil bool
+	}{
+		{name: "never blocked"},
+		{
+			name:    "blocked until lifted",
+			blocks:  []domain.UserStatus{{ID: 1, UsersID: 1}},
+			blocked: true,
+		},
+		{
+			name:      "timed block still running",
+			blocks:    []domain.UserStatus{{ID: 1, UsersID: 1, BlockedUntil: timeFromNow(time.Hour)}},
+			blocked:   true,
+			wantUntil: true,
+		},
+		{
+			name:   "timed block ran out",
+			blocks: []domain.UserStatus{{ID: 1, UsersID: 1, BlockedUntil: timeFromNow(-time.Minute)}},
+		},
+		{
+			name:   "block lifted by an admin",
+			blocks: []domain.UserStatus{{ID: 1, UsersID: 1, UnblockedAt: timeFromNow(-time.Minute)}},
+		},
+		{
+			name: "old block ran out but a new one runs",
+			blocks: []domain.UserStatus{
+				{ID: 1, UsersID: 1, BlockedUntil: timeFromNow(-time.Hour)},
+				{ID: 2, UsersID: 1, BlockedUntil: timeFromNow(time.Hour)},
+			},
+			blocked:   true,
+			wantUntil: true,
+		},
+		{
+			name:   "another user is blocked",
+			blocks: []domain.UserStatus{{ID: 1, UsersID: 2}},
+		},
+		{
+			name:    "repository fails",
+			repoErr: errors.New("database is down"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &memoryUserRepo{blocks: tt.blocks, err: tt.repoErr}
+			err := NewUserUseCase(repo, nil, &issuedTokens{}).CheckBlocked(context.Background(), 1)
+
+			if errors.Is(err, domain.ErrUserBlocked) != tt.blocked {
+				t.Fatalf("expected blocked %v, got %v", tt.blocked, err)
+			}
+			if tt.repoErr != nil && !errors.Is(err, tt.repoErr) {
+				t.Fatalf("expected the repository error, got %v", err)
+			}
+			if tt.repoErr == nil && !tt.blocked && err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got := err != nil && strings.Contains(err.Error(), "until"); got != tt.wantUntil {
+				t.Fatalf("expected the block end in the error %v, got %v", tt.wantUntil, err)
+			}
+		})
+	}
+}
+
+func TestLoginRefusesBlockedUsers(t *testing.T) {
+	user := testUser(t, "password123")
+
+	tests := []struct {
+		name     string
+		blocks   []domain.UserStatus
+		password string
+		wantErr  error
+	}{
+		{name: "not blocked", password: "password123"},
+		{
+			name:     "blocked",
+			blocks:   []domain.UserStatus{{ID: 1, UsersID: 1}},
+			password: "password123",
+			wantErr:  domain.ErrUserBlocked,
+		},
+		{
+			name:     "timed block running",
+			blocks:   []domain.UserStatus{{ID: 1, UsersID: 1, BlockedUntil: timeFromNow(time.Hour)}},
+			password: "password123",
+			wantErr:  domain.ErrUserBlocked,
+		},
+		{
+			name:     "timed block ran out",
+			blocks:   []domain.UserStatus{{ID: 1, UsersID: 1, BlockedUntil: timeFromNow(-time.Second)}},
+			password: "password123",
+		},
+		{
+			name:     "wrong password",
+			password: "wrong",
+			wantErr:  bcrypt.ErrMismatchedHashAndPassword,
+		},
+	}
+	logins := map[string]func(services.UserUseCase, string) (response.TokenPair, error){
+		"password": func(users services.UserUseCase, password string) (response.TokenPair, error) {
+			return users.UserLogin(context.Background(), requests.Login{Email: user.Email, Password: password}, requests.Device{})
+		},
+		"otp": func(users services.UserUseCase, password string) (response.TokenPair, error) {
+			return users.OtpLogin(context.Background(), user.Mobile, requests.Device{})
+		},
+	}
+	for login, loginWith := range logins {
+		for _, tt := range tests {
+			if login == "otp" && tt.password != "password123" {
+				// the otp is checked before OtpLogin is reached
+				continue
+			}
+			t.Run(login+"/"+tt.name, func(t *testing.T) {
+				tokens := &issuedTokens{}
+				users := NewUserUseCase(&memoryUserRepo{users: []domain.Users{user}, blocks: tt.blocks}, nil, tokens)
+
+				_, err := loginWith(users, tt.password)
+				if tt.wantErr == nil {
+					if err != nil {
+						t.Fatalf("expected to log in, got %v", err)
+					}
+					if len(tokens.issued) != 1 || tokens.issued[0] != user.ID {
+						t.Fatalf("expected tokens for user %d, issued %v", user.ID, tokens.issued)
+					}
+					return
+				}
+				if !errors.Is(err, tt.wantErr) {
+					t.Fatalf("expected %v, got %v", tt.wantErr, err)
+				}
+				if len(tokens.issued) != 0 {
+					t.Fatalf("expected no tokens, issued %v", tokens.issued)
+				}
+			})
+		}
+	}
+}
+
+func TestSweepExpiredBlocksUnblocksOnlyRunOutBlocks(t *testing.T) {
+	users := &memoryUserRepo{blocks: []domain.UserStatus{
+		{ID: 1, UsersID: 1, BlockedUntil: timeFromNow(-time.Minute)},
+		{ID: 2, UsersID: 2, BlockedUntil: timeFromNow(time.Hour)},
+		{ID: 3, UsersID: 3},
+	}}
+	// the first sweep fails, the next one still runs
+	admins := &sweepAdminRepo{users: users, failures: 1, swept: make(chan int64)}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		SweepExpiredBlocks(ctx, admins, time.Millisecond)
+		close(done)
+	}()
+
+	for _, want := range []int64{-1, 1, 0} {
+		select {
+		case got := <-admins.swept:
+			if got != want {
+				t.Fatalf("expected the sweep to unblock %d, it unblocked %d", want, got)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("the sweep did not run")
+		}
+	}
+	cancel()
+	// sweeps already due may still run as ctx is cancelled, they are let finish
+	timeout := time.After(time.Second)
+	for stopped := false; !stopped; {
+		select {
+		case <-admins.swept:
+		case <-done:
+			stopped = true
+		case <-timeout:
+			t.Fatal("the sweep did not stop with its context")
+		}
+	}
+
+	now := time.Now()
+	for userId, wantBlocked := range map[uint]bool{1: false, 2: true, 3: true} {
+		if blocked := users.activeBlock(userId, now).ID != 0; blocked != wantBlocked {
+			t.Fatalf("user %d: expected blocked %v", userId, wantBlocked)
+		}
+	}
+	if users.blocks[0].UnblockedAt == nil {
+		t.Fatal("the run out block should be closed")
+	}
+}